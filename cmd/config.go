@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	memoryStorage = "memory"
+	sqliteStorage = "sqlite"
+)
+
+type config struct {
+	// storage is the repositories backend, either memory or sqlite
+	storage string
+	// sqlitePath is the database file used by the sqlite backend
+	sqlitePath string
+	// authDisabled authenticates every bearer token as a local admin and clinician, for development only
+	authDisabled bool
+	// authHMACSecret verifies HS256 tokens, authRSAPublicKeyFile points to the PEM key verifying RS256 tokens
+	authHMACSecret       string
+	authRSAPublicKeyFile string
+	authIssuer           string
+	authAudience         string
+	authRolesClaim       string
+	// hl7ListenAddr is the TCP address of the HL7 MLLP listener, which is not started when empty
+	hl7ListenAddr string
+	// hl7ActorID is the practitioner ID the HL7 messages are applied as
+	hl7ActorID string
+	// idempotencyTTL is how long the response to a request sent with an Idempotency-Key is replayed to its retries
+	idempotencyTTL time.Duration
+	// httpAddr is the TCP address the API listens on
+	httpAddr string
+	// httpTimeouts bound the connections of the API and how long the requests are drained when it stops
+	httpTimeouts http.Timeouts
+}
+
+func loadConfig() (config, error) {
+	authDisabled, err := strconv.ParseBool(getEnv("AUTH_DISABLED", "false"))
+	if err != nil {
+		return config{}, fmt.Errorf("invalid AUTH_DISABLED: %w", err)
+	}
+
+	idempotencyTTL, err := getDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return config{}, err
+	}
+
+	timeouts := http.DefaultTimeouts
+	for _, setting := range []struct {
+		key     string
+		timeout *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", &timeouts.Read},
+		{"HTTP_WRITE_TIMEOUT", &timeouts.Write},
+		{"HTTP_IDLE_TIMEOUT", &timeouts.Idle},
+		{"SHUTDOWN_TIMEOUT", &timeouts.Shutdown},
+	} {
+		if *setting.timeout, err = getDuration(setting.key, *setting.timeout); err != nil {
+			return config{}, err
+		}
+	}
+
+	cfg := config{
+		storage:              getEnv("STORAGE_BACKEND", memoryStorage),
+		sqlitePath:           getEnv("SQLITE_PATH", "diagnoses.db"),
+		authDisabled:         authDisabled,
+		authHMACSecret:       getEnv("AUTH_HMAC_SECRET", ""),
+		authRSAPublicKeyFile: getEnv("AUTH_RSA_PUBLIC_KEY_FILE", ""),
+		authIssuer:           getEnv("AUTH_ISSUER", ""),
+		authAudience:         getEnv("AUTH_AUDIENCE", ""),
+		authRolesClaim:       getEnv("AUTH_ROLES_CLAIM", ""),
+		hl7ListenAddr:        getEnv("HL7_LISTEN_ADDR", ""),
+		hl7ActorID:           getEnv("HL7_ACTOR_ID", "hl7-interface"),
+		idempotencyTTL:       idempotencyTTL,
+		httpAddr:             getEnv("HTTP_ADDR", ":8080"),
+		httpTimeouts:         timeouts,
+	}
+
+	if cfg.storage != memoryStorage && cfg.storage != sqliteStorage {
+		return config{}, fmt.Errorf("invalid STORAGE_BACKEND %q, expected %s or %s", cfg.storage, memoryStorage, sqliteStorage)
+	}
+
+	if !cfg.authDisabled && cfg.authHMACSecret == "" && cfg.authRSAPublicKeyFile == "" {
+		return config{}, fmt.Errorf("AUTH_HMAC_SECRET or AUTH_RSA_PUBLIC_KEY_FILE is required, set AUTH_DISABLED=true for local development")
+	}
+
+	return cfg, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a positive duration such as 30s or 24h", key, value)
+	}
+	return duration, nil
+}
@@ -1,9 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/codes/icd10"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/hl7"
 	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
 	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/storage/memory"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/storage/sqlite"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 // @Title			Patient Diagnoses API
@@ -11,9 +25,124 @@ import (
 // @Description		This is API service to handle patient diagnoses
 // @host			localhost:8080
 // @BasePath 		/api/v1
+//
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description					JWT sent as "Bearer {token}"
 func main() {
-	repository := memory.NewRepository()
-	appServices := app.NewServices(&repository, &repository)
-	server := http.NewServer(appServices)
-	server.Run(":8080")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx)
+	stop()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves until ctx is done, draining the requests being handled before the storage is closed.
+func run(ctx context.Context) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	icd10Catalog, err := icd10.NewCatalog()
+	if err != nil {
+		return err
+	}
+
+	var appServices app.Services
+	var idempotencyStore idempotency.Store
+	switch cfg.storage {
+	case sqliteStorage:
+		repository, err := sqlite.Open(cfg.sqlitePath)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := repository.Close(); err != nil {
+				slog.Error("error closing storage", "err", err)
+				return
+			}
+			slog.Info("storage closed")
+		}()
+		appServices = app.NewServices(repository, repository, repository, repository, icd10Catalog)
+		idempotencyStore = repository
+	default:
+		repository := memory.NewRepository()
+		appServices = app.NewServices(repository, repository, repository, repository, icd10Catalog)
+		idempotencyStore = repository
+	}
+
+	authenticator, err := newAuthenticator(cfg)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("storage backend selected", "storage", cfg.storage)
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	hl7Errs := make(chan error, 1)
+	if cfg.hl7ListenAddr != "" {
+		actor := auth.Principal{ID: cfg.hl7ActorID, Roles: []auth.Role{auth.RoleClinician}}
+		listener := hl7.NewListener(appServices, actor, idempotencyStore, cfg.idempotencyTTL)
+		go func() {
+			// the API is stopped too when the listener fails
+			hl7Errs <- listener.ListenAndServe(cfg.hl7ListenAddr)
+			stop()
+		}()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.httpTimeouts.Shutdown)
+			defer cancel()
+			if err := listener.Close(ctx); err != nil {
+				slog.Error("error closing HL7 listener", "err", err)
+			}
+		}()
+	}
+
+	server := http.NewServer(appServices, authenticator, idempotencyStore, cfg.idempotencyTTL, cfg.httpTimeouts)
+	if err := server.Run(ctx, cfg.httpAddr); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-hl7Errs:
+		if !errors.Is(err, hl7.ErrListenerClosed) {
+			return fmt.Errorf("HL7 listener: %w", err)
+		}
+	default:
+	}
+	return nil
+}
+
+func newAuthenticator(cfg config) (authentication.Authenticator, error) {
+	if cfg.authDisabled {
+		slog.Warn("authentication is disabled, every bearer token is accepted as a local admin and clinician")
+		return authentication.StaticAuthenticator{Principal: auth.Principal{
+			ID:    "local-developer",
+			Roles: []auth.Role{auth.RoleAdmin, auth.RoleClinician},
+		}}, nil
+	}
+
+	jwtConfig := authentication.JWTConfig{
+		HMACSecret: []byte(cfg.authHMACSecret),
+		Issuer:     cfg.authIssuer,
+		Audience:   cfg.authAudience,
+		RolesClaim: cfg.authRolesClaim,
+	}
+
+	if cfg.authRSAPublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.authRSAPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey, err := authentication.ParseRSAPublicKey(pem)
+		if err != nil {
+			return nil, err
+		}
+		jwtConfig.RSAPublicKey = publicKey
+	}
+
+	return authentication.NewJWTAuthenticator(jwtConfig)
 }
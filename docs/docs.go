@@ -15,59 +15,248 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/patient/diagnoses": {
+        "/audit/records": {
             "get": {
-                "description": "Get patient diagnoses",
-                "consumes": [
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get who read or changed patient data, filtered by patient and/or actor. Only admins and auditors are allowed.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Get audit trail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "records of this patient",
+                        "name": "patientId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "records of this actor",
+                        "name": "actorId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/audit.GetAuditTrailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/audit/verify": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Check the hash chain of the whole audit trail, reporting the first record changed or missing. Only admins and auditors are allowed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "diagnosis"
+                    "audit"
                 ],
-                "summary": "Get patient diagnoses",
+                "summary": "Verify audit trail",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/audit.VerifyAuditTrailResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/codes/icd10": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Search ICD-10 codes by code prefix, such as J45, or by keywords of their display, such as allergic asthma.\nCodes matching the prefix come first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "codes"
+                ],
+                "summary": "Search ICD-10 codes",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "diagnoses search by patient name",
-                        "name": "patientName",
+                        "description": "code prefix or keywords",
+                        "name": "q",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum number of codes, 20 by default and 100 at most",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.GetDiagnosesResponse"
+                            "$ref": "#/definitions/codes.SearchCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/diagnoses/{diagnosisID}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the current version of the diagnosis along with its patient. Diagnoses entered in error are\nnot found, their versions are still returned by the diagnosis history.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "diagnosis"
+                ],
+                "summary": "Get diagnosis",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "diagnosis ID",
+                        "name": "diagnosisID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.GetDiagnosisResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.HTTPError"
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.HTTPError"
+                            "$ref": "#/definitions/render.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.HTTPError"
+                            "$ref": "#/definitions/render.Problem"
                         }
                     }
                 }
             }
         },
-        "/patient/{patientID}/diagnoses": {
+        "/diagnoses/{diagnosisID}/amend": {
             "post": {
-                "description": "Add patient diagnosis",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Amend the diagnosis, its prescription and its coding, which are replaced by the ones sent.\nThe amendment is written as a new version with the reason, the previous ones are kept in its history.\nOnly clinicians can amend diagnoses, and diagnoses entered in error can't be amended.",
                 "consumes": [
                     "application/json"
                 ],
@@ -77,113 +266,2310 @@ const docTemplate = `{
                 "tags": [
                     "diagnosis"
                 ],
-                "summary": "Add patient diagnosis",
+                "summary": "Amend diagnosis",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "patient ID",
-                        "name": "patientID",
+                        "description": "diagnosis ID",
+                        "name": "diagnosisID",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "add diagnosis",
-                        "name": "diagnosis",
+                        "description": "amended diagnosis",
+                        "name": "amendment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.AddDiagnosisRequest"
+                            "$ref": "#/definitions/diagnoses.AmendDiagnosisRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.HTTPError"
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.HTTPError"
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/diagnoses.HTTPError"
+                            "$ref": "#/definitions/render.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "diagnoses.AddDiagnosisRequest": {
-            "type": "object",
-            "properties": {
-                "diagnosis": {
-                    "type": "string"
-                },
-                "prescription": {
-                    "type": "string"
-                }
-            }
         },
-        "diagnoses.Diagnosis": {
-            "type": "object",
-            "properties": {
-                "createdAt": {
-                    "type": "string"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "patientID": {
-                    "type": "string"
-                },
-                "prescription": {
-                    "type": "string"
+        "/diagnoses/{diagnosisID}/enter-in-error": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retract a diagnosis that should never have been written, such as one added to the wrong patient.\nIt is written as a new version with the reason. Searches stop returning the diagnosis, but its\nhistory is kept. Only clinicians can enter diagnoses in error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "diagnosis"
+                ],
+                "summary": "Enter diagnosis in error",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "diagnosis ID",
+                        "name": "diagnosisID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "reason of the retraction",
+                        "name": "retraction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.EnterInErrorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
                 }
             }
         },
-        "diagnoses.GetDiagnosesResponse": {
-            "type": "object",
-            "properties": {
-                "patient_diagnoses": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/diagnoses.Diagnosis"
+        "/diagnoses/{diagnosisID}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
                     }
-                },
+                ],
+                "description": "Get every version of the diagnosis from the first one, with who wrote each version, when and why.\nDiagnoses entered in error are only returned here.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "diagnosis"
+                ],
+                "summary": "Get diagnosis history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "diagnosis ID",
+                        "name": "diagnosisID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.DiagnosisHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add the diagnoses sent as Conditions in a batch or transaction Bundle, whose entries must be POST\nrequests to Condition. Batch entries are processed independently and the response has the outcome\nof each of them. Transaction entries are all validated, and their patients resolved, before they are\nadded together: if any fails, none is added and the response is an OperationOutcome locating every\nfailure.",
+                "consumes": [
+                    "application/fhir+json"
+                ],
+                "produces": [
+                    "application/fhir+json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Process FHIR batch or transaction Bundle",
+                "parameters": [
+                    {
+                        "description": "batch or transaction bundle",
+                        "name": "bundle",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/fhir.IncomingBundle"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.Bundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/Condition": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Search diagnoses as FHIR R4 Condition resources, returned in a searchset Bundle sorted by recorded date.\nrecorded-date can be repeated and takes the eq, ge, gt, le and lt prefixes, such as ge2024-03-01.",
+                "produces": [
+                    "application/fhir+json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Search FHIR Conditions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient/{id} or the patient ID",
+                        "name": "patient",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "date the diagnosis was recorded, with an optional prefix",
+                        "name": "recorded-date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 50 by default and 500 at most",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "set by the next link of the previous page",
+                        "name": "_cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.Bundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a diagnosis sent as a FHIR R4 Condition. The subject must reference the patient as Patient/{id},\nor by an identifier of the urn:diagnosis-service:legal-id system. The diagnosis is the code text,\nand the ICD-10 coding, if any, codes it. Only clinicians can add diagnoses.",
+                "consumes": [
+                    "application/fhir+json"
+                ],
+                "produces": [
+                    "application/fhir+json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Create FHIR Condition",
+                "parameters": [
+                    {
+                        "description": "condition",
+                        "name": "condition",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/fhir.Condition"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/MedicationRequest": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the prescriptions of the patient as FHIR R4 MedicationRequest resources, one per medication,\nreturned in a searchset Bundle. Prescriptions recorded as free text are a single MedicationRequest.\nThe results are paged by prescription, so a page can have more entries than _count, and the total is\nonly given when every MedicationRequest of the patient is in the page.",
+                "produces": [
+                    "application/fhir+json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Search FHIR MedicationRequests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient/{id} or the patient ID",
+                        "name": "patient",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "prescriptions per page, 50 by default and 500 at most",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "cursor of the next link of the previous page",
+                        "name": "_cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.Bundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/Patient/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the patient as a FHIR R4 Patient resource.",
+                "produces": [
+                    "application/fhir+json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Read FHIR Patient",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "patient ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.Patient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/fhir.OperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/patient/diagnoses": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get diagnoses filtered by patient, creation date and/or ICD-10 code. At least one filter is required.\nThe patient is identified by only one of patientId, legalId or patientName. When several patients\nhave the name the response is a 409 listing them, to search again by the ID of the right one.\nDates accept RFC 3339 timestamps or plain YYYY-MM-DD dates, which are read in the tz timezone.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "diagnosis"
+                ],
+                "summary": "Get patient diagnoses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "diagnoses search by patient ID",
+                        "name": "patientId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "diagnoses search by patient legal ID",
+                        "name": "legalId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "diagnoses search by patient name",
+                        "name": "patientName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "diagnoses created at or after this date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "diagnoses created at or before this date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA timezone for plain dates, UTC by default",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "diagnoses coded with this ICD-10 code",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "diagnoses coded with an ICD-10 code starting with this prefix",
+                        "name": "codePrefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 50 by default and 500 at most",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at:asc (default) or created_at:desc",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.GetDiagnosesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.AmbiguousPatientResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/patient/{patientID}/diagnoses": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add patient diagnosis. Only clinicians can add diagnoses, which record them as practitioner.\nThe created diagnosis is returned, along with its URL in the Location header.\nThe diagnosis can be coded in ICD-10, the code must be in the ICD-10 table.\nThe prescription is structured in medications, a string is still accepted as its notes.\nRetries sent with the same Idempotency-Key get the first response replayed, with the Idempotent-Replayed header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "diagnosis"
+                ],
+                "summary": "Add patient diagnosis",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "patient ID",
+                        "name": "patientID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "add diagnosis",
+                        "name": "diagnosis",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.AddDiagnosisRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "key making the request safe to retry, up to 255 characters",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.Diagnosis"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created diagnosis"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List every patient sorted by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patient"
+                ],
+                "summary": "List patients",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/patients.ListPatientsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a patient. The legal ID must not belong to another patient.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patient"
+                ],
+                "summary": "Create patient",
+                "parameters": [
+                    {
+                        "description": "patient to create",
+                        "name": "patient",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/patients.CreatePatientRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/patients.PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/search": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Search the patients whose name matches ignoring case, accents, word order and small typos, such as\njose perez for José Pérez. Candidates come from the best match, with the legal ID and birth date\nto tell apart patients with similar names.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patient"
+                ],
+                "summary": "Search patients by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "name, or part of it, of the patient",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum number of candidates, 10 by default and 50 at most",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/patients.SearchPatientsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{patientID}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the identity and contact data of a patient",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patient"
+                ],
+                "summary": "Get patient",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "patient ID",
+                        "name": "patientID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/patients.PatientResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the patient, to send as If-Match when updating it"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{patientID}/contact": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the address, phone and email of a patient. Send the ETag of the patient read as If-Match to\nupdate only that version, otherwise the contact replaces the one of the latest version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patient"
+                ],
+                "summary": "Update patient contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "patient ID",
+                        "name": "patientID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the patient read, a single one",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "new contact data",
+                        "name": "contact",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/patients.UpdateContactRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/patients.PatientResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the updated patient"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{patientID}/prescriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the prescriptions of every diagnosis of the patient, oldest first.\nPrescriptions recorded as free text are returned as notes without medications.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prescriptions"
+                ],
+                "summary": "Get patient prescriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "patient ID",
+                        "name": "patientID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, 50 by default and 500 at most",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/diagnoses.GetPatientPrescriptionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/render.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "audit.AuditRecordResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_id": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "patient_id": {
+                    "type": "string"
+                },
+                "prev_hash": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "audit.GetAuditTrailResponse": {
+            "type": "object",
+            "properties": {
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.AuditRecordResponse"
+                    }
+                }
+            }
+        },
+        "audit.VerifyAuditTrailResponse": {
+            "type": "object",
+            "properties": {
+                "broken_at": {
+                    "description": "BrokenAt is the sequence of the first record that doesn't fit in the chain, omitted when it is valid",
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "records": {
+                    "type": "integer"
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "codes.CodeResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "J45.0"
+                },
+                "display": {
+                    "type": "string",
+                    "example": "Predominantly allergic asthma"
+                }
+            }
+        },
+        "codes.SearchCodesResponse": {
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codes.CodeResponse"
+                    }
+                }
+            }
+        },
+        "diagnoses.AddDiagnosisRequest": {
+            "type": "object",
+            "properties": {
+                "coding": {
+                    "$ref": "#/definitions/diagnoses.CodingRequest"
+                },
+                "diagnosis": {
+                    "type": "string"
+                },
+                "prescription": {
+                    "$ref": "#/definitions/diagnoses.PrescriptionRequest"
+                }
+            }
+        },
+        "diagnoses.AmbiguousPatientResponse": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/diagnoses.PatientSummaryResponse"
+                    }
+                },
+                "detail": {
+                    "description": "Detail explains this occurrence of the problem",
+                    "type": "string",
+                    "example": "the request has invalid fields"
+                },
+                "errors": {
+                    "description": "Errors lists the parameters and body fields that aren't valid, if any",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/render.FieldError"
+                    }
+                },
+                "instance": {
+                    "description": "Instance is the path of the request",
+                    "type": "string",
+                    "example": "/api/v1/patients"
+                },
+                "request_id": {
+                    "description": "RequestID correlates the problem with the service logs and the audit trail",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "description": "Title summarizes the kind of problem, it is the same for every problem of a type",
+                    "type": "string",
+                    "example": "The request is not valid"
+                },
+                "type": {
+                    "description": "Type identifies the kind of problem, relative to the API base URL",
+                    "type": "string",
+                    "example": "/problems/invalid-request"
+                }
+            }
+        },
+        "diagnoses.AmendDiagnosisRequest": {
+            "type": "object",
+            "properties": {
+                "coding": {
+                    "$ref": "#/definitions/diagnoses.CodingRequest"
+                },
+                "diagnosis": {
+                    "type": "string"
+                },
+                "prescription": {
+                    "$ref": "#/definitions/diagnoses.PrescriptionRequest"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "allergy test results"
+                }
+            }
+        },
+        "diagnoses.Coding": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "display": {
+                    "type": "string"
+                },
+                "system": {
+                    "type": "string"
+                }
+            }
+        },
+        "diagnoses.CodingRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "J45.0"
+                },
+                "system": {
+                    "description": "System defaults to ICD-10, which is the only system supported",
+                    "type": "string",
+                    "example": "http://hl7.org/fhir/sid/icd-10"
+                }
+            }
+        },
+        "diagnoses.Diagnosis": {
+            "type": "object",
+            "properties": {
+                "coding": {
+                    "description": "Coding is the diagnosis coded in ICD-10, nil when it is only described in free text",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/diagnoses.Coding"
+                        }
+                    ]
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "patientID": {
+                    "type": "string"
+                },
+                "practitionerID": {
+                    "description": "PractitionerID identifies the practitioner who made the diagnosis",
+                    "type": "string"
+                },
+                "prescription": {
+                    "description": "Prescription is the medication prescribed with the diagnosis, nil when there is none",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/diagnoses.Prescription"
+                        }
+                    ]
+                },
+                "reason": {
+                    "description": "Reason explains why this version was written, it is empty for the first one",
+                    "type": "string"
+                },
+                "recordedAt": {
+                    "description": "RecordedAt and RecordedBy tell when and by whom this version was written, CreatedAt and PractitionerID\nkeep those of the first version",
+                    "type": "string"
+                },
+                "recordedBy": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/diagnoses.Status"
+                },
+                "version": {
+                    "description": "Version is 1 for the diagnosis as first written, and is incremented by every new version",
+                    "type": "integer"
+                }
+            }
+        },
+        "diagnoses.DiagnosisHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "versions": {
+                    "description": "Versions are every version of the diagnosis, from the first one",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/diagnoses.Diagnosis"
+                    }
+                }
+            }
+        },
+        "diagnoses.EnterInErrorRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "recorded on the wrong patient"
+                }
+            }
+        },
+        "diagnoses.GetDiagnosesResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "description": "NextCursor is sent as cursor to get the next page, it is omitted on the last page",
+                    "type": "string"
+                },
+                "patient_diagnoses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/diagnoses.Diagnosis"
+                    }
+                },
                 "patient_name": {
+                    "description": "PatientName is the name searched, it is omitted when the diagnoses weren't searched by name",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Total is the number of diagnoses matching the filters, across all pages",
+                    "type": "integer"
+                }
+            }
+        },
+        "diagnoses.GetDiagnosisResponse": {
+            "type": "object",
+            "properties": {
+                "diagnosis": {
+                    "$ref": "#/definitions/diagnoses.Diagnosis"
+                },
+                "patient": {
+                    "$ref": "#/definitions/diagnoses.PatientSummaryResponse"
+                }
+            }
+        },
+        "diagnoses.GetPatientPrescriptionsResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "description": "NextCursor is sent as cursor to get the next page, it is omitted on the last page",
+                    "type": "string"
+                },
+                "prescriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/diagnoses.PrescriptionResponse"
+                    }
+                },
+                "total": {
+                    "description": "Total is the number of prescriptions of the patient, across all pages",
+                    "type": "integer"
+                }
+            }
+        },
+        "diagnoses.PatientSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "birth_date": {
+                    "type": "string",
+                    "example": "1980-01-31"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "legal_id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "diagnoses.Prescription": {
+            "type": "object",
+            "properties": {
+                "medications": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_domain_diagnoses.Medication"
+                    }
+                },
+                "notes": {
+                    "description": "Notes are free-text instructions for the patient or the pharmacy",
+                    "type": "string"
+                }
+            }
+        },
+        "diagnoses.PrescriptionRequest": {
+            "type": "object",
+            "properties": {
+                "medications": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_infrastracture_http_diagnoses.Medication"
+                    }
+                },
+                "notes": {
+                    "type": "string",
+                    "example": "take with food"
+                }
+            }
+        },
+        "diagnoses.PrescriptionResponse": {
+            "type": "object",
+            "properties": {
+                "diagnosis": {
+                    "type": "string"
+                },
+                "diagnosis_id": {
+                    "type": "string"
+                },
+                "medications": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_infrastracture_http_diagnoses.Medication"
+                    }
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "practitioner_id": {
+                    "type": "string"
+                },
+                "prescribed_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "diagnoses.Route": {
+            "type": "string",
+            "enum": [
+                "oral",
+                "sublingual",
+                "topical",
+                "transdermal",
+                "inhalation",
+                "nasal",
+                "ophthalmic",
+                "otic",
+                "rectal",
+                "vaginal",
+                "intravenous",
+                "intramuscular",
+                "subcutaneous"
+            ],
+            "x-enum-varnames": [
+                "RouteOral",
+                "RouteSublingual",
+                "RouteTopical",
+                "RouteTransdermal",
+                "RouteInhalation",
+                "RouteNasal",
+                "RouteOphthalmic",
+                "RouteOtic",
+                "RouteRectal",
+                "RouteVaginal",
+                "RouteIntravenous",
+                "RouteIntramuscular",
+                "RouteSubcutaneous"
+            ]
+        },
+        "diagnoses.Status": {
+            "type": "string",
+            "enum": [
+                "final",
+                "amended",
+                "entered-in-error"
+            ],
+            "x-enum-varnames": [
+                "StatusFinal",
+                "StatusAmended",
+                "StatusEnteredInError"
+            ]
+        },
+        "diagnoses.TimeUnit": {
+            "type": "string",
+            "enum": [
+                "hour",
+                "day",
+                "week",
+                "month"
+            ],
+            "x-enum-varnames": [
+                "UnitHour",
+                "UnitDay",
+                "UnitWeek",
+                "UnitMonth"
+            ]
+        },
+        "fhir.Address": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.Bundle": {
+            "type": "object",
+            "properties": {
+                "entry": {
+                    "description": "Entry is omitted when there are no entries, FHIR doesn't allow empty arrays",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.BundleEntry"
+                    }
+                },
+                "link": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.BundleLink"
+                    }
+                },
+                "resourceType": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.BundleEntry": {
+            "type": "object",
+            "properties": {
+                "fullUrl": {
+                    "type": "string"
+                },
+                "resource": {},
+                "response": {
+                    "description": "Response is the result of the entry in batch and transaction responses",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/fhir.EntryResponse"
+                        }
+                    ]
+                },
+                "search": {
+                    "$ref": "#/definitions/fhir.EntrySearch"
+                }
+            }
+        },
+        "fhir.BundleLink": {
+            "type": "object",
+            "properties": {
+                "relation": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.CodeableConcept": {
+            "type": "object",
+            "properties": {
+                "coding": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.Coding"
+                    }
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.Coding": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "display": {
+                    "type": "string"
+                },
+                "system": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.Condition": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.CodeableConcept"
+                    }
+                },
+                "code": {
+                    "$ref": "#/definitions/fhir.CodeableConcept"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "recordedDate": {
+                    "type": "string"
+                },
+                "recorder": {
+                    "$ref": "#/definitions/fhir.Reference"
+                },
+                "resourceType": {
+                    "type": "string"
+                },
+                "subject": {
+                    "$ref": "#/definitions/fhir.Reference"
+                }
+            }
+        },
+        "fhir.ContactPoint": {
+            "type": "object",
+            "properties": {
+                "system": {
+                    "description": "System is phone or email",
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.EntryResponse": {
+            "type": "object",
+            "properties": {
+                "location": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "$ref": "#/definitions/fhir.OperationOutcome"
+                },
+                "status": {
+                    "description": "Status is the HTTP status code and reason, such as 201 Created",
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.EntrySearch": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.HumanName": {
+            "type": "object",
+            "properties": {
+                "text": {
                     "type": "string"
                 }
             }
         },
-        "diagnoses.HTTPError": {
+        "fhir.Identifier": {
+            "type": "object",
+            "properties": {
+                "system": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.IncomingBundle": {
+            "type": "object"
+        },
+        "fhir.OperationOutcome": {
+            "type": "object",
+            "properties": {
+                "issue": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.OperationOutcomeIssue"
+                    }
+                },
+                "resourceType": {
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.OperationOutcomeIssue": {
             "type": "object",
             "properties": {
                 "code": {
+                    "description": "Code is the issue type, such as invalid, not-found or forbidden",
+                    "type": "string"
+                },
+                "diagnostics": {
+                    "type": "string"
+                },
+                "expression": {
+                    "description": "Expression locates the issue in the resource, such as Bundle.entry[2]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "severity": {
+                    "description": "Severity is fatal, error, warning or information",
+                    "type": "string"
+                }
+            }
+        },
+        "fhir.Patient": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.Address"
+                    }
+                },
+                "birthDate": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "identifier": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.Identifier"
+                    }
+                },
+                "name": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.HumanName"
+                    }
+                },
+                "resourceType": {
+                    "type": "string"
+                },
+                "telecom": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fhir.ContactPoint"
+                    }
+                }
+            }
+        },
+        "fhir.Reference": {
+            "type": "object",
+            "properties": {
+                "identifier": {
+                    "$ref": "#/definitions/fhir.Identifier"
+                },
+                "reference": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_domain_diagnoses.Duration": {
+            "type": "object",
+            "properties": {
+                "unit": {
+                    "$ref": "#/definitions/diagnoses.TimeUnit"
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_domain_diagnoses.Frequency": {
+            "type": "object",
+            "properties": {
+                "period": {
+                    "type": "integer"
+                },
+                "times": {
+                    "type": "integer"
+                },
+                "unit": {
+                    "$ref": "#/definitions/diagnoses.TimeUnit"
+                }
+            }
+        },
+        "internal_domain_diagnoses.Medication": {
+            "type": "object",
+            "properties": {
+                "dose": {
+                    "type": "number"
+                },
+                "doseUnit": {
+                    "type": "string"
+                },
+                "drugCode": {
+                    "description": "DrugCode optionally identifies the drug in a drug catalog, such as ATC or RxNorm",
+                    "type": "string"
+                },
+                "drugName": {
+                    "type": "string"
+                },
+                "duration": {
+                    "$ref": "#/definitions/internal_domain_diagnoses.Duration"
+                },
+                "frequency": {
+                    "$ref": "#/definitions/internal_domain_diagnoses.Frequency"
+                },
+                "quantity": {
+                    "description": "Quantity is the number of units to dispense, such as 30 tablets",
+                    "type": "integer"
+                },
+                "refills": {
+                    "description": "Refills is the number of times it can be dispensed again, 0 when it can't",
+                    "type": "integer"
+                },
+                "route": {
+                    "$ref": "#/definitions/diagnoses.Route"
+                }
+            }
+        },
+        "internal_infrastracture_http_diagnoses.Duration": {
+            "type": "object",
+            "properties": {
+                "unit": {
+                    "description": "Unit is one of hour, day, week or month",
+                    "type": "string",
+                    "example": "day"
+                },
+                "value": {
                     "type": "integer",
-                    "example": 400
+                    "example": 7
+                }
+            }
+        },
+        "internal_infrastracture_http_diagnoses.Frequency": {
+            "type": "object",
+            "properties": {
+                "period": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "times": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "unit": {
+                    "description": "Unit is one of hour, day, week or month",
+                    "type": "string",
+                    "example": "hour"
+                }
+            }
+        },
+        "internal_infrastracture_http_diagnoses.Medication": {
+            "type": "object",
+            "properties": {
+                "dose": {
+                    "type": "number",
+                    "example": 500
+                },
+                "dose_unit": {
+                    "type": "string",
+                    "example": "mg"
+                },
+                "drug_code": {
+                    "description": "DrugCode optionally identifies the drug in a drug catalog, such as ATC or RxNorm",
+                    "type": "string",
+                    "example": "J01CA04"
+                },
+                "drug_name": {
+                    "type": "string",
+                    "example": "Amoxicillin"
+                },
+                "duration": {
+                    "$ref": "#/definitions/internal_infrastracture_http_diagnoses.Duration"
+                },
+                "frequency": {
+                    "$ref": "#/definitions/internal_infrastracture_http_diagnoses.Frequency"
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 21
+                },
+                "refills": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "route": {
+                    "description": "Route is one of oral, sublingual, topical, transdermal, inhalation, nasal, ophthalmic, otic, rectal,\nvaginal, intravenous, intramuscular or subcutaneous",
+                    "type": "string",
+                    "example": "oral"
+                }
+            }
+        },
+        "patients.CandidateResponse": {
+            "type": "object",
+            "properties": {
+                "patient": {
+                    "$ref": "#/definitions/patients.PatientResponse"
+                },
+                "score": {
+                    "description": "Score goes from 0.6 to 1 for an exact match",
+                    "type": "number",
+                    "example": 0.87
+                }
+            }
+        },
+        "patients.CreatePatientRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "birth_date": {
+                    "type": "string",
+                    "example": "1980-01-31"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "legal_id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "patients.ListPatientsResponse": {
+            "type": "object",
+            "properties": {
+                "patients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/patients.PatientResponse"
+                    }
+                }
+            }
+        },
+        "patients.PatientResponse": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "birth_date": {
+                    "type": "string",
+                    "example": "1980-01-31"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "legal_id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "patients.SearchPatientsResponse": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/patients.CandidateResponse"
+                    }
+                }
+            }
+        },
+        "patients.UpdateContactRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "render.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field is the name of the parameter, or the path of the body field such as prescription.medications[0].dose",
+                    "type": "string",
+                    "example": "birth_date"
                 },
                 "message": {
                     "type": "string",
-                    "example": "status bad request"
+                    "example": "invalid birth date, expected YYYY-MM-DD"
+                }
+            }
+        },
+        "render.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "Detail explains this occurrence of the problem",
+                    "type": "string",
+                    "example": "the request has invalid fields"
+                },
+                "errors": {
+                    "description": "Errors lists the parameters and body fields that aren't valid, if any",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/render.FieldError"
+                    }
+                },
+                "instance": {
+                    "description": "Instance is the path of the request",
+                    "type": "string",
+                    "example": "/api/v1/patients"
+                },
+                "request_id": {
+                    "description": "RequestID correlates the problem with the service logs and the audit trail",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "description": "Title summarizes the kind of problem, it is the same for every problem of a type",
+                    "type": "string",
+                    "example": "The request is not valid"
+                },
+                "type": {
+                    "description": "Type identifies the kind of problem, relative to the API base URL",
+                    "type": "string",
+                    "example": "/problems/invalid-request"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "JWT sent as \"Bearer {token}\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
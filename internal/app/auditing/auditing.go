@@ -0,0 +1,65 @@
+package auditing
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"time"
+)
+
+var ErrRecordingAudit = errors.New("error recording the audit trail")
+
+// Entry describes a handled command or query to be recorded in the audit trail.
+type Entry struct {
+	Actor     auth.Principal
+	Action    audit.Action
+	PatientID *uuid.UUID
+	RequestID string
+	// Err is the error returned by the handler, it sets the outcome of the record
+	Err error
+}
+
+// Recorder appends the entries of the application handlers to the audit trail. Queries don't return what they read
+// when its entry can't be recorded, commands only log the error as their change is already stored.
+type Recorder interface {
+	Record(ctx context.Context, entry Entry) error
+}
+
+type recorder struct {
+	records audit.Repository
+}
+
+func NewRecorder(records audit.Repository) Recorder {
+	return &recorder{records: records}
+}
+
+// Record appends the entry even when ctx is done, so the handlers that were cancelled are audited too.
+func (r *recorder) Record(ctx context.Context, entry Entry) error {
+	_, err := r.records.Append(context.WithoutCancel(ctx), audit.Record{
+		Timestamp: time.Now().UTC(),
+		ActorID:   entry.Actor.ID,
+		Action:    entry.Action,
+		PatientID: entry.PatientID,
+		Outcome:   OutcomeOf(entry.Err),
+		RequestID: entry.RequestID,
+	})
+	if err != nil {
+		return errors.Join(ErrRecordingAudit, err)
+	}
+
+	return nil
+}
+
+// OutcomeOf returns the outcome of a handler that returned err.
+func OutcomeOf(err error) audit.Outcome {
+	switch {
+	case err == nil:
+		return audit.OutcomeSuccess
+	case errors.Is(err, auth.ErrUnauthenticated), errors.Is(err, auth.ErrForbidden):
+		return audit.OutcomeDenied
+	default:
+		return audit.OutcomeFailure
+	}
+}
@@ -0,0 +1,59 @@
+package auditing
+
+import (
+	"context"
+	"errors"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestRecorder_Record(t *testing.T) {
+	actor := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+	tests := []struct {
+		name      string
+		cancelled bool
+		appendErr error
+		wantErr   error
+	}{
+		{
+			name: "append the entry",
+		},
+		{
+			name:      "append the entry of a cancelled request",
+			cancelled: true,
+		},
+		{
+			name:      "return error when the record can't be appended",
+			appendErr: errors.New("DB error"),
+			wantErr:   ErrRecordingAudit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+
+			records := &audit.MockRepository{}
+			notCancelled := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil })
+			records.On("Append", notCancelled, mock.MatchedBy(func(record audit.Record) bool {
+				return record.ActorID == actor.ID && record.Outcome == audit.OutcomeFailure && record.RequestID == "request-1"
+			})).Return(audit.Record{}, tt.appendErr)
+
+			err := NewRecorder(records).Record(ctx, Entry{
+				Actor:     actor,
+				Action:    audit.ActionAddDiagnosis,
+				RequestID: "request-1",
+				Err:       errors.New("handler failure"),
+			})
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			records.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package auditing
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRecorder struct {
+	mock.Mock
+}
+
+func (m *MockRecorder) Record(ctx context.Context, entry Entry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
@@ -0,0 +1,45 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"log/slog"
+)
+
+var ErrGettingAuditTrail = errors.New("error getting the audit trail")
+
+// GetAuditTrailQuery filters the audit trail by patient and/or actor. Both can be omitted to get the whole trail.
+type GetAuditTrailQuery struct {
+	PatientID *uuid.UUID
+	ActorID   string
+	Actor     auth.Principal
+}
+
+type GetAuditTrailHandler interface {
+	Handle(ctx context.Context, query GetAuditTrailQuery) ([]audit.Record, error)
+}
+
+type getAuditTrail struct {
+	auditRepo audit.Repository
+}
+
+func NewGetAuditTrailHandler(auditRepo audit.Repository) GetAuditTrailHandler {
+	return &getAuditTrail{auditRepo: auditRepo}
+}
+
+func (g *getAuditTrail) Handle(ctx context.Context, query GetAuditTrailQuery) ([]audit.Record, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadAuditTrail); err != nil {
+		return nil, err
+	}
+
+	records, err := g.auditRepo.Find(ctx, audit.Filter{PatientID: query.PatientID, ActorID: query.ActorID})
+	if err != nil {
+		slog.Error("error getting audit records", "err", err, "query", query)
+		return nil, ErrGettingAuditTrail
+	}
+
+	return records, nil
+}
@@ -0,0 +1,69 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+)
+
+func Test_getAuditTrail_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	admin := auth.Principal{ID: "admin-1", Roles: []auth.Role{auth.RoleAdmin}}
+	records := []audit.Record{{Sequence: 1, ActorID: "practitioner-1", PatientID: &patientID}}
+
+	tests := []struct {
+		name      string
+		auditRepo audit.Repository
+		query     GetAuditTrailQuery
+		want      []audit.Record
+		wantErr   error
+	}{
+		{
+			name:      "return error when the actor can't read the audit trail",
+			auditRepo: &audit.MockRepository{},
+			query:     GetAuditTrailQuery{Actor: auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}},
+			want:      nil,
+			wantErr:   auth.ErrForbidden,
+		},
+		{
+			name: "return error when can't get the records",
+			auditRepo: func() audit.Repository {
+				mockRepo := &audit.MockRepository{}
+				mockRepo.On("Find", mock.Anything, audit.Filter{ActorID: "practitioner-1"}).Return(([]audit.Record)(nil), errors.New("DB error"))
+				return mockRepo
+			}(),
+			query:   GetAuditTrailQuery{ActorID: "practitioner-1", Actor: admin},
+			want:    nil,
+			wantErr: ErrGettingAuditTrail,
+		},
+		{
+			name: "return the records of the patient and actor",
+			auditRepo: func() audit.Repository {
+				mockRepo := &audit.MockRepository{}
+				mockRepo.On("Find", mock.Anything, audit.Filter{PatientID: &patientID, ActorID: "practitioner-1"}).Return(records, nil)
+				return mockRepo
+			}(),
+			query:   GetAuditTrailQuery{PatientID: &patientID, ActorID: "practitioner-1", Actor: admin},
+			want:    records,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &getAuditTrail{auditRepo: tt.auditRepo}
+			got, err := g.Handle(context.Background(), tt.query)
+			if err != tt.wantErr {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handle() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
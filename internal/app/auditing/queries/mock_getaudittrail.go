@@ -0,0 +1,16 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockGetAuditTrail struct {
+	mock.Mock
+}
+
+func (m *MockGetAuditTrail) Handle(ctx context.Context, query GetAuditTrailQuery) ([]audit.Record, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]audit.Record), args.Error(1)
+}
@@ -0,0 +1,15 @@
+package queries
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockVerifyAuditTrail struct {
+	mock.Mock
+}
+
+func (m *MockVerifyAuditTrail) Handle(ctx context.Context, query VerifyAuditTrailQuery) (Verification, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(Verification), args.Error(1)
+}
@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"log/slog"
+)
+
+type VerifyAuditTrailQuery struct {
+	Actor auth.Principal
+}
+
+// Verification is the result of checking the audit trail hash chain.
+type Verification struct {
+	Valid bool
+	// Records is the number of records checked
+	Records int
+	// BrokenChain tells where and why the chain is broken, nil when it is valid
+	BrokenChain *audit.BrokenChainError
+}
+
+type VerifyAuditTrailHandler interface {
+	Handle(ctx context.Context, query VerifyAuditTrailQuery) (Verification, error)
+}
+
+type verifyAuditTrail struct {
+	auditRepo audit.Repository
+}
+
+func NewVerifyAuditTrailHandler(auditRepo audit.Repository) VerifyAuditTrailHandler {
+	return &verifyAuditTrail{auditRepo: auditRepo}
+}
+
+func (v *verifyAuditTrail) Handle(ctx context.Context, query VerifyAuditTrailQuery) (Verification, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadAuditTrail); err != nil {
+		return Verification{}, err
+	}
+
+	records, err := v.auditRepo.Find(ctx, audit.Filter{})
+	if err != nil {
+		slog.Error("error getting audit records", "err", err)
+		return Verification{}, ErrGettingAuditTrail
+	}
+
+	verification := Verification{Valid: true, Records: len(records)}
+	var brokenChain *audit.BrokenChainError
+	if errors.As(audit.Verify(records), &brokenChain) {
+		slog.Warn("the audit trail is broken", "err", brokenChain)
+		verification.Valid = false
+		verification.BrokenChain = brokenChain
+	}
+
+	return verification, nil
+}
@@ -0,0 +1,77 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_verifyAuditTrail_Handle(t *testing.T) {
+	auditor := auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}}
+	first := audit.Chain(nil, audit.Record{Timestamp: time.Unix(0, 1), ActorID: "practitioner-1", Outcome: audit.OutcomeSuccess})
+	second := audit.Chain(&first, audit.Record{Timestamp: time.Unix(0, 2), ActorID: "nurse-1", Outcome: audit.OutcomeDenied})
+	tampered := second
+	tampered.Outcome = audit.OutcomeSuccess
+
+	tests := []struct {
+		name    string
+		records []audit.Record
+		findErr error
+		query   VerifyAuditTrailQuery
+		want    Verification
+		wantErr error
+	}{
+		{
+			name:    "return error when the actor can't read the audit trail",
+			query:   VerifyAuditTrailQuery{Actor: auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}},
+			wantErr: auth.ErrForbidden,
+		},
+		{
+			name:    "return error when can't get the records",
+			findErr: errors.New("DB error"),
+			query:   VerifyAuditTrailQuery{Actor: auditor},
+			wantErr: ErrGettingAuditTrail,
+		},
+		{
+			name:    "report an empty trail as valid",
+			records: []audit.Record{},
+			query:   VerifyAuditTrailQuery{Actor: auditor},
+			want:    Verification{Valid: true, Records: 0},
+		},
+		{
+			name:    "report a valid chain",
+			records: []audit.Record{first, second},
+			query:   VerifyAuditTrailQuery{Actor: auditor},
+			want:    Verification{Valid: true, Records: 2},
+		},
+		{
+			name:    "report where the chain is broken",
+			records: []audit.Record{first, tampered},
+			query:   VerifyAuditTrailQuery{Actor: auditor},
+			want: Verification{Valid: false, Records: 2, BrokenChain: &audit.BrokenChainError{
+				Sequence: 2,
+				Reason:   "hash doesn't match the record content",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditRepo := &audit.MockRepository{}
+			auditRepo.On("Find", mock.Anything, audit.Filter{}).Return(tt.records, tt.findErr)
+			v := &verifyAuditTrail{auditRepo: auditRepo}
+			got, err := v.Handle(context.Background(), tt.query)
+			if err != tt.wantErr {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handle() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import "errors"
+
+var (
+	ErrUnauthenticated = errors.New("the request is not authenticated")
+	ErrForbidden       = errors.New("the user is not allowed to perform this action")
+)
+
+type Role string
+
+const (
+	RoleClinician Role = "clinician"
+	RoleNurse     Role = "nurse"
+	RoleAdmin     Role = "admin"
+	// RoleAuditor can read every record but can't change any of them
+	RoleAuditor Role = "auditor"
+)
+
+type Permission string
+
+const (
+	ReadPatients   Permission = "patients:read"
+	WritePatients  Permission = "patients:write"
+	ReadDiagnoses  Permission = "diagnoses:read"
+	WriteDiagnoses Permission = "diagnoses:write"
+	ReadAuditTrail Permission = "audit:read"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleClinician: {ReadPatients, WritePatients, ReadDiagnoses, WriteDiagnoses},
+	RoleNurse:     {ReadPatients, WritePatients, ReadDiagnoses},
+	RoleAdmin:     {ReadPatients, WritePatients, ReadDiagnoses, ReadAuditTrail},
+	RoleAuditor:   {ReadPatients, ReadDiagnoses, ReadAuditTrail},
+}
+
+// ParseRole returns the role with that name, and false when there is none.
+func ParseRole(name string) (Role, bool) {
+	role := Role(name)
+	_, ok := rolePermissions[role]
+	return role, ok
+}
+
+// Principal is the authenticated user issuing a command or query. For practitioners, ID is their practitioner ID.
+type Principal struct {
+	ID    string
+	Roles []Role
+}
+
+func (p Principal) HasRole(role Role) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether any of the principal roles grants the permission.
+func (p Principal) Can(permission Permission) bool {
+	for _, role := range p.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authorize returns ErrUnauthenticated for an anonymous principal, and ErrForbidden when none of its roles
+// grants the permission.
+func Authorize(principal Principal, permission Permission) error {
+	if principal.ID == "" {
+		return ErrUnauthenticated
+	}
+
+	if !principal.Can(permission) {
+		return ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  Principal
+		permission Permission
+		wantErr    error
+	}{
+		{
+			name:       "reject anonymous principal",
+			principal:  Principal{Roles: []Role{RoleClinician}},
+			permission: ReadDiagnoses,
+			wantErr:    ErrUnauthenticated,
+		},
+		{
+			name:       "reject principal without roles",
+			principal:  Principal{ID: "practitioner-1"},
+			permission: ReadDiagnoses,
+			wantErr:    ErrForbidden,
+		},
+		{
+			name:       "allow only clinicians to write diagnoses",
+			principal:  Principal{ID: "nurse-1", Roles: []Role{RoleNurse, RoleAdmin}},
+			permission: WriteDiagnoses,
+			wantErr:    ErrForbidden,
+		},
+		{
+			name:       "reject writes from auditors",
+			principal:  Principal{ID: "auditor-1", Roles: []Role{RoleAuditor}},
+			permission: WritePatients,
+			wantErr:    ErrForbidden,
+		},
+		{
+			name:       "allow auditors to read",
+			principal:  Principal{ID: "auditor-1", Roles: []Role{RoleAuditor}},
+			permission: ReadDiagnoses,
+			wantErr:    nil,
+		},
+		{
+			name:       "allow only admins and auditors to read the audit trail",
+			principal:  Principal{ID: "practitioner-1", Roles: []Role{RoleClinician, RoleNurse}},
+			permission: ReadAuditTrail,
+			wantErr:    ErrForbidden,
+		},
+		{
+			name:       "allow clinicians to write diagnoses",
+			principal:  Principal{ID: "practitioner-1", Roles: []Role{RoleClinician}},
+			permission: WriteDiagnoses,
+			wantErr:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Authorize(tt.principal, tt.permission); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
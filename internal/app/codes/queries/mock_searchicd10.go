@@ -0,0 +1,16 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSearchICD10 struct {
+	mock.Mock
+}
+
+func (m *MockSearchICD10) Handle(ctx context.Context, query SearchICD10Query) ([]codes.Concept, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]codes.Concept), args.Error(1)
+}
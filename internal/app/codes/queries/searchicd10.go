@@ -0,0 +1,50 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchICD10Query looks up ICD-10 codes by code prefix, such as J45, or by keywords of their display.
+type SearchICD10Query struct {
+	Text string
+	// Limit is the maximum number of codes, DefaultSearchLimit when zero and never more than MaxSearchLimit
+	Limit int
+	Actor auth.Principal
+}
+
+type SearchICD10Handler interface {
+	Handle(ctx context.Context, query SearchICD10Query) ([]codes.Concept, error)
+}
+
+type searchICD10 struct {
+	icd10 codes.Catalog
+}
+
+func NewSearchICD10Handler(icd10 codes.Catalog) SearchICD10Handler {
+	return &searchICD10{icd10: icd10}
+}
+
+func (s *searchICD10) Handle(ctx context.Context, query SearchICD10Query) ([]codes.Concept, error) {
+	// codes are used to record diagnoses, so anyone reading them can look them up
+	if err := auth.Authorize(query.Actor, auth.ReadDiagnoses); err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	return s.icd10.Search(query.Text, limit), nil
+}
@@ -0,0 +1,65 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"reflect"
+	"testing"
+)
+
+func Test_searchICD10_Handle(t *testing.T) {
+	reader := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	asthma := []codes.Concept{{Code: "J45", Display: "Asthma"}}
+
+	tests := []struct {
+		name    string
+		catalog codes.Catalog
+		query   SearchICD10Query
+		want    []codes.Concept
+		wantErr error
+	}{
+		{
+			name:    "return error when the actor is anonymous",
+			catalog: &codes.MockCatalog{},
+			query:   SearchICD10Query{Text: "asthma"},
+			want:    nil,
+			wantErr: auth.ErrUnauthenticated,
+		},
+		{
+			name: "search with the default limit",
+			catalog: func() codes.Catalog {
+				mockCatalog := &codes.MockCatalog{}
+				mockCatalog.On("Search", "asthma", DefaultSearchLimit).Return(asthma)
+				return mockCatalog
+			}(),
+			query:   SearchICD10Query{Text: "asthma", Actor: reader},
+			want:    asthma,
+			wantErr: nil,
+		},
+		{
+			name: "bound the limit",
+			catalog: func() codes.Catalog {
+				mockCatalog := &codes.MockCatalog{}
+				mockCatalog.On("Search", "J4", MaxSearchLimit).Return(asthma)
+				return mockCatalog
+			}(),
+			query:   SearchICD10Query{Text: "J4", Limit: 1000, Actor: reader},
+			want:    asthma,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &searchICD10{icd10: tt.catalog}
+			got, err := s.Handle(context.Background(), tt.query)
+			if err != tt.wantErr {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handle() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// ErrDiagnosisNotAdded is the failure of the diagnoses of an AddPatientDiagnoses command that were valid, but
+// weren't added because another diagnosis of the command failed.
+var ErrDiagnosisNotAdded = errors.New("diagnosis not added, as another diagnosis failed")
+
+// AddPatientDiagnoses adds several diagnoses as a single change: either every diagnosis is added or none is.
+type AddPatientDiagnoses struct {
+	Diagnoses []AddPatientDiagnosis
+}
+
+// DiagnosisError is the failure of the diagnosis at Index of an AddPatientDiagnoses command.
+type DiagnosisError struct {
+	Index int
+	Err   error
+}
+
+func (e DiagnosisError) Error() string {
+	return fmt.Sprintf("diagnosis %d: %v", e.Index, e.Err)
+}
+
+func (e DiagnosisError) Unwrap() error {
+	return e.Err
+}
+
+// DiagnosesErrors are the failures of the diagnoses of an AddPatientDiagnoses command that caused it to fail.
+// The other diagnoses failed with ErrDiagnosisNotAdded and aren't part of them.
+type DiagnosesErrors []DiagnosisError
+
+func (e DiagnosesErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, diagnosisErr := range e {
+		messages = append(messages, diagnosisErr.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the failures, so the errors of the diagnoses can be matched with errors.Is.
+func (e DiagnosesErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, diagnosisErr := range e {
+		errs = append(errs, diagnosisErr)
+	}
+	return errs
+}
+
+type AddPatientDiagnosesHandler interface {
+	// Handle returns the stored diagnoses in the order of the command. When any of them fails the error is
+	// DiagnosesErrors and none is stored.
+	Handle(ctx context.Context, command AddPatientDiagnoses) ([]*diagnoses.Diagnosis, error)
+}
+
+type addPatientDiagnosesHandler struct {
+	unitOfWork unitofwork.UnitOfWork
+	recorder   auditing.Recorder
+	icd10      codes.Catalog
+}
+
+// NewAddPatientDiagnosesHandler returns a handler that validates every diagnosis before adding them all in a single
+// unit of work.
+func NewAddPatientDiagnosesHandler(unitOfWork unitofwork.UnitOfWork, recorder auditing.Recorder,
+	icd10 codes.Catalog) AddPatientDiagnosesHandler {
+	return &addPatientDiagnosesHandler{
+		unitOfWork: unitOfWork,
+		recorder:   recorder,
+		icd10:      icd10,
+	}
+}
+
+func (h *addPatientDiagnosesHandler) Handle(ctx context.Context, command AddPatientDiagnoses) ([]*diagnoses.Diagnosis, error) {
+	added, errs := h.handle(ctx, command)
+
+	var failures DiagnosesErrors
+	for i, diagnosis := range command.Diagnoses {
+		if errs[i] != nil && !errors.Is(errs[i], ErrDiagnosisNotAdded) {
+			failures = append(failures, DiagnosisError{Index: i, Err: errs[i]})
+		}
+
+		auditErr := h.recorder.Record(ctx, auditing.Entry{
+			Actor:     diagnosis.Actor,
+			Action:    audit.ActionAddDiagnosis,
+			PatientID: &diagnosis.PatientID,
+			RequestID: diagnosis.RequestID,
+			Err:       errs[i],
+		})
+		if auditErr != nil {
+			slog.Error(auditErr.Error(), "patientID", diagnosis.PatientID, "actor", diagnosis.Actor.ID)
+		}
+	}
+
+	if len(failures) > 0 {
+		return nil, failures
+	}
+	return added, nil
+}
+
+func (h *addPatientDiagnosesHandler) handle(ctx context.Context, command AddPatientDiagnoses) ([]*diagnoses.Diagnosis, []error) {
+	errs := make([]error, len(command.Diagnoses))
+	codings := make([]*diagnoses.Coding, len(command.Diagnoses))
+	failed := false
+	for i, diagnosis := range command.Diagnoses {
+		codings[i], errs[i] = validateNewDiagnosis(h.icd10, diagnosis)
+		failed = failed || errs[i] != nil
+	}
+
+	if failed {
+		return nil, notAdded(errs)
+	}
+
+	added := make([]*diagnoses.Diagnosis, len(command.Diagnoses))
+	err := h.unitOfWork.Do(ctx, func(repos unitofwork.Repositories) error {
+		for i, diagnosis := range command.Diagnoses {
+			newDiagnosis, err := addDiagnosis(ctx, repos, diagnosis, codings[i])
+			if err != nil {
+				errs[i] = err
+				return err
+			}
+			added[i] = newDiagnosis
+		}
+		return nil
+	})
+
+	if err != nil {
+		failedAt := slices.IndexFunc(errs, func(err error) bool { return err != nil })
+		for i, diagnosis := range command.Diagnoses {
+			// when no diagnosis failed, the unit of work couldn't commit them, which fails every one of them
+			if i == failedAt || failedAt == -1 {
+				errs[i] = addingError(err, diagnosis)
+			}
+		}
+		return nil, notAdded(errs)
+	}
+
+	slog.Info("patient diagnoses successfully added", "count", len(added))
+	return added, errs
+}
+
+func notAdded(errs []error) []error {
+	for i := range errs {
+		if errs[i] == nil {
+			errs[i] = ErrDiagnosisNotAdded
+		}
+	}
+	return errs
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func Test_addPatientDiagnosesHandler_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	missingID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+	migraine := AddPatientDiagnosis{PatientID: patientID, Diagnosis: "migraine", Actor: clinician}
+	asthma := AddPatientDiagnosis{PatientID: patientID, Diagnosis: "asthma", Actor: clinician}
+
+	tests := []struct {
+		name          string
+		command       AddPatientDiagnoses
+		addErr        error
+		unitOfWorkErr error
+		// wantErrs are the errors of each diagnosis, as recorded in the audit trail
+		wantErrs   []error
+		wantFailed []int
+	}{
+		{
+			name:     "add every diagnosis",
+			command:  AddPatientDiagnoses{Diagnoses: []AddPatientDiagnosis{migraine, asthma}},
+			wantErrs: []error{nil, nil},
+		},
+		{
+			name: "add none when any diagnosis is invalid",
+			command: AddPatientDiagnoses{Diagnoses: []AddPatientDiagnosis{
+				migraine, {PatientID: patientID, Actor: clinician}, {PatientID: patientID, Actor: clinician},
+			}},
+			wantErrs:   []error{ErrDiagnosisNotAdded, validation.ErrInvalidCommand, validation.ErrInvalidCommand},
+			wantFailed: []int{1, 2},
+		},
+		{
+			name: "add none when the patient of a diagnosis doesn't exist",
+			command: AddPatientDiagnoses{Diagnoses: []AddPatientDiagnosis{
+				migraine, {PatientID: missingID, Diagnosis: "asthma", Actor: clinician}, asthma,
+			}},
+			wantErrs:   []error{ErrDiagnosisNotAdded, patients.ErrPatientNotFound, ErrDiagnosisNotAdded},
+			wantFailed: []int{1},
+		},
+		{
+			name:       "add none when a diagnosis can't be stored",
+			command:    AddPatientDiagnoses{Diagnoses: []AddPatientDiagnosis{migraine, asthma}},
+			addErr:     errors.New("DB error"),
+			wantErrs:   []error{ErrAddingDiagnosis, ErrDiagnosisNotAdded},
+			wantFailed: []int{0},
+		},
+		{
+			name:          "fail every diagnosis when the unit of work fails",
+			command:       AddPatientDiagnoses{Diagnoses: []AddPatientDiagnosis{migraine, asthma}},
+			unitOfWorkErr: errors.New("begin error"),
+			wantErrs:      []error{ErrAddingDiagnosis, ErrAddingDiagnosis},
+			wantFailed:    []int{0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patientRepo := &patients.MockRepository{}
+			patientRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+			patientRepo.On("GetByID", mock.Anything, missingID).Return((*patients.Patient)(nil), nil)
+			diagnosisRepo := &diagnoses.MockRepository{}
+			diagnosisRepo.On("AddDiagnosis", mock.Anything, mock.Anything).Return(tt.addErr)
+			unitOfWork := &unitofwork.MockUnitOfWork{}
+			unitOfWork.On("Do", mock.Anything, mock.Anything).Return(unitofwork.Repositories{
+				Patients:  patientRepo,
+				Diagnoses: diagnosisRepo,
+			}, tt.unitOfWorkErr)
+			recorder := &auditing.MockRecorder{}
+			for i, wantErr := range tt.wantErrs {
+				recorder.On("Record", mock.Anything, mock.MatchedBy(func(entry auditing.Entry) bool {
+					return entry.Actor.ID == clinician.ID && entry.PatientID != nil &&
+						*entry.PatientID == tt.command.Diagnoses[i].PatientID && errors.Is(entry.Err, wantErr) &&
+						(wantErr != nil || entry.Err == nil)
+				})).Return(nil).Once()
+			}
+
+			h := &addPatientDiagnosesHandler{unitOfWork: unitOfWork, recorder: recorder}
+			got, err := h.Handle(context.Background(), tt.command)
+
+			var diagnosesErrs DiagnosesErrors
+			if tt.wantFailed == nil {
+				assert.NoError(t, err)
+				assert.Len(t, got, len(tt.command.Diagnoses))
+			} else if assert.ErrorAs(t, err, &diagnosesErrs) {
+				var failed []int
+				for _, diagnosisErr := range diagnosesErrs {
+					failed = append(failed, diagnosisErr.Index)
+					assert.ErrorIs(t, diagnosisErr, tt.wantErrs[diagnosisErr.Index])
+				}
+				assert.Equal(t, tt.wantFailed, failed)
+				assert.Nil(t, got)
+			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
@@ -1,77 +1,181 @@
 package commands
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
 	"log/slog"
 	"time"
 )
 
 var (
-	ErrPatientNotFound = errors.New("patient not found")
-	ErrGettingPatient  = errors.New("error getting patient")
-	ErrAddingDiagnosis = errors.New("error adding diagnosis")
-	ErrUpdatingPatient = errors.New("error updating patient")
+	ErrAddingDiagnosis   = errors.New("error adding diagnosis")
+	ErrGettingDiagnoses  = errors.New("error getting diagnoses")
+	ErrInvalidCoding     = errors.New("invalid diagnosis coding")
+	ErrDiagnosisNotFound = errors.New("diagnosis not found")
+	ErrGettingDiagnosis  = errors.New("error getting diagnosis")
+	ErrUpdatingDiagnosis = errors.New("error updating diagnosis")
 )
 
 type AddPatientDiagnosis struct {
-	PatientID    uuid.UUID
-	Diagnosis    string
-	Prescription *string
+	PatientID uuid.UUID
+	Diagnosis string
+	// Prescription is optional, it must have at least a medication or notes when set
+	Prescription *diagnoses.Prescription
+	// Coding optionally codes the diagnosis in ICD-10. The system defaults to ICD-10 and the display is taken
+	// from the code table.
+	Coding *diagnoses.Coding
+	// Actor is the practitioner making the diagnosis, only clinicians are allowed
+	Actor     auth.Principal
+	RequestID string
 }
 
 type AddPatientDiagnosisHandler interface {
-	Handle(command AddPatientDiagnosis) error
+	// Handle returns the stored diagnosis, with the ID and timestamps it was given.
+	Handle(ctx context.Context, command AddPatientDiagnosis) (*diagnoses.Diagnosis, error)
 }
 
 type addPatientDiagnosisHandler struct {
-	patientRepo   patients.Repository
-	diagnosisRepo diagnoses.Repository
+	unitOfWork unitofwork.UnitOfWork
+	recorder   auditing.Recorder
+	icd10      codes.Catalog
 }
 
-func NewAddPatientDiagnosisHandler(patientRepo patients.Repository, diagnosisRepo diagnoses.Repository) AddPatientDiagnosisHandler {
+// NewAddPatientDiagnosisHandler returns a handler that checks the patient exists and stores the new diagnosis
+// in a single unit of work, so the diagnosis is never stored for a patient removed meanwhile. Coded diagnoses are
+// validated against the icd10 catalog.
+func NewAddPatientDiagnosisHandler(unitOfWork unitofwork.UnitOfWork, recorder auditing.Recorder,
+	icd10 codes.Catalog) AddPatientDiagnosisHandler {
 	return &addPatientDiagnosisHandler{
-		patientRepo:   patientRepo,
-		diagnosisRepo: diagnosisRepo,
+		unitOfWork: unitOfWork,
+		recorder:   recorder,
+		icd10:      icd10,
 	}
 }
 
-func (h *addPatientDiagnosisHandler) Handle(command AddPatientDiagnosis) error {
-	patient, err := h.patientRepo.GetByID(command.PatientID)
+func (h *addPatientDiagnosisHandler) Handle(ctx context.Context, command AddPatientDiagnosis) (*diagnoses.Diagnosis, error) {
+	diagnosis, err := h.handle(ctx, command)
+
+	auditErr := h.recorder.Record(ctx, auditing.Entry{
+		Actor:     command.Actor,
+		Action:    audit.ActionAddDiagnosis,
+		PatientID: &command.PatientID,
+		RequestID: command.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "patientID", command.PatientID, "actor", command.Actor.ID)
+	}
+
+	return diagnosis, err
+}
+
+func (h *addPatientDiagnosisHandler) handle(ctx context.Context, command AddPatientDiagnosis) (*diagnoses.Diagnosis, error) {
+	coding, err := validateNewDiagnosis(h.icd10, command)
+	if err != nil {
+		return nil, err
+	}
+
+	var newDiagnosis *diagnoses.Diagnosis
+	err = h.unitOfWork.Do(ctx, func(repos unitofwork.Repositories) error {
+		newDiagnosis, err = addDiagnosis(ctx, repos, command, coding)
+		return err
+	})
+	if err != nil {
+		return nil, addingError(err, command)
+	}
+
+	slog.Info("patient diagnosis successfully added", "newDiagnosis", newDiagnosis)
+	return newDiagnosis, nil
+}
+
+func validateNewDiagnosis(icd10 codes.Catalog, command AddPatientDiagnosis) (*diagnoses.Coding, error) {
+	if err := auth.Authorize(command.Actor, auth.WriteDiagnoses); err != nil {
+		slog.Info("add patient diagnosis not authorized", "err", err, "actor", command.Actor.ID)
+		return nil, err
+	}
+
+	if err := command.Validate(); err != nil {
+		slog.Info(err.Error(), "patientID", command.PatientID)
+		return nil, err
+	}
+
+	coding, err := validateCoding(icd10, command.Coding)
+	if err != nil {
+		slog.Info(err.Error(), "coding", command.Coding)
+		return nil, err
+	}
+
+	return coding, nil
+}
+
+func addDiagnosis(ctx context.Context, repos unitofwork.Repositories, command AddPatientDiagnosis,
+	coding *diagnoses.Coding) (*diagnoses.Diagnosis, error) {
+	patient, err := repos.Patients.GetByID(ctx, command.PatientID)
 	if err != nil {
 		slog.Error(err.Error(), "patientID", command.PatientID)
-		return ErrGettingPatient
+		return nil, patients.ErrGettingPatient
 	}
 
 	if patient == nil {
-		slog.Info(ErrPatientNotFound.Error(), "patientID", command.PatientID)
-		return ErrPatientNotFound
+		slog.Info(patients.ErrPatientNotFound.Error(), "patientID", command.PatientID)
+		return nil, patients.ErrPatientNotFound
 	}
 
+	now := time.Now()
 	newDiagnosis := diagnoses.Diagnosis{
-		ID:           uuid.New(),
-		Description:  command.Diagnosis,
-		PatientID:    patient.ID,
-		CreatedAt:    time.Now(),
-		Prescription: command.Prescription,
+		ID:             uuid.New(),
+		Description:    command.Diagnosis,
+		PatientID:      patient.ID,
+		CreatedAt:      now,
+		Prescription:   command.Prescription,
+		PractitionerID: command.Actor.ID,
+		Coding:         coding,
+		Version:        1,
+		Status:         diagnoses.StatusFinal,
+		RecordedAt:     now,
+		RecordedBy:     command.Actor.ID,
+	}
+
+	if err := repos.Diagnoses.AddDiagnosis(ctx, newDiagnosis); err != nil {
+		slog.Error(err.Error(), "newDiagnosis", newDiagnosis)
+		return nil, ErrAddingDiagnosis
 	}
 
-	patient.Diagnostics = append(patient.Diagnostics, &newDiagnosis)
+	return &newDiagnosis, nil
+}
 
-	updateErr := h.patientRepo.Update(*patient)
-	if updateErr != nil {
-		slog.Error(updateErr.Error(), "patient", *patient)
-		return ErrUpdatingPatient
+func addingError(err error, command AddPatientDiagnosis) error {
+	if errors.Is(err, patients.ErrGettingPatient) || errors.Is(err, patients.ErrPatientNotFound) ||
+		errors.Is(err, ErrAddingDiagnosis) {
+		return err
 	}
 
-	addErr := h.diagnosisRepo.AddDiagnosis(newDiagnosis)
-	if addErr != nil {
-		slog.Error(addErr.Error(), "newDiagnosis", newDiagnosis)
-		return ErrAddingDiagnosis
+	slog.Error(err.Error(), "patientID", command.PatientID)
+	return ErrAddingDiagnosis
+}
+
+func validateCoding(icd10 codes.Catalog, coding *diagnoses.Coding) (*diagnoses.Coding, error) {
+	if coding == nil {
+		return nil, nil
 	}
 
-	slog.Info("patient diagnosis successfully added", "newDiagnosis", newDiagnosis)
-	return nil
+	if coding.System != "" && coding.System != diagnoses.SystemICD10 {
+		return nil, fmt.Errorf("%w: unsupported code system %q, only %s is supported", ErrInvalidCoding, coding.System, diagnoses.SystemICD10)
+	}
+
+	concept, ok := icd10.Lookup(coding.Code)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown ICD-10 code %q", ErrInvalidCoding, coding.Code)
+	}
+
+	return &diagnoses.Coding{System: diagnoses.SystemICD10, Code: concept.Code, Display: concept.Display}, nil
 }
@@ -1,11 +1,19 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
 	"github.com/stretchr/testify/mock"
+	"reflect"
 	"testing"
 )
 
@@ -16,62 +24,72 @@ func Test_addPatientDiagnosisHandler_Handle(t *testing.T) {
 		PatientID:    patientID,
 		Diagnosis:    "test diagnosis",
 		Prescription: nil,
+		Actor:        auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}},
 	}
 
 	tests := []struct {
 		name          string
 		patientRepo   patients.Repository
 		diagnosisRepo diagnoses.Repository
+		unitOfWorkErr error
 		command       AddPatientDiagnosis
 		wantErr       error
 	}{
 		{
-			name: "return error when fails getting patient",
-			patientRepo: func() patients.Repository {
-				mockRepo := &patients.MockRepository{}
-				mockRepo.On("GetByID", patientID).Return((*patients.Patient)(nil), errors.New("cannot get user"))
-				return mockRepo
+			name:          "return error when the actor is not a clinician",
+			patientRepo:   &patients.MockRepository{},
+			diagnosisRepo: &diagnoses.MockRepository{},
+			command: func() AddPatientDiagnosis {
+				c := command
+				c.Actor = auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+				return c
 			}(),
+			wantErr: auth.ErrForbidden,
+		},
+		{
+			name:          "return error when the actor is anonymous",
+			patientRepo:   &patients.MockRepository{},
 			diagnosisRepo: &diagnoses.MockRepository{},
-			command:       command,
-			wantErr:       ErrGettingPatient,
+			command: func() AddPatientDiagnosis {
+				c := command
+				c.Actor = auth.Principal{}
+				return c
+			}(),
+			wantErr: auth.ErrUnauthenticated,
 		},
 		{
-			name: "return error when there is no patient for that ID",
+			name: "return error when fails getting patient",
 			patientRepo: func() patients.Repository {
 				mockRepo := &patients.MockRepository{}
-				mockRepo.On("GetByID", patientID).Return((*patients.Patient)(nil), nil)
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), errors.New("cannot get user"))
 				return mockRepo
 			}(),
 			diagnosisRepo: &diagnoses.MockRepository{},
 			command:       command,
-			wantErr:       ErrPatientNotFound,
+			wantErr:       patients.ErrGettingPatient,
 		},
 		{
-			name: "return error when the patient cant be updated",
+			name: "return error when there is no patient for that ID",
 			patientRepo: func() patients.Repository {
 				mockRepo := &patients.MockRepository{}
-				patient := &patients.Patient{}
-				mockRepo.On("GetByID", patientID).Return(patient, nil)
-				mockRepo.On("Update", mock.Anything).Return(errors.New("update error"))
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), nil)
 				return mockRepo
 			}(),
 			diagnosisRepo: &diagnoses.MockRepository{},
 			command:       command,
-			wantErr:       ErrUpdatingPatient,
+			wantErr:       patients.ErrPatientNotFound,
 		},
 		{
-			name: "return error when the patient cant be updated",
+			name: "return error when the diagnosis cant be added",
 			patientRepo: func() patients.Repository {
 				mockRepo := &patients.MockRepository{}
 				patient := &patients.Patient{}
-				mockRepo.On("GetByID", patientID).Return(patient, nil)
-				mockRepo.On("Update", mock.Anything).Return(nil)
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(patient, nil)
 				return mockRepo
 			}(),
 			diagnosisRepo: func() diagnoses.Repository {
 				mockRepo := &diagnoses.MockRepository{}
-				mockRepo.On("AddDiagnosis", mock.Anything).Return(errors.New("add error"))
+				mockRepo.On("AddDiagnosis", mock.Anything, mock.Anything).Return(errors.New("add error"))
 				return mockRepo
 			}(),
 			command: command,
@@ -82,28 +100,247 @@ func Test_addPatientDiagnosisHandler_Handle(t *testing.T) {
 			patientRepo: func() patients.Repository {
 				mockRepo := &patients.MockRepository{}
 				patient := &patients.Patient{}
-				mockRepo.On("GetByID", patientID).Return(patient, nil)
-				mockRepo.On("Update", mock.Anything).Return(nil)
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(patient, nil)
 				return mockRepo
 			}(),
 			diagnosisRepo: func() diagnoses.Repository {
 				mockRepo := &diagnoses.MockRepository{}
-				mockRepo.On("AddDiagnosis", mock.Anything).Return(nil)
+				mockRepo.On("AddDiagnosis", mock.Anything, mock.MatchedBy(func(d diagnoses.Diagnosis) bool {
+					return d.PractitionerID == "practitioner-1"
+				})).Return(nil)
 				return mockRepo
 			}(),
 			command: command,
 			wantErr: nil,
 		},
+		{
+			name:          "return error when the unit of work fails",
+			patientRepo:   &patients.MockRepository{},
+			diagnosisRepo: &diagnoses.MockRepository{},
+			unitOfWorkErr: errors.New("begin error"),
+			command:       command,
+			wantErr:       ErrAddingDiagnosis,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			h := &addPatientDiagnosisHandler{
-				patientRepo:   tt.patientRepo,
-				diagnosisRepo: tt.diagnosisRepo,
+			unitOfWork := &unitofwork.MockUnitOfWork{}
+			unitOfWork.On("Do", mock.Anything, mock.Anything).Return(unitofwork.Repositories{
+				Patients:  tt.patientRepo,
+				Diagnoses: tt.diagnosisRepo,
+			}, tt.unitOfWorkErr)
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+			h := &addPatientDiagnosisHandler{unitOfWork: unitOfWork, recorder: recorder}
+			got, err := h.Handle(context.Background(), tt.command)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if err := h.Handle(tt.command); !errors.Is(err, tt.wantErr) {
+
+			if tt.wantErr != nil {
+				if got != nil {
+					t.Errorf("Handle() got = %+v, expected no diagnosis", got)
+				}
+				return
+			}
+			if got == nil || got.ID == uuid.Nil || got.Description != "test diagnosis" || got.CreatedAt.IsZero() {
+				t.Fatalf("Handle() got = %+v, expected the created diagnosis", got)
+			}
+			tt.diagnosisRepo.(*diagnoses.MockRepository).AssertCalled(t, "AddDiagnosis", mock.Anything, *got)
+		})
+	}
+}
+
+func Test_addPatientDiagnosisHandler_Handle_Audit(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	nurse := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+
+	tests := []struct {
+		name      string
+		command   AddPatientDiagnosis
+		recordErr error
+		wantEntry auditing.Entry
+		wantErr   error
+	}{
+		{
+			name:      "record the denied attempts",
+			command:   AddPatientDiagnosis{PatientID: patientID, Actor: nurse, RequestID: "request-1"},
+			wantEntry: auditing.Entry{Actor: nurse, Action: audit.ActionAddDiagnosis, PatientID: &patientID, RequestID: "request-1", Err: auth.ErrForbidden},
+			wantErr:   auth.ErrForbidden,
+		},
+		{
+			name:      "keep the handler result when the audit record can't be appended",
+			command:   AddPatientDiagnosis{PatientID: patientID, Actor: nurse, RequestID: "request-2"},
+			recordErr: auditing.ErrRecordingAudit,
+			wantEntry: auditing.Entry{Actor: nurse, Action: audit.ActionAddDiagnosis, PatientID: &patientID, RequestID: "request-2", Err: auth.ErrForbidden},
+			wantErr:   auth.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, tt.wantEntry).Return(tt.recordErr)
+			h := &addPatientDiagnosisHandler{unitOfWork: &unitofwork.MockUnitOfWork{}, recorder: recorder}
+			if _, err := h.Handle(context.Background(), tt.command); !errors.Is(err, tt.wantErr) {
 				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_addPatientDiagnosisHandler_Handle_Coding(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+
+	tests := []struct {
+		name       string
+		coding     *diagnoses.Coding
+		wantCoding *diagnoses.Coding
+		wantErr    error
+	}{
+		{
+			name:    "return error when the code system is not ICD-10",
+			coding:  &diagnoses.Coding{System: "http://snomed.info/sct", Code: "195967001"},
+			wantErr: ErrInvalidCoding,
+		},
+		{
+			name:    "return error when the code is not in the ICD-10 table",
+			coding:  &diagnoses.Coding{Code: "J45.3"},
+			wantErr: ErrInvalidCoding,
+		},
+		{
+			name:       "store the normalized code with the display of the ICD-10 table",
+			coding:     &diagnoses.Coding{Code: "j450", Display: "allergic asthma"},
+			wantCoding: &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0", Display: "Predominantly allergic asthma"},
+		},
+		{
+			name:       "store diagnoses without coding",
+			coding:     nil,
+			wantCoding: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icd10 := &codes.MockCatalog{}
+			icd10.On("Lookup", "J45.3").Return(codes.Concept{}, false)
+			icd10.On("Lookup", "j450").Return(codes.Concept{Code: "J45.0", Display: "Predominantly allergic asthma"}, true)
+
+			patientRepo := &patients.MockRepository{}
+			patientRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+			patientRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+			diagnosisRepo := &diagnoses.MockRepository{}
+			diagnosisRepo.On("AddDiagnosis", mock.Anything, mock.Anything).Return(nil)
+			unitOfWork := &unitofwork.MockUnitOfWork{}
+			unitOfWork.On("Do", mock.Anything, mock.Anything).Return(unitofwork.Repositories{Patients: patientRepo, Diagnoses: diagnosisRepo}, nil)
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+			h := &addPatientDiagnosisHandler{unitOfWork: unitOfWork, recorder: recorder, icd10: icd10}
+			_, err := h.Handle(context.Background(), AddPatientDiagnosis{PatientID: patientID, Diagnosis: "asthma", Coding: tt.coding, Actor: clinician})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				diagnosisRepo.AssertNotCalled(t, "AddDiagnosis", mock.Anything, mock.Anything)
+				return
+			}
+			diagnosisRepo.AssertCalled(t, "AddDiagnosis", mock.Anything, mock.MatchedBy(func(d diagnoses.Diagnosis) bool {
+				return reflect.DeepEqual(d.Coding, tt.wantCoding)
+			}))
+		})
+	}
+}
+
+func Test_addPatientDiagnosisHandler_Handle_Prescription(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+	amoxicillin := diagnoses.Medication{
+		DrugName:  "Amoxicillin",
+		Dose:      500,
+		DoseUnit:  "mg",
+		Route:     diagnoses.RouteOral,
+		Frequency: diagnoses.Frequency{Times: 1, Period: 8, Unit: diagnoses.UnitHour},
+		Duration:  diagnoses.Duration{Value: 7, Unit: diagnoses.UnitDay},
+		Quantity:  21,
+	}
+	withoutRoute := amoxicillin
+	withoutRoute.Route = ""
+	tooManyRefills := amoxicillin
+	tooManyRefills.Refills = diagnoses.MaxRefills + 1
+	withoutDose := amoxicillin
+	withoutDose.Dose = 0
+	withoutDose.Duration = diagnoses.Duration{Value: 7}
+
+	tests := []struct {
+		name         string
+		prescription *diagnoses.Prescription
+		wantErrs     []error
+	}{
+		{
+			name:         "return error when the prescription is empty",
+			prescription: &diagnoses.Prescription{},
+			wantErrs:     []error{validation.ErrInvalidCommand, diagnoses.ErrEmptyPrescription},
+		},
+		{
+			name:         "return error when the route is not supported",
+			prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{amoxicillin, withoutRoute}},
+			wantErrs:     []error{validation.ErrInvalidCommand, diagnoses.ErrInvalidRoute},
+		},
+		{
+			name:         "return error when there are too many refills",
+			prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{tooManyRefills}},
+			wantErrs:     []error{validation.ErrInvalidCommand, diagnoses.ErrInvalidRefills},
+		},
+		{
+			name:         "return every error of the medication",
+			prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{withoutDose}},
+			wantErrs:     []error{validation.ErrInvalidCommand, diagnoses.ErrInvalidDose, diagnoses.ErrInvalidDuration},
+		},
+		{
+			name:         "store structured prescriptions",
+			prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{amoxicillin}, Notes: "take with food"},
+		},
+		{
+			name:         "store prescriptions with notes only",
+			prescription: &diagnoses.Prescription{Notes: "ibuprofen 400mg if needed"},
+		},
+		{
+			name:         "store diagnoses without prescription",
+			prescription: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patientRepo := &patients.MockRepository{}
+			patientRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+			patientRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+			diagnosisRepo := &diagnoses.MockRepository{}
+			diagnosisRepo.On("AddDiagnosis", mock.Anything, mock.Anything).Return(nil)
+			unitOfWork := &unitofwork.MockUnitOfWork{}
+			unitOfWork.On("Do", mock.Anything, mock.Anything).Return(unitofwork.Repositories{Patients: patientRepo, Diagnoses: diagnosisRepo}, nil)
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+			h := &addPatientDiagnosisHandler{unitOfWork: unitOfWork, recorder: recorder, icd10: &codes.MockCatalog{}}
+			_, err := h.Handle(context.Background(), AddPatientDiagnosis{PatientID: patientID, Diagnosis: "infection", Prescription: tt.prescription, Actor: clinician})
+			for _, wantErr := range tt.wantErrs {
+				if !errors.Is(err, wantErr) {
+					t.Errorf("Handle() error = %v, expected it to wrap %v", err, wantErr)
+				}
+			}
+
+			if len(tt.wantErrs) > 0 {
+				diagnosisRepo.AssertNotCalled(t, "AddDiagnosis", mock.Anything, mock.Anything)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Handle() error = %v, but no error expected", err)
+			}
+			diagnosisRepo.AssertCalled(t, "AddDiagnosis", mock.Anything, mock.MatchedBy(func(d diagnoses.Diagnosis) bool {
+				return reflect.DeepEqual(d.Prescription, tt.prescription)
+			}))
 		})
 	}
 }
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"log/slog"
+	"time"
+)
+
+// AmendDiagnosis replaces the description, prescription and coding of a diagnosis, see AddPatientDiagnosis.
+type AmendDiagnosis struct {
+	DiagnosisID  uuid.UUID
+	Diagnosis    string
+	Prescription *diagnoses.Prescription
+	Coding       *diagnoses.Coding
+	// Reason explains why the diagnosis is amended, it is required
+	Reason string
+	// Actor is the practitioner amending the diagnosis, only clinicians are allowed
+	Actor     auth.Principal
+	RequestID string
+}
+
+type AmendDiagnosisHandler interface {
+	Handle(ctx context.Context, command AmendDiagnosis) error
+}
+
+type amendDiagnosisHandler struct {
+	unitOfWork unitofwork.UnitOfWork
+	recorder   auditing.Recorder
+	icd10      codes.Catalog
+}
+
+// NewAmendDiagnosisHandler returns a handler that writes the amended diagnosis as a new version, keeping the
+// previous ones in its history. Diagnoses entered in error can't be amended.
+func NewAmendDiagnosisHandler(unitOfWork unitofwork.UnitOfWork, recorder auditing.Recorder,
+	icd10 codes.Catalog) AmendDiagnosisHandler {
+	return &amendDiagnosisHandler{
+		unitOfWork: unitOfWork,
+		recorder:   recorder,
+		icd10:      icd10,
+	}
+}
+
+func (h *amendDiagnosisHandler) Handle(ctx context.Context, command AmendDiagnosis) error {
+	patientID, err := h.handle(ctx, command)
+
+	auditErr := h.recorder.Record(ctx, auditing.Entry{
+		Actor:     command.Actor,
+		Action:    audit.ActionAmendDiagnosis,
+		PatientID: patientID,
+		RequestID: command.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "diagnosisID", command.DiagnosisID, "actor", command.Actor.ID)
+	}
+
+	return err
+}
+
+func (h *amendDiagnosisHandler) handle(ctx context.Context, command AmendDiagnosis) (*uuid.UUID, error) {
+	if err := auth.Authorize(command.Actor, auth.WriteDiagnoses); err != nil {
+		slog.Info("amend diagnosis not authorized", "err", err, "actor", command.Actor.ID)
+		return nil, err
+	}
+
+	if err := command.Validate(); err != nil {
+		slog.Info(err.Error(), "diagnosisID", command.DiagnosisID)
+		return nil, err
+	}
+
+	coding, err := validateCoding(h.icd10, command.Coding)
+	if err != nil {
+		slog.Info(err.Error(), "coding", command.Coding)
+		return nil, err
+	}
+
+	revision := diagnoses.Revision{AuthorID: command.Actor.ID, At: time.Now(), Reason: command.Reason}
+	return reviseDiagnosis(ctx, h.unitOfWork, command.DiagnosisID, func(current diagnoses.Diagnosis) (diagnoses.Diagnosis, error) {
+		return current.Amend(command.Diagnosis, command.Prescription, coding, revision)
+	})
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_amendDiagnosisHandler_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	diagnosisID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	clinician := auth.Principal{ID: "practitioner-2", Roles: []auth.Role{auth.RoleClinician}}
+	stored := diagnoses.Diagnosis{
+		ID:             diagnosisID,
+		PatientID:      patientID,
+		Description:    "asthma",
+		CreatedAt:      time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		PractitionerID: "practitioner-1",
+		Version:        1,
+		Status:         diagnoses.StatusFinal,
+	}
+	retracted := stored
+	retracted.Version = 2
+	retracted.Status = diagnoses.StatusEnteredInError
+
+	command := AmendDiagnosis{
+		DiagnosisID:  diagnosisID,
+		Diagnosis:    "allergic asthma",
+		Prescription: &diagnoses.Prescription{Notes: "avoid pollen"},
+		Coding:       &diagnoses.Coding{Code: "J45.0"},
+		Reason:       "allergy test results",
+		Actor:        clinician,
+		RequestID:    "request-1",
+	}
+
+	tests := []struct {
+		name          string
+		command       AmendDiagnosis
+		stored        *diagnoses.Diagnosis
+		getErr        error
+		updateErr     error
+		wantErr       error
+		wantPatientID *uuid.UUID
+	}{
+		{
+			name: "return error when the actor is not a clinician",
+			command: func() AmendDiagnosis {
+				c := command
+				c.Actor = auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+				return c
+			}(),
+			wantErr: auth.ErrForbidden,
+		},
+		{
+			name: "return error when the code is not in the ICD-10 table",
+			command: func() AmendDiagnosis {
+				c := command
+				c.Coding = &diagnoses.Coding{Code: "J45.3"}
+				return c
+			}(),
+			wantErr: ErrInvalidCoding,
+		},
+		{
+			name: "return error when the prescription is invalid",
+			command: func() AmendDiagnosis {
+				c := command
+				c.Prescription = &diagnoses.Prescription{}
+				return c
+			}(),
+			wantErr: diagnoses.ErrEmptyPrescription,
+		},
+		{
+			name:    "return error when the diagnosis can't be read",
+			command: command,
+			getErr:  errors.New("DB error"),
+			wantErr: ErrGettingDiagnosis,
+		},
+		{
+			name:    "return error when there is no diagnosis for that ID",
+			command: command,
+			wantErr: ErrDiagnosisNotFound,
+		},
+		{
+			name: "return error when the reason is missing",
+			command: func() AmendDiagnosis {
+				c := command
+				c.Reason = " "
+				return c
+			}(),
+			wantErr: diagnoses.ErrMissingReason,
+		},
+		{
+			name: "return error when the diagnosis has control characters",
+			command: func() AmendDiagnosis {
+				c := command
+				c.Diagnosis = "allergic\x00asthma"
+				return c
+			}(),
+			wantErr: validation.ErrInvalidCommand,
+		},
+		{
+			name:          "return error when the diagnosis was entered in error",
+			command:       command,
+			stored:        &retracted,
+			wantErr:       diagnoses.ErrEnteredInError,
+			wantPatientID: &patientID,
+		},
+		{
+			name:          "return error when the new version can't be stored",
+			command:       command,
+			stored:        &stored,
+			updateErr:     errors.New("DB error"),
+			wantErr:       ErrUpdatingDiagnosis,
+			wantPatientID: &patientID,
+		},
+		{
+			name:          "store the amended diagnosis as a new version",
+			command:       command,
+			stored:        &stored,
+			wantPatientID: &patientID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icd10 := &codes.MockCatalog{}
+			icd10.On("Lookup", "J45.3").Return(codes.Concept{}, false)
+			icd10.On("Lookup", "J45.0").Return(codes.Concept{Code: "J45.0", Display: "Predominantly allergic asthma"}, true)
+
+			// the patient repository has no expectations, any call to it fails the test
+			patientRepo := &patients.MockRepository{}
+			diagnosisRepo := &diagnoses.MockRepository{}
+			diagnosisRepo.On("GetDiagnosisByID", mock.Anything, diagnosisID).Return(tt.stored, tt.getErr)
+			diagnosisRepo.On("UpdateDiagnosis", mock.Anything, mock.Anything).Return(tt.updateErr)
+			unitOfWork := &unitofwork.MockUnitOfWork{}
+			unitOfWork.On("Do", mock.Anything, mock.Anything).Return(unitofwork.Repositories{Patients: patientRepo, Diagnoses: diagnosisRepo}, nil)
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.MatchedBy(func(entry auditing.Entry) bool {
+				return entry.Action == audit.ActionAmendDiagnosis && reflect.DeepEqual(entry.PatientID, tt.wantPatientID) &&
+					errors.Is(entry.Err, tt.wantErr) && entry.RequestID == "request-1"
+			})).Return(nil).Once()
+
+			h := &amendDiagnosisHandler{unitOfWork: unitOfWork, recorder: recorder, icd10: icd10}
+			err := h.Handle(context.Background(), tt.command)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			recorder.AssertExpectations(t)
+
+			if tt.wantErr != nil {
+				if tt.updateErr == nil {
+					diagnosisRepo.AssertNotCalled(t, "UpdateDiagnosis", mock.Anything, mock.Anything)
+				}
+				return
+			}
+
+			diagnosisRepo.AssertCalled(t, "UpdateDiagnosis", mock.Anything, mock.MatchedBy(func(d diagnoses.Diagnosis) bool {
+				return d.Version == 2 && d.Status == diagnoses.StatusAmended && d.Description == "allergic asthma" &&
+					d.Coding.Display == "Predominantly allergic asthma" && d.Prescription.Notes == "avoid pollen" &&
+					d.Reason == "allergy test results" && d.RecordedBy == "practitioner-2" &&
+					d.PractitionerID == "practitioner-1" && d.CreatedAt.Equal(stored.CreatedAt)
+			}))
+		})
+	}
+}
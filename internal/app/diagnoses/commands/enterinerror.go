@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"log/slog"
+	"time"
+)
+
+// EnterInError retracts a diagnosis that should never have been written, such as one added to the wrong patient.
+type EnterInError struct {
+	DiagnosisID uuid.UUID
+	// Reason explains why the diagnosis is retracted, it is required
+	Reason string
+	// Actor is the practitioner retracting the diagnosis, only clinicians are allowed
+	Actor     auth.Principal
+	RequestID string
+}
+
+type EnterInErrorHandler interface {
+	Handle(ctx context.Context, command EnterInError) error
+}
+
+type enterInErrorHandler struct {
+	unitOfWork unitofwork.UnitOfWork
+	recorder   auditing.Recorder
+}
+
+// NewEnterInErrorHandler returns a handler that writes a new version of the diagnosis marked as entered in error.
+// Searches stop returning it, but it is kept with its history.
+func NewEnterInErrorHandler(unitOfWork unitofwork.UnitOfWork, recorder auditing.Recorder) EnterInErrorHandler {
+	return &enterInErrorHandler{
+		unitOfWork: unitOfWork,
+		recorder:   recorder,
+	}
+}
+
+func (h *enterInErrorHandler) Handle(ctx context.Context, command EnterInError) error {
+	patientID, err := h.handle(ctx, command)
+
+	auditErr := h.recorder.Record(ctx, auditing.Entry{
+		Actor:     command.Actor,
+		Action:    audit.ActionEnterInError,
+		PatientID: patientID,
+		RequestID: command.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "diagnosisID", command.DiagnosisID, "actor", command.Actor.ID)
+	}
+
+	return err
+}
+
+func (h *enterInErrorHandler) handle(ctx context.Context, command EnterInError) (*uuid.UUID, error) {
+	if err := auth.Authorize(command.Actor, auth.WriteDiagnoses); err != nil {
+		slog.Info("enter diagnosis in error not authorized", "err", err, "actor", command.Actor.ID)
+		return nil, err
+	}
+
+	if err := command.Validate(); err != nil {
+		slog.Info(err.Error(), "diagnosisID", command.DiagnosisID)
+		return nil, err
+	}
+
+	revision := diagnoses.Revision{AuthorID: command.Actor.ID, At: time.Now(), Reason: command.Reason}
+	return reviseDiagnosis(ctx, h.unitOfWork, command.DiagnosisID, func(current diagnoses.Diagnosis) (diagnoses.Diagnosis, error) {
+		return current.EnterInError(revision)
+	})
+}
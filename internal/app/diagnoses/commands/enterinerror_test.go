@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_enterInErrorHandler_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	diagnosisID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+	stored := diagnoses.Diagnosis{ID: diagnosisID, PatientID: patientID, Description: "asthma", Version: 2,
+		Status: diagnoses.StatusAmended}
+	retracted := stored
+	retracted.Version = 3
+	retracted.Status = diagnoses.StatusEnteredInError
+	command := EnterInError{DiagnosisID: diagnosisID, Reason: "wrong patient", Actor: clinician, RequestID: "request-1"}
+
+	tests := []struct {
+		name          string
+		command       EnterInError
+		stored        *diagnoses.Diagnosis
+		unitOfWorkErr error
+		wantErr       error
+	}{
+		{
+			name: "return error when the actor is anonymous",
+			command: func() EnterInError {
+				c := command
+				c.Actor = auth.Principal{}
+				return c
+			}(),
+			wantErr: auth.ErrUnauthenticated,
+		},
+		{
+			name: "return error when the reason is missing",
+			command: func() EnterInError {
+				c := command
+				c.Reason = ""
+				return c
+			}(),
+			wantErr: diagnoses.ErrMissingReason,
+		},
+		{
+			name: "return error when the reason is too long",
+			command: func() EnterInError {
+				c := command
+				c.Reason = strings.Repeat("a", MaxReasonLength+1)
+				return c
+			}(),
+			wantErr: validation.ErrInvalidCommand,
+		},
+		{
+			name:    "return error when the diagnosis was already entered in error",
+			command: command,
+			stored:  &retracted,
+			wantErr: diagnoses.ErrEnteredInError,
+		},
+		{
+			name:          "return error when the unit of work fails",
+			command:       command,
+			stored:        &stored,
+			unitOfWorkErr: errors.New("begin error"),
+			wantErr:       ErrUpdatingDiagnosis,
+		},
+		{
+			name:    "store the diagnosis as entered in error without rewriting the patient",
+			command: command,
+			stored:  &stored,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the patient repository has no expectations, any call to it fails the test
+			patientRepo := &patients.MockRepository{}
+			diagnosisRepo := &diagnoses.MockRepository{}
+			diagnosisRepo.On("GetDiagnosisByID", mock.Anything, diagnosisID).Return(tt.stored, nil)
+			diagnosisRepo.On("UpdateDiagnosis", mock.Anything, mock.Anything).Return(nil)
+			unitOfWork := &unitofwork.MockUnitOfWork{}
+			unitOfWork.On("Do", mock.Anything, mock.Anything).Return(unitofwork.Repositories{Patients: patientRepo, Diagnoses: diagnosisRepo}, tt.unitOfWorkErr)
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.MatchedBy(func(entry auditing.Entry) bool {
+				return entry.Action == audit.ActionEnterInError && errors.Is(entry.Err, tt.wantErr)
+			})).Return(nil).Once()
+
+			h := &enterInErrorHandler{unitOfWork: unitOfWork, recorder: recorder}
+			err := h.Handle(context.Background(), tt.command)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			recorder.AssertExpectations(t)
+
+			if tt.wantErr != nil {
+				diagnosisRepo.AssertNotCalled(t, "UpdateDiagnosis", mock.Anything, mock.Anything)
+				return
+			}
+			diagnosisRepo.AssertCalled(t, "UpdateDiagnosis", mock.Anything, mock.MatchedBy(func(d diagnoses.Diagnosis) bool {
+				return d.Version == 3 && d.Status == diagnoses.StatusEnteredInError && d.Reason == "wrong patient" &&
+					d.Description == "asthma" && d.RecordedBy == "practitioner-1" && time.Since(d.RecordedAt) < time.Minute
+			}))
+		})
+	}
+}
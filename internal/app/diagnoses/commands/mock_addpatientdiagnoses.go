@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAddPatientDiagnoses struct {
+	mock.Mock
+}
+
+func (m *MockAddPatientDiagnoses) Handle(ctx context.Context, command AddPatientDiagnoses) ([]*diagnoses.Diagnosis, error) {
+	args := m.Called(ctx, command)
+	return args.Get(0).([]*diagnoses.Diagnosis), args.Error(1)
+}
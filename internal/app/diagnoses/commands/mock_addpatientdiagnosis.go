@@ -1,12 +1,16 @@
 package commands
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/stretchr/testify/mock"
+)
 
 type MockAddPatientDiagnosis struct {
 	mock.Mock
 }
 
-func (m *MockAddPatientDiagnosis) Handle(command AddPatientDiagnosis) error {
-	args := m.Called(command)
-	return args.Error(0)
+func (m *MockAddPatientDiagnosis) Handle(ctx context.Context, command AddPatientDiagnosis) (*diagnoses.Diagnosis, error) {
+	args := m.Called(ctx, command)
+	return args.Get(0).(*diagnoses.Diagnosis), args.Error(1)
 }
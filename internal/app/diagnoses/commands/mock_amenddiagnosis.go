@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAmendDiagnosis struct {
+	mock.Mock
+}
+
+func (m *MockAmendDiagnosis) Handle(ctx context.Context, command AmendDiagnosis) error {
+	args := m.Called(ctx, command)
+	return args.Error(0)
+}
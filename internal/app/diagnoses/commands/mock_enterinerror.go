@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEnterInError struct {
+	mock.Mock
+}
+
+func (m *MockEnterInError) Handle(ctx context.Context, command EnterInError) error {
+	args := m.Called(ctx, command)
+	return args.Error(0)
+}
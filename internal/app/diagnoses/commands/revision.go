@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"log/slog"
+)
+
+// revisionErrors are returned unchanged by reviseDiagnosis, any other error fails the revision as a whole.
+var revisionErrors = []error{ErrGettingDiagnosis, ErrDiagnosisNotFound, ErrUpdatingDiagnosis,
+	diagnoses.ErrMissingReason, diagnoses.ErrEnteredInError}
+
+// reviseDiagnosis returns the ID of the patient, nil when the diagnosis couldn't be read.
+func reviseDiagnosis(ctx context.Context, unitOfWork unitofwork.UnitOfWork, ID uuid.UUID,
+	revise func(current diagnoses.Diagnosis) (diagnoses.Diagnosis, error)) (*uuid.UUID, error) {
+	var patientID *uuid.UUID
+	var revised diagnoses.Diagnosis
+	err := unitOfWork.Do(ctx, func(repos unitofwork.Repositories) error {
+		current, err := repos.Diagnoses.GetDiagnosisByID(ctx, ID)
+		if err != nil {
+			slog.Error(err.Error(), "diagnosisID", ID)
+			return ErrGettingDiagnosis
+		}
+
+		if current == nil {
+			slog.Info(ErrDiagnosisNotFound.Error(), "diagnosisID", ID)
+			return ErrDiagnosisNotFound
+		}
+
+		patientID = &current.PatientID
+		revised, err = revise(*current)
+		if err != nil {
+			slog.Info(err.Error(), "diagnosisID", ID)
+			return err
+		}
+
+		if err := repos.Diagnoses.UpdateDiagnosis(ctx, revised); err != nil {
+			slog.Error(err.Error(), "revised", revised)
+			return ErrUpdatingDiagnosis
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		for _, known := range revisionErrors {
+			if errors.Is(err, known) {
+				return patientID, err
+			}
+		}
+
+		slog.Error(err.Error(), "revised", revised)
+		return patientID, ErrUpdatingDiagnosis
+	}
+
+	slog.Info("diagnosis successfully revised", "diagnosisID", ID, "version", revised.Version, "status", revised.Status)
+	return patientID, nil
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"strings"
+)
+
+// Limits of the diagnosis commands, in characters.
+const (
+	MaxDiagnosisLength = 2000
+	MaxReasonLength    = 500
+	MaxNotesLength     = 2000
+	MaxCodeLength      = 10
+	MaxDrugNameLength  = 200
+	MaxDrugCodeLength  = 20
+	MaxDoseUnitLength  = 20
+	// MaxMedications is the maximum number of medications of a prescription
+	MaxMedications = 50
+)
+
+var medicationFields = []struct {
+	err   error
+	field string
+}{
+	{diagnoses.ErrInvalidDrug, "drug_name"},
+	{diagnoses.ErrInvalidDose, "dose"},
+	{diagnoses.ErrInvalidDoseUnit, "dose_unit"},
+	{diagnoses.ErrInvalidRoute, "route"},
+	{diagnoses.ErrInvalidFrequency, "frequency"},
+	{diagnoses.ErrInvalidDuration, "duration"},
+	{diagnoses.ErrInvalidQuantity, "quantity"},
+	{diagnoses.ErrInvalidRefills, "refills"},
+}
+
+// Validate checks the input of the command, returning validation.Errors with every failure found.
+func (c AddPatientDiagnosis) Validate() error {
+	var v validation.Validator
+	validateDiagnosis(&v, c.Diagnosis, c.Prescription, c.Coding)
+	return v.Err()
+}
+
+// Validate checks the input of the command, returning validation.Errors with every failure found.
+func (c AmendDiagnosis) Validate() error {
+	var v validation.Validator
+	validateDiagnosis(&v, c.Diagnosis, c.Prescription, c.Coding)
+	validateReason(&v, c.Reason)
+	return v.Err()
+}
+
+// Validate checks the input of the command, returning validation.Errors with every failure found.
+func (c EnterInError) Validate() error {
+	var v validation.Validator
+	validateReason(&v, c.Reason)
+	return v.Err()
+}
+
+func validateDiagnosis(v *validation.Validator, description string, prescription *diagnoses.Prescription,
+	coding *diagnoses.Coding) {
+	if v.Required("diagnosis", description) {
+		v.Text("diagnosis", description, MaxDiagnosisLength)
+	}
+
+	if coding != nil && v.Required("coding.code", coding.Code) {
+		v.Code("coding.code", strings.TrimSpace(coding.Code), MaxCodeLength)
+	}
+
+	if prescription != nil {
+		validatePrescription(v, *prescription)
+	}
+}
+
+func validateReason(v *validation.Validator, reason string) {
+	if strings.TrimSpace(reason) == "" {
+		v.AddError("reason", diagnoses.ErrMissingReason)
+		return
+	}
+	v.Text("reason", reason, MaxReasonLength)
+}
+
+func validatePrescription(v *validation.Validator, prescription diagnoses.Prescription) {
+	if len(prescription.Medications) == 0 && strings.TrimSpace(prescription.Notes) == "" {
+		v.AddError("prescription", diagnoses.ErrEmptyPrescription)
+		return
+	}
+
+	v.Text("prescription.notes", prescription.Notes, MaxNotesLength)
+	v.MaxItems("prescription.medications", len(prescription.Medications), MaxMedications)
+	for i, medication := range prescription.Medications {
+		prefix := fmt.Sprintf("prescription.medications[%d].", i)
+		v.Text(prefix+"drug_name", medication.DrugName, MaxDrugNameLength)
+		v.Code(prefix+"drug_code", medication.DrugCode, MaxDrugCodeLength)
+		v.Text(prefix+"dose_unit", medication.DoseUnit, MaxDoseUnitLength)
+
+		err := medication.Validate()
+		for _, mapping := range medicationFields {
+			if errors.Is(err, mapping.err) {
+				v.AddError(prefix+mapping.field, mapping.err)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddPatientDiagnosis_Validate(t *testing.T) {
+	amoxicillin := diagnoses.Medication{
+		DrugName:  "Amoxicillin",
+		DrugCode:  "J01CA04",
+		Dose:      500,
+		DoseUnit:  "mg",
+		Route:     diagnoses.RouteOral,
+		Frequency: diagnoses.Frequency{Times: 1, Period: 8, Unit: diagnoses.UnitHour},
+		Duration:  diagnoses.Duration{Value: 7, Unit: diagnoses.UnitDay},
+		Quantity:  21,
+	}
+	invalid := amoxicillin
+	invalid.DrugCode = "J01 CA04"
+	invalid.Dose = 0
+	invalid.Route = "by mouth"
+	tooMany := make([]diagnoses.Medication, MaxMedications+1)
+	for i := range tooMany {
+		tooMany[i] = amoxicillin
+	}
+
+	tests := []struct {
+		name    string
+		command AddPatientDiagnosis
+		want    []validation.FieldError
+	}{
+		{
+			name: "accept a valid command",
+			command: AddPatientDiagnosis{
+				Diagnosis:    "otitis media",
+				Coding:       &diagnoses.Coding{Code: "H66.9"},
+				Prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{amoxicillin}, Notes: "take with food"},
+			},
+		},
+		{
+			name:    "reject a missing diagnosis",
+			command: AddPatientDiagnosis{Diagnosis: "  "},
+			want:    []validation.FieldError{{Field: "diagnosis", Message: "is required"}},
+		},
+		{
+			name:    "reject a diagnosis too long",
+			command: AddPatientDiagnosis{Diagnosis: strings.Repeat("a", MaxDiagnosisLength+1)},
+			want:    []validation.FieldError{{Field: "diagnosis", Message: "can't be longer than 2000 characters"}},
+		},
+		{
+			name:    "reject codes with characters other than letters, digits and dots",
+			command: AddPatientDiagnosis{Diagnosis: "asthma", Coding: &diagnoses.Coding{Code: "J45.0'--"}},
+			want:    []validation.FieldError{{Field: "coding.code", Message: "can only have letters, digits, dots and hyphens"}},
+		},
+		{
+			name:    "reject empty prescriptions",
+			command: AddPatientDiagnosis{Diagnosis: "otitis", Prescription: &diagnoses.Prescription{}},
+			want: []validation.FieldError{{Field: "prescription", Message: diagnoses.ErrEmptyPrescription.Error(),
+				Err: diagnoses.ErrEmptyPrescription}},
+		},
+		{
+			name: "report the path of every medication field that is invalid",
+			command: AddPatientDiagnosis{
+				Diagnosis:    "otitis",
+				Prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{amoxicillin, invalid}},
+			},
+			want: []validation.FieldError{
+				{Field: "prescription.medications[1].drug_code", Message: "can only have letters, digits, dots and hyphens"},
+				{Field: "prescription.medications[1].dose", Message: diagnoses.ErrInvalidDose.Error(), Err: diagnoses.ErrInvalidDose},
+				{Field: "prescription.medications[1].route", Message: diagnoses.ErrInvalidRoute.Error(), Err: diagnoses.ErrInvalidRoute},
+			},
+		},
+		{
+			name: "reject too many medications",
+			command: AddPatientDiagnosis{
+				Diagnosis:    "otitis",
+				Prescription: &diagnoses.Prescription{Medications: tooMany},
+			},
+			want: []validation.FieldError{{Field: "prescription.medications", Message: "can't have more than 50 items"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.command.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, but no error expected", err)
+				}
+				return
+			}
+
+			errs, ok := err.(validation.Errors)
+			if !ok {
+				t.Fatalf("Validate() error = %v, want validation.Errors", err)
+			}
+			if !reflect.DeepEqual([]validation.FieldError(errs), tt.want) {
+				t.Errorf("Validate() error = %#v, want %#v", errs, tt.want)
+			}
+		})
+	}
+}
@@ -1,38 +1,203 @@
 package queries
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
 	"log/slog"
+	"time"
 )
 
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+var ErrAmbiguousPatient = errors.New("several patients have that name")
+
+// AmbiguousPatientError is returned instead of the diagnoses of any of them when several patients have the name
+// searched, so the caller can pick the right one and search by its ID or legal ID.
+type AmbiguousPatientError struct {
+	// Candidates are the patients with the name, sorted by ID
+	Candidates []*patients.Patient
+}
+
+func (e *AmbiguousPatientError) Error() string {
+	return fmt.Sprintf("%s: %d patients", ErrAmbiguousPatient, len(e.Candidates))
+}
+
+func (e *AmbiguousPatientError) Unwrap() error {
+	return ErrAmbiguousPatient
+}
+
+// GetDiagnosesQuery filters diagnoses by patient, creation date and/or ICD-10 code.
+// Any of the fields can be omitted, but at least one should be set.
 type GetDiagnosesQuery struct {
+	// PatientID, LegalID and PatientName identify the patient, only the first one set is used
+	PatientID   *uuid.UUID
+	LegalID     string
 	PatientName string
+	From        *time.Time
+	To          *time.Time
+	// Code and CodePrefix must be normalized, see diagnoses.NormalizeICD10
+	Code       string
+	CodePrefix string
+	// Limit is the page size, DefaultPageSize when zero and never more than MaxPageSize
+	Limit int
+	// Cursor is the Page.Next of the previous page, nil for the first one
+	Cursor    *diagnoses.Cursor
+	Order     diagnoses.SortOrder
+	Actor     auth.Principal
+	RequestID string
 }
 
 type GetDiagnosesHandler interface {
-	Handle(query GetDiagnosesQuery) ([]*diagnoses.Diagnosis, error)
+	Handle(ctx context.Context, query GetDiagnosesQuery) (diagnoses.Page, error)
 }
 
 type getDiagnoses struct {
-	patientRepo patients.Repository
+	patientRepo   patients.Repository
+	diagnosisRepo diagnoses.Repository
+	recorder      auditing.Recorder
+}
+
+// NewGetDiagnosesHandler returns a handler that records every search in the audit trail, once per patient
+// whose diagnoses are returned.
+func NewGetDiagnosesHandler(patientRepo patients.Repository, diagnosisRepo diagnoses.Repository, recorder auditing.Recorder) GetDiagnosesHandler {
+	return &getDiagnoses{
+		patientRepo:   patientRepo,
+		diagnosisRepo: diagnosisRepo,
+		recorder:      recorder,
+	}
 }
 
-func NewGetDiagnosesHandler(patientRepo patients.Repository) GetDiagnosesHandler {
-	return &getDiagnoses{patientRepo: patientRepo}
+func (g *getDiagnoses) Handle(ctx context.Context, query GetDiagnosesQuery) (diagnoses.Page, error) {
+	page, patientID, err := g.handle(ctx, query)
+
+	patientIDs := []*uuid.UUID{patientID}
+	if patientID == nil && err == nil {
+		patientIDs = readPatientIDs(page)
+	}
+
+	for _, id := range patientIDs {
+		auditErr := g.recorder.Record(ctx, auditing.Entry{
+			Actor:     query.Actor,
+			Action:    audit.ActionReadDiagnoses,
+			PatientID: id,
+			RequestID: query.RequestID,
+			Err:       err,
+		})
+		if auditErr != nil {
+			slog.Error(auditErr.Error(), "query", query)
+			if err == nil {
+				return diagnoses.Page{}, auditErr
+			}
+		}
+	}
+
+	return page, err
+}
+
+// handle returns the page of diagnoses and, for searches by name, the ID of the patient.
+func (g *getDiagnoses) handle(ctx context.Context, query GetDiagnosesQuery) (diagnoses.Page, *uuid.UUID, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadDiagnoses); err != nil {
+		return diagnoses.Page{}, nil, err
+	}
+
+	filter := diagnoses.Filter{From: query.From, To: query.To, Code: query.Code, CodePrefix: query.CodePrefix}
+
+	if query.PatientID != nil || query.LegalID != "" || query.PatientName != "" {
+		patient, err := g.getPatient(ctx, query)
+		if err != nil {
+			return diagnoses.Page{}, nil, err
+		}
+
+		filter.PatientID = &patient.ID
+	}
+
+	page, err := g.diagnosisRepo.GetDiagnoses(ctx, filter, pageRequest(query.Limit, query.Cursor, query.Order))
+	if err != nil {
+		slog.Error("error getting diagnoses", "err", err, "query", query)
+		return diagnoses.Page{}, filter.PatientID, commands.ErrGettingDiagnoses
+	}
+
+	return page, filter.PatientID, nil
 }
 
-func (g *getDiagnoses) Handle(query GetDiagnosesQuery) ([]*diagnoses.Diagnosis, error) {
-	patient, err := g.patientRepo.GetByName(query.PatientName)
+func (g *getDiagnoses) getPatient(ctx context.Context, query GetDiagnosesQuery) (*patients.Patient, error) {
+	var found []*patients.Patient
+	var err error
+	switch {
+	case query.PatientID != nil:
+		found, err = oneOrNone(g.patientRepo.GetByID(ctx, *query.PatientID))
+	case query.LegalID != "":
+		found, err = oneOrNone(g.patientRepo.GetByLegalID(ctx, query.LegalID))
+	default:
+		found, err = g.patientRepo.FindByName(ctx, query.PatientName)
+	}
+
 	if err != nil {
 		slog.Error("error getting patient", "err", err, "query", query)
-		return nil, commands.ErrGettingPatient
+		return nil, patients.ErrGettingPatient
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, patients.ErrPatientNotFound
+	case 1:
+		return found[0], nil
+	default:
+		slog.Warn("patient name is ambiguous", "candidates", len(found), "requestID", query.RequestID)
+		return nil, &AmbiguousPatientError{Candidates: found}
 	}
+}
 
+func oneOrNone(patient *patients.Patient, err error) ([]*patients.Patient, error) {
 	if patient == nil {
-		return nil, commands.ErrPatientNotFound
+		return nil, err
+	}
+	return []*patients.Patient{patient}, err
+}
+
+// readPatientIDs returns the distinct patients of the page diagnoses, or a single nil ID when the page is empty
+// so the search itself is still recorded.
+func readPatientIDs(page diagnoses.Page) []*uuid.UUID {
+	if len(page.Diagnoses) == 0 {
+		return []*uuid.UUID{nil}
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var patientIDs []*uuid.UUID
+	for _, diagnosis := range page.Diagnoses {
+		if !seen[diagnosis.PatientID] {
+			seen[diagnosis.PatientID] = true
+			patientID := diagnosis.PatientID
+			patientIDs = append(patientIDs, &patientID)
+		}
+	}
+
+	return patientIDs
+}
+
+func pageRequest(limit int, cursor *diagnoses.Cursor, order diagnoses.SortOrder) diagnoses.PageRequest {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	if order == "" {
+		order = diagnoses.SortAscending
 	}
 
-	return patient.Diagnostics, nil
+	return diagnoses.PageRequest{Limit: limit, After: cursor, Order: order}
 }
@@ -1,71 +1,237 @@
 package queries
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
 	"reflect"
 	"testing"
 	"time"
 )
 
 func Test_getDiagnoses_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	otherID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+	cursor := diagnoses.Cursor{CreatedAt: from, ID: patientID}
+	firstPage := diagnoses.PageRequest{Limit: DefaultPageSize, Order: diagnoses.SortAscending}
+	reader := auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}}
+
 	tests := []struct {
-		name        string
-		patientRepo patients.Repository
-		query       GetDiagnosesQuery
-		want        []*diagnoses.Diagnosis
-		wantErr     error
+		name          string
+		patientRepo   patients.Repository
+		diagnosisRepo diagnoses.Repository
+		query         GetDiagnosesQuery
+		want          diagnoses.Page
+		wantErr       error
 	}{
+		{
+			name:        "return error when the actor has no role",
+			patientRepo: &patients.MockRepository{},
+			query:       GetDiagnosesQuery{PatientName: "John", Actor: auth.Principal{ID: "practitioner-1"}},
+			want:        diagnoses.Page{},
+			wantErr:     auth.ErrForbidden,
+		},
 		{
 			name: "return error when can't get the patient",
 			patientRepo: func() patients.Repository {
 				mockRepo := &patients.MockRepository{}
-				mockRepo.On("GetByName", "John").Return((*patients.Patient)(nil), errors.New("DB error"))
+				mockRepo.On("FindByName", mock.Anything, "John").Return(([]*patients.Patient)(nil), errors.New("DB error"))
 				return mockRepo
 			}(),
-			query:   GetDiagnosesQuery{PatientName: "John"},
-			want:    nil,
-			wantErr: commands.ErrGettingPatient,
+			query:   GetDiagnosesQuery{PatientName: "John", Actor: reader},
+			want:    diagnoses.Page{},
+			wantErr: patients.ErrGettingPatient,
 		},
 		{
 			name: "return error when the patient doesn't exists",
 			patientRepo: func() patients.Repository {
 				mockRepo := &patients.MockRepository{}
-				mockRepo.On("GetByName", "John").Return((*patients.Patient)(nil), nil)
+				mockRepo.On("FindByName", mock.Anything, "John").Return([]*patients.Patient{}, nil)
 				return mockRepo
 			}(),
-			query:   GetDiagnosesQuery{PatientName: "John"},
-			want:    nil,
-			wantErr: commands.ErrPatientNotFound,
+			query:   GetDiagnosesQuery{PatientName: "John", Actor: reader},
+			want:    diagnoses.Page{},
+			wantErr: patients.ErrPatientNotFound,
 		},
 		{
 			name: "return patient diagnoses without error",
 			patientRepo: func() patients.Repository {
 				mockRepo := &patients.MockRepository{}
-				mockRepo.On("GetByName", "John Doe").Return(&patients.Patient{
-					ID:          uuid.MustParse("11111111-1111-1111-1111-111111111111"),
-					LegalID:     "1234",
-					Name:        "Jhon Doe",
-					Address:     "test",
-					Phone:       "1234",
-					Email:       "test@example.com",
-					Diagnostics: createFakeDiagnoses(),
-				}, nil)
-				return mockRepo
-			}(),
-			query:   GetDiagnosesQuery{PatientName: "John Doe"},
-			want:    createFakeDiagnoses(),
+				mockRepo.On("FindByName", mock.Anything, "John Doe").Return([]*patients.Patient{{
+					ID:      patientID,
+					LegalID: "1234",
+					Name:    "Jhon Doe",
+					Address: "test",
+					Phone:   "1234",
+					Email:   "test@example.com",
+				}}, nil)
+				return mockRepo
+			}(),
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{PatientID: &patientID}, firstPage).
+					Return(diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1}, nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{PatientName: "John Doe", Actor: reader},
+			want:    diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1},
+			wantErr: nil,
+		},
+		{
+			name: "return the diagnoses of the patient ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID, Name: "John Doe"}, nil)
+				return mockRepo
+			}(),
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{PatientID: &patientID}, firstPage).
+					Return(diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1}, nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{PatientID: &patientID, PatientName: "Someone Else", Actor: reader},
+			want:    diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1},
+			wantErr: nil,
+		},
+		{
+			name: "return error when several patients have the name",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("FindByName", mock.Anything, "John Doe").Return([]*patients.Patient{{ID: patientID}, {ID: otherID}}, nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{PatientName: "John Doe", Actor: reader},
+			want:    diagnoses.Page{},
+			wantErr: ErrAmbiguousPatient,
+		},
+		{
+			name: "return the diagnoses of the legal ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return(&patients.Patient{ID: patientID, Name: "John Doe"}, nil)
+				return mockRepo
+			}(),
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{PatientID: &patientID}, firstPage).
+					Return(diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1}, nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{LegalID: "ABC1234", PatientName: "John Doe", Actor: reader},
+			want:    diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1},
+			wantErr: nil,
+		},
+		{
+			name: "return error when there is no patient with that legal ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return((*patients.Patient)(nil), nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{LegalID: "ABC1234", Actor: reader},
+			want:    diagnoses.Page{},
+			wantErr: patients.ErrPatientNotFound,
+		},
+		{
+			name: "return error when there is no patient with that ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{PatientID: &patientID, Actor: reader},
+			want:    diagnoses.Page{},
+			wantErr: patients.ErrPatientNotFound,
+		},
+		{
+			name: "return the patient diagnoses inside the date range",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("FindByName", mock.Anything, "John Doe").Return([]*patients.Patient{{ID: patientID, Name: "John Doe"}}, nil)
+				return mockRepo
+			}(),
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{PatientID: &patientID, From: &from, To: &to}, firstPage).
+					Return(diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1}, nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{PatientName: "John Doe", From: &from, To: &to, Actor: reader},
+			want:    diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1},
+			wantErr: nil,
+		},
+		{
+			name:        "return error when can't search diagnoses by date",
+			patientRepo: &patients.MockRepository{},
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{From: &from}, firstPage).
+					Return(diagnoses.Page{}, errors.New("DB error"))
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{From: &from, Actor: reader},
+			want:    diagnoses.Page{},
+			wantErr: commands.ErrGettingDiagnoses,
+		},
+		{
+			name:        "return diagnoses of every patient when searching only by date",
+			patientRepo: &patients.MockRepository{},
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{From: &from, To: &to}, firstPage).
+					Return(diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1}, nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{From: &from, To: &to, Actor: reader},
+			want:    diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1},
 			wantErr: nil,
 		},
+		{
+			name:        "return diagnoses of every patient by code and code prefix",
+			patientRepo: &patients.MockRepository{},
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{Code: "J45.0", CodePrefix: "J4"}, firstPage).
+					Return(diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1}, nil)
+				return mockRepo
+			}(),
+			query:   GetDiagnosesQuery{Code: "J45.0", CodePrefix: "J4", Actor: reader},
+			want:    diagnoses.Page{Diagnoses: createFakeDiagnoses(), Total: 1},
+			wantErr: nil,
+		},
+		{
+			name:        "request the next page with the cursor, order and a bounded limit",
+			patientRepo: &patients.MockRepository{},
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, diagnoses.Filter{From: &from}, diagnoses.PageRequest{
+					Limit: MaxPageSize,
+					After: &cursor,
+					Order: diagnoses.SortDescending,
+				}).Return(diagnoses.Page{Diagnoses: createFakeDiagnoses(), Next: &cursor, Total: 600}, nil)
+				return mockRepo
+			}(),
+			query: GetDiagnosesQuery{From: &from, Limit: 1000, Cursor: &cursor, Order: diagnoses.SortDescending, Actor: reader},
+			want:  diagnoses.Page{Diagnoses: createFakeDiagnoses(), Next: &cursor, Total: 600},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			g := &getDiagnoses{patientRepo: tt.patientRepo}
-			got, err := g.Handle(tt.query)
-			if err != nil && err != tt.wantErr {
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+			g := &getDiagnoses{patientRepo: tt.patientRepo, diagnosisRepo: tt.diagnosisRepo, recorder: recorder}
+			got, err := g.Handle(context.Background(), tt.query)
+			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
@@ -76,6 +242,126 @@ func Test_getDiagnoses_Handle(t *testing.T) {
 	}
 }
 
+func Test_getDiagnoses_Handle_Audit(t *testing.T) {
+	johnID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	janeID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	reader := auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}}
+	readEntry := func(patientID *uuid.UUID, err error) auditing.Entry {
+		return auditing.Entry{Actor: reader, Action: audit.ActionReadDiagnoses, PatientID: patientID, RequestID: "request-1", Err: err}
+	}
+
+	tests := []struct {
+		name          string
+		patientRepo   patients.Repository
+		diagnosisRepo diagnoses.Repository
+		query         GetDiagnosesQuery
+		recordErr     error
+		wantEntries   []auditing.Entry
+		wantErr       error
+	}{
+		{
+			name: "record the patient searched by name",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("FindByName", mock.Anything, "John").Return([]*patients.Patient{{ID: johnID}}, nil)
+				return mockRepo
+			}(),
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, mock.Anything, mock.Anything).Return(diagnoses.Page{}, nil)
+				return mockRepo
+			}(),
+			query:       GetDiagnosesQuery{PatientName: "John", Actor: reader, RequestID: "request-1"},
+			wantEntries: []auditing.Entry{readEntry(&johnID, nil)},
+		},
+		{
+			name: "record a search by name without patient",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("FindByName", mock.Anything, "John").Return([]*patients.Patient{}, nil)
+				return mockRepo
+			}(),
+			query:       GetDiagnosesQuery{PatientName: "John", Actor: reader, RequestID: "request-1"},
+			wantEntries: []auditing.Entry{readEntry(nil, patients.ErrPatientNotFound)},
+			wantErr:     patients.ErrPatientNotFound,
+		},
+		{
+			name:        "record once every patient whose diagnoses are returned",
+			patientRepo: &patients.MockRepository{},
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, mock.Anything, mock.Anything).Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{
+					{PatientID: johnID}, {PatientID: janeID}, {PatientID: johnID},
+				}}, nil)
+				return mockRepo
+			}(),
+			query:       GetDiagnosesQuery{From: &from, Actor: reader, RequestID: "request-1"},
+			wantEntries: []auditing.Entry{readEntry(&johnID, nil), readEntry(&janeID, nil)},
+		},
+		{
+			name:        "don't return the diagnoses when the read can't be recorded",
+			patientRepo: &patients.MockRepository{},
+			diagnosisRepo: func() diagnoses.Repository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, mock.Anything, mock.Anything).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{{PatientID: johnID}}}, nil)
+				return mockRepo
+			}(),
+			query:       GetDiagnosesQuery{From: &from, Actor: reader, RequestID: "request-1"},
+			recordErr:   auditing.ErrRecordingAudit,
+			wantEntries: []auditing.Entry{readEntry(&johnID, nil)},
+			wantErr:     auditing.ErrRecordingAudit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &auditing.MockRecorder{}
+			for _, entry := range tt.wantEntries {
+				recorder.On("Record", mock.Anything, entry).Return(tt.recordErr).Once()
+			}
+			g := &getDiagnoses{patientRepo: tt.patientRepo, diagnosisRepo: tt.diagnosisRepo, recorder: recorder}
+			got, err := g.Handle(context.Background(), tt.query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && !reflect.DeepEqual(got, diagnoses.Page{}) {
+				t.Errorf("Handle() got = %v, expected no diagnoses on error", got)
+			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_getDiagnoses_Handle_AmbiguousName(t *testing.T) {
+	candidates := []*patients.Patient{
+		{ID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), LegalID: "ABC1234", Name: "John Doe"},
+		{ID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), LegalID: "XYZ9876", Name: "John Doe"},
+	}
+	patientRepo := &patients.MockRepository{}
+	patientRepo.On("FindByName", mock.Anything, "John Doe").Return(candidates, nil)
+	recorder := &auditing.MockRecorder{}
+	recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+	g := &getDiagnoses{patientRepo: patientRepo, diagnosisRepo: &diagnoses.MockRepository{}, recorder: recorder}
+
+	_, err := g.Handle(context.Background(), GetDiagnosesQuery{
+		PatientName: "John Doe",
+		Actor:       auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}},
+	})
+
+	var ambiguous *AmbiguousPatientError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("Handle() error = %v, expected an AmbiguousPatientError", err)
+	}
+	if !reflect.DeepEqual(ambiguous.Candidates, candidates) {
+		t.Errorf("Handle() candidates = %v, want %v", ambiguous.Candidates, candidates)
+	}
+	// the search is recorded without patient, as none of the candidates was read
+	recorder.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(entry auditing.Entry) bool {
+		return entry.PatientID == nil && errors.Is(entry.Err, ErrAmbiguousPatient)
+	}))
+}
+
 func createFakeDiagnoses() []*diagnoses.Diagnosis {
 	return []*diagnoses.Diagnosis{{
 		ID:           uuid.MustParse("11111111-1111-1111-1111-111111111112"),
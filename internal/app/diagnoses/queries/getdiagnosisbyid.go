@@ -0,0 +1,99 @@
+package queries
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+)
+
+type GetDiagnosisByIDQuery struct {
+	DiagnosisID uuid.UUID
+	Actor       auth.Principal
+	RequestID   string
+}
+
+// DiagnosisWithPatient is a diagnosis along with the patient it was made to.
+type DiagnosisWithPatient struct {
+	Diagnosis *diagnoses.Diagnosis
+	Patient   *patients.Patient
+}
+
+type GetDiagnosisByIDHandler interface {
+	// Handle returns the current version of the diagnosis, diagnoses entered in error are not found.
+	Handle(ctx context.Context, query GetDiagnosisByIDQuery) (DiagnosisWithPatient, error)
+}
+
+type getDiagnosisByID struct {
+	patientRepo   patients.Repository
+	diagnosisRepo diagnoses.Repository
+	recorder      auditing.Recorder
+}
+
+func NewGetDiagnosisByIDHandler(patientRepo patients.Repository, diagnosisRepo diagnoses.Repository,
+	recorder auditing.Recorder) GetDiagnosisByIDHandler {
+	return &getDiagnosisByID{
+		patientRepo:   patientRepo,
+		diagnosisRepo: diagnosisRepo,
+		recorder:      recorder,
+	}
+}
+
+func (g *getDiagnosisByID) Handle(ctx context.Context, query GetDiagnosisByIDQuery) (DiagnosisWithPatient, error) {
+	found, err := g.handle(ctx, query)
+
+	var patientID *uuid.UUID
+	if found.Diagnosis != nil {
+		patientID = &found.Diagnosis.PatientID
+	}
+
+	auditErr := g.recorder.Record(ctx, auditing.Entry{
+		Actor:     query.Actor,
+		Action:    audit.ActionReadDiagnoses,
+		PatientID: patientID,
+		RequestID: query.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "query", query)
+		if err == nil {
+			return DiagnosisWithPatient{}, auditErr
+		}
+	}
+
+	return found, err
+}
+
+func (g *getDiagnosisByID) handle(ctx context.Context, query GetDiagnosisByIDQuery) (DiagnosisWithPatient, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadDiagnoses); err != nil {
+		return DiagnosisWithPatient{}, err
+	}
+
+	diagnosis, err := g.diagnosisRepo.GetDiagnosisByID(ctx, query.DiagnosisID)
+	if err != nil {
+		slog.Error("error getting diagnosis", "err", err, "query", query)
+		return DiagnosisWithPatient{}, commands.ErrGettingDiagnosis
+	}
+
+	if diagnosis == nil || diagnosis.Status == diagnoses.StatusEnteredInError {
+		return DiagnosisWithPatient{}, commands.ErrDiagnosisNotFound
+	}
+
+	patient, err := g.patientRepo.GetByID(ctx, diagnosis.PatientID)
+	if err != nil {
+		slog.Error("error getting patient of diagnosis", "err", err, "query", query)
+		return DiagnosisWithPatient{Diagnosis: diagnosis}, patients.ErrGettingPatient
+	}
+
+	if patient == nil {
+		slog.Warn("patient of diagnosis not found", "patientID", diagnosis.PatientID, "query", query)
+		return DiagnosisWithPatient{Diagnosis: diagnosis}, patients.ErrPatientNotFound
+	}
+
+	return DiagnosisWithPatient{Diagnosis: diagnosis, Patient: patient}, nil
+}
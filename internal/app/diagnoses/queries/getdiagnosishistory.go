@@ -0,0 +1,78 @@
+package queries
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"log/slog"
+)
+
+type GetDiagnosisHistoryQuery struct {
+	DiagnosisID uuid.UUID
+	Actor       auth.Principal
+	RequestID   string
+}
+
+type GetDiagnosisHistoryHandler interface {
+	// Handle returns every version of the diagnosis from the first one, including those entered in error.
+	Handle(ctx context.Context, query GetDiagnosisHistoryQuery) ([]*diagnoses.Diagnosis, error)
+}
+
+type getDiagnosisHistory struct {
+	diagnosisRepo diagnoses.Repository
+	recorder      auditing.Recorder
+}
+
+func NewGetDiagnosisHistoryHandler(diagnosisRepo diagnoses.Repository, recorder auditing.Recorder) GetDiagnosisHistoryHandler {
+	return &getDiagnosisHistory{
+		diagnosisRepo: diagnosisRepo,
+		recorder:      recorder,
+	}
+}
+
+func (g *getDiagnosisHistory) Handle(ctx context.Context, query GetDiagnosisHistoryQuery) ([]*diagnoses.Diagnosis, error) {
+	versions, err := g.handle(ctx, query)
+
+	var patientID *uuid.UUID
+	if len(versions) > 0 {
+		patientID = &versions[0].PatientID
+	}
+
+	auditErr := g.recorder.Record(ctx, auditing.Entry{
+		Actor:     query.Actor,
+		Action:    audit.ActionReadDiagnoses,
+		PatientID: patientID,
+		RequestID: query.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "query", query)
+		if err == nil {
+			return nil, auditErr
+		}
+	}
+
+	return versions, err
+}
+
+func (g *getDiagnosisHistory) handle(ctx context.Context, query GetDiagnosisHistoryQuery) ([]*diagnoses.Diagnosis, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadDiagnoses); err != nil {
+		return nil, err
+	}
+
+	versions, err := g.diagnosisRepo.GetDiagnosisHistory(ctx, query.DiagnosisID)
+	if err != nil {
+		slog.Error("error getting diagnosis history", "err", err, "query", query)
+		return nil, commands.ErrGettingDiagnosis
+	}
+
+	if len(versions) == 0 {
+		return nil, commands.ErrDiagnosisNotFound
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,115 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+)
+
+func Test_getDiagnosisHistory_Handle(t *testing.T) {
+	diagnosisID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	reader := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	versions := []*diagnoses.Diagnosis{
+		{ID: diagnosisID, PatientID: patientID, Description: "asthma", Version: 1, Status: diagnoses.StatusFinal},
+		{ID: diagnosisID, PatientID: patientID, Description: "asthma", Version: 2, Status: diagnoses.StatusEnteredInError,
+			Reason: "wrong patient"},
+	}
+
+	tests := []struct {
+		name          string
+		actor         auth.Principal
+		diagnosisRepo func() *diagnoses.MockRepository
+		// recordedErr is the error recorded in the audit trail, recordErr the one returned when recording it
+		recordedErr error
+		recordErr   error
+		// recordedPatient is the patient of the audit record, nil when the diagnosis wasn't read
+		recordedPatient *uuid.UUID
+		want            []*diagnoses.Diagnosis
+		wantErr         error
+	}{
+		{
+			name:          "return error when the actor has no role",
+			actor:         auth.Principal{ID: "someone"},
+			diagnosisRepo: func() *diagnoses.MockRepository { return &diagnoses.MockRepository{} },
+			recordedErr:   auth.ErrForbidden,
+			wantErr:       auth.ErrForbidden,
+		},
+		{
+			name:  "return error when the history can't be read",
+			actor: reader,
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnosisHistory", mock.Anything, diagnosisID).Return([]*diagnoses.Diagnosis(nil), errors.New("DB error"))
+				return mockRepo
+			},
+			recordedErr: commands.ErrGettingDiagnosis,
+			wantErr:     commands.ErrGettingDiagnosis,
+		},
+		{
+			name:  "return error when the diagnosis doesn't exist",
+			actor: reader,
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnosisHistory", mock.Anything, diagnosisID).Return([]*diagnoses.Diagnosis{}, nil)
+				return mockRepo
+			},
+			recordedErr: commands.ErrDiagnosisNotFound,
+			wantErr:     commands.ErrDiagnosisNotFound,
+		},
+		{
+			name:  "return every version, entered in error included",
+			actor: reader,
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnosisHistory", mock.Anything, diagnosisID).Return(versions, nil)
+				return mockRepo
+			},
+			recordedPatient: &patientID,
+			want:            versions,
+		},
+		{
+			name:  "return no history when the read can't be recorded",
+			actor: reader,
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnosisHistory", mock.Anything, diagnosisID).Return(versions, nil)
+				return mockRepo
+			},
+			recordedPatient: &patientID,
+			recordErr:       auditing.ErrRecordingAudit,
+			wantErr:         auditing.ErrRecordingAudit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := GetDiagnosisHistoryQuery{DiagnosisID: diagnosisID, Actor: tt.actor, RequestID: "request-1"}
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, auditing.Entry{
+				Actor:     tt.actor,
+				Action:    audit.ActionReadDiagnoses,
+				PatientID: tt.recordedPatient,
+				RequestID: "request-1",
+				Err:       tt.recordedErr,
+			}).Return(tt.recordErr).Once()
+
+			g := &getDiagnosisHistory{diagnosisRepo: tt.diagnosisRepo(), recorder: recorder}
+			got, err := g.Handle(context.Background(), query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handle() got = %v, want %v", got, tt.want)
+			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
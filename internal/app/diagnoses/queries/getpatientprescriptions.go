@@ -0,0 +1,88 @@
+package queries
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+)
+
+type GetPatientPrescriptionsQuery struct {
+	PatientID uuid.UUID
+	// Limit is the page size, DefaultPageSize when zero and never more than MaxPageSize
+	Limit int
+	// Cursor is the Page.Next of the previous page, nil for the first one
+	Cursor    *diagnoses.Cursor
+	Actor     auth.Principal
+	RequestID string
+}
+
+type GetPatientPrescriptionsHandler interface {
+	// Handle returns a page of the diagnoses of the patient that have a prescription, oldest first.
+	Handle(ctx context.Context, query GetPatientPrescriptionsQuery) (diagnoses.Page, error)
+}
+
+type getPatientPrescriptions struct {
+	patientRepo   patients.Repository
+	diagnosisRepo diagnoses.Repository
+	recorder      auditing.Recorder
+}
+
+func NewGetPatientPrescriptionsHandler(patientRepo patients.Repository, diagnosisRepo diagnoses.Repository,
+	recorder auditing.Recorder) GetPatientPrescriptionsHandler {
+	return &getPatientPrescriptions{
+		patientRepo:   patientRepo,
+		diagnosisRepo: diagnosisRepo,
+		recorder:      recorder,
+	}
+}
+
+func (g *getPatientPrescriptions) Handle(ctx context.Context, query GetPatientPrescriptionsQuery) (diagnoses.Page, error) {
+	page, err := g.handle(ctx, query)
+
+	auditErr := g.recorder.Record(ctx, auditing.Entry{
+		Actor:     query.Actor,
+		Action:    audit.ActionReadPrescriptions,
+		PatientID: &query.PatientID,
+		RequestID: query.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "query", query)
+		if err == nil {
+			return diagnoses.Page{}, auditErr
+		}
+	}
+
+	return page, err
+}
+
+func (g *getPatientPrescriptions) handle(ctx context.Context, query GetPatientPrescriptionsQuery) (diagnoses.Page, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadDiagnoses); err != nil {
+		return diagnoses.Page{}, err
+	}
+
+	patient, err := g.patientRepo.GetByID(ctx, query.PatientID)
+	if err != nil {
+		slog.Error("error getting patient", "err", err, "query", query)
+		return diagnoses.Page{}, patients.ErrGettingPatient
+	}
+
+	if patient == nil {
+		return diagnoses.Page{}, patients.ErrPatientNotFound
+	}
+
+	filter := diagnoses.Filter{PatientID: &patient.ID, HasPrescription: true}
+	page, err := g.diagnosisRepo.GetDiagnoses(ctx, filter, pageRequest(query.Limit, query.Cursor, diagnoses.SortAscending))
+	if err != nil {
+		slog.Error("error getting prescriptions", "err", err, "query", query)
+		return diagnoses.Page{}, commands.ErrGettingDiagnoses
+	}
+
+	return page, nil
+}
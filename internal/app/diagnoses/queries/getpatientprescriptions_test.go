@@ -0,0 +1,160 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_getPatientPrescriptions_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	reader := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	prescribed := []*diagnoses.Diagnosis{{
+		ID:           uuid.MustParse("11111111-1111-1111-1111-111111111112"),
+		PatientID:    patientID,
+		Description:  "migraine",
+		Prescription: &diagnoses.Prescription{Notes: "ibuprofen 400mg"},
+	}}
+	filter := diagnoses.Filter{PatientID: &patientID, HasPrescription: true}
+	firstPage := diagnoses.PageRequest{Limit: DefaultPageSize, Order: diagnoses.SortAscending}
+	cursor := &diagnoses.Cursor{CreatedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), ID: prescribed[0].ID}
+
+	tests := []struct {
+		name          string
+		actor         auth.Principal
+		limit         int
+		cursor        *diagnoses.Cursor
+		patientRepo   func() *patients.MockRepository
+		diagnosisRepo func() *diagnoses.MockRepository
+		// recordedErr is the error recorded in the audit trail, recordErr the one returned when recording it
+		recordedErr error
+		recordErr   error
+		want        diagnoses.Page
+		wantErr     error
+	}{
+		{
+			name:          "return error when the actor has no role",
+			actor:         auth.Principal{ID: "someone"},
+			patientRepo:   func() *patients.MockRepository { return &patients.MockRepository{} },
+			diagnosisRepo: func() *diagnoses.MockRepository { return &diagnoses.MockRepository{} },
+			wantErr:       auth.ErrForbidden,
+			recordedErr:   auth.ErrForbidden,
+		},
+		{
+			name:  "return error when the patient doesn't exists",
+			actor: reader,
+			patientRepo: func() *patients.MockRepository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), nil)
+				return mockRepo
+			},
+			diagnosisRepo: func() *diagnoses.MockRepository { return &diagnoses.MockRepository{} },
+			wantErr:       patients.ErrPatientNotFound,
+			recordedErr:   patients.ErrPatientNotFound,
+		},
+		{
+			name:  "return error when the prescriptions can't be read",
+			actor: reader,
+			patientRepo: func() *patients.MockRepository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+				return mockRepo
+			},
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, filter, firstPage).Return(diagnoses.Page{}, errors.New("DB error"))
+				return mockRepo
+			},
+			wantErr:     commands.ErrGettingDiagnoses,
+			recordedErr: commands.ErrGettingDiagnoses,
+		},
+		{
+			name:  "return the diagnoses with prescription",
+			actor: reader,
+			patientRepo: func() *patients.MockRepository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+				return mockRepo
+			},
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, filter, firstPage).Return(diagnoses.Page{Diagnoses: prescribed, Total: 1}, nil)
+				return mockRepo
+			},
+			want: diagnoses.Page{Diagnoses: prescribed, Total: 1},
+		},
+		{
+			name:   "return the requested page",
+			actor:  reader,
+			limit:  1,
+			cursor: cursor,
+			patientRepo: func() *patients.MockRepository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+				return mockRepo
+			},
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				page := diagnoses.PageRequest{Limit: 1, After: cursor, Order: diagnoses.SortAscending}
+				mockRepo.On("GetDiagnoses", mock.Anything, filter, page).Return(diagnoses.Page{Diagnoses: prescribed, Total: 3, Next: cursor}, nil)
+				return mockRepo
+			},
+			want: diagnoses.Page{Diagnoses: prescribed, Total: 3, Next: cursor},
+		},
+		{
+			name:  "return no prescriptions when the read can't be recorded",
+			actor: reader,
+			patientRepo: func() *patients.MockRepository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+				return mockRepo
+			},
+			diagnosisRepo: func() *diagnoses.MockRepository {
+				mockRepo := &diagnoses.MockRepository{}
+				mockRepo.On("GetDiagnoses", mock.Anything, filter, firstPage).Return(diagnoses.Page{Diagnoses: prescribed, Total: 1}, nil)
+				return mockRepo
+			},
+			recordErr: auditing.ErrRecordingAudit,
+			wantErr:   auditing.ErrRecordingAudit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := GetPatientPrescriptionsQuery{
+				PatientID: patientID,
+				Limit:     tt.limit,
+				Cursor:    tt.cursor,
+				Actor:     tt.actor,
+				RequestID: "request-1",
+			}
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, auditing.Entry{
+				Actor:     tt.actor,
+				Action:    audit.ActionReadPrescriptions,
+				PatientID: &patientID,
+				RequestID: "request-1",
+				Err:       tt.recordedErr,
+			}).Return(tt.recordErr).Once()
+
+			g := &getPatientPrescriptions{patientRepo: tt.patientRepo(), diagnosisRepo: tt.diagnosisRepo(), recorder: recorder}
+			got, err := g.Handle(context.Background(), query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handle() got = %v, want %v", got, tt.want)
+			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
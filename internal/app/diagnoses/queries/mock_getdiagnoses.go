@@ -1,6 +1,7 @@
 package queries
 
 import (
+	"context"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
 	"github.com/stretchr/testify/mock"
 )
@@ -9,7 +10,7 @@ type MockGetDiagnoses struct {
 	mock.Mock
 }
 
-func (m *MockGetDiagnoses) Handle(query GetDiagnosesQuery) ([]*diagnoses.Diagnosis, error) {
-	args := m.Called(query)
-	return args.Get(0).([]*diagnoses.Diagnosis), args.Error(1)
+func (m *MockGetDiagnoses) Handle(ctx context.Context, query GetDiagnosesQuery) (diagnoses.Page, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(diagnoses.Page), args.Error(1)
 }
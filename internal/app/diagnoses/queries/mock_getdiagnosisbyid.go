@@ -0,0 +1,15 @@
+package queries
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockGetDiagnosisByID struct {
+	mock.Mock
+}
+
+func (m *MockGetDiagnosisByID) Handle(ctx context.Context, query GetDiagnosisByIDQuery) (DiagnosisWithPatient, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(DiagnosisWithPatient), args.Error(1)
+}
@@ -0,0 +1,16 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockGetDiagnosisHistory struct {
+	mock.Mock
+}
+
+func (m *MockGetDiagnosisHistory) Handle(ctx context.Context, query GetDiagnosisHistoryQuery) ([]*diagnoses.Diagnosis, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]*diagnoses.Diagnosis), args.Error(1)
+}
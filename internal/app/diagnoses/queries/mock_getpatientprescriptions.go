@@ -0,0 +1,16 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockGetPatientPrescriptions struct {
+	mock.Mock
+}
+
+func (m *MockGetPatientPrescriptions) Handle(ctx context.Context, query GetPatientPrescriptionsQuery) (diagnoses.Page, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(diagnoses.Page), args.Error(1)
+}
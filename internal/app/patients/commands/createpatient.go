@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidPatient  = errors.New("invalid patient")
+	ErrCreatingPatient = errors.New("error creating patient")
+)
+
+type CreatePatient struct {
+	LegalID string
+	Name    string
+	// BirthDate is optional, only its date is kept
+	BirthDate *time.Time
+	Address   string
+	Phone     string
+	Email     string
+	Actor     auth.Principal
+	RequestID string
+}
+
+type CreatePatientHandler interface {
+	Handle(ctx context.Context, command CreatePatient) (*patients.Patient, error)
+}
+
+type createPatientHandler struct {
+	patientRepo patients.Repository
+	recorder    auditing.Recorder
+}
+
+func NewCreatePatientHandler(patientRepo patients.Repository, recorder auditing.Recorder) CreatePatientHandler {
+	return &createPatientHandler{patientRepo: patientRepo, recorder: recorder}
+}
+
+func (h *createPatientHandler) Handle(ctx context.Context, command CreatePatient) (*patients.Patient, error) {
+	patient, err := h.handle(ctx, command)
+
+	var patientID *uuid.UUID
+	if patient != nil {
+		patientID = &patient.ID
+	}
+
+	auditErr := h.recorder.Record(ctx, auditing.Entry{
+		Actor:     command.Actor,
+		Action:    audit.ActionCreatePatient,
+		PatientID: patientID,
+		RequestID: command.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "patientID", patientID, "actor", command.Actor.ID)
+	}
+
+	return patient, err
+}
+
+func (h *createPatientHandler) handle(ctx context.Context, command CreatePatient) (*patients.Patient, error) {
+	if err := auth.Authorize(command.Actor, auth.WritePatients); err != nil {
+		return nil, err
+	}
+
+	if err := command.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatient, err)
+	}
+
+	patient := patients.Patient{
+		ID:        uuid.New(),
+		LegalID:   strings.TrimSpace(command.LegalID),
+		Name:      strings.TrimSpace(command.Name),
+		BirthDate: dateOf(command.BirthDate),
+		Address:   strings.TrimSpace(command.Address),
+		Phone:     strings.TrimSpace(command.Phone),
+		Email:     strings.TrimSpace(command.Email),
+		Version:   1,
+	}
+
+	if err := patient.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatient, err)
+	}
+
+	existing, err := h.patientRepo.GetByLegalID(ctx, patient.LegalID)
+	if err != nil {
+		slog.Error(err.Error(), "legalID", patient.LegalID)
+		return nil, patients.ErrGettingPatient
+	}
+
+	if existing != nil {
+		return nil, patients.ErrDuplicatedLegalID
+	}
+
+	createErr := h.patientRepo.Create(ctx, patient)
+	if errors.Is(createErr, patients.ErrDuplicatedLegalID) {
+		return nil, createErr
+	}
+
+	if createErr != nil {
+		slog.Error(createErr.Error(), "patient", patient)
+		return nil, ErrCreatingPatient
+	}
+
+	slog.Info("patient successfully created", "patientID", patient.ID)
+	return &patient, nil
+}
+
+func dateOf(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return &date
+}
@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_createPatientHandler_Handle(t *testing.T) {
+	command := CreatePatient{
+		LegalID: " ABC1234 ",
+		Name:    "John Doe",
+		Address: "Wall Street 123",
+		Phone:   "+1 (555) 123-4567",
+		Email:   "john.doe@example.com",
+		Actor:   auth.Principal{ID: "admin-1", Roles: []auth.Role{auth.RoleAdmin}},
+	}
+
+	tests := []struct {
+		name        string
+		patientRepo patients.Repository
+		command     CreatePatient
+		wantErr     error
+	}{
+		{
+			name:        "return error when the actor is an auditor",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				c.Actor = auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}}
+				return c
+			}(),
+			wantErr: auth.ErrForbidden,
+		},
+		{
+			name:        "return error when the email is invalid",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				c.Email = "john.doe"
+				return c
+			}(),
+			wantErr: patients.ErrInvalidEmail,
+		},
+		{
+			name:        "return error when the phone is invalid",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				c.Phone = "12ab"
+				return c
+			}(),
+			wantErr: ErrInvalidPatient,
+		},
+		{
+			name:        "return error when the legal ID is missing",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				c.LegalID = "  "
+				return c
+			}(),
+			wantErr: patients.ErrInvalidLegalID,
+		},
+		{
+			name:        "return error when the birth date is in the future",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				tomorrow := time.Now().AddDate(0, 0, 1)
+				c.BirthDate = &tomorrow
+				return c
+			}(),
+			wantErr: patients.ErrInvalidBirthDate,
+		},
+		{
+			name:        "return error when the legal ID has line breaks",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				c.LegalID = "ABC\n1234"
+				return c
+			}(),
+			wantErr: validation.ErrInvalidCommand,
+		},
+		{
+			name:        "return error when the legal ID is too long",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				c.LegalID = strings.Repeat("1", MaxLegalIDLength+1)
+				return c
+			}(),
+			wantErr: validation.ErrInvalidCommand,
+		},
+		{
+			name:        "return error when the address is too long",
+			patientRepo: &patients.MockRepository{},
+			command: func() CreatePatient {
+				c := command
+				c.Address = strings.Repeat("a", MaxAddressLength+1)
+				return c
+			}(),
+			wantErr: ErrInvalidPatient,
+		},
+		{
+			name: "return error when fails getting patient by legal ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return((*patients.Patient)(nil), errors.New("DB error"))
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: patients.ErrGettingPatient,
+		},
+		{
+			name: "return error when the legal ID is already registered",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return(&patients.Patient{LegalID: "ABC1234"}, nil)
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: patients.ErrDuplicatedLegalID,
+		},
+		{
+			name: "return error when the legal ID is registered concurrently",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return((*patients.Patient)(nil), nil)
+				mockRepo.On("Create", mock.Anything, mock.Anything).Return(patients.ErrDuplicatedLegalID)
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: patients.ErrDuplicatedLegalID,
+		},
+		{
+			name: "return error when the patient can't be created",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return((*patients.Patient)(nil), nil)
+				mockRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New("DB error"))
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: ErrCreatingPatient,
+		},
+		{
+			name: "create patient without error",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return((*patients.Patient)(nil), nil)
+				mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p patients.Patient) bool {
+					return p.LegalID == "ABC1234" && p.Name == "John Doe"
+				})).Return(nil)
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: nil,
+		},
+		{
+			name: "create patient with spaces and slashes in the legal ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "AB 12/345-6").Return((*patients.Patient)(nil), nil)
+				mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p patients.Patient) bool {
+					return p.LegalID == "AB 12/345-6"
+				})).Return(nil)
+				return mockRepo
+			}(),
+			command: func() CreatePatient {
+				c := command
+				c.LegalID = " AB 12/345-6 "
+				return c
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "create patient keeping only the date of birth",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "ABC1234").Return((*patients.Patient)(nil), nil)
+				mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(p patients.Patient) bool {
+					return p.BirthDate != nil && p.BirthDate.Equal(time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC))
+				})).Return(nil)
+				return mockRepo
+			}(),
+			command: func() CreatePatient {
+				c := command
+				birthDate := time.Date(1980, 1, 2, 15, 30, 0, 0, time.UTC)
+				c.BirthDate = &birthDate
+				return c
+			}(),
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorded auditing.Entry
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				recorded = args.Get(1).(auditing.Entry)
+			}).Return(nil).Once()
+
+			h := &createPatientHandler{patientRepo: tt.patientRepo, recorder: recorder}
+			tt.command.RequestID = "request-1"
+			got, err := h.Handle(context.Background(), tt.command)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got == nil {
+				t.Errorf("Handle() got <nil>, but a patient was expected")
+			}
+
+			recorder.AssertExpectations(t)
+			var wantPatientID *uuid.UUID
+			if got != nil {
+				wantPatientID = &got.ID
+			}
+			assert.Equal(t, auditing.Entry{Actor: tt.command.Actor, Action: audit.ActionCreatePatient,
+				PatientID: wantPatientID, RequestID: "request-1", Err: err}, recorded)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCreatePatient struct {
+	mock.Mock
+}
+
+func (m *MockCreatePatient) Handle(ctx context.Context, command CreatePatient) (*patients.Patient, error) {
+	args := m.Called(ctx, command)
+	return args.Get(0).(*patients.Patient), args.Error(1)
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+	"strings"
+)
+
+const maxUpdateAttempts = 3
+
+// UpdatePatientContact replaces the contact data of a patient. Identity data, as the legal ID, cannot be changed.
+type UpdatePatientContact struct {
+	PatientID uuid.UUID
+	Address   string
+	Phone     string
+	Email     string
+	// Version is the version of the patient the caller read, if any. When set, the update fails with
+	// patients.ErrConcurrentModification if the patient changed since. Otherwise it is applied on the latest version.
+	Version   *int
+	Actor     auth.Principal
+	RequestID string
+}
+
+type UpdatePatientContactHandler interface {
+	Handle(ctx context.Context, command UpdatePatientContact) (*patients.Patient, error)
+}
+
+type updatePatientContactHandler struct {
+	patientRepo patients.Repository
+	recorder    auditing.Recorder
+}
+
+func NewUpdatePatientContactHandler(patientRepo patients.Repository, recorder auditing.Recorder) UpdatePatientContactHandler {
+	return &updatePatientContactHandler{patientRepo: patientRepo, recorder: recorder}
+}
+
+func (h *updatePatientContactHandler) Handle(ctx context.Context, command UpdatePatientContact) (*patients.Patient, error) {
+	patient, err := h.handle(ctx, command)
+
+	auditErr := h.recorder.Record(ctx, auditing.Entry{
+		Actor:     command.Actor,
+		Action:    audit.ActionUpdatePatient,
+		PatientID: &command.PatientID,
+		RequestID: command.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "patientID", command.PatientID, "actor", command.Actor.ID)
+	}
+
+	return patient, err
+}
+
+// handle retries the update, up to maxUpdateAttempts times, when the patient is changed by someone else between
+// reading and updating it, unless the command has a Version.
+func (h *updatePatientContactHandler) handle(ctx context.Context, command UpdatePatientContact) (*patients.Patient, error) {
+	if err := auth.Authorize(command.Actor, auth.WritePatients); err != nil {
+		return nil, err
+	}
+
+	if err := command.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatient, err)
+	}
+
+	phone := strings.TrimSpace(command.Phone)
+	email := strings.TrimSpace(command.Email)
+	if err := patients.ValidateContact(phone, email); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatient, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		patient, err := h.update(ctx, command, phone, email)
+		if !errors.Is(err, patients.ErrConcurrentModification) || command.Version != nil || attempt == maxUpdateAttempts {
+			return patient, err
+		}
+
+		slog.Info("retrying concurrently modified patient update", "patientID", command.PatientID, "attempt", attempt)
+	}
+}
+
+func (h *updatePatientContactHandler) update(ctx context.Context, command UpdatePatientContact, phone, email string) (*patients.Patient, error) {
+	patient, err := h.patientRepo.GetByID(ctx, command.PatientID)
+	if err != nil {
+		slog.Error(err.Error(), "patientID", command.PatientID)
+		return nil, patients.ErrGettingPatient
+	}
+
+	if patient == nil {
+		slog.Info(patients.ErrPatientNotFound.Error(), "patientID", command.PatientID)
+		return nil, patients.ErrPatientNotFound
+	}
+
+	if command.Version != nil && *command.Version != patient.Version {
+		slog.Info(patients.ErrConcurrentModification.Error(), "patientID", patient.ID, "version", *command.Version,
+			"storedVersion", patient.Version)
+		return nil, fmt.Errorf("%w: version %d was read, the stored one is %d", patients.ErrConcurrentModification,
+			*command.Version, patient.Version)
+	}
+
+	patient.Address = strings.TrimSpace(command.Address)
+	patient.Phone = phone
+	patient.Email = email
+
+	updateErr := h.patientRepo.Update(ctx, *patient)
+	if errors.Is(updateErr, patients.ErrConcurrentModification) {
+		slog.Info(updateErr.Error(), "patientID", patient.ID, "version", patient.Version)
+		return nil, updateErr
+	}
+
+	if updateErr != nil {
+		slog.Error(updateErr.Error(), "patientID", patient.ID)
+		return nil, patients.ErrUpdatingPatient
+	}
+
+	patient.Version++
+	slog.Info("patient contact successfully updated", "patientID", patient.ID, "version", patient.Version)
+	return patient, nil
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func Test_updatePatientContactHandler_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	command := UpdatePatientContact{
+		PatientID: patientID,
+		Address:   "Main Street 1",
+		Phone:     "987654321",
+		Email:     "new.mail@example.com",
+		Actor:     auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}},
+	}
+
+	tests := []struct {
+		name        string
+		patientRepo patients.Repository
+		command     UpdatePatientContact
+		want        *patients.Patient
+		wantErr     error
+	}{
+		{
+			name:        "return error when the actor is anonymous",
+			patientRepo: &patients.MockRepository{},
+			command:     UpdatePatientContact{PatientID: patientID, Phone: "987654321", Email: "new.mail@example.com"},
+			wantErr:     auth.ErrUnauthenticated,
+		},
+		{
+			name:        "return error when the contact data is invalid",
+			patientRepo: &patients.MockRepository{},
+			command:     UpdatePatientContact{PatientID: patientID, Phone: "1", Email: "invalid", Actor: command.Actor},
+			wantErr:     ErrInvalidPatient,
+		},
+		{
+			name: "return error when fails getting patient",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), errors.New("DB error"))
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: patients.ErrGettingPatient,
+		},
+		{
+			name: "return error when there is no patient for that ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), nil)
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: patients.ErrPatientNotFound,
+		},
+		{
+			name: "return error when the patient can't be updated",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID}, nil)
+				mockRepo.On("Update", mock.Anything, mock.Anything).Return(errors.New("DB error"))
+				return mockRepo
+			}(),
+			command: command,
+			wantErr: patients.ErrUpdatingPatient,
+		},
+		{
+			name: "update the contact keeping the identity data",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{
+					ID:      patientID,
+					LegalID: "ABC1234",
+					Name:    "John Doe",
+					Phone:   "123456789",
+					Email:   "john.doe@example.com",
+					Version: 1,
+				}, nil)
+				mockRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+				return mockRepo
+			}(),
+			command: command,
+			want: &patients.Patient{
+				ID:      patientID,
+				LegalID: "ABC1234",
+				Name:    "John Doe",
+				Address: "Main Street 1",
+				Phone:   "987654321",
+				Email:   "new.mail@example.com",
+				Version: 2,
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.MatchedBy(func(entry auditing.Entry) bool {
+				return entry.Actor.ID == tt.command.Actor.ID && entry.Action == audit.ActionUpdatePatient &&
+					*entry.PatientID == patientID && entry.RequestID == "request-1" && errors.Is(entry.Err, tt.wantErr) &&
+					(tt.wantErr != nil || entry.Err == nil)
+			})).Return(nil).Once()
+
+			h := &updatePatientContactHandler{patientRepo: tt.patientRepo, recorder: recorder}
+			tt.command.RequestID = "request-1"
+			got, err := h.Handle(context.Background(), tt.command)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+			recorder.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_updatePatientContactHandler_Handle_Concurrency(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	readVersion := 1
+	staleVersion := 0
+
+	tests := []struct {
+		name    string
+		version *int
+		// updateErrs are returned by the successive updates, the patient read is one version newer after each
+		updateErrs  []error
+		wantGets    int
+		wantVersion int
+		wantErr     error
+	}{
+		{
+			name:        "retry on the latest version when the patient was modified concurrently",
+			updateErrs:  []error{patients.ErrConcurrentModification, nil},
+			wantGets:    2,
+			wantVersion: 3,
+		},
+		{
+			name: "give up when the patient keeps being modified",
+			updateErrs: []error{patients.ErrConcurrentModification, patients.ErrConcurrentModification,
+				patients.ErrConcurrentModification},
+			wantGets: maxUpdateAttempts,
+			wantErr:  patients.ErrConcurrentModification,
+		},
+		{
+			name:        "update the version read",
+			version:     &readVersion,
+			updateErrs:  []error{nil},
+			wantGets:    1,
+			wantVersion: 2,
+		},
+		{
+			name:     "return error without updating when the version read is stale",
+			version:  &staleVersion,
+			wantGets: 1,
+			wantErr:  patients.ErrConcurrentModification,
+		},
+		{
+			name:       "return error without retrying when the version read was modified concurrently",
+			version:    &readVersion,
+			updateErrs: []error{patients.ErrConcurrentModification},
+			wantGets:   1,
+			wantErr:    patients.ErrConcurrentModification,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patientRepo := &patients.MockRepository{}
+			for i := 0; i < tt.wantGets; i++ {
+				patientRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID, Version: i + 1}, nil).Once()
+			}
+			for _, updateErr := range tt.updateErrs {
+				patientRepo.On("Update", mock.Anything, mock.Anything).Return(updateErr).Once()
+			}
+
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+			h := &updatePatientContactHandler{patientRepo: patientRepo, recorder: recorder}
+			got, err := h.Handle(context.Background(), UpdatePatientContact{
+				PatientID: patientID,
+				Phone:     "987654321",
+				Email:     "new.mail@example.com",
+				Version:   tt.version,
+				Actor:     auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.wantVersion, got.Version)
+			}
+			patientRepo.AssertNumberOfCalls(t, "GetByID", tt.wantGets)
+			patientRepo.AssertNumberOfCalls(t, "Update", len(tt.updateErrs))
+		})
+	}
+}
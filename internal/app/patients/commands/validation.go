@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"strings"
+	"time"
+)
+
+// Limits of the patient commands, in characters.
+const (
+	MaxNameLength    = 200
+	MaxLegalIDLength = 30
+	MaxAddressLength = 500
+	MaxPhoneLength   = 30
+	// MaxEmailLength is the maximum length of an email address, see RFC 5321
+	MaxEmailLength = 254
+)
+
+// Validate checks the input of the command, returning validation.Errors with every failure found. The format of
+// the phone and email is checked by the patient.
+func (c CreatePatient) Validate() error {
+	var v validation.Validator
+	if strings.TrimSpace(c.Name) == "" {
+		v.AddError("name", patients.ErrInvalidName)
+	} else {
+		v.Text("name", c.Name, MaxNameLength)
+	}
+
+	if strings.TrimSpace(c.LegalID) == "" {
+		v.AddError("legal_id", patients.ErrInvalidLegalID)
+	} else {
+		legalID := strings.TrimSpace(c.LegalID)
+		v.Text("legal_id", legalID, MaxLegalIDLength)
+		v.Check(!strings.ContainsAny(legalID, "\n\r\t"), "legal_id", "can't have line breaks or tabs")
+	}
+
+	v.NotFuture("birth_date", c.BirthDate, time.Now(), patients.ErrInvalidBirthDate)
+	validateContact(&v, c.Address, c.Phone, c.Email)
+	return v.Err()
+}
+
+// Validate checks the input of the command, returning validation.Errors with every failure found.
+func (c UpdatePatientContact) Validate() error {
+	var v validation.Validator
+	validateContact(&v, c.Address, c.Phone, c.Email)
+	return v.Err()
+}
+
+func validateContact(v *validation.Validator, address, phone, email string) {
+	v.Text("address", address, MaxAddressLength)
+	v.Text("phone", phone, MaxPhoneLength)
+	v.Text("email", email, MaxEmailLength)
+}
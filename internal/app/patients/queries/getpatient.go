@@ -0,0 +1,84 @@
+package queries
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+)
+
+// GetPatientQuery gets a patient by ID, or by legal ID when LegalID is set.
+type GetPatientQuery struct {
+	PatientID uuid.UUID
+	LegalID   string
+	Actor     auth.Principal
+	RequestID string
+}
+
+type GetPatientHandler interface {
+	Handle(ctx context.Context, query GetPatientQuery) (*patients.Patient, error)
+}
+
+type getPatient struct {
+	patientRepo patients.Repository
+	recorder    auditing.Recorder
+}
+
+func NewGetPatientHandler(patientRepo patients.Repository, recorder auditing.Recorder) GetPatientHandler {
+	return &getPatient{patientRepo: patientRepo, recorder: recorder}
+}
+
+func (g *getPatient) Handle(ctx context.Context, query GetPatientQuery) (*patients.Patient, error) {
+	patient, err := g.handle(ctx, query)
+
+	var patientID *uuid.UUID
+	switch {
+	case patient != nil:
+		patientID = &patient.ID
+	case query.LegalID == "":
+		patientID = &query.PatientID
+	}
+
+	auditErr := g.recorder.Record(ctx, auditing.Entry{
+		Actor:     query.Actor,
+		Action:    audit.ActionReadPatient,
+		PatientID: patientID,
+		RequestID: query.RequestID,
+		Err:       err,
+	})
+	if auditErr != nil {
+		slog.Error(auditErr.Error(), "query", query)
+		if err == nil {
+			return nil, auditErr
+		}
+	}
+
+	return patient, err
+}
+
+func (g *getPatient) handle(ctx context.Context, query GetPatientQuery) (*patients.Patient, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadPatients); err != nil {
+		return nil, err
+	}
+
+	var patient *patients.Patient
+	var err error
+	if query.LegalID != "" {
+		patient, err = g.patientRepo.GetByLegalID(ctx, query.LegalID)
+	} else {
+		patient, err = g.patientRepo.GetByID(ctx, query.PatientID)
+	}
+	if err != nil {
+		slog.Error("error getting patient", "err", err, "query", query)
+		return nil, patients.ErrGettingPatient
+	}
+
+	if patient == nil {
+		return nil, patients.ErrPatientNotFound
+	}
+
+	return patient, nil
+}
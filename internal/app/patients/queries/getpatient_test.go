@@ -0,0 +1,141 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+)
+
+func Test_getPatient_Handle(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	reader := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	tests := []struct {
+		name        string
+		patientRepo patients.Repository
+		legalID     string
+		actor       auth.Principal
+		// recordedPatient and recordedErr are the patient ID and error of the audit entry
+		recordedPatient *uuid.UUID
+		recordedErr     error
+		recordErr       error
+		want            *patients.Patient
+		wantErr         error
+	}{
+		{
+			name:            "return error when the actor is anonymous",
+			patientRepo:     &patients.MockRepository{},
+			recordedPatient: &patientID,
+			recordedErr:     auth.ErrUnauthenticated,
+			want:            nil,
+			wantErr:         auth.ErrUnauthenticated,
+		},
+		{
+			name: "return error when can't get the patient",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), errors.New("DB error"))
+				return mockRepo
+			}(),
+			actor:           reader,
+			recordedPatient: &patientID,
+			recordedErr:     patients.ErrGettingPatient,
+			want:            nil,
+			wantErr:         patients.ErrGettingPatient,
+		},
+		{
+			name: "return error when the patient doesn't exists",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return((*patients.Patient)(nil), nil)
+				return mockRepo
+			}(),
+			actor:           reader,
+			recordedPatient: &patientID,
+			recordedErr:     patients.ErrPatientNotFound,
+			want:            nil,
+			wantErr:         patients.ErrPatientNotFound,
+		},
+		{
+			name: "return the patient without error",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID, Name: "John Doe"}, nil)
+				return mockRepo
+			}(),
+			actor:           reader,
+			recordedPatient: &patientID,
+			want:            &patients.Patient{ID: patientID, Name: "John Doe"},
+			wantErr:         nil,
+		},
+		{
+			name: "return the patient with the legal ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "12345678").Return(&patients.Patient{ID: patientID, LegalID: "12345678"}, nil)
+				return mockRepo
+			}(),
+			legalID:         "12345678",
+			actor:           reader,
+			recordedPatient: &patientID,
+			want:            &patients.Patient{ID: patientID, LegalID: "12345678"},
+			wantErr:         nil,
+		},
+		{
+			name: "record no patient when there is none with the legal ID",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByLegalID", mock.Anything, "12345678").Return((*patients.Patient)(nil), nil)
+				return mockRepo
+			}(),
+			legalID:     "12345678",
+			actor:       reader,
+			recordedErr: patients.ErrPatientNotFound,
+			want:        nil,
+			wantErr:     patients.ErrPatientNotFound,
+		},
+		{
+			name: "return error when the read can't be recorded",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("GetByID", mock.Anything, patientID).Return(&patients.Patient{ID: patientID, Name: "John Doe"}, nil)
+				return mockRepo
+			}(),
+			actor:           reader,
+			recordedPatient: &patientID,
+			recordErr:       auditing.ErrRecordingAudit,
+			want:            nil,
+			wantErr:         auditing.ErrRecordingAudit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, auditing.Entry{
+				Actor:     tt.actor,
+				Action:    audit.ActionReadPatient,
+				PatientID: tt.recordedPatient,
+				RequestID: "request-1",
+				Err:       tt.recordedErr,
+			}).Return(tt.recordErr).Once()
+
+			g := &getPatient{patientRepo: tt.patientRepo, recorder: recorder}
+			got, err := g.Handle(context.Background(), GetPatientQuery{PatientID: patientID, LegalID: tt.legalID,
+				Actor: tt.actor, RequestID: "request-1"})
+			if err != tt.wantErr {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handle() got = %v, want %v", got, tt.want)
+			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package queries
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+)
+
+type ListPatientsQuery struct {
+	Actor     auth.Principal
+	RequestID string
+}
+
+type ListPatientsHandler interface {
+	Handle(ctx context.Context, query ListPatientsQuery) ([]*patients.Patient, error)
+}
+
+type listPatients struct {
+	patientRepo patients.Repository
+	recorder    auditing.Recorder
+}
+
+// NewListPatientsHandler returns a handler that records the read of every patient listed in the audit trail.
+func NewListPatientsHandler(patientRepo patients.Repository, recorder auditing.Recorder) ListPatientsHandler {
+	return &listPatients{patientRepo: patientRepo, recorder: recorder}
+}
+
+func (l *listPatients) Handle(ctx context.Context, query ListPatientsQuery) ([]*patients.Patient, error) {
+	found, err := l.handle(ctx, query)
+
+	patientIDs := make([]uuid.UUID, 0, len(found))
+	for _, patient := range found {
+		patientIDs = append(patientIDs, patient.ID)
+	}
+
+	if auditErr := recordReads(ctx, l.recorder, query.Actor, query.RequestID, patientIDs, err); auditErr != nil {
+		slog.Error(auditErr.Error(), "query", query)
+		if err == nil {
+			return nil, auditErr
+		}
+	}
+
+	return found, err
+}
+
+func (l *listPatients) handle(ctx context.Context, query ListPatientsQuery) ([]*patients.Patient, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadPatients); err != nil {
+		return nil, err
+	}
+
+	found, err := l.patientRepo.List(ctx)
+	if err != nil {
+		slog.Error("error listing patients", "err", err, "query", query)
+		return nil, patients.ErrGettingPatient
+	}
+
+	return found, nil
+}
+
+// recordReads records the read of each patient, or a single record without patient when none was read.
+func recordReads(ctx context.Context, recorder auditing.Recorder, actor auth.Principal, requestID string,
+	patientIDs []uuid.UUID, err error) error {
+	entry := auditing.Entry{Actor: actor, Action: audit.ActionReadPatient, RequestID: requestID, Err: err}
+	if len(patientIDs) == 0 {
+		return recorder.Record(ctx, entry)
+	}
+
+	for _, patientID := range patientIDs {
+		entry.PatientID = &patientID
+		if auditErr := recorder.Record(ctx, entry); auditErr != nil {
+			return auditErr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+)
+
+func Test_listPatients_Handle(t *testing.T) {
+	johnID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	janeID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	auditor := auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}}
+	tests := []struct {
+		name        string
+		patientRepo patients.Repository
+		actor       auth.Principal
+		// recorded are the audit entries of the query, without its actor and request ID
+		recorded  []auditing.Entry
+		recordErr error
+		want      []*patients.Patient
+		wantErr   error
+	}{
+		{
+			name:        "return error when the actor has no role",
+			patientRepo: &patients.MockRepository{},
+			actor:       auth.Principal{ID: "practitioner-1"},
+			recorded:    []auditing.Entry{{Err: auth.ErrForbidden}},
+			want:        nil,
+			wantErr:     auth.ErrForbidden,
+		},
+		{
+			name: "return error when can't list the patients",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("List", mock.Anything, mock.Anything).Return(([]*patients.Patient)(nil), errors.New("DB error"))
+				return mockRepo
+			}(),
+			actor:    auditor,
+			recorded: []auditing.Entry{{Err: patients.ErrGettingPatient}},
+			want:     nil,
+			wantErr:  patients.ErrGettingPatient,
+		},
+		{
+			name: "return the patients recording the read of each one",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("List", mock.Anything, mock.Anything).Return([]*patients.Patient{
+					{ID: janeID, Name: "Jane Doe"}, {ID: johnID, Name: "John Doe"},
+				}, nil)
+				return mockRepo
+			}(),
+			actor:    auditor,
+			recorded: []auditing.Entry{{PatientID: &janeID}, {PatientID: &johnID}},
+			want:     []*patients.Patient{{ID: janeID, Name: "Jane Doe"}, {ID: johnID, Name: "John Doe"}},
+			wantErr:  nil,
+		},
+		{
+			name: "return error when the reads can't be recorded",
+			patientRepo: func() patients.Repository {
+				mockRepo := &patients.MockRepository{}
+				mockRepo.On("List", mock.Anything, mock.Anything).Return([]*patients.Patient{{ID: johnID, Name: "John Doe"}}, nil)
+				return mockRepo
+			}(),
+			actor:     auditor,
+			recorded:  []auditing.Entry{{PatientID: &johnID}},
+			recordErr: auditing.ErrRecordingAudit,
+			want:      nil,
+			wantErr:   auditing.ErrRecordingAudit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &auditing.MockRecorder{}
+			for _, entry := range tt.recorded {
+				entry.Actor, entry.Action, entry.RequestID = tt.actor, audit.ActionReadPatient, "request-1"
+				recorder.On("Record", mock.Anything, entry).Return(tt.recordErr).Once()
+			}
+
+			l := &listPatients{patientRepo: tt.patientRepo, recorder: recorder}
+			got, err := l.Handle(context.Background(), ListPatientsQuery{Actor: tt.actor, RequestID: "request-1"})
+			if err != tt.wantErr {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handle() got = %v, want %v", got, tt.want)
+			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
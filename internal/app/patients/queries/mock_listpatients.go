@@ -0,0 +1,16 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockListPatients struct {
+	mock.Mock
+}
+
+func (m *MockListPatients) Handle(ctx context.Context, query ListPatientsQuery) ([]*patients.Patient, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]*patients.Patient), args.Error(1)
+}
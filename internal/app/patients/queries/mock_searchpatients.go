@@ -0,0 +1,16 @@
+package queries
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSearchPatients struct {
+	mock.Mock
+}
+
+func (m *MockSearchPatients) Handle(ctx context.Context, query SearchPatientsQuery) ([]patients.Candidate, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]patients.Candidate), args.Error(1)
+}
@@ -0,0 +1,80 @@
+package queries
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+)
+
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 50
+)
+
+// SearchPatientsQuery looks up the patients whose name matches Name ignoring case, accents, word order and typos.
+type SearchPatientsQuery struct {
+	Name string
+	// Limit is the maximum number of candidates, DefaultSearchLimit when zero and never more than MaxSearchLimit
+	Limit     int
+	Actor     auth.Principal
+	RequestID string
+}
+
+type SearchPatientsHandler interface {
+	Handle(ctx context.Context, query SearchPatientsQuery) ([]patients.Candidate, error)
+}
+
+type searchPatients struct {
+	patientRepo patients.Repository
+	recorder    auditing.Recorder
+}
+
+// NewSearchPatientsHandler returns a handler that records the read of every candidate in the audit trail.
+func NewSearchPatientsHandler(patientRepo patients.Repository, recorder auditing.Recorder) SearchPatientsHandler {
+	return &searchPatients{patientRepo: patientRepo, recorder: recorder}
+}
+
+func (s *searchPatients) Handle(ctx context.Context, query SearchPatientsQuery) ([]patients.Candidate, error) {
+	candidates, err := s.handle(ctx, query)
+
+	patientIDs := make([]uuid.UUID, 0, len(candidates))
+	for _, candidate := range candidates {
+		patientIDs = append(patientIDs, candidate.Patient.ID)
+	}
+
+	if auditErr := recordReads(ctx, s.recorder, query.Actor, query.RequestID, patientIDs, err); auditErr != nil {
+		slog.Error(auditErr.Error(), "query", query)
+		if err == nil {
+			return nil, auditErr
+		}
+	}
+
+	return candidates, err
+}
+
+// handle scores every patient, typos can't be matched with an index and the patients of a clinic fit in memory.
+func (s *searchPatients) handle(ctx context.Context, query SearchPatientsQuery) ([]patients.Candidate, error) {
+	if err := auth.Authorize(query.Actor, auth.ReadPatients); err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	found, err := s.patientRepo.List(ctx)
+	if err != nil {
+		slog.Error("error listing patients", "err", err, "query", query)
+		return nil, patients.ErrGettingPatient
+	}
+
+	return patients.RankByName(query.Name, found, limit), nil
+}
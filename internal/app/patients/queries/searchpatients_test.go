@@ -0,0 +1,176 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/mock"
+	"reflect"
+	"testing"
+)
+
+func Test_searchPatients_Handle(t *testing.T) {
+	registry := []*patients.Patient{
+		{ID: uuid.New(), LegalID: "P1", Name: "José Pérez"},
+		{ID: uuid.New(), LegalID: "P2", Name: "Josefina Pérez García"},
+		{ID: uuid.New(), LegalID: "P3", Name: "John Doe"},
+		{ID: uuid.New(), LegalID: "P4", Name: "Jane Doe"},
+		{ID: uuid.New(), LegalID: "P5", Name: "Sean O'Brien"},
+		{ID: uuid.New(), LegalID: "P6", Name: "John Michael Doe"},
+	}
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+
+	tests := []struct {
+		name        string
+		search      string
+		limit       int
+		actor       auth.Principal
+		listErr     error
+		wantLegalID []string
+		wantErr     error
+	}{
+		{
+			name:    "return error when the actor has no role",
+			search:  "John Doe",
+			actor:   auth.Principal{ID: "practitioner-1"},
+			wantErr: auth.ErrForbidden,
+		},
+		{
+			name:    "return error when can't list the patients",
+			search:  "John Doe",
+			actor:   clinician,
+			listErr: errors.New("DB error"),
+			wantErr: patients.ErrGettingPatient,
+		},
+		{
+			name:        "match ignoring case and accents",
+			search:      "JOSE PEREZ",
+			actor:       clinician,
+			wantLegalID: []string{"P1", "P2"},
+		},
+		{
+			name:        "match names with typos and in any order",
+			search:      "doe jhon",
+			actor:       clinician,
+			wantLegalID: []string{"P3", "P6"},
+		},
+		{
+			name:        "match the surname alone",
+			search:      "Doe",
+			actor:       clinician,
+			wantLegalID: []string{"P4", "P3", "P6"},
+		},
+		{
+			name:        "match names without punctuation",
+			search:      "sean obrien",
+			actor:       clinician,
+			wantLegalID: []string{"P5"},
+		},
+		{
+			name:        "return at most limit candidates",
+			search:      "Doe",
+			limit:       1,
+			actor:       clinician,
+			wantLegalID: []string{"P4"},
+		},
+		{
+			name:        "return no candidates when no name matches",
+			search:      "Mary Smith",
+			actor:       clinician,
+			wantLegalID: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &patients.MockRepository{}
+			mockRepo.On("List", mock.Anything, mock.Anything).Return(registry, tt.listErr)
+			recorder := &auditing.MockRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Return(nil)
+			s := &searchPatients{patientRepo: mockRepo, recorder: recorder}
+
+			got, err := s.Handle(context.Background(), SearchPatientsQuery{Name: tt.search, Limit: tt.limit, Actor: tt.actor})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			legalIDs := make([]string, 0, len(got))
+			for i, candidate := range got {
+				legalIDs = append(legalIDs, candidate.Patient.LegalID)
+				if candidate.Score < patients.MinMatchScore || candidate.Score > 1 ||
+					(i > 0 && candidate.Score > got[i-1].Score) {
+					t.Errorf("Handle() candidate %s has score %v out of order or range", candidate.Patient.Name, candidate.Score)
+				}
+			}
+			if !reflect.DeepEqual(legalIDs, tt.wantLegalID) {
+				t.Errorf("Handle() got = %v, want %v", legalIDs, tt.wantLegalID)
+			}
+		})
+	}
+}
+
+func Test_searchPatients_Handle_RecordsReads(t *testing.T) {
+	johnID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	janeID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	registry := []*patients.Patient{{ID: johnID, Name: "John Doe"}, {ID: janeID, Name: "Jane Doe"}}
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+
+	tests := []struct {
+		name   string
+		search string
+		// recordedPatients are the patient IDs of the audit entries, in order
+		recordedPatients []*uuid.UUID
+		recordErr        error
+		wantErr          error
+	}{
+		{
+			name:             "record the read of every candidate",
+			search:           "Doe",
+			recordedPatients: []*uuid.UUID{&janeID, &johnID},
+		},
+		{
+			name:             "record a search without candidates",
+			search:           "Mary Smith",
+			recordedPatients: []*uuid.UUID{nil},
+		},
+		{
+			name:             "return error when the reads can't be recorded",
+			search:           "John Doe",
+			recordedPatients: []*uuid.UUID{&johnID},
+			recordErr:        auditing.ErrRecordingAudit,
+			wantErr:          auditing.ErrRecordingAudit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &patients.MockRepository{}
+			mockRepo.On("List", mock.Anything, mock.Anything).Return(registry, nil)
+			recorder := &auditing.MockRecorder{}
+			for _, patientID := range tt.recordedPatients {
+				recorder.On("Record", mock.Anything, auditing.Entry{
+					Actor:     clinician,
+					Action:    audit.ActionReadPatient,
+					PatientID: patientID,
+					RequestID: "request-1",
+				}).Return(tt.recordErr).Once()
+			}
+			s := &searchPatients{patientRepo: mockRepo, recorder: recorder}
+
+			got, err := s.Handle(context.Background(), SearchPatientsQuery{Name: tt.search, Actor: clinician, RequestID: "request-1"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && got != nil {
+				t.Errorf("Handle() got = %v, want no candidates", got)
+			}
+			recorder.AssertExpectations(t)
+		})
+	}
+}
@@ -1,18 +1,32 @@
 package app
 
 import (
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	auditqueries "github.com/juanmabaracat/diagnosis-service/internal/app/auditing/queries"
+	codequeries "github.com/juanmabaracat/diagnosis-service/internal/app/codes/queries"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	patientcommands "github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
 )
 
 type Commands struct {
 	AddPatientDiagnosisHandler commands.AddPatientDiagnosisHandler
+	AddPatientDiagnosesHandler commands.AddPatientDiagnosesHandler
+	AmendDiagnosisHandler      commands.AmendDiagnosisHandler
+	EnterInErrorHandler        commands.EnterInErrorHandler
 }
 
 type Queries struct {
-	GetDiagnoses queries.GetDiagnosesHandler
+	GetDiagnoses            queries.GetDiagnosesHandler
+	GetDiagnosisByID        queries.GetDiagnosisByIDHandler
+	GetDiagnosisHistory     queries.GetDiagnosisHistoryHandler
+	GetPatientPrescriptions queries.GetPatientPrescriptionsHandler
 }
 
 type DiagnosisServices struct {
@@ -20,19 +34,86 @@ type DiagnosisServices struct {
 	Queries  Queries
 }
 
+type PatientCommands struct {
+	CreatePatient        patientcommands.CreatePatientHandler
+	UpdatePatientContact patientcommands.UpdatePatientContactHandler
+}
+
+type PatientQueries struct {
+	GetPatient     patientqueries.GetPatientHandler
+	ListPatients   patientqueries.ListPatientsHandler
+	SearchPatients patientqueries.SearchPatientsHandler
+}
+
+type PatientServices struct {
+	Commands PatientCommands
+	Queries  PatientQueries
+}
+
+type AuditQueries struct {
+	GetAuditTrail    auditqueries.GetAuditTrailHandler
+	VerifyAuditTrail auditqueries.VerifyAuditTrailHandler
+}
+
+type AuditServices struct {
+	Queries AuditQueries
+}
+
+type CodeQueries struct {
+	SearchICD10 codequeries.SearchICD10Handler
+}
+
+type CodeServices struct {
+	Queries CodeQueries
+}
+
 // Services contains all services exposed of the application layer
 type Services struct {
 	DiagnosisServices DiagnosisServices
+	PatientServices   PatientServices
+	AuditServices     AuditServices
+	CodeServices      CodeServices
 }
 
-func NewServices(patientRepo patients.Repository, diagnosisRepo diagnoses.Repository) Services {
+func NewServices(patientRepo patients.Repository, diagnosisRepo diagnoses.Repository, unitOfWork unitofwork.UnitOfWork,
+	auditRepo audit.Repository, icd10 codes.Catalog) Services {
+	recorder := auditing.NewRecorder(auditRepo)
 	return Services{
 		DiagnosisServices: DiagnosisServices{
 			Commands: Commands{
-				AddPatientDiagnosisHandler: commands.NewAddPatientDiagnosisHandler(patientRepo, diagnosisRepo),
+				AddPatientDiagnosisHandler: commands.NewAddPatientDiagnosisHandler(unitOfWork, recorder, icd10),
+				AddPatientDiagnosesHandler: commands.NewAddPatientDiagnosesHandler(unitOfWork, recorder, icd10),
+				AmendDiagnosisHandler:      commands.NewAmendDiagnosisHandler(unitOfWork, recorder, icd10),
+				EnterInErrorHandler:        commands.NewEnterInErrorHandler(unitOfWork, recorder),
 			},
 			Queries: Queries{
-				GetDiagnoses: queries.NewGetDiagnosesHandler(patientRepo)},
+				GetDiagnoses:            queries.NewGetDiagnosesHandler(patientRepo, diagnosisRepo, recorder),
+				GetDiagnosisByID:        queries.NewGetDiagnosisByIDHandler(patientRepo, diagnosisRepo, recorder),
+				GetDiagnosisHistory:     queries.NewGetDiagnosisHistoryHandler(diagnosisRepo, recorder),
+				GetPatientPrescriptions: queries.NewGetPatientPrescriptionsHandler(patientRepo, diagnosisRepo, recorder),
+			},
+		},
+		PatientServices: PatientServices{
+			Commands: PatientCommands{
+				CreatePatient:        patientcommands.NewCreatePatientHandler(patientRepo, recorder),
+				UpdatePatientContact: patientcommands.NewUpdatePatientContactHandler(patientRepo, recorder),
+			},
+			Queries: PatientQueries{
+				GetPatient:     patientqueries.NewGetPatientHandler(patientRepo, recorder),
+				ListPatients:   patientqueries.NewListPatientsHandler(patientRepo, recorder),
+				SearchPatients: patientqueries.NewSearchPatientsHandler(patientRepo, recorder),
+			},
+		},
+		AuditServices: AuditServices{
+			Queries: AuditQueries{
+				GetAuditTrail:    auditqueries.NewGetAuditTrailHandler(auditRepo),
+				VerifyAuditTrail: auditqueries.NewVerifyAuditTrailHandler(auditRepo),
+			},
+		},
+		CodeServices: CodeServices{
+			Queries: CodeQueries{
+				SearchICD10: codequeries.NewSearchICD10Handler(icd10),
+			},
 		},
 	}
 }
@@ -1,10 +1,18 @@
 package app
 
 import (
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	auditqueries "github.com/juanmabaracat/diagnosis-service/internal/app/auditing/queries"
+	codequeries "github.com/juanmabaracat/diagnosis-service/internal/app/codes/queries"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	patientcommands "github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -12,17 +20,50 @@ import (
 func TestNewServices(t *testing.T) {
 	patientRepo := &patients.MockRepository{}
 	diagnosisRepo := &diagnoses.MockRepository{}
+	unitOfWork := &unitofwork.MockUnitOfWork{}
+	auditRepo := &audit.MockRepository{}
+	recorder := auditing.NewRecorder(auditRepo)
+	icd10 := &codes.MockCatalog{}
 	expected := Services{
 		DiagnosisServices: DiagnosisServices{
 			Commands: Commands{
-				AddPatientDiagnosisHandler: commands.NewAddPatientDiagnosisHandler(patientRepo, diagnosisRepo),
+				AddPatientDiagnosisHandler: commands.NewAddPatientDiagnosisHandler(unitOfWork, recorder, icd10),
+				AddPatientDiagnosesHandler: commands.NewAddPatientDiagnosesHandler(unitOfWork, recorder, icd10),
+				AmendDiagnosisHandler:      commands.NewAmendDiagnosisHandler(unitOfWork, recorder, icd10),
+				EnterInErrorHandler:        commands.NewEnterInErrorHandler(unitOfWork, recorder),
 			},
 			Queries: Queries{
-				GetDiagnoses: queries.NewGetDiagnosesHandler(patientRepo)},
+				GetDiagnoses:            queries.NewGetDiagnosesHandler(patientRepo, diagnosisRepo, recorder),
+				GetDiagnosisByID:        queries.NewGetDiagnosisByIDHandler(patientRepo, diagnosisRepo, recorder),
+				GetDiagnosisHistory:     queries.NewGetDiagnosisHistoryHandler(diagnosisRepo, recorder),
+				GetPatientPrescriptions: queries.NewGetPatientPrescriptionsHandler(patientRepo, diagnosisRepo, recorder),
+			},
+		},
+		PatientServices: PatientServices{
+			Commands: PatientCommands{
+				CreatePatient:        patientcommands.NewCreatePatientHandler(patientRepo, recorder),
+				UpdatePatientContact: patientcommands.NewUpdatePatientContactHandler(patientRepo, recorder),
+			},
+			Queries: PatientQueries{
+				GetPatient:     patientqueries.NewGetPatientHandler(patientRepo, recorder),
+				ListPatients:   patientqueries.NewListPatientsHandler(patientRepo, recorder),
+				SearchPatients: patientqueries.NewSearchPatientsHandler(patientRepo, recorder),
+			},
+		},
+		AuditServices: AuditServices{
+			Queries: AuditQueries{
+				GetAuditTrail:    auditqueries.NewGetAuditTrailHandler(auditRepo),
+				VerifyAuditTrail: auditqueries.NewVerifyAuditTrailHandler(auditRepo),
+			},
+		},
+		CodeServices: CodeServices{
+			Queries: CodeQueries{
+				SearchICD10: codequeries.NewSearchICD10Handler(icd10),
+			},
 		},
 	}
 
-	got := NewServices(patientRepo, diagnosisRepo)
+	got := NewServices(patientRepo, diagnosisRepo, unitOfWork, auditRepo, icd10)
 
 	assert.Equal(t, got, expected)
 }
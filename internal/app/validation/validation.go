@@ -0,0 +1,148 @@
+// Package validation checks the input of the application commands before they are handled, so a command is
+// validated the same way whether it came from HTTP, HL7 or FHIR. It checks the shape of the input, such as
+// lengths and character sets, the domain checks its own rules.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidCommand is matched by the Errors of every command that isn't valid.
+var ErrInvalidCommand = errors.New("invalid command")
+
+// FieldError is a failure of a command field. Field is the path of the field as clients send it, such as
+// prescription.medications[0].dose.
+type FieldError struct {
+	Field   string
+	Message string
+	// Err is the domain error of the failure, if any
+	Err error
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors are every failure found validating a command.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Error())
+	}
+	return ErrInvalidCommand.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e Errors) Is(target error) bool {
+	return target == ErrInvalidCommand
+}
+
+// Unwrap returns the failures, so the domain errors of the fields can be matched with errors.Is.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fieldErr := range e {
+		errs = append(errs, fieldErr)
+	}
+	return errs
+}
+
+// Validator collects the failures of the fields of a command, see Err.
+type Validator struct {
+	errs Errors
+}
+
+// Err returns the Errors found, or nil when every field is valid.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Add records a failure of the field.
+func (v *Validator) Add(field, message string) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: message})
+}
+
+// AddError records the domain error as a failure of the field.
+func (v *Validator) AddError(field string, err error) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: err.Error(), Err: err})
+}
+
+// Check records a failure of the field unless ok.
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.Add(field, message)
+	}
+}
+
+// Required checks the value isn't blank, returning whether it isn't.
+func (v *Validator) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// Text checks the value is valid UTF-8 of at most max characters without control or formatting characters,
+// other than line breaks and tabs. Formatting characters, such as bidirectional overrides, can make the text
+// read differently than it is stored.
+func (v *Validator) Text(field, value string, max int) {
+	if !utf8.ValidString(value) {
+		v.Add(field, "must be valid UTF-8")
+		return
+	}
+
+	v.maxLength(field, value, max)
+	for _, r := range value {
+		if !unicode.IsPrint(r) && !strings.ContainsRune("\n\r\t", r) {
+			v.Add(field, fmt.Sprintf("has a character that isn't allowed: %U", r))
+			return
+		}
+	}
+}
+
+// Code checks the value is an identifier of at most max characters made of ASCII letters, digits, dots and
+// hyphens, such as an ICD-10 or ATC code.
+func (v *Validator) Code(field, value string, max int) {
+	v.maxLength(field, value, max)
+	for _, r := range value {
+		if !isCodeRune(r) {
+			v.Add(field, "can only have letters, digits, dots and hyphens")
+			return
+		}
+	}
+}
+
+// MaxItems checks a list has at most max items.
+func (v *Validator) MaxItems(field string, items, max int) {
+	v.Check(items <= max, field, fmt.Sprintf("can't have more than %d items", max))
+}
+
+// NotFuture checks the date isn't after now, recording err as the failure. It passes when the date is nil.
+func (v *Validator) NotFuture(field string, date *time.Time, now time.Time, err error) {
+	if date != nil && date.After(now) {
+		v.AddError(field, err)
+	}
+}
+
+func (v *Validator) maxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, fmt.Sprintf("can't be longer than %d characters", max))
+	}
+}
+
+func isCodeRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-'
+}
@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidator(t *testing.T) {
+	errTooLate := errors.New("date is too late")
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tomorrow := now.AddDate(0, 0, 1)
+	yesterday := now.AddDate(0, 0, -1)
+
+	tests := []struct {
+		name     string
+		validate func(v *Validator)
+		want     Errors
+	}{
+		{
+			name: "accept valid values",
+			validate: func(v *Validator) {
+				v.Required("diagnosis", "asthma")
+				v.Text("diagnosis", "asma alérgica\nsevera\t(leve)", 30)
+				v.Code("code", "J45.0", 10)
+				v.MaxItems("medications", 2, 2)
+				v.NotFuture("birth_date", &yesterday, now, errTooLate)
+				v.NotFuture("birth_date", nil, now, errTooLate)
+			},
+			want: nil,
+		},
+		{
+			name: "reject blank required values",
+			validate: func(v *Validator) {
+				v.Required("diagnosis", " \n\t")
+			},
+			want: Errors{{Field: "diagnosis", Message: "is required"}},
+		},
+		{
+			name: "count the length in characters",
+			validate: func(v *Validator) {
+				v.Text("name", "José", 4)
+				v.Text("reason", strings.Repeat("é", 6), 5)
+			},
+			want: Errors{{Field: "reason", Message: "can't be longer than 5 characters"}},
+		},
+		{
+			name: "reject control and formatting characters",
+			validate: func(v *Validator) {
+				v.Text("diagnosis", "asthma\x00", 100)
+				v.Text("reason", "wrong \u202epatient", 100)
+				v.Text("notes", "take\xffwith food", 100)
+			},
+			want: Errors{
+				{Field: "diagnosis", Message: "has a character that isn't allowed: U+0000"},
+				{Field: "reason", Message: "has a character that isn't allowed: U+202E"},
+				{Field: "notes", Message: "must be valid UTF-8"},
+			},
+		},
+		{
+			name: "reject codes with other characters",
+			validate: func(v *Validator) {
+				v.Code("code", "J45.0; DROP", 20)
+				v.Code("drug_code", "J01CA04", 5)
+			},
+			want: Errors{
+				{Field: "code", Message: "can only have letters, digits, dots and hyphens"},
+				{Field: "drug_code", Message: "can't be longer than 5 characters"},
+			},
+		},
+		{
+			name: "reject too many items",
+			validate: func(v *Validator) {
+				v.MaxItems("medications", 3, 2)
+			},
+			want: Errors{{Field: "medications", Message: "can't have more than 2 items"}},
+		},
+		{
+			name: "reject future dates with the error given",
+			validate: func(v *Validator) {
+				v.NotFuture("birth_date", &tomorrow, now, errTooLate)
+			},
+			want: Errors{{Field: "birth_date", Message: errTooLate.Error(), Err: errTooLate}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			tt.validate(&v)
+			err := v.Err()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Err() = %v, but no error expected", err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("Err() = %#v, want %#v", err, tt.want)
+			}
+			if !errors.Is(err, ErrInvalidCommand) {
+				t.Errorf("Err() = %v, expected it to match ErrInvalidCommand", err)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	errMissingReason := errors.New("reason is missing")
+	errs := Errors{
+		{Field: "diagnosis", Message: "is required"},
+		{Field: "reason", Message: "reason is missing", Err: errMissingReason},
+	}
+
+	if got, want := errs.Error(), "invalid command: diagnosis: is required; reason: reason is missing"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(errs, errMissingReason) {
+		t.Errorf("Errors %v expected to wrap %v", errs, errMissingReason)
+	}
+	var found Errors
+	if !errors.As(fmt.Errorf("invalid patient: %w", errs), &found) || len(found) != 2 {
+		t.Errorf("errors.As() found %v, want %v", found, errs)
+	}
+}
@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Append(ctx context.Context, record Record) (Record, error) {
+	args := m.Called(ctx, record)
+	return args.Get(0).(Record), args.Error(1)
+}
+
+func (m *MockRepository) Find(ctx context.Context, filter Filter) ([]Record, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]Record), args.Error(1)
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionReadDiagnoses  Action = "diagnoses:read"
+	ActionAddDiagnosis   Action = "diagnoses:add"
+	ActionAmendDiagnosis Action = "diagnoses:amend"
+	// ActionEnterInError is recorded when a diagnosis is retracted as entered in error
+	ActionEnterInError      Action = "diagnoses:enter-in-error"
+	ActionReadPrescriptions Action = "prescriptions:read"
+	// ActionReadPatient is recorded for each patient whose identity and contact data is returned
+	ActionReadPatient   Action = "patients:read"
+	ActionCreatePatient Action = "patients:create"
+	ActionUpdatePatient Action = "patients:update"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	// OutcomeDenied is recorded when the actor is not authenticated or not allowed to perform the action
+	OutcomeDenied  Outcome = "denied"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Record tells who performed an action on a patient record, when and how it ended. Records are chained:
+// each one holds the hash of the previous record, so changing or removing any of them breaks the chain.
+type Record struct {
+	// Sequence is the position of the record in the trail, starting at 1
+	Sequence  uint64
+	Timestamp time.Time
+	ActorID   string
+	Action    Action
+	// PatientID is nil when the action didn't reach any patient, such as a search without results
+	PatientID *uuid.UUID
+	Outcome   Outcome
+	RequestID string
+	// PrevHash is the Hash of the previous record, empty for the first one
+	PrevHash string
+	Hash     string
+}
+
+// Chain returns the record placed after prev in the trail, with its sequence and hashes set.
+// prev is nil for the first record.
+func Chain(prev *Record, record Record) Record {
+	record.Sequence = 1
+	record.PrevHash = ""
+	if prev != nil {
+		record.Sequence = prev.Sequence + 1
+		record.PrevHash = prev.Hash
+	}
+
+	record.Hash = record.ComputeHash()
+	return record
+}
+
+// ComputeHash returns the SHA-256 of every field of the record but Hash, hex encoded.
+func (r Record) ComputeHash() string {
+	patientID := ""
+	if r.PatientID != nil {
+		patientID = r.PatientID.String()
+	}
+
+	// the timestamp is hashed as unix nanoseconds, so the hash doesn't depend on the location
+	content, _ := json.Marshal(struct {
+		Sequence  uint64
+		Timestamp int64
+		ActorID   string
+		Action    Action
+		PatientID string
+		Outcome   Outcome
+		RequestID string
+		PrevHash  string
+	}{r.Sequence, r.Timestamp.UnixNano(), r.ActorID, r.Action, patientID, r.Outcome, r.RequestID, r.PrevHash})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+// Filter selects audit records. Empty fields don't filter, so the zero value selects the whole trail.
+type Filter struct {
+	PatientID *uuid.UUID
+	ActorID   string
+}
+
+func (f Filter) Matches(record Record) bool {
+	if f.PatientID != nil && (record.PatientID == nil || *record.PatientID != *f.PatientID) {
+		return false
+	}
+
+	return f.ActorID == "" || record.ActorID == f.ActorID
+}
+
+// Repository is an append-only store of audit records. There is no way to change or remove a record.
+type Repository interface {
+	// Append chains the record after the last one of the trail, see Chain, and returns it as stored.
+	Append(ctx context.Context, record Record) (Record, error)
+	// Find returns the matching records sorted by sequence.
+	Find(ctx context.Context, filter Filter) ([]Record, error)
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrBrokenChain = errors.New("the audit chain is broken")
+
+// BrokenChainError tells where the chain is broken and why. It matches ErrBrokenChain with errors.Is.
+type BrokenChainError struct {
+	// Sequence is the position of the first record that doesn't fit in the chain
+	Sequence uint64
+	Reason   string
+}
+
+func (e *BrokenChainError) Error() string {
+	return fmt.Sprintf("%s at record %d: %s", ErrBrokenChain, e.Sequence, e.Reason)
+}
+
+func (e *BrokenChainError) Is(target error) bool {
+	return target == ErrBrokenChain
+}
+
+// Verify checks that records, sorted by sequence, are the whole trail and were not changed since they
+// were appended. It returns a *BrokenChainError for the first record that doesn't fit.
+func Verify(records []Record) error {
+	var prev *Record
+	for i := range records {
+		record := records[i]
+		expectedSequence, expectedPrevHash := uint64(1), ""
+		if prev != nil {
+			expectedSequence, expectedPrevHash = prev.Sequence+1, prev.Hash
+		}
+
+		if record.Sequence != expectedSequence {
+			return &BrokenChainError{
+				Sequence: expectedSequence,
+				Reason:   fmt.Sprintf("found record %d instead, records are missing", record.Sequence),
+			}
+		}
+
+		if record.PrevHash != expectedPrevHash {
+			return &BrokenChainError{Sequence: record.Sequence, Reason: "previous hash doesn't match the previous record"}
+		}
+
+		if record.Hash != record.ComputeHash() {
+			return &BrokenChainError{Sequence: record.Sequence, Reason: "hash doesn't match the record content"}
+		}
+
+		prev = &record
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package codes
+
+// Concept is an entry of a code system.
+type Concept struct {
+	Code    string
+	Display string
+}
+
+// Catalog looks up the concepts of a code system. Catalogs are code tables loaded in memory, so unlike the
+// repositories their lookups take no context.
+type Catalog interface {
+	// Lookup returns the concept with that code, and false when there is none.
+	Lookup(code string) (Concept, bool)
+	// Search returns at most limit concepts whose code starts with text or whose display contains every word of it,
+	// the code matches first.
+	Search(text string, limit int) []Concept
+}
@@ -0,0 +1,17 @@
+package codes
+
+import "github.com/stretchr/testify/mock"
+
+type MockCatalog struct {
+	mock.Mock
+}
+
+func (m *MockCatalog) Lookup(code string) (Concept, bool) {
+	args := m.Called(code)
+	return args.Get(0).(Concept), args.Bool(1)
+}
+
+func (m *MockCatalog) Search(text string, limit int) []Concept {
+	args := m.Called(text, limit)
+	return args.Get(0).([]Concept)
+}
@@ -0,0 +1,52 @@
+package diagnoses
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// SystemICD10 identifies the ICD-10 code system, using its FHIR canonical URL.
+const SystemICD10 = "http://hl7.org/fhir/sid/icd-10"
+
+var ErrInvalidCode = errors.New("invalid ICD-10 code")
+
+// Coding is a diagnosis coded in a code system, such as ICD-10.
+type Coding struct {
+	System  string
+	Code    string
+	Display string
+}
+
+var (
+	icd10Pattern       = regexp.MustCompile(`^[A-Z][0-9][0-9A-Z](\.[0-9A-Z]{1,4})?$`)
+	icd10PrefixPattern = regexp.MustCompile(`^[A-Z]([0-9]([0-9A-Z](\.[0-9A-Z]{0,4})?)?)?$`)
+)
+
+// NormalizeICD10 returns the code in upper case, with the dot after the category (J450 becomes J45.0).
+func NormalizeICD10(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) > 3 && !strings.Contains(code, ".") {
+		code = code[:3] + "." + code[3:]
+	}
+
+	if !icd10Pattern.MatchString(code) {
+		return "", ErrInvalidCode
+	}
+
+	return code, nil
+}
+
+// NormalizeICD10Prefix is NormalizeICD10 for the beginning of a code, such as J4 or J45.
+func NormalizeICD10Prefix(prefix string) (string, error) {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if len(prefix) > 3 && !strings.Contains(prefix, ".") {
+		prefix = prefix[:3] + "." + prefix[3:]
+	}
+
+	if !icd10PrefixPattern.MatchString(prefix) {
+		return "", ErrInvalidCode
+	}
+
+	return prefix, nil
+}
@@ -5,10 +5,26 @@ import (
 	"time"
 )
 
+// Diagnosis is the current version of a diagnosis. Diagnoses are never changed in place, amending or correcting
+// one writes a new version with the same ID, see Amend and EnterInError.
 type Diagnosis struct {
-	ID           uuid.UUID
-	Description  string
-	PatientID    uuid.UUID
-	CreatedAt    time.Time
-	Prescription *string
+	ID          uuid.UUID
+	Description string
+	PatientID   uuid.UUID
+	CreatedAt   time.Time
+	// Prescription is the medication prescribed with the diagnosis, nil when there is none
+	Prescription *Prescription
+	// PractitionerID identifies the practitioner who made the diagnosis
+	PractitionerID string
+	// Coding is the diagnosis coded in ICD-10, nil when it is only described in free text
+	Coding *Coding
+	// Version is 1 for the diagnosis as first written, and is incremented by every new version
+	Version int
+	Status  Status
+	// RecordedAt and RecordedBy tell when and by whom this version was written, CreatedAt and PractitionerID
+	// keep those of the first version
+	RecordedAt time.Time
+	RecordedBy string
+	// Reason explains why this version was written, it is empty for the first one
+	Reason string
 }
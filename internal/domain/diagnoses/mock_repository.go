@@ -1,12 +1,36 @@
 package diagnoses
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
 
 type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) AddDiagnosis(diagnosis Diagnosis) error {
-	args := m.Called(diagnosis)
+func (m *MockRepository) AddDiagnosis(ctx context.Context, diagnosis Diagnosis) error {
+	args := m.Called(ctx, diagnosis)
 	return args.Error(0)
 }
+
+func (m *MockRepository) GetDiagnoses(ctx context.Context, filter Filter, page PageRequest) (Page, error) {
+	args := m.Called(ctx, filter, page)
+	return args.Get(0).(Page), args.Error(1)
+}
+
+func (m *MockRepository) UpdateDiagnosis(ctx context.Context, diagnosis Diagnosis) error {
+	args := m.Called(ctx, diagnosis)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetDiagnosisByID(ctx context.Context, ID uuid.UUID) (*Diagnosis, error) {
+	args := m.Called(ctx, ID)
+	return args.Get(0).(*Diagnosis), args.Error(1)
+}
+
+func (m *MockRepository) GetDiagnosisHistory(ctx context.Context, ID uuid.UUID) ([]*Diagnosis, error) {
+	args := m.Called(ctx, ID)
+	return args.Get(0).([]*Diagnosis), args.Error(1)
+}
@@ -0,0 +1,118 @@
+package diagnoses
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// Cursor points to the last diagnosis of a page. Diagnoses are ordered by creation date and then by ID,
+// so the position stays stable when new diagnoses are added.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// String encodes the cursor as an opaque token that can be sent to clients.
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "_" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a token created by Cursor.String.
+func ParseCursor(token string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	nanos, id, found := strings.Cut(string(raw), "_")
+	if !found {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	createdAt, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	ID, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return Cursor{CreatedAt: time.Unix(0, createdAt).UTC(), ID: ID}, nil
+}
+
+// PageRequest selects a page of a diagnoses search. The zero value returns every diagnosis in ascending order.
+type PageRequest struct {
+	// Limit is the maximum number of diagnoses in the page, zero means no limit
+	Limit int
+	// After skips the diagnoses up to the cursor, included, in the requested order
+	After *Cursor
+	// Order sorts by creation date, ascending when empty
+	Order SortOrder
+}
+
+type Page struct {
+	Diagnoses []*Diagnosis
+	// Next points to the last diagnosis of the page, it is nil when there are no more pages
+	Next *Cursor
+	// Total is the number of diagnoses matching the filter, across all pages
+	Total int
+}
+
+// Less reports whether a goes before b in ascending order.
+func Less(a, b Diagnosis) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.ID.String() < b.ID.String()
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// Paginate picks the requested page out of every diagnosis matching a filter. It is meant for
+// repositories that can't paginate natively.
+func Paginate(matching []*Diagnosis, page PageRequest) Page {
+	sorted := make([]*Diagnosis, len(matching))
+	copy(sorted, matching)
+	sort.Slice(sorted, func(i, j int) bool {
+		if page.Order == SortDescending {
+			return Less(*sorted[j], *sorted[i])
+		}
+		return Less(*sorted[i], *sorted[j])
+	})
+
+	start := 0
+	if page.After != nil {
+		cursor := Diagnosis{CreatedAt: page.After.CreatedAt, ID: page.After.ID}
+		start = sort.Search(len(sorted), func(i int) bool {
+			if page.Order == SortDescending {
+				return Less(*sorted[i], cursor)
+			}
+			return Less(cursor, *sorted[i])
+		})
+	}
+
+	result := Page{Diagnoses: sorted[start:], Total: len(sorted)}
+	if page.Limit > 0 && len(result.Diagnoses) > page.Limit {
+		result.Diagnoses = result.Diagnoses[:page.Limit]
+		last := result.Diagnoses[page.Limit-1]
+		result.Next = &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	return result
+}
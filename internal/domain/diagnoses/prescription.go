@@ -0,0 +1,150 @@
+package diagnoses
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrEmptyPrescription = errors.New("prescription must have at least one medication or notes")
+	ErrInvalidDrug       = errors.New("drug name cannot be empty")
+	ErrInvalidDose       = errors.New("dose must be greater than zero")
+	ErrInvalidDoseUnit   = errors.New("dose unit cannot be empty")
+	ErrInvalidRoute      = errors.New("invalid route of administration")
+	ErrInvalidFrequency  = errors.New("frequency times and period must be greater than zero with a valid unit")
+	ErrInvalidDuration   = errors.New("duration must be greater than zero with a valid unit")
+	ErrInvalidQuantity   = errors.New("quantity must be greater than zero")
+	ErrInvalidRefills    = errors.New("refills must be between 0 and 12")
+)
+
+// MaxRefills is the maximum number of times a prescription can be refilled after the first dispense.
+const MaxRefills = 12
+
+// Prescription is the medication prescribed with a diagnosis. Prescriptions recorded as free text before
+// they were structured only have Notes.
+type Prescription struct {
+	Medications []Medication
+	// Notes are free-text instructions for the patient or the pharmacy
+	Notes string
+}
+
+// Medication is a line of a prescription: take Dose DoseUnit of the drug by Route, Frequency, for Duration.
+type Medication struct {
+	DrugName string
+	// DrugCode optionally identifies the drug in a drug catalog, such as ATC or RxNorm
+	DrugCode  string
+	Dose      float64
+	DoseUnit  string
+	Route     Route
+	Frequency Frequency
+	Duration  Duration
+	// Quantity is the number of units to dispense, such as 30 tablets
+	Quantity int
+	// Refills is the number of times it can be dispensed again, 0 when it can't
+	Refills int
+}
+
+// Route is the route of administration of a medication.
+type Route string
+
+const (
+	RouteOral          Route = "oral"
+	RouteSublingual    Route = "sublingual"
+	RouteTopical       Route = "topical"
+	RouteTransdermal   Route = "transdermal"
+	RouteInhalation    Route = "inhalation"
+	RouteNasal         Route = "nasal"
+	RouteOphthalmic    Route = "ophthalmic"
+	RouteOtic          Route = "otic"
+	RouteRectal        Route = "rectal"
+	RouteVaginal       Route = "vaginal"
+	RouteIntravenous   Route = "intravenous"
+	RouteIntramuscular Route = "intramuscular"
+	RouteSubcutaneous  Route = "subcutaneous"
+)
+
+var routes = map[Route]bool{
+	RouteOral: true, RouteSublingual: true, RouteTopical: true, RouteTransdermal: true, RouteInhalation: true,
+	RouteNasal: true, RouteOphthalmic: true, RouteOtic: true, RouteRectal: true, RouteVaginal: true,
+	RouteIntravenous: true, RouteIntramuscular: true, RouteSubcutaneous: true,
+}
+
+// TimeUnit is the unit of frequency periods and durations.
+type TimeUnit string
+
+const (
+	UnitHour  TimeUnit = "hour"
+	UnitDay   TimeUnit = "day"
+	UnitWeek  TimeUnit = "week"
+	UnitMonth TimeUnit = "month"
+)
+
+var timeUnits = map[TimeUnit]bool{UnitHour: true, UnitDay: true, UnitWeek: true, UnitMonth: true}
+
+// Frequency is how often a dose is taken: Times every Period Unit, such as 3 times every 1 day.
+type Frequency struct {
+	Times  int
+	Period int
+	Unit   TimeUnit
+}
+
+// Duration is how long the medication is taken, such as 10 days.
+type Duration struct {
+	Value int
+	Unit  TimeUnit
+}
+
+// Validate checks every medication of the prescription, returning every failure found.
+func (p Prescription) Validate() error {
+	if len(p.Medications) == 0 && strings.TrimSpace(p.Notes) == "" {
+		return ErrEmptyPrescription
+	}
+
+	var errs []error
+	for i, medication := range p.Medications {
+		if err := medication.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("medication %d: %w", i+1, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the medication line, returning every failure found.
+func (m Medication) Validate() error {
+	var errs []error
+	if strings.TrimSpace(m.DrugName) == "" {
+		errs = append(errs, ErrInvalidDrug)
+	}
+
+	if m.Dose <= 0 {
+		errs = append(errs, ErrInvalidDose)
+	}
+
+	if strings.TrimSpace(m.DoseUnit) == "" {
+		errs = append(errs, ErrInvalidDoseUnit)
+	}
+
+	if !routes[m.Route] {
+		errs = append(errs, ErrInvalidRoute)
+	}
+
+	if m.Frequency.Times <= 0 || m.Frequency.Period <= 0 || !timeUnits[m.Frequency.Unit] {
+		errs = append(errs, ErrInvalidFrequency)
+	}
+
+	if m.Duration.Value <= 0 || !timeUnits[m.Duration.Unit] {
+		errs = append(errs, ErrInvalidDuration)
+	}
+
+	if m.Quantity <= 0 {
+		errs = append(errs, ErrInvalidQuantity)
+	}
+
+	if m.Refills < 0 || m.Refills > MaxRefills {
+		errs = append(errs, ErrInvalidRefills)
+	}
+
+	return errors.Join(errs...)
+}
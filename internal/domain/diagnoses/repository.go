@@ -1,5 +1,69 @@
 package diagnoses
 
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+var ErrDuplicatedID = errors.New("there is already a diagnosis with the same ID")
+
 type Repository interface {
-	AddDiagnosis(diagnosis Diagnosis) error
+	// AddDiagnosis stores the first version of a diagnosis, ErrDuplicatedID when its ID is already stored
+	AddDiagnosis(ctx context.Context, diagnosis Diagnosis) error
+	// UpdateDiagnosis stores a new version of a stored diagnosis, keeping the previous ones in its history
+	UpdateDiagnosis(ctx context.Context, diagnosis Diagnosis) error
+	// GetDiagnosisByID returns the current version of the diagnosis, whatever its status, or nil when there is none
+	GetDiagnosisByID(ctx context.Context, ID uuid.UUID) (*Diagnosis, error)
+	// GetDiagnosisHistory returns every version of the diagnosis from the first one, empty when there is none
+	GetDiagnosisHistory(ctx context.Context, ID uuid.UUID) ([]*Diagnosis, error)
+	GetDiagnoses(ctx context.Context, filter Filter, page PageRequest) (Page, error)
+}
+
+// Filter narrows a diagnoses search. Nil and empty fields are not applied, and both date bounds are inclusive.
+// Diagnoses entered in error never match, they are only kept for their history.
+type Filter struct {
+	PatientID *uuid.UUID
+	From      *time.Time
+	To        *time.Time
+	// Code and CodePrefix match the normalized ICD-10 code of coded diagnoses, see NormalizeICD10
+	Code       string
+	CodePrefix string
+	// HasPrescription only matches diagnoses with a prescription when set
+	HasPrescription bool
+}
+
+// Matches reports whether the diagnosis satisfies every criteria set in the filter.
+func (f Filter) Matches(diagnosis Diagnosis) bool {
+	if diagnosis.Status == StatusEnteredInError {
+		return false
+	}
+
+	if f.PatientID != nil && diagnosis.PatientID != *f.PatientID {
+		return false
+	}
+
+	if f.From != nil && diagnosis.CreatedAt.Before(*f.From) {
+		return false
+	}
+
+	if f.To != nil && diagnosis.CreatedAt.After(*f.To) {
+		return false
+	}
+
+	if f.Code != "" && (diagnosis.Coding == nil || diagnosis.Coding.Code != f.Code) {
+		return false
+	}
+
+	if f.CodePrefix != "" && (diagnosis.Coding == nil || !strings.HasPrefix(diagnosis.Coding.Code, f.CodePrefix)) {
+		return false
+	}
+
+	if f.HasPrescription && diagnosis.Prescription == nil {
+		return false
+	}
+
+	return true
 }
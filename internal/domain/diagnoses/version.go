@@ -0,0 +1,68 @@
+package diagnoses
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingReason  = errors.New("a reason is required to amend or correct a diagnosis")
+	ErrEnteredInError = errors.New("the diagnosis was entered in error and can't be changed")
+)
+
+// Status is the status of a diagnosis version, named after the FHIR observation status codes.
+type Status string
+
+const (
+	// StatusFinal is the status of the diagnosis as first written
+	StatusFinal Status = "final"
+	// StatusAmended is the status of the versions that changed the diagnosis content
+	StatusAmended Status = "amended"
+	// StatusEnteredInError retracts a diagnosis that should never have been written. Searches don't return it
+	// anymore, but its history is kept.
+	StatusEnteredInError Status = "entered-in-error"
+)
+
+// Revision tells who writes a new version of a diagnosis, when and why.
+type Revision struct {
+	AuthorID string
+	At       time.Time
+	Reason   string
+}
+
+// Amend returns the next version of the diagnosis, with the description, prescription and coding replaced.
+func (d Diagnosis) Amend(description string, prescription *Prescription, coding *Coding, revision Revision) (Diagnosis, error) {
+	next, err := d.revise(StatusAmended, revision)
+	if err != nil {
+		return Diagnosis{}, err
+	}
+
+	next.Description = description
+	next.Prescription = prescription
+	next.Coding = coding
+	return next, nil
+}
+
+// EnterInError returns the next version of the diagnosis, which retracts it keeping its content.
+func (d Diagnosis) EnterInError(revision Revision) (Diagnosis, error) {
+	return d.revise(StatusEnteredInError, revision)
+}
+
+func (d Diagnosis) revise(status Status, revision Revision) (Diagnosis, error) {
+	if d.Status == StatusEnteredInError {
+		return Diagnosis{}, ErrEnteredInError
+	}
+
+	reason := strings.TrimSpace(revision.Reason)
+	if reason == "" {
+		return Diagnosis{}, ErrMissingReason
+	}
+
+	d.Version++
+	d.Status = status
+	d.RecordedAt = revision.At
+	d.RecordedBy = revision.AuthorID
+	d.Reason = reason
+	return d, nil
+}
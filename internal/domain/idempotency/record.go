@@ -0,0 +1,39 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// Response is the response to the first request sent with an idempotency key, which is replayed to its retries.
+type Response struct {
+	StatusCode int
+	// Header holds the response headers worth replaying, such as Content-Type and Location
+	Header map[string]string
+	Body   []byte
+}
+
+// Record holds what is known of the first request sent with an idempotency key.
+type Record struct {
+	// Key is scoped to the client that sent it, two clients can send the same key
+	Key string
+	// Fingerprint identifies the request, its retries have the same one
+	Fingerprint string
+	// Response is nil while the first request is being processed
+	Response  *Response
+	ExpiresAt time.Time
+}
+
+func (r Record) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// Store keeps the records of the idempotency keys until they expire.
+type Store interface {
+	// Reserve stores the record, unless its key has a record that didn't expire at now, which is returned instead.
+	Reserve(ctx context.Context, record Record, now time.Time) (*Record, error)
+	// Complete stores the response to the request reserving the key, which expires at expiresAt instead.
+	Complete(ctx context.Context, key string, response Response, expiresAt time.Time) error
+	// Release removes the record of the key, so the request can be sent again with it.
+	Release(ctx context.Context, key string) error
+}
@@ -1,6 +1,7 @@
 package patients
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 )
@@ -9,17 +10,32 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) GetByName(name string) (*Patient, error) {
-	args := m.Called(name)
+func (m *MockRepository) Create(ctx context.Context, patient Patient) error {
+	args := m.Called(ctx, patient)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindByName(ctx context.Context, name string) ([]*Patient, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).([]*Patient), args.Error(1)
+}
+
+func (m *MockRepository) GetByID(ctx context.Context, ID uuid.UUID) (*Patient, error) {
+	args := m.Called(ctx, ID)
 	return args.Get(0).(*Patient), args.Error(1)
 }
 
-func (m *MockRepository) GetByID(ID uuid.UUID) (*Patient, error) {
-	args := m.Called(ID)
+func (m *MockRepository) GetByLegalID(ctx context.Context, legalID string) (*Patient, error) {
+	args := m.Called(ctx, legalID)
 	return args.Get(0).(*Patient), args.Error(1)
 }
 
-func (m *MockRepository) Update(patient Patient) error {
-	args := m.Called(patient)
+func (m *MockRepository) List(ctx context.Context) ([]*Patient, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*Patient), args.Error(1)
+}
+
+func (m *MockRepository) Update(ctx context.Context, patient Patient) error {
+	args := m.Called(ctx, patient)
 	return args.Error(0)
 }
@@ -2,15 +2,21 @@ package patients
 
 import (
 	"github.com/google/uuid"
-	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"time"
 )
 
+// Patient holds the identity and contact data of a patient, their diagnoses are queried by patient ID from the
+// diagnoses repository.
 type Patient struct {
-	ID          uuid.UUID
-	LegalID     string
-	Name        string
-	Address     string
-	Phone       string
-	Email       string
-	Diagnostics []*diagnoses.Diagnosis
+	ID      uuid.UUID
+	LegalID string
+	Name    string
+	// BirthDate is the date of birth at midnight UTC, nil when unknown
+	BirthDate *time.Time
+	Address   string
+	Phone     string
+	Email     string
+	// Version counts the updates of the patient, it is 1 once created. Updates are made on the version read and
+	// rejected when it is no longer the stored one.
+	Version int
 }
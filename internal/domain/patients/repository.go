@@ -1,9 +1,31 @@
 package patients
 
-import "github.com/google/uuid"
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPatientNotFound   = errors.New("patient not found")
+	ErrGettingPatient    = errors.New("error getting patient")
+	ErrUpdatingPatient   = errors.New("error updating patient")
+	ErrDuplicatedLegalID = errors.New("there is already a patient with the same legal ID")
+	ErrDuplicatedID      = errors.New("there is already a patient with the same ID")
+	// ErrConcurrentModification is returned when a patient is updated on a version that is no longer the stored one
+	ErrConcurrentModification = errors.New("the patient was modified concurrently")
+)
 
 type Repository interface {
-	GetByName(name string) (*Patient, error)
-	GetByID(ID uuid.UUID) (*Patient, error)
-	Update(patient Patient) error
+	// Create stores the patient as its version 1, whatever its Version. It returns ErrDuplicatedID or
+	// ErrDuplicatedLegalID when another patient has its ID or legal ID.
+	Create(ctx context.Context, patient Patient) error
+	// FindByName returns every patient with exactly that name sorted by ID, several patients can share a name
+	FindByName(ctx context.Context, name string) ([]*Patient, error)
+	GetByID(ctx context.Context, ID uuid.UUID) (*Patient, error)
+	GetByLegalID(ctx context.Context, legalID string) (*Patient, error)
+	List(ctx context.Context) ([]*Patient, error)
+	// Update stores the patient as the version following its Version, which must be the stored one. Otherwise,
+	// or when the patient doesn't exist, it returns ErrConcurrentModification.
+	Update(ctx context.Context, patient Patient) error
 }
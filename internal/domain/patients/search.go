@@ -0,0 +1,135 @@
+package patients
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MinMatchScore is the lowest score of a patient that is still a candidate of a name search.
+const MinMatchScore = 0.6
+
+// Candidate is a patient whose name matches a search, Score goes from MinMatchScore to 1 for an exact match.
+type Candidate struct {
+	Patient *Patient
+	Score   float64
+}
+
+var folds = func() map[rune]string {
+	groups := map[string]string{
+		"a": "àáâãäåāăą", "c": "çćĉċč", "d": "ďđ", "e": "èéêëēĕėęě", "g": "ĝğġģ", "h": "ĥħ", "i": "ìíîïĩīĭįı",
+		"j": "ĵ", "k": "ķ", "l": "ĺļľŀł", "n": "ñńņňŉ", "o": "òóôõöøōŏő", "r": "ŕŗř", "s": "śŝşš", "t": "ţťŧ",
+		"u": "ùúûüũūŭůűų", "w": "ŵ", "y": "ýÿŷ", "z": "źżž", "ss": "ß", "ae": "æ", "oe": "œ",
+	}
+
+	folds := make(map[rune]string)
+	for base, letters := range groups {
+		for _, letter := range letters {
+			folds[letter] = base
+		}
+	}
+	return folds
+}()
+
+// NormalizeName lowercases the name and removes its accents, apostrophes and punctuation, so names typed
+// differently compare equal. Words are separated by a single space.
+func NormalizeName(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == '\'' || r == '’':
+			// O'Brien is typed as OBrien as often as O Brien
+		case folds[r] != "":
+			builder.WriteString(folds[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// MatchName scores how well name matches the searched one, from 0 to 1. Words are compared in any order and
+// allowing typos, every searched word counts and so do, to a lesser extent, the words of name that weren't searched.
+// Searching a surname alone scores its patients above MinMatchScore.
+func MatchName(search, name string) float64 {
+	searched := strings.Fields(NormalizeName(search))
+	words := strings.Fields(NormalizeName(name))
+	if len(searched) == 0 || len(words) == 0 {
+		return 0
+	}
+
+	var matched float64
+	for _, s := range searched {
+		var best float64
+		for _, w := range words {
+			best = max(best, wordSimilarity(s, w))
+		}
+		matched += best
+	}
+
+	return 0.8*matched/float64(len(searched)) + 0.2*min(matched/float64(len(words)), 1)
+}
+
+// RankByName returns, from the best match, at most limit candidates whose name scores MinMatchScore or more.
+// Candidates with the same score are sorted by name and ID.
+func RankByName(search string, found []*Patient, limit int) []Candidate {
+	candidates := make([]Candidate, 0)
+	for _, patient := range found {
+		if score := MatchName(search, patient.Name); score >= MinMatchScore {
+			candidates = append(candidates, Candidate{Patient: patient, Score: score})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Patient.Name != b.Patient.Name {
+			return a.Patient.Name < b.Patient.Name
+		}
+		return a.Patient.ID.String() < b.Patient.ID.String()
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	return candidates
+}
+
+// wordSimilarity is 1 minus the Levenshtein distance relative to the longest word. Words sharing less than half
+// of their letters are different words and score 0.
+func wordSimilarity(a, b string) float64 {
+	x, y := []rune(a), []rune(b)
+	similarity := 1 - float64(levenshtein(x, y))/float64(max(len(x), len(y)))
+	if similarity < 0.5 {
+		return 0
+	}
+	return similarity
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,71 @@
+package patients
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidName      = errors.New("name cannot be empty")
+	ErrInvalidLegalID   = errors.New("legal ID cannot be empty")
+	ErrInvalidBirthDate = errors.New("birth date cannot be in the future")
+	ErrInvalidEmail     = errors.New("invalid email address")
+	ErrInvalidPhone     = errors.New("invalid phone number, expected 6 to 15 digits with an optional leading +")
+)
+
+var phoneFormat = regexp.MustCompile(`^\+?[0-9][0-9 ()-]*$`)
+
+// Validate checks the identity and contact data of the patient, returning every failure found.
+func (p Patient) Validate() error {
+	var errs []error
+	if strings.TrimSpace(p.Name) == "" {
+		errs = append(errs, ErrInvalidName)
+	}
+
+	if strings.TrimSpace(p.LegalID) == "" {
+		errs = append(errs, ErrInvalidLegalID)
+	}
+
+	if p.BirthDate != nil && p.BirthDate.After(time.Now()) {
+		errs = append(errs, ErrInvalidBirthDate)
+	}
+
+	return errors.Join(append(errs, ValidateContact(p.Phone, p.Email))...)
+}
+
+// ValidateContact checks the phone and email of a patient. Both are optional, an empty one has no contact.
+func ValidateContact(phone, email string) error {
+	var errs []error
+	if phone != "" && !validPhone(phone) {
+		errs = append(errs, ErrInvalidPhone)
+	}
+
+	if email != "" && !validEmail(email) {
+		errs = append(errs, ErrInvalidEmail)
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPhone(phone string) bool {
+	if !phoneFormat.MatchString(phone) {
+		return false
+	}
+
+	digits := 0
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+
+	return digits >= 6 && digits <= 15
+}
+
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
@@ -0,0 +1,19 @@
+package unitofwork
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUnitOfWork calls fn with the Repositories set as first return value, unless an error is set as second one.
+type MockUnitOfWork struct {
+	mock.Mock
+}
+
+func (m *MockUnitOfWork) Do(ctx context.Context, fn func(repos Repositories) error) error {
+	args := m.Called(ctx, fn)
+	if err := args.Error(1); err != nil {
+		return err
+	}
+	return fn(args.Get(0).(Repositories))
+}
@@ -0,0 +1,21 @@
+package unitofwork
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+)
+
+// Repositories are the repositories bound to a unit of work. They must not be used after it finishes.
+type Repositories struct {
+	Patients  patients.Repository
+	Diagnoses diagnoses.Repository
+}
+
+// UnitOfWork runs operations that span several repositories as a single atomic change.
+type UnitOfWork interface {
+	// Do calls fn with repositories bound to a new unit of work. Every write is committed when fn returns nil,
+	// and rolled back when it returns an error or panics. The error returned by fn is returned unchanged. When ctx
+	// is done before the unit of work is committed, it is rolled back and the error of ctx is returned.
+	Do(ctx context.Context, fn func(repos Repositories) error) error
+}
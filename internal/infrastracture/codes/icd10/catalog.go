@@ -0,0 +1,118 @@
+// Package icd10 holds the ICD-10 code table bundled with the service.
+package icd10
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"sort"
+	"strings"
+)
+
+//go:embed icd10.tsv
+var table []byte
+
+// Catalog is an in-memory codes.Catalog of the bundled ICD-10 table, sorted by code.
+type Catalog struct {
+	concepts []codes.Concept
+	byCode   map[string]codes.Concept
+}
+
+// NewCatalog parses the bundled table. It only fails if the table is malformed.
+func NewCatalog() (*Catalog, error) {
+	return parse(table)
+}
+
+func parse(data []byte) (*Catalog, error) {
+	catalog := &Catalog{byCode: make(map[string]codes.Concept)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		code, display, found := strings.Cut(text, "\t")
+		if !found {
+			return nil, fmt.Errorf("icd10 table line %d: expected code and display separated by a tab", line)
+		}
+
+		normalized, err := diagnoses.NormalizeICD10(code)
+		if err != nil || normalized != code {
+			return nil, fmt.Errorf("icd10 table line %d: invalid code %q", line, code)
+		}
+
+		if _, duplicated := catalog.byCode[code]; duplicated {
+			return nil, fmt.Errorf("icd10 table line %d: duplicated code %q", line, code)
+		}
+
+		concept := codes.Concept{Code: code, Display: strings.TrimSpace(display)}
+		catalog.concepts = append(catalog.concepts, concept)
+		catalog.byCode[code] = concept
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(catalog.concepts, func(i, j int) bool {
+		return catalog.concepts[i].Code < catalog.concepts[j].Code
+	})
+
+	return catalog, nil
+}
+
+// Lookup accepts the code in any of the forms NormalizeICD10 does, such as j450 for J45.0.
+func (c *Catalog) Lookup(code string) (codes.Concept, bool) {
+	normalized, err := diagnoses.NormalizeICD10(code)
+	if err != nil {
+		return codes.Concept{}, false
+	}
+
+	concept, ok := c.byCode[normalized]
+	return concept, ok
+}
+
+func (c *Catalog) Search(text string, limit int) []codes.Concept {
+	found := make([]codes.Concept, 0)
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 0 || limit <= 0 {
+		return found
+	}
+
+	matched := make(map[string]bool)
+	if prefix, err := diagnoses.NormalizeICD10Prefix(text); err == nil {
+		start := sort.Search(len(c.concepts), func(i int) bool { return c.concepts[i].Code >= prefix })
+		for i := start; i < len(c.concepts) && strings.HasPrefix(c.concepts[i].Code, prefix); i++ {
+			if len(found) == limit {
+				return found
+			}
+			found = append(found, c.concepts[i])
+			matched[c.concepts[i].Code] = true
+		}
+	}
+
+	for _, concept := range c.concepts {
+		if len(found) == limit {
+			break
+		}
+
+		if !matched[concept.Code] && containsWords(strings.ToLower(concept.Display), words) {
+			found = append(found, concept)
+		}
+	}
+
+	return found
+}
+
+func containsWords(display string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(display, word) {
+			return false
+		}
+	}
+	return true
+}
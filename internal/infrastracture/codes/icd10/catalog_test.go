@@ -0,0 +1,107 @@
+package icd10
+
+import (
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"reflect"
+	"testing"
+)
+
+func TestNewCatalog(t *testing.T) {
+	catalog, err := NewCatalog()
+	if err != nil {
+		t.Fatalf("NewCatalog() error=%v, but no error expected", err)
+	}
+
+	if len(catalog.concepts) == 0 {
+		t.Errorf("NewCatalog() loaded no codes from the bundled table")
+	}
+}
+
+func TestParse_RejectsMalformedTables(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "missing display", data: "J45.0\n"},
+		{name: "invalid code", data: "J4X5\tAsthma\n"},
+		{name: "not normalized code", data: "j45.0\tAsthma\n"},
+		{name: "duplicated code", data: "J45\tAsthma\nJ45\tAsthma\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse([]byte(tt.data)); err == nil {
+				t.Errorf("parse() error=nil, expected the table to be rejected")
+			}
+		})
+	}
+}
+
+func TestCatalog_Lookup(t *testing.T) {
+	catalog := mustParse(t)
+
+	tests := []struct {
+		name   string
+		code   string
+		want   codes.Concept
+		wantOk bool
+	}{
+		{name: "exact code", code: "J45.0", want: codes.Concept{Code: "J45.0", Display: "Predominantly allergic asthma"}, wantOk: true},
+		{name: "lower case code without dot", code: "j450", want: codes.Concept{Code: "J45.0", Display: "Predominantly allergic asthma"}, wantOk: true},
+		{name: "unknown code", code: "J45.3", wantOk: false},
+		{name: "invalid code", code: "asthma", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := catalog.Lookup(tt.code)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("Lookup() got=(%v, %v), want=(%v, %v)", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestCatalog_Search(t *testing.T) {
+	catalog := mustParse(t)
+
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{name: "code prefix", text: "J45", limit: 10, want: []string{"J45", "J45.0", "J45.9"}},
+		{name: "code prefix without dot", text: "j450", limit: 10, want: []string{"J45.0"}},
+		{name: "keyword in any case", text: "ASTHMA", limit: 10, want: []string{"J45", "J45.0", "J45.9", "J46"}},
+		{name: "every keyword must match", text: "allergic asthma", limit: 10, want: []string{"J45.0"}},
+		{name: "code matches first", text: "e", limit: 10, want: []string{"E11.9", "I10", "J45.0", "J45.9"}},
+		{name: "limit results", text: "asthma", limit: 2, want: []string{"J45", "J45.0"}},
+		{name: "no match", text: "fracture", limit: 10, want: []string{}},
+		{name: "empty text", text: " ", limit: 10, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, concept := range catalog.Search(tt.text, tt.limit) {
+				got = append(got, concept.Code)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() got=%v, want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustParse(t *testing.T) *Catalog {
+	t.Helper()
+	catalog, err := parse([]byte("# test table\n" +
+		"J46\tStatus asthmaticus\n" +
+		"J45\tAsthma\n" +
+		"J45.0\tPredominantly allergic asthma\n" +
+		"J45.9\tAsthma, unspecified\n" +
+		"E11.9\tType 2 diabetes mellitus without complications\n" +
+		"I10\tEssential (primary) hypertension\n"))
+	if err != nil {
+		t.Fatalf("parse() error=%v, but no error expected", err)
+	}
+	return catalog
+}
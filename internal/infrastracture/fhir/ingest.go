@@ -0,0 +1,129 @@
+package fhir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"strings"
+)
+
+var (
+	ErrInvalidResource     = errors.New("invalid resource")
+	ErrInvalidResourceType = errors.New("unexpected resource type")
+	ErrInvalidBundleType   = errors.New("only batch and transaction bundles are supported")
+	ErrTooManyEntries      = fmt.Errorf("bundles can have at most %d entries", MaxBundleEntries)
+	ErrMissingCode         = errors.New("the condition code must have a text or an ICD-10 coding")
+	ErrInvalidSubject      = errors.New("the condition subject must reference a Patient by ID or by legal ID identifier")
+)
+
+// MaxBundleEntries is the maximum number of entries of the bundles sent to the service.
+const MaxBundleEntries = 100
+
+const (
+	BundleTypeBatch       = "batch"
+	BundleTypeTransaction = "transaction"
+)
+
+// IncomingBundle is a batch or transaction bundle sent to the service. Its resources are decoded by the entry
+// request they are sent with.
+type IncomingBundle struct {
+	ResourceType string          `json:"resourceType"`
+	Type         string          `json:"type"`
+	Entry        []IncomingEntry `json:"entry"`
+}
+
+type IncomingEntry struct {
+	FullURL  string          `json:"fullUrl,omitempty"`
+	Resource json.RawMessage `json:"resource"`
+	Request  *EntryRequest   `json:"request"`
+}
+
+// DiagnosisInput is the diagnosis sent as a Condition. The patient is identified by PatientID, or by LegalID
+// when the subject is a legal ID identifier.
+type DiagnosisInput struct {
+	PatientID   uuid.UUID
+	LegalID     string
+	Description string
+	// Coding is the ICD-10 coding of the condition, nil when it only has a text
+	Coding *diagnoses.Coding
+}
+
+// ParseBundle decodes a batch or transaction bundle, whose entries are not decoded yet.
+func ParseBundle(data []byte) (IncomingBundle, error) {
+	var bundle IncomingBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return IncomingBundle{}, fmt.Errorf("%w: %w", ErrInvalidResource, err)
+	}
+
+	if bundle.ResourceType != "Bundle" {
+		return IncomingBundle{}, fmt.Errorf("%w %q, expected Bundle", ErrInvalidResourceType, bundle.ResourceType)
+	}
+
+	if bundle.Type != BundleTypeBatch && bundle.Type != BundleTypeTransaction {
+		return IncomingBundle{}, ErrInvalidBundleType
+	}
+
+	if len(bundle.Entry) > MaxBundleEntries {
+		return IncomingBundle{}, ErrTooManyEntries
+	}
+
+	return bundle, nil
+}
+
+// ParseCondition decodes a Condition into the diagnosis it records, returning every failure found. The description
+// is the code text, or the display of the ICD-10 coding when there is no text. Codings of other systems are ignored.
+func ParseCondition(data []byte) (DiagnosisInput, error) {
+	var condition Condition
+	if err := json.Unmarshal(data, &condition); err != nil {
+		return DiagnosisInput{}, fmt.Errorf("%w: %w", ErrInvalidResource, err)
+	}
+
+	if condition.ResourceType != "Condition" {
+		return DiagnosisInput{}, fmt.Errorf("%w %q, expected Condition", ErrInvalidResourceType, condition.ResourceType)
+	}
+
+	var input DiagnosisInput
+	var errs []error
+	if condition.Code != nil {
+		input.Description = strings.TrimSpace(condition.Code.Text)
+		for _, coding := range condition.Code.Coding {
+			if coding.System == diagnoses.SystemICD10 {
+				input.Coding = &diagnoses.Coding{System: coding.System, Code: coding.Code}
+				if input.Description == "" {
+					input.Description = strings.TrimSpace(coding.Display)
+				}
+				break
+			}
+		}
+	}
+
+	if input.Description == "" && input.Coding != nil {
+		input.Description = input.Coding.Code
+	}
+
+	if input.Description == "" {
+		errs = append(errs, ErrMissingCode)
+	}
+
+	subject := condition.Subject
+	switch {
+	case subject.Reference != "":
+		patientID, err := ParseReference("Patient", subject.Reference)
+		if err != nil || !strings.HasPrefix(subject.Reference, "Patient/") {
+			errs = append(errs, ErrInvalidSubject)
+		}
+		input.PatientID = patientID
+	case subject.Identifier != nil && subject.Identifier.System == LegalIDSystem && subject.Identifier.Value != "":
+		input.LegalID = subject.Identifier.Value
+	default:
+		errs = append(errs, ErrInvalidSubject)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return DiagnosisInput{}, err
+	}
+
+	return input, nil
+}
@@ -0,0 +1,124 @@
+package fhir
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	tests := []struct {
+		name     string
+		resource string
+		want     DiagnosisInput
+		wantErrs []error
+	}{
+		{
+			name: "parse a coded condition of a patient",
+			resource: `{"resourceType": "Condition", "subject": {"reference": "Patient/11111111-1111-1111-1111-111111111111"},
+				"code": {"text": "Asthma attack", "coding": [
+					{"system": "http://snomed.info/sct", "code": "266364000"},
+					{"system": "http://hl7.org/fhir/sid/icd-10", "code": "J45.0", "display": "Predominantly allergic asthma"}]}}`,
+			want: DiagnosisInput{
+				PatientID:   patientID,
+				Description: "Asthma attack",
+				Coding:      &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0"},
+			},
+		},
+		{
+			name: "describe the condition with the coding display when there is no text",
+			resource: `{"resourceType": "Condition", "subject": {"identifier": {"system": "urn:diagnosis-service:legal-id", "value": "123"}},
+				"code": {"coding": [{"system": "http://hl7.org/fhir/sid/icd-10", "code": "J45.0", "display": "Predominantly allergic asthma"}]}}`,
+			want: DiagnosisInput{
+				LegalID:     "123",
+				Description: "Predominantly allergic asthma",
+				Coding:      &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0"},
+			},
+		},
+		{
+			name:     "parse an uncoded condition",
+			resource: `{"resourceType": "Condition", "subject": {"reference": "Patient/11111111-1111-1111-1111-111111111111"}, "code": {"text": "migraine"}}`,
+			want:     DiagnosisInput{PatientID: patientID, Description: "migraine"},
+		},
+		{
+			name:     "return every failure of the condition",
+			resource: `{"resourceType": "Condition", "subject": {"reference": "Practitioner/abc"}, "code": {"coding": [{"system": "http://snomed.info/sct", "code": "266364000"}]}}`,
+			wantErrs: []error{ErrMissingCode, ErrInvalidSubject},
+		},
+		{
+			name:     "return error when the subject is an unknown identifier",
+			resource: `{"resourceType": "Condition", "subject": {"identifier": {"system": "urn:other", "value": "123"}}, "code": {"text": "migraine"}}`,
+			wantErrs: []error{ErrInvalidSubject},
+		},
+		{
+			name:     "return error when the resource is not a condition",
+			resource: `{"resourceType": "Observation"}`,
+			wantErrs: []error{ErrInvalidResourceType},
+		},
+		{
+			name:     "return error when the resource is malformed",
+			resource: `{"resourceType": "Condition", "code": "migraine"}`,
+			wantErrs: []error{ErrInvalidResource},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCondition([]byte(tt.resource))
+			for _, wantErr := range tt.wantErrs {
+				assert.ErrorIs(t, err, wantErr)
+			}
+			if tt.wantErrs == nil {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	tests := []struct {
+		name        string
+		resource    string
+		wantEntries int
+		wantErr     error
+	}{
+		{
+			name: "parse a transaction bundle",
+			resource: `{"resourceType": "Bundle", "type": "transaction", "entry": [
+				{"resource": {"resourceType": "Condition"}, "request": {"method": "POST", "url": "Condition"}}]}`,
+			wantEntries: 1,
+		},
+		{
+			name:     "parse an empty batch bundle",
+			resource: `{"resourceType": "Bundle", "type": "batch"}`,
+		},
+		{
+			name:     "return error when the bundle is a searchset",
+			resource: `{"resourceType": "Bundle", "type": "searchset"}`,
+			wantErr:  ErrInvalidBundleType,
+		},
+		{
+			name:     "return error when the resource is not a bundle",
+			resource: `{"resourceType": "Condition"}`,
+			wantErr:  ErrInvalidResourceType,
+		},
+		{
+			name:     "return error when the bundle is malformed",
+			resource: `{"resourceType": "Bundle", "entry": {}}`,
+			wantErr:  ErrInvalidResource,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBundle([]byte(tt.resource))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseBundle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Len(t, got.Entry, tt.wantEntries)
+		})
+	}
+}
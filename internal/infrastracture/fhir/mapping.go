@@ -0,0 +1,191 @@
+package fhir
+
+import (
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"strconv"
+	"time"
+)
+
+var ucumTimeUnits = map[diagnoses.TimeUnit]string{
+	diagnoses.UnitHour:  "h",
+	diagnoses.UnitDay:   "d",
+	diagnoses.UnitWeek:  "wk",
+	diagnoses.UnitMonth: "mo",
+}
+
+func NewPatient(patient patients.Patient) Patient {
+	resource := Patient{
+		ResourceType: "Patient",
+		ID:           patient.ID.String(),
+		Name:         []HumanName{{Text: patient.Name}},
+	}
+
+	if patient.LegalID != "" {
+		resource.Identifier = []Identifier{{System: LegalIDSystem, Value: patient.LegalID}}
+	}
+
+	if patient.Phone != "" {
+		resource.Telecom = append(resource.Telecom, ContactPoint{System: "phone", Value: patient.Phone})
+	}
+
+	if patient.Email != "" {
+		resource.Telecom = append(resource.Telecom, ContactPoint{System: "email", Value: patient.Email})
+	}
+
+	if patient.BirthDate != nil {
+		resource.BirthDate = patient.BirthDate.Format("2006-01-02")
+	}
+
+	if patient.Address != "" {
+		resource.Address = []Address{{Text: patient.Address}}
+	}
+
+	return resource
+}
+
+// NewCondition maps a diagnosis to an encounter diagnosis, whose code holds the ICD-10 coding, when there is one,
+// and the description written by the practitioner as text.
+func NewCondition(diagnosis diagnoses.Diagnosis) Condition {
+	code := &CodeableConcept{Text: diagnosis.Description}
+	if diagnosis.Coding != nil {
+		code.Coding = []Coding{{
+			System:  diagnosis.Coding.System,
+			Code:    diagnosis.Coding.Code,
+			Display: diagnosis.Coding.Display,
+		}}
+	}
+
+	return Condition{
+		ResourceType: "Condition",
+		ID:           diagnosis.ID.String(),
+		Category: []CodeableConcept{{Coding: []Coding{{
+			System:  ConditionCategorySystem,
+			Code:    "encounter-diagnosis",
+			Display: "Encounter Diagnosis",
+		}}}},
+		Code:         code,
+		Subject:      Reference{Reference: "Patient/" + diagnosis.PatientID.String()},
+		RecordedDate: formatDateTime(diagnosis.CreatedAt),
+		Recorder:     practitioner(diagnosis.PractitionerID),
+	}
+}
+
+// NewMedicationRequests maps every medication of the diagnosis prescription to a MedicationRequest, identified by
+// the diagnosis ID and the medication line. Prescriptions recorded as free text become a single request whose
+// medication is the text. It returns no requests when the diagnosis has no prescription.
+func NewMedicationRequests(diagnosis diagnoses.Diagnosis) []MedicationRequest {
+	prescription := diagnosis.Prescription
+	if prescription == nil {
+		return []MedicationRequest{}
+	}
+
+	if len(prescription.Medications) == 0 {
+		request := newMedicationRequest(diagnosis, 1)
+		request.MedicationCodeableConcept = CodeableConcept{Text: prescription.Notes}
+		return []MedicationRequest{request}
+	}
+
+	requests := make([]MedicationRequest, 0, len(prescription.Medications))
+	for i, medication := range prescription.Medications {
+		request := newMedicationRequest(diagnosis, i+1)
+		request.MedicationCodeableConcept = CodeableConcept{Text: medication.DrugName}
+		if medication.DrugCode != "" {
+			request.MedicationCodeableConcept.Coding = []Coding{{Code: medication.DrugCode}}
+		}
+
+		if prescription.Notes != "" {
+			request.Note = []Annotation{{Text: prescription.Notes}}
+		}
+
+		duration := &Quantity{
+			Value:  float64(medication.Duration.Value),
+			Unit:   string(medication.Duration.Unit),
+			System: UCUMSystem,
+			Code:   ucumTimeUnits[medication.Duration.Unit],
+		}
+		request.DosageInstruction = []Dosage{{
+			Timing: &Timing{Repeat: TimingRepeat{
+				BoundsDuration: duration,
+				Frequency:      medication.Frequency.Times,
+				Period:         medication.Frequency.Period,
+				PeriodUnit:     ucumTimeUnits[medication.Frequency.Unit],
+			}},
+			Route:       &CodeableConcept{Text: string(medication.Route)},
+			DoseAndRate: []DoseAndRate{{DoseQuantity: Quantity{Value: medication.Dose, Unit: medication.DoseUnit}}},
+		}}
+		request.DispenseRequest = &DispenseRequest{
+			NumberOfRepeatsAllowed: medication.Refills,
+			Quantity:               &Quantity{Value: float64(medication.Quantity)},
+			ExpectedSupplyDuration: duration,
+		}
+		requests = append(requests, request)
+	}
+
+	return requests
+}
+
+func newMedicationRequest(diagnosis diagnoses.Diagnosis, line int) MedicationRequest {
+	return MedicationRequest{
+		ResourceType:    "MedicationRequest",
+		ID:              diagnosis.ID.String() + "-" + strconv.Itoa(line),
+		Status:          "active",
+		Intent:          "order",
+		Subject:         Reference{Reference: "Patient/" + diagnosis.PatientID.String()},
+		AuthoredOn:      formatDateTime(diagnosis.CreatedAt),
+		Requester:       practitioner(diagnosis.PractitionerID),
+		ReasonReference: []Reference{{Reference: "Condition/" + diagnosis.ID.String()}},
+	}
+}
+
+// NewSearchBundle returns a searchset bundle of the resources matching a search, whose full URLs are relative to
+// baseURL. total counts the matches across all pages, nil when it isn't known, and next is the URL of the next
+// page, empty on the last one.
+func NewSearchBundle[R Resource](baseURL string, resources []R, total *int, self, next string) Bundle {
+	bundle := Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Total:        total,
+		Link:         []BundleLink{{Relation: "self", URL: self}},
+	}
+
+	if next != "" {
+		bundle.Link = append(bundle.Link, BundleLink{Relation: "next", URL: next})
+	}
+
+	for _, resource := range resources {
+		bundle.Entry = append(bundle.Entry, BundleEntry{
+			FullURL:  baseURL + "/" + resource.Reference(),
+			Resource: resource,
+			Search:   &EntrySearch{Mode: "match"},
+		})
+	}
+
+	return bundle
+}
+
+func NewOperationOutcome(code, diagnostics string) OperationOutcome {
+	return OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []OperationOutcomeIssue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
+
+// NewInformationOutcome returns an outcome reporting that the operation succeeded.
+func NewInformationOutcome(diagnostics string) OperationOutcome {
+	return OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []OperationOutcomeIssue{{Severity: "information", Code: "informational", Diagnostics: diagnostics}},
+	}
+}
+
+func practitioner(practitionerID string) *Reference {
+	if practitionerID == "" {
+		return nil
+	}
+	return &Reference{Identifier: &Identifier{System: PractitionerIDSystem, Value: practitionerID}}
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,209 @@
+package fhir
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// update rewrites the golden files with the current output: go test ./internal/infrastracture/fhir -update
+var update = flag.Bool("update", false, "update the golden files")
+
+var (
+	patientID   = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	diagnosisID = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	recordedAt  = time.Date(2024, 3, 1, 13, 30, 0, 0, time.UTC)
+	birthDate   = time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)
+)
+
+func TestNewPatient(t *testing.T) {
+	tests := []struct {
+		name    string
+		patient patients.Patient
+		golden  string
+	}{
+		{
+			name: "with identity and contact data",
+			patient: patients.Patient{
+				ID:        patientID,
+				LegalID:   "ABC1234",
+				Name:      "John Doe",
+				BirthDate: &birthDate,
+				Address:   "Wall Street 123",
+				Phone:     "+54 11 5555-1234",
+				Email:     "john.doe@example.com",
+			},
+			golden: "patient.json",
+		},
+		{
+			name:    "without contact data",
+			patient: patients.Patient{ID: patientID, LegalID: "ABC1234", Name: "John Doe"},
+			golden:  "patient_without_contact.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.golden, NewPatient(tt.patient))
+		})
+	}
+}
+
+func TestNewCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		diagnosis diagnoses.Diagnosis
+		golden    string
+	}{
+		{
+			name:      "coded in ICD-10",
+			diagnosis: codedDiagnosis(),
+			golden:    "condition.json",
+		},
+		{
+			name:      "described in free text",
+			diagnosis: legacyDiagnosis(),
+			golden:    "condition_uncoded.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.golden, NewCondition(tt.diagnosis))
+		})
+	}
+}
+
+func TestNewMedicationRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		diagnosis diagnoses.Diagnosis
+		golden    string
+	}{
+		{
+			name:      "one request per medication line",
+			diagnosis: codedDiagnosis(),
+			golden:    "medication_requests.json",
+		},
+		{
+			name:      "free-text prescription",
+			diagnosis: legacyDiagnosis(),
+			golden:    "medication_requests_legacy.json",
+		},
+		{
+			name:      "without prescription",
+			diagnosis: diagnoses.Diagnosis{ID: diagnosisID, PatientID: patientID, CreatedAt: recordedAt},
+			golden:    "medication_requests_empty.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.golden, NewMedicationRequests(tt.diagnosis))
+		})
+	}
+}
+
+func TestNewSearchBundle(t *testing.T) {
+	base := "http://localhost:8080/api/v1/fhir"
+	conditions := []Condition{NewCondition(codedDiagnosis()), NewCondition(legacyDiagnosis())}
+	three, zero := 3, 0
+	tests := []struct {
+		name   string
+		bundle Bundle
+		golden string
+	}{
+		{
+			name: "first page with a next link",
+			bundle: NewSearchBundle(base, conditions, &three, base+"/Condition?patient=Patient/"+patientID.String()+"&_count=2",
+				base+"/Condition?patient=Patient/"+patientID.String()+"&_count=2&_cursor=next-page"),
+			golden: "bundle.json",
+		},
+		{
+			name:   "without matches",
+			bundle: NewSearchBundle(base, []Condition{}, &zero, base+"/Condition?recorded-date=2020", ""),
+			golden: "bundle_empty.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.golden, tt.bundle)
+		})
+	}
+}
+
+func codedDiagnosis() diagnoses.Diagnosis {
+	return diagnoses.Diagnosis{
+		ID:             diagnosisID,
+		Description:    "Acute otitis media with fever",
+		PatientID:      patientID,
+		CreatedAt:      recordedAt,
+		PractitionerID: "practitioner-1",
+		Coding:         &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "H66.9", Display: "Otitis media, unspecified"},
+		Prescription: &diagnoses.Prescription{
+			Medications: []diagnoses.Medication{
+				{
+					DrugName:  "Amoxicillin",
+					DrugCode:  "J01CA04",
+					Dose:      500,
+					DoseUnit:  "mg",
+					Route:     diagnoses.RouteOral,
+					Frequency: diagnoses.Frequency{Times: 1, Period: 8, Unit: diagnoses.UnitHour},
+					Duration:  diagnoses.Duration{Value: 7, Unit: diagnoses.UnitDay},
+					Quantity:  21,
+					Refills:   0,
+				},
+				{
+					DrugName:  "Paracetamol",
+					Dose:      1,
+					DoseUnit:  "g",
+					Route:     diagnoses.RouteOral,
+					Frequency: diagnoses.Frequency{Times: 3, Period: 1, Unit: diagnoses.UnitDay},
+					Duration:  diagnoses.Duration{Value: 1, Unit: diagnoses.UnitWeek},
+					Quantity:  20,
+					Refills:   1,
+				},
+			},
+			Notes: "take with food",
+		},
+	}
+}
+
+func legacyDiagnosis() diagnoses.Diagnosis {
+	return diagnoses.Diagnosis{
+		ID:           uuid.MustParse("33333333-3333-3333-3333-333333333333"),
+		Description:  "migraine",
+		PatientID:    patientID,
+		CreatedAt:    recordedAt.Add(-24 * time.Hour),
+		Prescription: &diagnoses.Prescription{Notes: "ibuprofen 400mg"},
+	}
+}
+
+// assertGolden compares the resource encoded as indented JSON with the golden file in testdata.
+func assertGolden(t *testing.T, golden string, resource any) {
+	t.Helper()
+	got, err := json.MarshalIndent(resource, "", "  ")
+	if err != nil {
+		t.Fatalf("encoding the resource error=%v, but no error expected", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", golden)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("writing golden file error=%v", err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file error=%v, run the tests with -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("resource doesn't match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,198 @@
+// Package fhir maps patients and diagnoses to FHIR R4 resources (https://hl7.org/fhir/R4), so they can be
+// exchanged with EHR systems. Only the elements this service has data for are mapped.
+package fhir
+
+// ContentType is the media type of FHIR resources in JSON.
+const ContentType = "application/fhir+json"
+
+const (
+	// LegalIDSystem is the identifier system of the patient legal IDs
+	LegalIDSystem = "urn:diagnosis-service:legal-id"
+	// PractitionerIDSystem is the identifier system of practitioners, the subject of their access tokens
+	PractitionerIDSystem = "urn:diagnosis-service:practitioner-id"
+	// UCUMSystem is the system of units of measure used for durations
+	UCUMSystem = "http://unitsofmeasure.org"
+	// ConditionCategorySystem is the code system of Condition categories
+	ConditionCategorySystem = "http://terminology.hl7.org/CodeSystem/condition-category"
+)
+
+// Resource is any FHIR resource, which is referenced as ResourceType/ID.
+type Resource interface {
+	Reference() string
+}
+
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+	BirthDate    string         `json:"birthDate,omitempty"`
+	Address      []Address      `json:"address,omitempty"`
+}
+
+func (p Patient) Reference() string {
+	return "Patient/" + p.ID
+}
+
+type Condition struct {
+	ResourceType string            `json:"resourceType"`
+	ID           string            `json:"id"`
+	Category     []CodeableConcept `json:"category,omitempty"`
+	Code         *CodeableConcept  `json:"code,omitempty"`
+	Subject      Reference         `json:"subject"`
+	RecordedDate string            `json:"recordedDate,omitempty"`
+	Recorder     *Reference        `json:"recorder,omitempty"`
+}
+
+func (c Condition) Reference() string {
+	return "Condition/" + c.ID
+}
+
+type MedicationRequest struct {
+	ResourceType              string           `json:"resourceType"`
+	ID                        string           `json:"id"`
+	Status                    string           `json:"status"`
+	Intent                    string           `json:"intent"`
+	MedicationCodeableConcept CodeableConcept  `json:"medicationCodeableConcept"`
+	Subject                   Reference        `json:"subject"`
+	AuthoredOn                string           `json:"authoredOn,omitempty"`
+	Requester                 *Reference       `json:"requester,omitempty"`
+	ReasonReference           []Reference      `json:"reasonReference,omitempty"`
+	Note                      []Annotation     `json:"note,omitempty"`
+	DosageInstruction         []Dosage         `json:"dosageInstruction,omitempty"`
+	DispenseRequest           *DispenseRequest `json:"dispenseRequest,omitempty"`
+}
+
+func (m MedicationRequest) Reference() string {
+	return "MedicationRequest/" + m.ID
+}
+
+type Bundle struct {
+	ResourceType string       `json:"resourceType"`
+	Type         string       `json:"type"`
+	Total        *int         `json:"total,omitempty"`
+	Link         []BundleLink `json:"link,omitempty"`
+	// Entry is omitted when there are no entries, FHIR doesn't allow empty arrays
+	Entry []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleEntry struct {
+	FullURL  string       `json:"fullUrl,omitempty"`
+	Resource any          `json:"resource,omitempty"`
+	Search   *EntrySearch `json:"search,omitempty"`
+	// Response is the result of the entry in batch and transaction responses
+	Response *EntryResponse `json:"response,omitempty"`
+}
+
+type EntrySearch struct {
+	Mode string `json:"mode"`
+}
+
+type EntryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type EntryResponse struct {
+	// Status is the HTTP status code and reason, such as 201 Created
+	Status   string            `json:"status"`
+	Location string            `json:"location,omitempty"`
+	Outcome  *OperationOutcome `json:"outcome,omitempty"`
+}
+
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+type OperationOutcomeIssue struct {
+	// Severity is fatal, error, warning or information
+	Severity string `json:"severity"`
+	// Code is the issue type, such as invalid, not-found or forbidden
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+	// Expression locates the issue in the resource, such as Bundle.entry[2]
+	Expression []string `json:"expression,omitempty"`
+}
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value"`
+}
+
+type HumanName struct {
+	Text string `json:"text"`
+}
+
+type ContactPoint struct {
+	// System is phone or email
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+type Address struct {
+	Text string `json:"text"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+// Reference points to a resource by its Reference, or by a business Identifier when the resource is not
+// served by this service, such as practitioners.
+type Reference struct {
+	Reference  string      `json:"reference,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+type Dosage struct {
+	Timing      *Timing          `json:"timing,omitempty"`
+	Route       *CodeableConcept `json:"route,omitempty"`
+	DoseAndRate []DoseAndRate    `json:"doseAndRate,omitempty"`
+}
+
+type Timing struct {
+	Repeat TimingRepeat `json:"repeat"`
+}
+
+type TimingRepeat struct {
+	BoundsDuration *Quantity `json:"boundsDuration,omitempty"`
+	Frequency      int       `json:"frequency"`
+	Period         int       `json:"period"`
+	// PeriodUnit is an UCUM unit of time: h, d, wk or mo
+	PeriodUnit string `json:"periodUnit"`
+}
+
+type DoseAndRate struct {
+	DoseQuantity Quantity `json:"doseQuantity"`
+}
+
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+type DispenseRequest struct {
+	NumberOfRepeatsAllowed int       `json:"numberOfRepeatsAllowed"`
+	Quantity               *Quantity `json:"quantity,omitempty"`
+	ExpectedSupplyDuration *Quantity `json:"expectedSupplyDuration,omitempty"`
+}
@@ -0,0 +1,106 @@
+package fhir
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDate      = errors.New("invalid date, expected an optional eq, ge, gt, le or lt prefix and a YYYY, YYYY-MM, YYYY-MM-DD or RFC 3339 date")
+	ErrInvalidReference = errors.New("invalid reference")
+)
+
+var dateLayouts = []string{"2006", "2006-01", "2006-01-02", time.RFC3339Nano}
+
+// DateRange is the range of instants a date search parameter matches, both ends inclusive. Nil ends are open.
+type DateRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// ParseDateParams intersects the ranges of every value of a date search parameter, such as
+// recorded-date=ge2024-03-01&recorded-date=lt2024-04. Dates without a timezone are read in UTC, and
+// imprecise dates span their whole period: 2024-03 matches the whole month.
+func ParseDateParams(values []string) (DateRange, error) {
+	var dateRange DateRange
+	for _, value := range values {
+		from, to, err := parseDateParam(value)
+		if err != nil {
+			return DateRange{}, err
+		}
+
+		if from != nil && (dateRange.From == nil || from.After(*dateRange.From)) {
+			dateRange.From = from
+		}
+
+		if to != nil && (dateRange.To == nil || to.Before(*dateRange.To)) {
+			dateRange.To = to
+		}
+	}
+
+	return dateRange, nil
+}
+
+func parseDateParam(value string) (*time.Time, *time.Time, error) {
+	prefix := "eq"
+	if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		prefix, value = value[:2], value[2:]
+	}
+
+	start, end, err := parseDate(value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch prefix {
+	case "eq":
+		return &start, &end, nil
+	case "ge":
+		return &start, nil, nil
+	case "gt":
+		after := end.Add(time.Nanosecond)
+		return &after, nil, nil
+	case "le":
+		return nil, &end, nil
+	case "lt":
+		before := start.Add(-time.Nanosecond)
+		return nil, &before, nil
+	default:
+		return nil, nil, ErrInvalidDate
+	}
+}
+
+func parseDate(value string) (time.Time, time.Time, error) {
+	for i, layout := range dateLayouts {
+		start, err := time.ParseInLocation(layout, value, time.UTC)
+		if err != nil {
+			continue
+		}
+
+		switch i {
+		case 0:
+			return start, start.AddDate(1, 0, 0).Add(-time.Nanosecond), nil
+		case 1:
+			return start, start.AddDate(0, 1, 0).Add(-time.Nanosecond), nil
+		case 2:
+			return start, start.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+		default:
+			return start, start, nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, ErrInvalidDate
+}
+
+// ParseReference returns the ID of a reference to a resource of that type, which can be sent as
+// ResourceType/ID or as the plain ID.
+func ParseReference(resourceType, reference string) (uuid.UUID, error) {
+	id := strings.TrimPrefix(reference, resourceType+"/")
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.UUID{}, ErrInvalidReference
+	}
+	return parsed, nil
+}
@@ -0,0 +1,74 @@
+package fhir
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestParseDateParams(t *testing.T) {
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	endOfMarch := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	instant := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", -3*60*60))
+
+	tests := []struct {
+		name     string
+		values   []string
+		wantFrom *time.Time
+		wantTo   *time.Time
+		wantErr  error
+	}{
+		{name: "without values", values: nil},
+		{name: "month matches the whole month", values: []string{"2024-03"}, wantFrom: &march, wantTo: &endOfMarch},
+		{name: "eq prefix is the default", values: []string{"eq2024-03"}, wantFrom: &march, wantTo: &endOfMarch},
+		{name: "ge and le bound the range", values: []string{"ge2024-03-01", "le2024-03-31"}, wantFrom: &march, wantTo: &endOfMarch},
+		{name: "gt starts after the period", values: []string{"gt2024-02"}, wantFrom: &march},
+		{name: "lt ends before the period", values: []string{"lt2024-04"}, wantTo: &endOfMarch},
+		{name: "ranges are intersected", values: []string{"2024", "ge2024-03", "lt2024-04-01"}, wantFrom: &march, wantTo: &endOfMarch},
+		{name: "timestamp with timezone", values: []string{"ge2024-03-01T10:00:00-03:00"}, wantFrom: &instant},
+		{name: "unsupported prefix", values: []string{"ne2024-03"}, wantErr: ErrInvalidDate},
+		{name: "invalid date", values: []string{"03/01/2024"}, wantErr: ErrInvalidDate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDateParams(tt.values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseDateParams() error=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if !sameTime(got.From, tt.wantFrom) || !sameTime(got.To, tt.wantTo) {
+				t.Errorf("ParseDateParams() got=(%v, %v), want=(%v, %v)", got.From, got.To, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	id := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	tests := []struct {
+		name      string
+		reference string
+		want      uuid.UUID
+		wantErr   error
+	}{
+		{name: "relative reference", reference: "Patient/" + id.String(), want: id},
+		{name: "plain ID", reference: id.String(), want: id},
+		{name: "reference to another resource type", reference: "Practitioner/" + id.String(), wantErr: ErrInvalidReference},
+		{name: "invalid ID", reference: "Patient/john", wantErr: ErrInvalidReference},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReference("Patient", tt.reference)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("ParseReference() got=(%v, %v), want=(%v, %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func sameTime(got, want *time.Time) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.Equal(*want)
+}
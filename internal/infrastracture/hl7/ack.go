@@ -0,0 +1,108 @@
+package hl7
+
+import (
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Acknowledgment codes of MSA-1.
+const (
+	// AckAccept means the message was applied, its ERR segments being warnings of what was left out
+	AckAccept = "AA"
+	// AckError means the message was read but could not be applied
+	AckError = "AE"
+	// AckReject means the message was not read, because it is malformed or not supported, or won't be applied,
+	// because the actor isn't allowed to
+	AckReject = "AR"
+)
+
+// Error codes of ERR-3, from the HL7 table 0357.
+const (
+	ErrCodeSegmentSequence    = 100
+	ErrCodeRequiredField      = 101
+	ErrCodeDataType           = 102
+	ErrCodeTableValue         = 103
+	ErrCodeUnsupportedMessage = 200
+	ErrCodeUnsupportedEvent   = 201
+	ErrCodeUnknownKey         = 204
+	ErrCodeDuplicateKey       = 205
+	ErrCodeInternal           = 207
+	// ErrCodeNotAuthorized is a local code, as the HL7 table 0357 has none for the actor not being allowed
+	ErrCodeNotAuthorized = 900
+)
+
+var errorCodeTexts = map[int]string{
+	ErrCodeSegmentSequence:    "Segment sequence error",
+	ErrCodeRequiredField:      "Required field missing",
+	ErrCodeDataType:           "Data type error",
+	ErrCodeTableValue:         "Table value not found",
+	ErrCodeUnsupportedMessage: "Unsupported message type",
+	ErrCodeUnsupportedEvent:   "Unsupported event code",
+	ErrCodeUnknownKey:         "Unknown key identifier",
+	ErrCodeDuplicateKey:       "Duplicate key identifier",
+	ErrCodeInternal:           "Application internal error",
+	ErrCodeNotAuthorized:      "Not authorized",
+}
+
+// Issue is an error found applying a message, reported in an ERR segment of its acknowledgment.
+type Issue struct {
+	Code int
+	Text string
+	// Warning is set for the issues of a message that was applied anyway
+	Warning bool
+}
+
+// NewAck returns the acknowledgment of the message, addressed back to its sender, with an ERR segment per issue.
+// The message can be empty when it couldn't be parsed.
+func NewAck(message Message, code string, issues []Issue, now time.Time) []byte {
+	seps := message.separators
+	if seps.field == 0 {
+		seps = separators{field: '|', component: '^', repetition: '~', escape: '\\', subcomponent: '&'}
+	}
+
+	msh, _ := message.Segment("MSH")
+	_, event := message.Type()
+	processingID := msh.raw(11)
+	if processingID == "" {
+		processingID = "P"
+	}
+	version := msh.raw(12)
+	if version == "" {
+		version = "2.5"
+	}
+
+	sep := string(seps.field)
+	component := string(seps.component)
+	encoding := string([]byte{seps.component, seps.repetition, seps.escape, seps.subcomponent})
+	controlID := strings.ReplaceAll(uuid.NewString(), "-", "")[:20]
+	segments := []string{
+		strings.Join([]string{"MSH", encoding, msh.raw(5), msh.raw(6), msh.raw(3), msh.raw(4),
+			now.UTC().Format("20060102150405"), "", "ACK" + component + event + component + "ACK", controlID,
+			processingID, version}, sep),
+		strings.Join([]string{"MSA", code, msh.raw(10)}, sep),
+	}
+
+	for _, issue := range issues {
+		system := "HL70357"
+		if issue.Code == ErrCodeNotAuthorized {
+			system = "L"
+		}
+		errorCode := strconv.Itoa(issue.Code) + component + errorCodeTexts[issue.Code] + component + system
+		severity := "E"
+		if issue.Warning {
+			severity = "W"
+		}
+		segments = append(segments, strings.Join([]string{"ERR", "", "", errorCode, severity, "", "", "", seps.encode(issue.Text)}, sep))
+	}
+
+	return []byte(strings.Join(segments, "\r") + "\r")
+}
+
+func (s Segment) raw(field int) string {
+	if field <= 0 || field >= len(s.fields) {
+		return ""
+	}
+	return s.fields[field]
+}
@@ -0,0 +1,32 @@
+package hl7
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAck(t *testing.T) {
+	message, _ := Parse([]byte(admission))
+	now := time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC)
+
+	ack, err := Parse(NewAck(message, AckError, []Issue{{Code: ErrCodeUnknownKey, Text: "PID: patient not found | retry"}}, now))
+	assert.NoError(t, err)
+
+	msh, _ := ack.Segment("MSH")
+	assert.Equal(t, "DIAG", msh.Field(3))
+	assert.Equal(t, "EPIC", msh.Field(5))
+	assert.Equal(t, "20240301100001", msh.Field(7))
+	assert.Equal(t, "ACK^A01^ACK", strings.Join([]string{msh.Component(9, 1), msh.Component(9, 2), msh.Component(9, 3)}, "^"))
+	assert.Equal(t, "2.5", msh.Field(12))
+
+	msa, _ := ack.Segment("MSA")
+	assert.Equal(t, AckError, msa.Field(1))
+	assert.Equal(t, "MSG00001", msa.Field(2))
+
+	errSegment, _ := ack.Segment("ERR")
+	assert.Equal(t, "204", errSegment.Component(3, 1))
+	assert.Equal(t, "E", errSegment.Field(4))
+	assert.Equal(t, "PID: patient not found | retry", errSegment.Field(8))
+}
@@ -0,0 +1,188 @@
+package hl7
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// IdleTimeout closes the connections that send no message for that long. Interface engines reconnect when needed.
+const IdleTimeout = 5 * time.Minute
+
+var ErrListenerClosed = errors.New("HL7 listener closed")
+
+// Listener accepts MLLP connections and acknowledges every message they send once it is applied.
+type Listener struct {
+	processor *Processor
+	// messages is the context the messages are applied with, cancelled when Close stops waiting for them
+	messages context.Context
+	cancel   context.CancelFunc
+
+	mutex    sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewListener returns a listener applying the messages with a Processor, see NewProcessor.
+func NewListener(services app.Services, actor auth.Principal, store idempotency.Store, ttl time.Duration) *Listener {
+	messages, cancel := context.WithCancel(context.Background())
+	return &Listener{
+		processor: NewProcessor(services, actor, store, ttl),
+		messages:  messages,
+		cancel:    cancel,
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+// ListenAndServe listens on the TCP address, such as :2575, and serves it until the listener is closed.
+func (l *Listener) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return l.Serve(listener)
+}
+
+// Serve accepts connections until the listener is closed, when it returns ErrListenerClosed.
+func (l *Listener) Serve(listener net.Listener) error {
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		listener.Close()
+		return ErrListenerClosed
+	}
+	l.listener = listener
+	l.mutex.Unlock()
+
+	slog.Info("Listening for HL7 messages on " + listener.Addr().String())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			l.mutex.Lock()
+			closed := l.closed
+			l.mutex.Unlock()
+			if closed {
+				return ErrListenerClosed
+			}
+			return err
+		}
+
+		if !l.track(conn) {
+			conn.Close()
+			return ErrListenerClosed
+		}
+
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			defer l.untrack(conn)
+			l.serveConn(conn)
+		}()
+	}
+}
+
+// Close stops accepting connections and closes the open ones once the messages being applied are acknowledged.
+// When ctx is done first, the messages are cancelled, which rolls back what they didn't commit, and the connections
+// are closed without acknowledging them.
+func (l *Listener) Close(ctx context.Context) error {
+	l.mutex.Lock()
+	l.closed = true
+	var err error
+	if l.listener != nil {
+		err = l.listener.Close()
+	}
+	for conn := range l.conns {
+		// unblock the reads, the message being applied is still acknowledged
+		conn.SetReadDeadline(time.Now())
+	}
+	l.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		l.cancel()
+		return err
+	case <-ctx.Done():
+	}
+
+	l.cancel()
+	l.mutex.Lock()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mutex.Unlock()
+	<-done
+	return errors.Join(err, fmt.Errorf("draining HL7 connections: %w", ctx.Err()))
+}
+
+func (l *Listener) serveConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		if !l.awaitMessage(conn) {
+			return
+		}
+
+		message, err := ReadFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !isTimeout(err) {
+				slog.Info("closing HL7 connection", "remote", conn.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+
+		// messages are applied to the end, unless Close stops waiting for them
+		ack := l.processor.Process(l.messages, message)
+		if err := WriteFrame(conn, ack); err != nil {
+			slog.Info("error writing HL7 acknowledgment", "remote", conn.RemoteAddr().String(), "err", err)
+			return
+		}
+	}
+}
+
+// awaitMessage doesn't set the idle deadline once the listener is closed, it would replace the one unblocking the read.
+func (l *Listener) awaitMessage(conn net.Conn) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return false
+	}
+	return conn.SetReadDeadline(time.Now().Add(IdleTimeout)) == nil
+}
+
+func (l *Listener) track(conn net.Conn) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	return true
+}
+
+func (l *Listener) untrack(conn net.Conn) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.conns, conn)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
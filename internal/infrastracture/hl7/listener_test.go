@@ -0,0 +1,160 @@
+package hl7
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/codes/icd10"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListener(t *testing.T) {
+	catalog, err := icd10.NewCatalog()
+	assert.NoError(t, err)
+	repository := memory.NewRepository()
+	services := app.NewServices(repository, repository, repository, repository, catalog)
+
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	listener := NewListener(services, interfaceEngine, repository, time.Hour)
+	served := make(chan error)
+	go func() {
+		served <- listener.Serve(netListener)
+	}()
+
+	client, err := Dial(netListener.Addr().String(), 5*time.Second)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	ack, err := client.Send(admission)
+	assert.NoError(t, err)
+	msa, _ := ack.Segment("MSA")
+	assert.Equal(t, AckAccept, msa.Field(1))
+	assert.Equal(t, "MSG00001", msa.Field(2))
+
+	patient, err := services.PatientServices.Queries.GetPatient.Handle(context.Background(), patientqueries.GetPatientQuery{
+		LegalID: "12345678",
+		Actor:   interfaceEngine,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "John Michael Doe", patient.Name)
+
+	page, err := services.DiagnosisServices.Queries.GetDiagnoses.Handle(context.Background(), queries.GetDiagnosesQuery{
+		PatientID: &patient.ID,
+		Actor:     interfaceEngine,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+
+	// the connection stays open for the next messages
+	ack, err = client.Send("MSH|^~\\&|LAB|HOSP|DIAG|CLINIC|20240301100000||ORU^R01|MSG00002|P|2.5\nPID|1||87654321\nDG1|1||J45.0^^I10")
+	assert.NoError(t, err)
+	msa, _ = ack.Segment("MSA")
+	assert.Equal(t, AckError, msa.Field(1))
+
+	assert.NoError(t, listener.Close(context.Background()))
+	assert.ErrorIs(t, <-served, ErrListenerClosed)
+
+	_, err = client.Send(admission)
+	assert.Error(t, err)
+}
+
+func TestListener_Close_WaitsForMessageInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	listener, addr, served := serveBlockingListener(t, func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+
+	client, err := Dial(addr, 5*time.Second)
+	assert.NoError(t, err)
+	defer client.Close()
+	acks := make(chan error, 1)
+	go func() {
+		_, err := client.Send(results)
+		acks <- err
+	}()
+	<-started
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- listener.Close(context.Background())
+	}()
+	select {
+	case err := <-closed:
+		t.Fatalf("Close() returned %v before the message in flight was acknowledged", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the message is acknowledged and the connection closed, though the client keeps it open
+	close(release)
+	assert.NoError(t, <-acks)
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close() didn't return once the message in flight was acknowledged")
+	}
+	assert.ErrorIs(t, <-served, ErrListenerClosed)
+}
+
+func TestListener_Close_CancelsMessageInFlightAfterTimeout(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	listener, addr, served := serveBlockingListener(t, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+	})
+
+	client, err := Dial(addr, 5*time.Second)
+	assert.NoError(t, err)
+	defer client.Close()
+	go client.Send(results)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, listener.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+	assert.ErrorIs(t, <-served, ErrListenerClosed)
+}
+
+// results is an ORU^R01 message of an existing patient with a diagnosis.
+const results = "MSH|^~\\&|LAB|HOSP|DIAG|CLINIC|20240301100000||ORU^R01|MSG00003|P|2.5\rPID|1||12345678\rDG1|1||J45.0^^I10\r"
+
+// serveBlockingListener serves a listener whose diagnoses are added by calling add, returning it with its address
+// and the channel receiving the result of Serve.
+func serveBlockingListener(t *testing.T, add func(ctx context.Context)) (*Listener, string, <-chan error) {
+	t.Helper()
+	getPatient := &patientqueries.MockGetPatient{}
+	getPatient.On("Handle", mock.Anything, mock.Anything).Return(&patients.Patient{ID: patientID}, nil)
+	addDiagnoses := &commands.MockAddPatientDiagnoses{}
+	addDiagnoses.On("Handle", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		add(args.Get(0).(context.Context))
+	}).Return([]*diagnoses.Diagnosis{}, nil)
+	services := app.Services{
+		PatientServices:   app.PatientServices{Queries: app.PatientQueries{GetPatient: getPatient}},
+		DiagnosisServices: app.DiagnosisServices{Commands: app.Commands{AddPatientDiagnosesHandler: addDiagnoses}},
+	}
+
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error=%v, but no error expected", err)
+	}
+	listener := NewListener(services, interfaceEngine, memory.NewRepository(), time.Hour)
+	served := make(chan error, 1)
+	go func() {
+		served <- listener.Serve(netListener)
+	}()
+	return listener, netListener.Addr().String(), served
+}
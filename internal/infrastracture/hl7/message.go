@@ -0,0 +1,217 @@
+// Package hl7 receives HL7 v2 messages over MLLP from hospital interface engines, and turns ADT and ORU messages
+// into patient and diagnosis commands. Only the segments and fields this service has data for are read.
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidMessage = errors.New("invalid HL7 message")
+
+// Message is a parsed HL7 v2 message, whose segments keep the order they were sent in.
+type Message struct {
+	Segments []Segment
+	// separators are the field separator followed by the MSH-2 encoding characters: component, repetition, escape
+	// and subcomponent
+	separators separators
+}
+
+type separators struct {
+	field, component, repetition, escape, subcomponent byte
+}
+
+// Segment is an HL7 segment, such as PID. Fields are numbered from 1 like in the HL7 specification.
+type Segment struct {
+	Name   string
+	fields []string
+	seps   separators
+}
+
+// Parse parses a message whose segments are separated by carriage returns. Line feeds are accepted too, as some
+// systems use them.
+func Parse(data []byte) (Message, error) {
+	text := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\r"), "\n", "\r")
+	if len(text) < 8 || !strings.HasPrefix(text, "MSH") {
+		return Message{}, fmt.Errorf("%w, it must start with an MSH segment", ErrInvalidMessage)
+	}
+
+	seps := separators{field: text[3], component: '^', repetition: '~', escape: '\\', subcomponent: '&'}
+	encoding, _, _ := strings.Cut(text[4:], "\r")
+	encoding, _, _ = strings.Cut(encoding, string(seps.field))
+	for i, sep := range []*byte{&seps.component, &seps.repetition, &seps.escape, &seps.subcomponent} {
+		if i < len(encoding) {
+			*sep = encoding[i]
+		}
+	}
+
+	message := Message{separators: seps}
+	for _, line := range strings.Split(text, "\r") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, string(seps.field))
+		if len(fields[0]) != 3 {
+			return Message{}, fmt.Errorf("%w, invalid segment name %q", ErrInvalidMessage, fields[0])
+		}
+
+		segment := Segment{Name: fields[0], fields: fields, seps: seps}
+		if segment.Name == "MSH" {
+			// MSH-1 is the field separator itself, so the fields after it are shifted by one
+			segment.fields = append([]string{"MSH", string(seps.field)}, fields[1:]...)
+		}
+		message.Segments = append(message.Segments, segment)
+	}
+
+	return message, nil
+}
+
+// Segment returns the first segment with that name, and false when there is none.
+func (m Message) Segment(name string) (Segment, bool) {
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			return segment, true
+		}
+	}
+	return Segment{}, false
+}
+
+// All returns every segment with that name.
+func (m Message) All(name string) []Segment {
+	var segments []Segment
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// Type returns the message code and trigger event of MSH-9, such as ADT and A01.
+func (m Message) Type() (string, string) {
+	msh, _ := m.Segment("MSH")
+	return msh.Component(9, 1), msh.Component(9, 2)
+}
+
+// ControlID returns MSH-10, which identifies the message in its acknowledgment.
+func (m Message) ControlID() string {
+	msh, _ := m.Segment("MSH")
+	return msh.Field(10)
+}
+
+// Field returns the first repetition of the field, unescaped, empty when it is not present.
+func (s Segment) Field(field int) string {
+	return s.Component(field, 0)
+}
+
+// Component returns a component of the first repetition of the field, unescaped. Component 0 is the whole field.
+func (s Segment) Component(field, component int) string {
+	repetitions := s.Repetitions(field)
+	if len(repetitions) == 0 {
+		return ""
+	}
+	return repetitions[0].Component(component)
+}
+
+// Repetitions returns every repetition of the field.
+func (s Segment) Repetitions(field int) []Repetition {
+	if field <= 0 || field >= len(s.fields) || s.fields[field] == "" {
+		return nil
+	}
+
+	if s.Name == "MSH" && field <= 2 {
+		return []Repetition{{value: s.fields[field], seps: s.seps}}
+	}
+
+	var repetitions []Repetition
+	for _, value := range strings.Split(s.fields[field], string(s.seps.repetition)) {
+		repetitions = append(repetitions, Repetition{value: value, seps: s.seps})
+	}
+	return repetitions
+}
+
+// Repetition is a repetition of a field, made of components separated by ^.
+type Repetition struct {
+	value string
+	seps  separators
+}
+
+// Component returns the component, numbered from 1, unescaped. Component 0 is the whole repetition.
+func (r Repetition) Component(component int) string {
+	if component == 0 {
+		return r.seps.decode(r.value)
+	}
+
+	components := strings.Split(r.value, string(r.seps.component))
+	if component > len(components) {
+		return ""
+	}
+
+	// subcomponents are not used by this service, only the first one is kept
+	value, _, _ := strings.Cut(components[component-1], string(r.seps.subcomponent))
+	return r.seps.decode(value)
+}
+
+// decode replaces the escape sequences of the separators, such as \F\, by the characters they stand for.
+// Formatting and hexadecimal sequences are removed.
+func (s separators) decode(value string) string {
+	escape := string(s.escape)
+	if !strings.Contains(value, escape) {
+		return value
+	}
+
+	var builder strings.Builder
+	for {
+		start := strings.Index(value, escape)
+		if start < 0 {
+			builder.WriteString(value)
+			return builder.String()
+		}
+
+		end := strings.Index(value[start+1:], escape)
+		if end < 0 {
+			builder.WriteString(value)
+			return builder.String()
+		}
+
+		builder.WriteString(value[:start])
+		switch sequence := value[start+1 : start+1+end]; sequence {
+		case "F":
+			builder.WriteByte(s.field)
+		case "S":
+			builder.WriteByte(s.component)
+		case "R":
+			builder.WriteByte(s.repetition)
+		case "E":
+			builder.WriteByte(s.escape)
+		case "T":
+			builder.WriteByte(s.subcomponent)
+		}
+		value = value[start+2+end:]
+	}
+}
+
+func (s separators) encode(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case s.escape:
+			builder.WriteString(string(s.escape) + "E" + string(s.escape))
+		case s.field:
+			builder.WriteString(string(s.escape) + "F" + string(s.escape))
+		case s.component:
+			builder.WriteString(string(s.escape) + "S" + string(s.escape))
+		case s.repetition:
+			builder.WriteString(string(s.escape) + "R" + string(s.escape))
+		case s.subcomponent:
+			builder.WriteString(string(s.escape) + "T" + string(s.escape))
+		case '\r', '\n':
+			builder.WriteByte(' ')
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,59 @@
+package hl7
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const admission = "MSH|^~\\&|EPIC|HOSP|DIAG|CLINIC|20240301100000||ADT^A01^ADT_A01|MSG00001|P|2.5\r" +
+	"EVN|A01|20240301100000\r" +
+	"PID|1||12345678^^^HOSP^MR||Doe^John^Michael||19800101|M|||Main St 1^^Springfield^IL^62701||+1 555 0100^PRN^PH~^NET^Internet^john@doe.com\r" +
+	"DG1|1||J45.0^Predominantly allergic asthma^I10||20240301|A\r" +
+	"DG1|2||^^^|Headaches \\T\\ dizziness|20240301|A\r"
+
+func TestParse(t *testing.T) {
+	message, err := Parse([]byte(admission))
+	assert.NoError(t, err)
+
+	code, event := message.Type()
+	assert.Equal(t, "ADT", code)
+	assert.Equal(t, "A01", event)
+	assert.Equal(t, "MSG00001", message.ControlID())
+	assert.Len(t, message.Segments, 5)
+
+	msh, _ := message.Segment("MSH")
+	assert.Equal(t, "|", msh.Field(1))
+	assert.Equal(t, "EPIC", msh.Field(3))
+
+	pid, ok := message.Segment("PID")
+	assert.True(t, ok)
+	assert.Equal(t, "12345678", pid.Component(3, 1))
+	assert.Equal(t, "John", pid.Component(5, 2))
+	assert.Equal(t, "", pid.Component(5, 9))
+	assert.Equal(t, "", pid.Field(40))
+
+	telecoms := pid.Repetitions(13)
+	assert.Len(t, telecoms, 2)
+	assert.Equal(t, "john@doe.com", telecoms[1].Component(4))
+
+	dg1s := message.All("DG1")
+	assert.Len(t, dg1s, 2)
+	assert.Equal(t, "Headaches & dizziness", dg1s[1].Field(4))
+}
+
+func TestParse_Separators(t *testing.T) {
+	message, err := Parse([]byte("MSH#*~!$#APP\nPID#1##ID*1*2#\\#Doe*Jane!F!#"))
+	assert.NoError(t, err)
+
+	pid, _ := message.Segment("PID")
+	assert.Equal(t, "1", pid.Component(3, 2))
+	assert.Equal(t, `\`, pid.Field(4))
+	assert.Equal(t, "Jane#", pid.Component(5, 2))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, data := range []string{"", "PID|1", "MSH|^~\\&|APP\rPIDX|1"} {
+		_, err := Parse([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidMessage, data)
+	}
+}
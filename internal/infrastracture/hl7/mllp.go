@@ -0,0 +1,116 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// MLLP frames every message between a start block and an end block followed by a carriage return.
+const (
+	startBlock     = 0x0b
+	endBlock       = 0x1c
+	carriageReturn = 0x0d
+)
+
+// MaxMessageSize is the maximum size of the messages read, larger messages close the connection.
+const MaxMessageSize = 1 << 20
+
+var ErrMessageTooLarge = fmt.Errorf("HL7 messages can have at most %d bytes", MaxMessageSize)
+
+// ReadFrame reads the next MLLP framed message, skipping any byte sent before its start block. It returns io.EOF
+// when the connection is closed between messages.
+func ReadFrame(reader *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if b == startBlock {
+			break
+		}
+	}
+
+	var message []byte
+	for {
+		chunk, err := reader.ReadSlice(endBlock)
+		if len(message)+len(chunk) > MaxMessageSize {
+			return nil, ErrMessageTooLarge
+		}
+		message = append(message, chunk...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		if b == carriageReturn {
+			return message[:len(message)-1], nil
+		}
+
+		// the end block was part of the message, which is allowed by some encodings
+		message = append(message, b)
+	}
+}
+
+// WriteFrame writes the message framed by MLLP.
+func WriteFrame(writer io.Writer, message []byte) error {
+	frame := make([]byte, 0, len(message)+3)
+	frame = append(frame, startBlock)
+	frame = append(frame, message...)
+	frame = append(frame, endBlock, carriageReturn)
+	_, err := writer.Write(frame)
+	return err
+}
+
+// Client sends messages to an MLLP listener and waits for their acknowledgments, one at a time.
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+// Dial connects to the MLLP listener at addr. Every message waits for its acknowledgment up to timeout.
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+// Send sends the message, whose segments can be separated by line feeds, and returns its acknowledgment.
+func (c *Client) Send(message string) (Message, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return Message{}, err
+	}
+
+	data := bytes.ReplaceAll([]byte(message), []byte("\n"), []byte("\r"))
+	if err := WriteFrame(c.conn, data); err != nil {
+		return Message{}, err
+	}
+
+	ack, err := ReadFrame(c.reader)
+	if err != nil {
+		return Message{}, err
+	}
+	return Parse(ack)
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,41 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadFrame(t *testing.T) {
+	stream := "garbage\x0bfirst\x1c\x0d\x0bsecond with \x1c inside\x1c\x0d"
+	reader := bufio.NewReader(strings.NewReader(stream))
+
+	first, err := ReadFrame(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", string(first))
+
+	second, err := ReadFrame(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "second with \x1c inside", string(second))
+
+	_, err = ReadFrame(reader)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadFrame_Errors(t *testing.T) {
+	_, err := ReadFrame(bufio.NewReader(strings.NewReader("\x0bunfinished")))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	tooLarge := "\x0b" + strings.Repeat("a", MaxMessageSize+1) + "\x1c\x0d"
+	_, err = ReadFrame(bufio.NewReader(strings.NewReader(tooLarge)))
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+}
+
+func TestWriteFrame(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.NoError(t, WriteFrame(&buffer, []byte("MSH|^~\\&")))
+	assert.Equal(t, "\x0bMSH|^~\\&\x1c\x0d", buffer.String())
+}
@@ -0,0 +1,433 @@
+package hl7
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	patientcommands "github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// null is the HL7 null value, which clears a field instead of leaving it unchanged like an empty one.
+const null = `""`
+
+var errApplying = errors.New("the message could not be applied, send it again later")
+
+// icd10Systems are the coding systems of DG1-3 read as ICD-10, I10 being the code of the HL7 table 0396.
+var icd10Systems = map[string]bool{"I10": true, "ICD10": true, "ICD-10": true, diagnoses.SystemICD10: true}
+
+// pendingTimeout bounds how long a control ID stays reserved by a message that is never acknowledged, such as when
+// the service stops while applying it.
+const pendingTimeout = time.Minute
+
+// Processor applies HL7 messages through the application services:
+//   - ADT^A01 and ADT^A08 create the PID patient, identified by the PID-3 legal ID, or update its contact data when it
+//     exists. Empty fields keep the current data and "" clears it.
+//   - ORU^R01 needs the PID patient to exist.
+//
+// The DG1 segments of both are added as diagnoses of the patient, all of them or none. Commands are run as the actor,
+// as MLLP has no authentication of its own.
+type Processor struct {
+	services app.Services
+	actor    auth.Principal
+	store    idempotency.Store
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewProcessor returns a processor that applies each message once. The acknowledgment of a message is stored in the
+// store for ttl, keyed by its sender and MSH-10 control ID, and replayed when the message is sent again.
+func NewProcessor(services app.Services, actor auth.Principal, store idempotency.Store, ttl time.Duration) *Processor {
+	return &Processor{services: services, actor: actor, store: store, ttl: ttl, now: time.Now}
+}
+
+// Process applies the message and returns its acknowledgment. A message whose diagnoses can't be added is still
+// applied to its patient, which the acknowledgment reports as warnings. Messages failing with internal or
+// authorization errors aren't stored, so they can be sent again once fixed.
+func (p *Processor) Process(ctx context.Context, data []byte) []byte {
+	message, err := Parse(data)
+	if err != nil {
+		slog.Info("HL7 message rejected", "err", err)
+		return NewAck(Message{}, AckReject, []Issue{{Code: ErrCodeSegmentSequence, Text: err.Error()}}, p.now())
+	}
+
+	controlID := message.ControlID()
+	if controlID == "" {
+		ack, _ := p.apply(ctx, message)
+		return ack
+	}
+
+	record := idempotency.Record{
+		Key:         idempotencyKey(message),
+		Fingerprint: fingerprint(data),
+		ExpiresAt:   p.now().Add(pendingTimeout),
+	}
+	existing, err := p.store.Reserve(ctx, record, p.now())
+	switch {
+	case err != nil:
+		slog.Error("error reserving HL7 control ID", "controlID", controlID, "err", err)
+		return NewAck(message, AckError, []Issue{{Code: ErrCodeInternal, Text: errApplying.Error()}}, p.now())
+	case existing == nil:
+		return p.applyOnce(ctx, message, record.Key)
+	case existing.Fingerprint != record.Fingerprint:
+		issue := Issue{Code: ErrCodeDuplicateKey, Text: fmt.Sprintf("control ID %s was already used by another message", controlID)}
+		return NewAck(message, AckReject, []Issue{issue}, p.now())
+	case existing.Response == nil:
+		issue := Issue{Code: ErrCodeDuplicateKey, Text: fmt.Sprintf("message %s is still being applied", controlID)}
+		return NewAck(message, AckError, []Issue{issue}, p.now())
+	default:
+		slog.Info("HL7 message already applied, replaying its acknowledgment", "controlID", controlID)
+		return existing.Response.Body
+	}
+}
+
+func (p *Processor) applyOnce(ctx context.Context, message Message, key string) []byte {
+	ack, retry := p.apply(ctx, message)
+
+	// the acknowledgment is stored even when ctx is done, so the key isn't left pending
+	storeCtx := context.WithoutCancel(ctx)
+	if !retry {
+		err := p.store.Complete(storeCtx, key, idempotency.Response{Body: ack}, p.now().Add(p.ttl))
+		if err == nil {
+			return ack
+		}
+		slog.Error("error storing HL7 acknowledgment", "controlID", message.ControlID(), "err", err)
+	}
+
+	if err := p.store.Release(storeCtx, key); err != nil {
+		slog.Error("error releasing HL7 control ID", "controlID", message.ControlID(), "err", err)
+	}
+	return ack
+}
+
+// apply also returns whether the message can be sent again, after an internal or authorization error.
+func (p *Processor) apply(ctx context.Context, message Message) ([]byte, bool) {
+	code, event := message.Type()
+	var issues []Issue
+	switch {
+	case code == "ADT" && (event == "A01" || event == "A08"):
+		issues = p.admit(ctx, message)
+	case code == "ORU" && event == "R01":
+		issues = p.observe(ctx, message)
+	case code == "ADT" || code == "ORU":
+		issue := Issue{Code: ErrCodeUnsupportedEvent, Text: fmt.Sprintf("unsupported %s event %q", code, event)}
+		return NewAck(message, AckReject, []Issue{issue}, p.now()), false
+	default:
+		issue := Issue{Code: ErrCodeUnsupportedMessage, Text: fmt.Sprintf("unsupported message type %q", code)}
+		return NewAck(message, AckReject, []Issue{issue}, p.now()), false
+	}
+
+	ackCode, retry := AckAccept, false
+	for _, issue := range issues {
+		switch {
+		case issue.Code == ErrCodeNotAuthorized:
+			ackCode = AckReject
+		case !issue.Warning && ackCode == AckAccept:
+			ackCode = AckError
+		}
+		retry = retry || issue.Code == ErrCodeInternal || issue.Code == ErrCodeNotAuthorized
+	}
+
+	switch {
+	case ackCode != AckAccept:
+		slog.Info("HL7 message not applied", "controlID", message.ControlID(), "type", code+"^"+event, "issues", issues)
+	case len(issues) > 0:
+		slog.Info("HL7 message applied with warnings", "controlID", message.ControlID(), "type", code+"^"+event, "issues", issues)
+	default:
+		slog.Info("HL7 message applied", "controlID", message.ControlID(), "type", code+"^"+event)
+	}
+	return NewAck(message, ackCode, issues, p.now()), retry
+}
+
+func (p *Processor) admit(ctx context.Context, message Message) []Issue {
+	pid, legalID, issue := patientIdentification(message)
+	if issue != nil {
+		return []Issue{*issue}
+	}
+
+	patient, err := p.services.PatientServices.Queries.GetPatient.Handle(ctx, patientqueries.GetPatientQuery{
+		LegalID:   legalID,
+		Actor:     p.actor,
+		RequestID: message.ControlID(),
+	})
+	switch {
+	case errors.Is(err, patients.ErrPatientNotFound):
+		patient, err = p.createPatient(ctx, pid, legalID, message.ControlID())
+	case err == nil:
+		patient, err = p.updatePatient(ctx, pid, *patient, message.ControlID())
+	}
+	if err != nil {
+		return []Issue{issueOf("PID", err)}
+	}
+
+	return warnings(p.addDiagnoses(ctx, message, patient.ID))
+}
+
+func (p *Processor) observe(ctx context.Context, message Message) []Issue {
+	_, legalID, issue := patientIdentification(message)
+	if issue != nil {
+		return []Issue{*issue}
+	}
+
+	patient, err := p.services.PatientServices.Queries.GetPatient.Handle(ctx, patientqueries.GetPatientQuery{
+		LegalID:   legalID,
+		Actor:     p.actor,
+		RequestID: message.ControlID(),
+	})
+	if err != nil {
+		return []Issue{issueOf("PID", err)}
+	}
+
+	return p.addDiagnoses(ctx, message, patient.ID)
+}
+
+func (p *Processor) createPatient(ctx context.Context, pid Segment, legalID, requestID string) (*patients.Patient, error) {
+	birthDate, err := birthDateOf(pid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", patientcommands.ErrInvalidPatient, err)
+	}
+
+	address, phone, email := contactOf(pid)
+	return p.services.PatientServices.Commands.CreatePatient.Handle(ctx, patientcommands.CreatePatient{
+		LegalID:   legalID,
+		Name:      nameOf(pid),
+		BirthDate: birthDate,
+		Address:   valueOr(address, ""),
+		Phone:     valueOr(phone, ""),
+		Email:     valueOr(email, ""),
+		Actor:     p.actor,
+		RequestID: requestID,
+	})
+}
+
+// updatePatient replaces the contact data sent in the PID segment. Identity data, as the name, can't be changed.
+func (p *Processor) updatePatient(ctx context.Context, pid Segment, patient patients.Patient,
+	requestID string) (*patients.Patient, error) {
+	address, phone, email := contactOf(pid)
+	return p.services.PatientServices.Commands.UpdatePatientContact.Handle(ctx, patientcommands.UpdatePatientContact{
+		PatientID: patient.ID,
+		Address:   valueOr(address, patient.Address),
+		Phone:     valueOr(phone, patient.Phone),
+		Email:     valueOr(email, patient.Email),
+		Actor:     p.actor,
+		RequestID: requestID,
+	})
+}
+
+// addDiagnoses adds the DG1 diagnoses in a single unit of work, so none is added when any of them fails.
+func (p *Processor) addDiagnoses(ctx context.Context, message Message, patientID uuid.UUID) []Issue {
+	segments := message.All("DG1")
+	diagnosisCommands := make([]commands.AddPatientDiagnosis, 0, len(segments))
+	var issues []Issue
+	for i, dg1 := range segments {
+		description, coding := diagnosisOf(dg1)
+		if description == "" {
+			issues = append(issues, Issue{Code: ErrCodeRequiredField, Text: dg1Location(i) + ": DG1-3 diagnosis code or DG1-4 description is required"})
+			continue
+		}
+
+		diagnosisCommands = append(diagnosisCommands, commands.AddPatientDiagnosis{
+			PatientID: patientID,
+			Diagnosis: description,
+			Coding:    coding,
+			Actor:     p.actor,
+			RequestID: message.ControlID(),
+		})
+	}
+
+	if len(issues) > 0 || len(diagnosisCommands) == 0 {
+		return issues
+	}
+
+	_, err := p.services.DiagnosisServices.Commands.AddPatientDiagnosesHandler.Handle(ctx, commands.AddPatientDiagnoses{
+		Diagnoses: diagnosisCommands,
+	})
+	var diagnosesErrs commands.DiagnosesErrors
+	if errors.As(err, &diagnosesErrs) {
+		for _, diagnosisErr := range diagnosesErrs {
+			issues = append(issues, issueOf(dg1Location(diagnosisErr.Index), diagnosisErr.Err))
+		}
+		return issues
+	}
+	if err != nil {
+		return []Issue{issueOf("DG1", err)}
+	}
+	return nil
+}
+
+// warnings keeps internal and authorization issues as errors, as the message can be sent again once they're fixed.
+func warnings(issues []Issue) []Issue {
+	for _, issue := range issues {
+		if issue.Code == ErrCodeInternal || issue.Code == ErrCodeNotAuthorized {
+			return issues
+		}
+	}
+
+	for i := range issues {
+		issues[i].Warning = true
+	}
+	return issues
+}
+
+func dg1Location(index int) string {
+	return fmt.Sprintf("DG1 %d", index+1)
+}
+
+func idempotencyKey(message Message) string {
+	msh, _ := message.Segment("MSH")
+	return "hl7:" + msh.Field(3) + "^" + msh.Field(4) + ":" + message.ControlID()
+}
+
+func fingerprint(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func patientIdentification(message Message) (Segment, string, *Issue) {
+	pid, ok := message.Segment("PID")
+	if !ok {
+		return Segment{}, "", &Issue{Code: ErrCodeSegmentSequence, Text: "PID segment is required"}
+	}
+
+	legalID := strings.TrimSpace(pid.Component(3, 1))
+	if legalID == "" {
+		return Segment{}, "", &Issue{Code: ErrCodeRequiredField, Text: "PID-3 patient identifier is required"}
+	}
+
+	return pid, legalID, nil
+}
+
+func nameOf(pid Segment) string {
+	return joinPresent(" ", pid.Component(5, 2), pid.Component(5, 3), pid.Component(5, 1))
+}
+
+func birthDateOf(pid Segment) (*time.Time, error) {
+	value := pid.Component(7, 1)
+	if value == "" || value == null {
+		return nil, nil
+	}
+
+	if len(value) < 8 {
+		return nil, fmt.Errorf("PID-7 birth date %s is not a date", value)
+	}
+
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return nil, fmt.Errorf("PID-7 birth date %s is not a date", value)
+	}
+
+	return &date, nil
+}
+
+// contactOf returns the PID-11 address and the first phone and email of PID-13 and PID-14. They are nil when not
+// sent, and empty when sent as null.
+func contactOf(pid Segment) (address, phone, email *string) {
+	if field := pid.Field(11); field == null {
+		address = new(string)
+	} else if repetitions := pid.Repetitions(11); len(repetitions) > 0 {
+		r := repetitions[0]
+		value := joinPresent(", ", r.Component(1), r.Component(2), r.Component(3), r.Component(4), r.Component(5), r.Component(6))
+		address = &value
+	}
+
+	cleared := false
+	for _, field := range []int{13, 14} {
+		if pid.Field(field) == null {
+			cleared = true
+			continue
+		}
+
+		for _, telecom := range pid.Repetitions(field) {
+			isEmail := telecom.Component(2) == "NET" || telecom.Component(3) == "Internet"
+			switch {
+			case isEmail && email == nil && telecom.Component(4) != "":
+				value := telecom.Component(4)
+				email = &value
+			case !isEmail && phone == nil:
+				value := telecom.Component(1)
+				if value == "" {
+					value = telecom.Component(12)
+				}
+				if value != "" {
+					phone = &value
+				}
+			}
+		}
+	}
+
+	if cleared && phone == nil {
+		phone = new(string)
+	}
+	if cleared && email == nil {
+		email = new(string)
+	}
+
+	return address, phone, email
+}
+
+func diagnosisOf(dg1 Segment) (string, *diagnoses.Coding) {
+	code, text, system := dg1.Component(3, 1), dg1.Component(3, 2), dg1.Component(3, 3)
+	var coding *diagnoses.Coding
+	if code != "" && icd10Systems[strings.ToUpper(system)] {
+		coding = &diagnoses.Coding{System: diagnoses.SystemICD10, Code: code}
+	}
+
+	description := strings.TrimSpace(text)
+	if description == "" {
+		description = strings.TrimSpace(dg1.Field(4))
+	}
+	if description == "" && coding != nil {
+		description = coding.Code
+	}
+
+	return description, coding
+}
+
+// issueOf only describes validation, not found and authorization errors, the rest are logged.
+func issueOf(location string, err error) Issue {
+	text := location + ": " + strings.ReplaceAll(err.Error(), "\n", "; ")
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated), errors.Is(err, auth.ErrForbidden):
+		slog.Error("HL7 actor not allowed to apply the message", "location", location, "err", err)
+		return Issue{Code: ErrCodeNotAuthorized, Text: text}
+	case errors.Is(err, patientcommands.ErrInvalidPatient), errors.Is(err, validation.ErrInvalidCommand):
+		return Issue{Code: ErrCodeDataType, Text: text}
+	case errors.Is(err, patients.ErrPatientNotFound):
+		return Issue{Code: ErrCodeUnknownKey, Text: text}
+	case errors.Is(err, commands.ErrInvalidCoding):
+		return Issue{Code: ErrCodeTableValue, Text: text}
+	default:
+		slog.Error("error applying HL7 message", "location", location, "err", err)
+		return Issue{Code: ErrCodeInternal, Text: location + ": " + errApplying.Error()}
+	}
+}
+
+func joinPresent(separator string, values ...string) string {
+	present := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			present = append(present, value)
+		}
+	}
+	return strings.Join(present, separator)
+}
+
+func valueOr(value *string, defaultValue string) string {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
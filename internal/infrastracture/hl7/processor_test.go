@@ -0,0 +1,427 @@
+package hl7
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	patientcommands "github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/codes/icd10"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	interfaceEngine = auth.Principal{ID: "hl7-interface", Roles: []auth.Role{auth.RoleClinician}}
+	patientID       = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+)
+
+type processorMocks struct {
+	getPatient    *patientqueries.MockGetPatient
+	createPatient *patientcommands.MockCreatePatient
+	updateContact *patientcommands.MockUpdatePatientContact
+	addDiagnoses  *commands.MockAddPatientDiagnoses
+}
+
+func newProcessor(existing *patients.Patient, addErr error) (*Processor, processorMocks) {
+	mocks := processorMocks{
+		getPatient:    &patientqueries.MockGetPatient{},
+		createPatient: &patientcommands.MockCreatePatient{},
+		updateContact: &patientcommands.MockUpdatePatientContact{},
+		addDiagnoses:  &commands.MockAddPatientDiagnoses{},
+	}
+
+	if existing != nil {
+		mocks.getPatient.On("Handle", mock.Anything, mock.Anything).Return(existing, nil)
+	} else {
+		mocks.getPatient.On("Handle", mock.Anything, mock.Anything).Return((*patients.Patient)(nil), patients.ErrPatientNotFound)
+	}
+	mocks.createPatient.On("Handle", mock.Anything, mock.Anything).Return(&patients.Patient{ID: patientID}, nil)
+	mocks.updateContact.On("Handle", mock.Anything, mock.Anything).Return(&patients.Patient{ID: patientID}, nil)
+	mocks.addDiagnoses.On("Handle", mock.Anything, mock.Anything).Return([]*diagnoses.Diagnosis{}, addErr)
+
+	services := app.Services{
+		PatientServices: app.PatientServices{
+			Commands: app.PatientCommands{CreatePatient: mocks.createPatient, UpdatePatientContact: mocks.updateContact},
+			Queries:  app.PatientQueries{GetPatient: mocks.getPatient},
+		},
+		DiagnosisServices: app.DiagnosisServices{
+			Commands: app.Commands{AddPatientDiagnosesHandler: mocks.addDiagnoses},
+		},
+	}
+	return NewProcessor(services, interfaceEngine, memory.NewRepository(), time.Hour), mocks
+}
+
+// newServicesProcessor returns a processor applying the messages through the services of a memory repository.
+func newServicesProcessor(t *testing.T) (*Processor, app.Services, *memory.Repository) {
+	t.Helper()
+	catalog, err := icd10.NewCatalog()
+	assert.NoError(t, err)
+	repository := memory.NewRepository()
+	services := app.NewServices(repository, repository, repository, repository, catalog)
+	return NewProcessor(services, interfaceEngine, repository, time.Hour), services, repository
+}
+
+func process(t *testing.T, p *Processor, message string) (string, []string) {
+	t.Helper()
+	ack, err := Parse(p.Process(context.Background(), []byte(strings.ReplaceAll(message, "\n", "\r"))))
+	assert.NoError(t, err)
+
+	msa, _ := ack.Segment("MSA")
+	var errorCodes []string
+	for _, segment := range ack.All("ERR") {
+		errorCodes = append(errorCodes, segment.Component(3, 1))
+	}
+	return msa.Field(1), errorCodes
+}
+
+func TestProcessor_Admission(t *testing.T) {
+	p, mocks := newProcessor(nil, nil)
+
+	code, errorCodes := process(t, p, admission)
+
+	assert.Equal(t, AckAccept, code)
+	assert.Empty(t, errorCodes)
+	birthDate := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	mocks.createPatient.AssertCalled(t, "Handle", mock.Anything, patientcommands.CreatePatient{
+		LegalID:   "12345678",
+		Name:      "John Michael Doe",
+		BirthDate: &birthDate,
+		Address:   "Main St 1, Springfield, IL, 62701",
+		Phone:     "+1 555 0100",
+		Email:     "john@doe.com",
+		Actor:     interfaceEngine,
+		RequestID: "MSG00001",
+	})
+	mocks.addDiagnoses.AssertCalled(t, "Handle", mock.Anything, commands.AddPatientDiagnoses{Diagnoses: []commands.AddPatientDiagnosis{
+		{
+			PatientID: patientID,
+			Diagnosis: "Predominantly allergic asthma",
+			Coding:    &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0"},
+			Actor:     interfaceEngine,
+			RequestID: "MSG00001",
+		},
+		{
+			PatientID: patientID,
+			Diagnosis: "Headaches & dizziness",
+			Actor:     interfaceEngine,
+			RequestID: "MSG00001",
+		},
+	}})
+}
+
+func TestProcessor_Update(t *testing.T) {
+	existing := &patients.Patient{ID: patientID, LegalID: "12345678", Name: "John Doe", Address: "Old St 2",
+		Phone: "+1 555 0199", Email: "old@doe.com"}
+	p, mocks := newProcessor(existing, nil)
+
+	code, _ := process(t, p, "MSH|^~\\&|EPIC|HOSP|DIAG|CLINIC|20240301100000||ADT^A08|MSG00002|P|2.5\n"+
+		"PID|1||12345678||Doe^John||||||||\"\"|^NET^Internet^new@doe.com")
+
+	assert.Equal(t, AckAccept, code)
+	mocks.createPatient.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything)
+	mocks.updateContact.AssertCalled(t, "Handle", mock.Anything, patientcommands.UpdatePatientContact{
+		PatientID: patientID,
+		Address:   "Old St 2",
+		Phone:     "",
+		Email:     "new@doe.com",
+		Actor:     interfaceEngine,
+		RequestID: "MSG00002",
+	})
+	mocks.addDiagnoses.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything)
+}
+
+func TestProcessor_Process(t *testing.T) {
+	const msh = "MSH|^~\\&|LAB|HOSP|DIAG|CLINIC|20240301100000||"
+	tests := []struct {
+		name           string
+		message        string
+		existing       *patients.Patient
+		addErr         error
+		wantCode       string
+		wantErrorCodes []string
+		wantAdded      int
+	}{
+		{
+			name:           "add the diagnoses of results of an existing patient",
+			message:        msh + "ORU^R01|MSG1|P|2.5\nPID|1||12345678\nDG1|1||J45.0^^I10",
+			existing:       &patients.Patient{ID: patientID},
+			wantCode:       AckAccept,
+			wantErrorCodes: nil,
+			wantAdded:      1,
+		},
+		{
+			name:           "return error when the patient of results doesn't exist",
+			message:        msh + "ORU^R01|MSG1|P|2.5\nPID|1||12345678\nDG1|1||J45.0^^I10",
+			wantCode:       AckError,
+			wantErrorCodes: []string{"204"},
+		},
+		{
+			name:           "add no diagnosis when one has no description",
+			message:        msh + "ORU^R01|MSG1|P|2.5\nPID|1||12345678\nDG1|1||J45.0^^I10\nDG1|2",
+			existing:       &patients.Patient{ID: patientID},
+			wantCode:       AckError,
+			wantErrorCodes: []string{"101"},
+		},
+		{
+			name:     "return every diagnosis that can't be added",
+			message:  msh + "ORU^R01|MSG1|P|2.5\nPID|1||12345678\nDG1|1||X99.9^^I10\nDG1|2||J45.0^^I10\nDG1|3||^migraine",
+			existing: &patients.Patient{ID: patientID},
+			addErr: commands.DiagnosesErrors{
+				{Index: 0, Err: commands.ErrInvalidCoding},
+				{Index: 2, Err: commands.ErrInvalidCoding},
+			},
+			wantCode:       AckError,
+			wantErrorCodes: []string{"103", "103"},
+			wantAdded:      1,
+		},
+		{
+			name:           "return error when the diagnosis is not valid",
+			message:        msh + "ORU^R01|MSG1|P|2.5\nPID|1||12345678\nDG1|1||^migraine",
+			existing:       &patients.Patient{ID: patientID},
+			addErr:         commands.DiagnosesErrors{{Index: 0, Err: validation.Errors{{Field: "diagnosis", Message: "can't be longer than 2000 characters"}}}},
+			wantCode:       AckError,
+			wantErrorCodes: []string{"102"},
+			wantAdded:      1,
+		},
+		{
+			name:           "accept with warnings the admissions whose diagnoses can't be added",
+			message:        msh + "ADT^A08|MSG1|P|2.5\nPID|1||12345678\nDG1|1||X99.9^^I10",
+			existing:       &patients.Patient{ID: patientID},
+			addErr:         commands.DiagnosesErrors{{Index: 0, Err: commands.ErrInvalidCoding}},
+			wantCode:       AckAccept,
+			wantErrorCodes: []string{"103"},
+			wantAdded:      1,
+		},
+		{
+			name:           "return error when the diagnoses of an admission can't be stored",
+			message:        msh + "ADT^A08|MSG1|P|2.5\nPID|1||12345678\nDG1|1||J45.0^^I10",
+			existing:       &patients.Patient{ID: patientID},
+			addErr:         commands.DiagnosesErrors{{Index: 0, Err: commands.ErrAddingDiagnosis}},
+			wantCode:       AckError,
+			wantErrorCodes: []string{"207"},
+			wantAdded:      1,
+		},
+		{
+			name:           "reject admissions the actor isn't allowed to apply",
+			message:        msh + "ADT^A08|MSG1|P|2.5\nPID|1||12345678\nDG1|1||J45.0^^I10",
+			existing:       &patients.Patient{ID: patientID},
+			addErr:         commands.DiagnosesErrors{{Index: 0, Err: auth.ErrForbidden}},
+			wantCode:       AckReject,
+			wantErrorCodes: []string{"900"},
+			wantAdded:      1,
+		},
+		{
+			name:           "return error when there is no patient identifier",
+			message:        msh + "ADT^A01|MSG1|P|2.5\nPID|1||",
+			wantCode:       AckError,
+			wantErrorCodes: []string{"101"},
+		},
+		{
+			name:           "return error when the birth date is not a date",
+			message:        msh + "ADT^A01|MSG1|P|2.5\nPID|1||12345678||Doe^John||1980-01-01\nDG1|1||J45.0^^I10",
+			wantCode:       AckError,
+			wantErrorCodes: []string{"102"},
+		},
+		{
+			name:           "return error when there is no PID segment",
+			message:        msh + "ADT^A08|MSG1|P|2.5\nEVN|A08",
+			wantCode:       AckError,
+			wantErrorCodes: []string{"100"},
+		},
+		{
+			name:           "reject unsupported events",
+			message:        msh + "ADT^A03|MSG1|P|2.5\nPID|1||12345678",
+			wantCode:       AckReject,
+			wantErrorCodes: []string{"201"},
+		},
+		{
+			name:           "reject unsupported message types",
+			message:        msh + "SIU^S12|MSG1|P|2.5",
+			wantCode:       AckReject,
+			wantErrorCodes: []string{"200"},
+		},
+		{
+			name:           "reject malformed messages",
+			message:        "PID|1||12345678",
+			wantCode:       AckReject,
+			wantErrorCodes: []string{"100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, mocks := newProcessor(tt.existing, tt.addErr)
+
+			code, errorCodes := process(t, p, tt.message)
+
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantErrorCodes, errorCodes)
+			mocks.addDiagnoses.AssertNumberOfCalls(t, "Handle", tt.wantAdded)
+		})
+	}
+}
+
+func TestProcessor_AppliesPatients(t *testing.T) {
+	const msh = "MSH|^~\\&|EPIC|HOSP|DIAG|CLINIC|20240301110000||"
+	tests := []struct {
+		name      string
+		messages  []string
+		wantPhone string
+		wantEmail string
+	}{
+		{
+			name:      "create a patient without email",
+			messages:  []string{msh + "ADT^A01|MSG1|P|2.5\nPID|1||12345678||Doe^John||||||||+1 555 0100"},
+			wantPhone: "+1 555 0100",
+		},
+		{
+			name:     "create a patient without contact",
+			messages: []string{msh + "ADT^A01|MSG1|P|2.5\nPID|1||12345678||Doe^John"},
+		},
+		{
+			name:      "keep the contact sent empty",
+			messages:  []string{admission, msh + "ADT^A08|MSG2|P|2.5\nPID|1||12345678||Doe^John"},
+			wantPhone: "+1 555 0100",
+			wantEmail: "john@doe.com",
+		},
+		{
+			name: "clear the phone sent as null",
+			messages: []string{admission,
+				msh + "ADT^A08|MSG2|P|2.5\nPID|1||12345678||Doe^John||||||||\"\"|^NET^Internet^new@doe.com"},
+			wantEmail: "new@doe.com",
+		},
+		{
+			name:     "clear the contact sent as null",
+			messages: []string{admission, msh + "ADT^A08|MSG2|P|2.5\nPID|1||12345678||Doe^John||||||||\"\"|\"\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, services, _ := newServicesProcessor(t)
+
+			for _, message := range tt.messages {
+				code, errorCodes := process(t, p, message)
+				assert.Equal(t, AckAccept, code)
+				assert.Empty(t, errorCodes)
+			}
+
+			patient, err := services.PatientServices.Queries.GetPatient.Handle(context.Background(), patientqueries.GetPatientQuery{
+				LegalID: "12345678",
+				Actor:   interfaceEngine,
+			})
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.wantPhone, patient.Phone)
+				assert.Equal(t, tt.wantEmail, patient.Email)
+			}
+		})
+	}
+}
+
+func TestProcessor_AuditsPatients(t *testing.T) {
+	p, _, repository := newServicesProcessor(t)
+	update := "MSH|^~\\&|EPIC|HOSP|DIAG|CLINIC|20240301110000||ADT^A08|MSG00002|P|2.5\r" +
+		"PID|1||12345678||Doe^John||||||||+1 555 0199\r"
+
+	p.Process(context.Background(), []byte(admission))
+	p.Process(context.Background(), []byte(update))
+
+	records, err := repository.Find(context.Background(), audit.Filter{ActorID: interfaceEngine.ID})
+	assert.NoError(t, err)
+	var actions []string
+	for _, record := range records {
+		if record.Action == audit.ActionReadPatient || record.Action == audit.ActionCreatePatient ||
+			record.Action == audit.ActionUpdatePatient {
+			actions = append(actions, string(record.Action)+" "+record.RequestID+" "+string(record.Outcome))
+		}
+	}
+	assert.Equal(t, []string{
+		"patients:read MSG00001 failure",
+		"patients:create MSG00001 success",
+		"patients:read MSG00002 success",
+		"patients:update MSG00002 success",
+	}, actions)
+}
+
+func TestProcessor_ReplaysResentMessages(t *testing.T) {
+	p, services, _ := newServicesProcessor(t)
+	update := "MSH|^~\\&|EPIC|HOSP|DIAG|CLINIC|20240301110000||ADT^A08|MSG00002|P|2.5\r" +
+		"PID|1||12345678||Doe^John\rDG1|1||J45.0^^I10\r"
+	p.Process(context.Background(), []byte(admission))
+
+	first := p.Process(context.Background(), []byte(update))
+	resent := p.Process(context.Background(), []byte(update))
+
+	assert.Equal(t, string(first), string(resent))
+	ack, _ := Parse(first)
+	msa, _ := ack.Segment("MSA")
+	assert.Equal(t, AckAccept, msa.Field(1))
+	patient, err := services.PatientServices.Queries.GetPatient.Handle(context.Background(), patientqueries.GetPatientQuery{
+		LegalID: "12345678",
+		Actor:   interfaceEngine,
+	})
+	assert.NoError(t, err)
+	page, err := services.DiagnosisServices.Queries.GetDiagnoses.Handle(context.Background(), queries.GetDiagnosesQuery{
+		PatientID: &patient.ID,
+		Actor:     interfaceEngine,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+
+	// another message sent with the same control ID is rejected
+	reused := strings.Replace(update, "J45.0", "J45.1", 1)
+	code, errorCodes := process(t, p, reused)
+	assert.Equal(t, AckReject, code)
+	assert.Equal(t, []string{"205"}, errorCodes)
+}
+
+func Test_issueOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantText string
+	}{
+		{
+			name:     "describe the validation errors",
+			err:      validation.Errors{{Field: "diagnosis", Message: "can't be blank"}},
+			wantCode: ErrCodeDataType,
+			wantText: "DG1 1: invalid command: diagnosis: can't be blank",
+		},
+		{
+			name:     "describe the patients not found",
+			err:      patients.ErrPatientNotFound,
+			wantCode: ErrCodeUnknownKey,
+			wantText: "DG1 1: patient not found",
+		},
+		{
+			name:     "hide the internal errors",
+			err:      fmt.Errorf("%w: database is locked", commands.ErrAddingDiagnosis),
+			wantCode: ErrCodeInternal,
+			wantText: "DG1 1: the message could not be applied, send it again later",
+		},
+		{
+			name:     "describe the authorization errors",
+			err:      auth.ErrForbidden,
+			wantCode: ErrCodeNotAuthorized,
+			wantText: "DG1 1: the user is not allowed to perform this action",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := issueOf("DG1 1", tt.err)
+			assert.Equal(t, Issue{Code: tt.wantCode, Text: tt.wantText}, got)
+		})
+	}
+}
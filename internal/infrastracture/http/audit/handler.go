@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidPatientID = errors.New("invalid patient ID")
+)
+
+const (
+	PatientIDQueryParam = "patientId"
+	ActorIDQueryParam   = "actorId"
+)
+
+type Handler struct {
+	auditServices app.AuditServices
+}
+
+func NewHandler(auditServices app.AuditServices) *Handler {
+	return &Handler{
+		auditServices: auditServices,
+	}
+}
+
+type AuditRecordResponse struct {
+	Sequence  uint64     `json:"sequence"`
+	Timestamp time.Time  `json:"timestamp"`
+	ActorID   string     `json:"actor_id"`
+	Action    string     `json:"action"`
+	PatientID *uuid.UUID `json:"patient_id,omitempty"`
+	Outcome   string     `json:"outcome"`
+	RequestID string     `json:"request_id"`
+	PrevHash  string     `json:"prev_hash"`
+	Hash      string     `json:"hash"`
+}
+
+type GetAuditTrailResponse struct {
+	Records []AuditRecordResponse `json:"records"`
+}
+
+type VerifyAuditTrailResponse struct {
+	Valid   bool `json:"valid"`
+	Records int  `json:"records"`
+	// BrokenAt is the sequence of the first record that doesn't fit in the chain, omitted when it is valid
+	BrokenAt uint64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// GetAuditTrail godoc
+//
+//	@Summary		Get audit trail
+//	@Description	Get who read or changed patient data, filtered by patient and/or actor. Only admins and auditors are allowed.
+//	@Tags			audit
+//	@Produce		json
+//	@Param			patientId	query		string	false	"records of this patient"
+//	@Param			actorId		query		string	false	"records of this actor"
+//	@Success		200			{object}	GetAuditTrailResponse
+//	@Failure		400			{object}	render.Problem
+//	@Failure		401			{object}	render.Problem
+//	@Failure		403			{object}	render.Problem
+//	@Failure		500			{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/audit/records [get]
+func (h *Handler) GetAuditTrail(writer http.ResponseWriter, request *http.Request) {
+	params := request.URL.Query()
+	query := queries.GetAuditTrailQuery{
+		ActorID: strings.TrimSpace(params.Get(ActorIDQueryParam)),
+		Actor:   authentication.PrincipalFromContext(request.Context()),
+	}
+
+	if patientIDParam := params.Get(PatientIDQueryParam); patientIDParam != "" {
+		patientID, err := uuid.Parse(patientIDParam)
+		if err != nil {
+			render.Error(writer, request, render.InvalidParam(PatientIDQueryParam, errInvalidPatientID))
+			return
+		}
+		query.PatientID = &patientID
+	}
+
+	records, err := h.auditServices.Queries.GetAuditTrail.Handle(request.Context(), query)
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	response := GetAuditTrailResponse{Records: make([]AuditRecordResponse, 0, len(records))}
+	for _, record := range records {
+		response.Records = append(response.Records, toAuditRecordResponse(record))
+	}
+
+	render.JSON(writer, http.StatusOK, response)
+}
+
+// VerifyAuditTrail godoc
+//
+//	@Summary		Verify audit trail
+//	@Description	Check the hash chain of the whole audit trail, reporting the first record changed or missing. Only admins and auditors are allowed.
+//	@Tags			audit
+//	@Produce		json
+//	@Success		200	{object}	VerifyAuditTrailResponse
+//	@Failure		401	{object}	render.Problem
+//	@Failure		403	{object}	render.Problem
+//	@Failure		500	{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/audit/verify [get]
+func (h *Handler) VerifyAuditTrail(writer http.ResponseWriter, request *http.Request) {
+	verification, err := h.auditServices.Queries.VerifyAuditTrail.Handle(request.Context(), queries.VerifyAuditTrailQuery{
+		Actor: authentication.PrincipalFromContext(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	response := VerifyAuditTrailResponse{Valid: verification.Valid, Records: verification.Records}
+	if verification.BrokenChain != nil {
+		response.BrokenAt = verification.BrokenChain.Sequence
+		response.Reason = verification.BrokenChain.Reason
+	}
+
+	render.JSON(writer, http.StatusOK, response)
+}
+
+func toAuditRecordResponse(record audit.Record) AuditRecordResponse {
+	return AuditRecordResponse{
+		Sequence:  record.Sequence,
+		Timestamp: record.Timestamp,
+		ActorID:   record.ActorID,
+		Action:    string(record.Action),
+		PatientID: record.PatientID,
+		Outcome:   string(record.Outcome),
+		RequestID: record.RequestID,
+		PrevHash:  record.PrevHash,
+		Hash:      record.Hash,
+	}
+}
@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var (
+	patientID = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	auditor   = auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}}
+)
+
+func TestHandler_GetAuditTrail(t *testing.T) {
+	timestamp := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	record := audit.Record{
+		Sequence:  1,
+		Timestamp: timestamp,
+		ActorID:   "practitioner-1",
+		Action:    audit.ActionAddDiagnosis,
+		PatientID: &patientID,
+		Outcome:   audit.OutcomeSuccess,
+		RequestID: "request-1",
+		Hash:      "hash-1",
+	}
+
+	tests := []struct {
+		name       string
+		queryParam string
+		handler    queries.GetAuditTrailHandler
+		wantStatus int
+		wantBody   *GetAuditTrailResponse
+	}{
+		{
+			name:       "return bad request when the patient ID is invalid",
+			queryParam: "patientId=invalid",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return forbidden when the actor can't read the audit trail",
+			queryParam: "actorId=practitioner-1",
+			handler: func() queries.GetAuditTrailHandler {
+				handler := &queries.MockGetAuditTrail{}
+				handler.On("Handle", mock.Anything, queries.GetAuditTrailQuery{ActorID: "practitioner-1", Actor: auditor}).
+					Return(([]audit.Record)(nil), auth.ErrForbidden)
+				return handler
+			}(),
+			wantStatus: 403,
+		},
+		{
+			name:       "return server error when the audit trail can't be read",
+			queryParam: "",
+			handler: func() queries.GetAuditTrailHandler {
+				handler := &queries.MockGetAuditTrail{}
+				handler.On("Handle", mock.Anything, queries.GetAuditTrailQuery{Actor: auditor}).
+					Return(([]audit.Record)(nil), queries.ErrGettingAuditTrail)
+				return handler
+			}(),
+			wantStatus: 500,
+		},
+		{
+			name:       "return the records of the patient and actor",
+			queryParam: "patientId=" + patientID.String() + "&actorId=practitioner-1",
+			handler: func() queries.GetAuditTrailHandler {
+				handler := &queries.MockGetAuditTrail{}
+				handler.On("Handle", mock.Anything, queries.GetAuditTrailQuery{PatientID: &patientID, ActorID: "practitioner-1", Actor: auditor}).
+					Return([]audit.Record{record}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody: &GetAuditTrailResponse{Records: []AuditRecordResponse{{
+				Sequence:  1,
+				Timestamp: timestamp,
+				ActorID:   "practitioner-1",
+				Action:    "diagnoses:add",
+				PatientID: &patientID,
+				Outcome:   "success",
+				RequestID: "request-1",
+				Hash:      "hash-1",
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.AuditServices{Queries: app.AuditQueries{GetAuditTrail: tt.handler}})
+			r, _ := http.NewRequest("GET", "/audit/records?"+tt.queryParam, nil)
+			r = r.WithContext(authentication.WithPrincipal(r.Context(), auditor))
+			response := httptest.NewRecorder()
+			h.GetAuditTrail(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantBody != nil {
+				got := GetAuditTrailResponse{}
+				assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+				assert.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestHandler_VerifyAuditTrail(t *testing.T) {
+	tests := []struct {
+		name         string
+		verification queries.Verification
+		err          error
+		wantStatus   int
+		wantBody     *VerifyAuditTrailResponse
+	}{
+		{
+			name:       "return unauthorized when there is no authenticated actor",
+			err:        auth.ErrUnauthenticated,
+			wantStatus: 401,
+		},
+		{
+			name:         "report a valid chain",
+			verification: queries.Verification{Valid: true, Records: 3},
+			wantStatus:   200,
+			wantBody:     &VerifyAuditTrailResponse{Valid: true, Records: 3},
+		},
+		{
+			name: "report where the chain is broken",
+			verification: queries.Verification{Valid: false, Records: 3, BrokenChain: &audit.BrokenChainError{
+				Sequence: 2,
+				Reason:   "hash doesn't match the record content",
+			}},
+			wantStatus: 200,
+			wantBody: &VerifyAuditTrailResponse{
+				Valid:    false,
+				Records:  3,
+				BrokenAt: 2,
+				Reason:   "hash doesn't match the record content",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &queries.MockVerifyAuditTrail{}
+			handler.On("Handle", mock.Anything, queries.VerifyAuditTrailQuery{Actor: auditor}).Return(tt.verification, tt.err)
+			h := NewHandler(app.AuditServices{Queries: app.AuditQueries{VerifyAuditTrail: handler}})
+			r, _ := http.NewRequest("GET", "/audit/verify", nil)
+			r = r.WithContext(authentication.WithPrincipal(r.Context(), auditor))
+			response := httptest.NewRecorder()
+			h.VerifyAuditTrail(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantBody != nil {
+				got := VerifyAuditTrailResponse{}
+				assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+				assert.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
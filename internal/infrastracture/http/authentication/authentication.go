@@ -0,0 +1,70 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrMissingToken = fmt.Errorf("%w: missing bearer token", auth.ErrUnauthenticated)
+	ErrInvalidToken = fmt.Errorf("%w: invalid bearer token", auth.ErrUnauthenticated)
+)
+
+// Authenticator turns the bearer token of a request into the principal it identifies.
+type Authenticator interface {
+	Authenticate(token string) (auth.Principal, error)
+}
+
+type principalKey struct{}
+
+// Middleware rejects with 401 the requests without a valid bearer token, and stores the authenticated
+// principal in the request context for the handlers.
+func Middleware(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			token, found := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+			if !found || strings.TrimSpace(token) == "" {
+				unauthorized(writer, request, ErrMissingToken)
+				return
+			}
+
+			principal, err := authenticator.Authenticate(strings.TrimSpace(token))
+			if err != nil {
+				slog.Info("request authentication failed", "err", err, "path", request.URL.Path)
+				unauthorized(writer, request, ErrInvalidToken)
+				return
+			}
+
+			next.ServeHTTP(writer, request.WithContext(WithPrincipal(request.Context(), principal)))
+		})
+	}
+}
+
+func WithPrincipal(ctx context.Context, principal auth.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal, or an anonymous one when there is none.
+func PrincipalFromContext(ctx context.Context) auth.Principal {
+	principal, _ := ctx.Value(principalKey{}).(auth.Principal)
+	return principal
+}
+
+func unauthorized(writer http.ResponseWriter, request *http.Request, err error) {
+	writer.Header().Set("WWW-Authenticate", `Bearer realm="diagnoses-api"`)
+	render.Error(writer, request, err)
+}
+
+// StaticAuthenticator authenticates every token as the same principal. It is meant for local development only.
+type StaticAuthenticator struct {
+	Principal auth.Principal
+}
+
+func (s StaticAuthenticator) Authenticate(string) (auth.Principal, error) {
+	return s.Principal, nil
+}
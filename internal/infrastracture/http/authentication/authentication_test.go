@@ -0,0 +1,63 @@
+package authentication
+
+import (
+	"errors"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeAuthenticator map[string]auth.Principal
+
+func (f fakeAuthenticator) Authenticate(token string) (auth.Principal, error) {
+	principal, ok := f[token]
+	if !ok {
+		return auth.Principal{}, errors.New("unknown token")
+	}
+	return principal, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+	authenticator := fakeAuthenticator{"valid-token": clinician}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantPrincipal auth.Principal
+	}{
+		{name: "reject request without token", authorization: "", wantStatus: 401},
+		{name: "reject non bearer authorization", authorization: "Basic dXNlcjpwYXNz", wantStatus: 401},
+		{name: "reject invalid token", authorization: "Bearer invalid-token", wantStatus: 401},
+		{
+			name:          "store the principal of a valid token",
+			authorization: "Bearer valid-token",
+			wantStatus:    200,
+			wantPrincipal: clinician,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got auth.Principal
+			next := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				got = PrincipalFromContext(request.Context())
+			})
+
+			request, _ := http.NewRequest("GET", "/api/v1/patients", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			response := httptest.NewRecorder()
+			Middleware(authenticator)(next).ServeHTTP(response, request)
+
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Equal(t, tt.wantPrincipal, got)
+			if tt.wantStatus == 401 {
+				assert.NotEmpty(t, response.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package authentication
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"strings"
+	"time"
+)
+
+const DefaultRolesClaim = "roles"
+
+// JWTConfig sets how tokens are validated. At least one of HMACSecret or RSAPublicKey is required.
+type JWTConfig struct {
+	// HMACSecret validates HS256, HS384 and HS512 tokens
+	HMACSecret []byte
+	// RSAPublicKey validates RS256, RS384 and RS512 tokens
+	RSAPublicKey *rsa.PublicKey
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// RolesClaim holds the roles of the user, as a list or a space separated string. DefaultRolesClaim when empty.
+	RolesClaim string
+}
+
+// JWTAuthenticator validates signed JWTs locally. The sub claim is used as principal ID and the roles claim
+// is mapped to auth roles, ignoring the unknown ones.
+type JWTAuthenticator struct {
+	config  JWTConfig
+	methods []string
+}
+
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	var methods []string
+	if len(config.HMACSecret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+
+	if config.RSAPublicKey != nil {
+		methods = append(methods, "RS256", "RS384", "RS512")
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("a HMAC secret or a RSA public key is required to validate tokens")
+	}
+
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+
+	return &JWTAuthenticator{config: config, methods: methods}, nil
+}
+
+// ParseRSAPublicKey parses a PEM encoded RSA public key.
+func ParseRSAPublicKey(pem []byte) (*rsa.PublicKey, error) {
+	return jwt.ParseRSAPublicKeyFromPEM(pem)
+}
+
+func (a *JWTAuthenticator) Authenticate(token string) (auth.Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(a.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if a.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(a.config.Issuer))
+	}
+	if a.config.Audience != "" {
+		options = append(options, jwt.WithAudience(a.config.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, a.key, options...)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return auth.Principal{}, errors.New("the token has no subject")
+	}
+
+	return auth.Principal{ID: subject, Roles: parseRoles(claims[a.config.RolesClaim])}, nil
+}
+
+func (a *JWTAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return a.config.HMACSecret, nil
+	case *jwt.SigningMethodRSA:
+		return a.config.RSAPublicKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+func parseRoles(claim any) []auth.Role {
+	var names []string
+	switch value := claim.(type) {
+	case string:
+		names = strings.Fields(value)
+	case []any:
+		for _, v := range value {
+			if name, ok := v.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	roles := make([]auth.Role, 0, len(names))
+	for _, name := range names {
+		if role, ok := auth.ParseRole(strings.ToLower(name)); ok {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
@@ -0,0 +1,129 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestJWTAuthenticator_Authenticate(t *testing.T) {
+	secret := []byte("test-secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherRSAKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	authenticator, err := NewJWTAuthenticator(JWTConfig{
+		HMACSecret:   secret,
+		RSAPublicKey: &rsaKey.PublicKey,
+		Issuer:       "https://idp.example.com",
+		Audience:     "diagnoses-api",
+	})
+	assert.Nil(t, err)
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "practitioner-1",
+			"iss":   "https://idp.example.com",
+			"aud":   "diagnoses-api",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": []string{"clinician", "Nurse", "surgeon"},
+		}
+	}
+	withClaim := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		claims[name] = value
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    auth.Principal
+		wantErr bool
+	}{
+		{
+			name:  "accept HMAC signed token mapping the known roles",
+			token: sign(t, jwt.SigningMethodHS256, secret, validClaims()),
+			want:  auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician, auth.RoleNurse}},
+		},
+		{
+			name:  "accept RSA signed token",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, validClaims()),
+			want:  auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician, auth.RoleNurse}},
+		},
+		{
+			name:  "accept roles as a space separated string",
+			token: sign(t, jwt.SigningMethodHS256, secret, withClaim("roles", "auditor admin")),
+			want:  auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleAuditor, auth.RoleAdmin}},
+		},
+		{
+			name:    "reject token signed with another secret",
+			token:   sign(t, jwt.SigningMethodHS256, []byte("other-secret"), validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "reject token signed with another RSA key",
+			token:   sign(t, jwt.SigningMethodRS256, otherRSAKey, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "reject unsigned token",
+			token:   sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "reject expired token",
+			token:   sign(t, jwt.SigningMethodHS256, secret, withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+			wantErr: true,
+		},
+		{
+			name: "reject token without expiration",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return sign(t, jwt.SigningMethodHS256, secret, claims)
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "reject token from another issuer",
+			token:   sign(t, jwt.SigningMethodHS256, secret, withClaim("iss", "https://evil.example.com")),
+			wantErr: true,
+		},
+		{
+			name:    "reject token for another audience",
+			token:   sign(t, jwt.SigningMethodHS256, secret, withClaim("aud", "billing-api")),
+			wantErr: true,
+		},
+		{
+			name:    "reject token without subject",
+			token:   sign(t, jwt.SigningMethodHS256, secret, withClaim("sub", "")),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authenticator.Authenticate(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewJWTAuthenticator(t *testing.T) {
+	_, err := NewJWTAuthenticator(JWTConfig{})
+	assert.NotNil(t, err)
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	return token
+}
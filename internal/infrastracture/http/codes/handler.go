@@ -0,0 +1,93 @@
+package codes
+
+import (
+	"errors"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/codes/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	errMissingText  = errors.New("the q query param is required")
+	errInvalidLimit = errors.New("invalid limit, expected a number between 1 and 100")
+)
+
+const (
+	TextQueryParam  = "q"
+	LimitQueryParam = "limit"
+)
+
+type Handler struct {
+	codeServices app.CodeServices
+}
+
+func NewHandler(codeServices app.CodeServices) *Handler {
+	return &Handler{
+		codeServices: codeServices,
+	}
+}
+
+type CodeResponse struct {
+	Code    string `json:"code" example:"J45.0"`
+	Display string `json:"display" example:"Predominantly allergic asthma"`
+}
+
+type SearchCodesResponse struct {
+	Codes []CodeResponse `json:"codes"`
+}
+
+// SearchICD10 godoc
+//
+//	@Summary		Search ICD-10 codes
+//	@Description	Search ICD-10 codes by code prefix, such as J45, or by keywords of their display, such as allergic asthma.
+//	@Description	Codes matching the prefix come first.
+//	@Tags			codes
+//	@Produce		json
+//	@Param			q		query		string	true	"code prefix or keywords"
+//	@Param			limit	query		int		false	"maximum number of codes, 20 by default and 100 at most"
+//	@Success		200		{object}	SearchCodesResponse
+//	@Failure		400		{object}	render.Problem
+//	@Failure		401		{object}	render.Problem
+//	@Failure		403		{object}	render.Problem
+//	@Failure		500		{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/codes/icd10 [get]
+func (h *Handler) SearchICD10(writer http.ResponseWriter, request *http.Request) {
+	params := request.URL.Query()
+	text := strings.TrimSpace(params.Get(TextQueryParam))
+	if text == "" {
+		render.Error(writer, request, render.InvalidParam(TextQueryParam, errMissingText))
+		return
+	}
+
+	var limit int
+	if limitParam := params.Get(LimitQueryParam); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > queries.MaxSearchLimit {
+			render.Error(writer, request, render.InvalidParam(LimitQueryParam, errInvalidLimit))
+			return
+		}
+	}
+
+	concepts, err := h.codeServices.Queries.SearchICD10.Handle(request.Context(), queries.SearchICD10Query{
+		Text:  text,
+		Limit: limit,
+		Actor: authentication.PrincipalFromContext(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	response := SearchCodesResponse{Codes: make([]CodeResponse, 0, len(concepts))}
+	for _, concept := range concepts {
+		response.Codes = append(response.Codes, CodeResponse{Code: concept.Code, Display: concept.Display})
+	}
+
+	render.JSON(writer, http.StatusOK, response)
+}
@@ -0,0 +1,102 @@
+package codes
+
+import (
+	"encoding/json"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/codes/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var clinician = auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+
+func TestHandler_SearchICD10(t *testing.T) {
+	tests := []struct {
+		name       string
+		queryParam string
+		handler    queries.SearchICD10Handler
+		wantStatus int
+		wantBody   *SearchCodesResponse
+	}{
+		{
+			name:       "return bad request when the text is missing",
+			queryParam: "q=%20",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the limit is too big",
+			queryParam: "q=asthma&limit=101",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the limit isn't a number",
+			queryParam: "q=asthma&limit=ten",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return forbidden when the actor can't read diagnoses",
+			queryParam: "q=asthma",
+			handler: func() queries.SearchICD10Handler {
+				handler := &queries.MockSearchICD10{}
+				handler.On("Handle", mock.Anything, queries.SearchICD10Query{Text: "asthma", Actor: clinician}).
+					Return(([]codes.Concept)(nil), auth.ErrForbidden)
+				return handler
+			}(),
+			wantStatus: 403,
+		},
+		{
+			name:       "return the codes found",
+			queryParam: "q=asthma&limit=2",
+			handler: func() queries.SearchICD10Handler {
+				handler := &queries.MockSearchICD10{}
+				handler.On("Handle", mock.Anything, queries.SearchICD10Query{Text: "asthma", Limit: 2, Actor: clinician}).
+					Return([]codes.Concept{
+						{Code: "J45", Display: "Asthma"},
+						{Code: "J45.0", Display: "Predominantly allergic asthma"},
+					}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody: &SearchCodesResponse{Codes: []CodeResponse{
+				{Code: "J45", Display: "Asthma"},
+				{Code: "J45.0", Display: "Predominantly allergic asthma"},
+			}},
+		},
+		{
+			name:       "return an empty list when nothing matches",
+			queryParam: "q=fracture",
+			handler: func() queries.SearchICD10Handler {
+				handler := &queries.MockSearchICD10{}
+				handler.On("Handle", mock.Anything, queries.SearchICD10Query{Text: "fracture", Actor: clinician}).
+					Return([]codes.Concept{}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody:   &SearchCodesResponse{Codes: []CodeResponse{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.CodeServices{Queries: app.CodeQueries{SearchICD10: tt.handler}})
+			r, _ := http.NewRequest("GET", "/codes/icd10?"+tt.queryParam, nil)
+			r = r.WithContext(authentication.WithPrincipal(r.Context(), clinician))
+			response := httptest.NewRecorder()
+			h.SearchICD10(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantBody != nil {
+				got := SearchCodesResponse{}
+				assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+				assert.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
@@ -1,30 +1,55 @@
 package diagnoses
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"github.com/juanmabaracat/diagnosis-service/internal/app"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
-	"log/slog"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
 	errInvalidID          = errors.New("invalid ID")
-	errInvalidDiagnosis   = errors.New("diagnosis cannot be empty")
-	errPatientNotFound    = errors.New("there no patient for the ID supplied")
-	errProcessingRequest  = errors.New("error processing the request")
 	errInvalidPatientName = errors.New("invalid patient name")
+	errInvalidLegalID     = errors.New("invalid patient legal ID")
+	errMultiplePatients   = errors.New("only one of patientId, legalId and patientName can be supplied")
+	errAmbiguousPatient   = errors.New("several patients have the name supplied, search by patientId or legalId instead")
+	errMissingFilter      = errors.New("a patient, date or code filter is required")
+	errInvalidDate        = errors.New("invalid date, expected RFC 3339 or YYYY-MM-DD format")
+	errInvalidTimezone    = errors.New("invalid timezone, expected an IANA name such as America/Argentina/Buenos_Aires")
+	errInvalidDateRange   = errors.New("the from date must not be after the to date")
+	errInvalidLimit       = fmt.Errorf("limit must be a number between 1 and %d", queries.MaxPageSize)
+	errInvalidCursor      = errors.New("invalid cursor, use the next_cursor of a previous response")
+	errInvalidSort        = errors.New("invalid sort, expected created_at:asc or created_at:desc")
+	errInvalidCode        = errors.New("invalid ICD-10 code, expected a code such as J45.0 or a prefix such as J45")
 )
 
 const (
 	PatientIDURLParam     = "patientID"
 	PatientNameQueryParam = "patientName"
+	PatientIDQueryParam   = "patientId"
+	LegalIDQueryParam     = "legalId"
+	FromQueryParam        = "from"
+	ToQueryParam          = "to"
+	TimezoneQueryParam    = "tz"
+	LimitQueryParam       = "limit"
+	CursorQueryParam      = "cursor"
+	SortQueryParam        = "sort"
+	CodeQueryParam        = "code"
+	CodePrefixQueryParam  = "codePrefix"
+
+	plainDateLayout = "2006-01-02"
 )
 
 type Handler struct {
@@ -38,133 +63,392 @@ func NewHandler(diagnosisServices app.DiagnosisServices) *Handler {
 }
 
 type AddDiagnosisRequest struct {
-	Diagnosis    string  `json:"diagnosis"`
-	Prescription *string `json:"prescription"`
+	Diagnosis    string               `json:"diagnosis"`
+	Prescription *PrescriptionRequest `json:"prescription"`
+	Coding       *CodingRequest       `json:"coding"`
+}
+
+// CodingRequest codes the diagnosis in ICD-10, the display is taken from the ICD-10 table.
+type CodingRequest struct {
+	// System defaults to ICD-10, which is the only system supported
+	System string `json:"system" example:"http://hl7.org/fhir/sid/icd-10"`
+	Code   string `json:"code" example:"J45.0"`
 }
 
 // AddDiagnosis godoc
 //
 //	@Summary		Add patient diagnosis
-//	@Description	Add patient diagnosis
+//	@Description	Add patient diagnosis. Only clinicians can add diagnoses, which record them as practitioner.
+//	@Description	The created diagnosis is returned, along with its URL in the Location header.
+//	@Description	The diagnosis can be coded in ICD-10, the code must be in the ICD-10 table.
+//	@Description	The prescription is structured in medications, a string is still accepted as its notes.
+//	@Description	Retries sent with the same Idempotency-Key get the first response replayed, with the Idempotent-Replayed header.
 //	@Tags			diagnosis
 //	@Accept			json
 //	@Produce		json
 //	@Param			patientID			path		string		true	"patient ID"
 //	@Param			diagnosis body		AddDiagnosisRequest		true	"add diagnosis"
-//	@Success		201	{string}		status created
-//	@Failure		400	{object}		HTTPError
-//	@Failure		404	{object}		HTTPError
-//	@Failure		500	{object}		HTTPError
+//	@Param			Idempotency-Key		header		string		false	"key making the request safe to retry, up to 255 characters"
+//	@Success		201	{object}		diagnoses.Diagnosis
+//	@Header			201	{string}		Location	"URL of the created diagnosis"
+//	@Failure		400	{object}		render.Problem
+//	@Failure		401	{object}		render.Problem
+//	@Failure		403	{object}		render.Problem
+//	@Failure		404	{object}		render.Problem
+//	@Failure		409	{object}		render.Problem
+//	@Failure		413	{object}		render.Problem
+//	@Failure		422	{object}		render.Problem
+//	@Failure		500	{object}		render.Problem
+//	@Security		BearerAuth
 //	@Router			/patient/{patientID}/diagnoses [post]
 func (h *Handler) AddDiagnosis(writer http.ResponseWriter, request *http.Request) {
 	addDiagnosisRequest := AddDiagnosisRequest{}
 	patientIDParam := chi.URLParam(request, PatientIDURLParam)
 	patientID, parseErr := uuid.Parse(patientIDParam)
 	if parseErr != nil {
-		writeError(writer, http.StatusBadRequest, errInvalidID)
+		render.Error(writer, request, render.InvalidParam(PatientIDURLParam, errInvalidID))
 		return
 	}
 
-	decodeErr := json.NewDecoder(request.Body).Decode(&addDiagnosisRequest)
-	if decodeErr != nil {
-		writeError(writer, http.StatusBadRequest, decodeErr)
+	if err := render.DecodeJSON(writer, request, &addDiagnosisRequest); err != nil {
+		render.Error(writer, request, err)
 		return
 	}
 
-	addDiagnosisRequest.Diagnosis = strings.TrimSpace(addDiagnosisRequest.Diagnosis)
-	if addDiagnosisRequest.Diagnosis == "" {
-		writeError(writer, http.StatusBadRequest, errInvalidDiagnosis)
-		return
-	}
-
-	err := h.diagnosesServices.Commands.AddPatientDiagnosisHandler.Handle(commands.AddPatientDiagnosis{
+	diagnosis, err := h.diagnosesServices.Commands.AddPatientDiagnosisHandler.Handle(request.Context(), commands.AddPatientDiagnosis{
 		PatientID:    patientID,
-		Diagnosis:    addDiagnosisRequest.Diagnosis,
-		Prescription: addDiagnosisRequest.Prescription,
+		Diagnosis:    strings.TrimSpace(addDiagnosisRequest.Diagnosis),
+		Prescription: toPrescription(addDiagnosisRequest.Prescription),
+		Coding:       toCoding(addDiagnosisRequest.Coding),
+		Actor:        authentication.PrincipalFromContext(request.Context()),
+		RequestID:    middleware.GetReqID(request.Context()),
 	})
 
 	if err != nil {
-		slog.Error("error handling request for adding diagnosis", "error", err)
-		if errors.Is(err, commands.ErrPatientNotFound) {
-			writeError(writer, http.StatusNotFound, errPatientNotFound)
-			return
-		}
-		writeError(writer, http.StatusInternalServerError, errProcessingRequest)
+		render.Error(writer, request, err)
 		return
 	}
 
-	writer.WriteHeader(http.StatusCreated)
-	return
+	writer.Header().Set("Location", "/api/v1/diagnoses/"+diagnosis.ID.String())
+	render.JSON(writer, http.StatusCreated, diagnosis)
+}
+
+func toCoding(request *CodingRequest) *diagnoses.Coding {
+	if request == nil {
+		return nil
+	}
+	return &diagnoses.Coding{System: request.System, Code: request.Code}
 }
 
 type GetDiagnosesResponse struct {
-	PatientName string                 `json:"patient_name"`
+	// PatientName is the name searched, it is omitted when the diagnoses weren't searched by name
+	PatientName string                 `json:"patient_name,omitempty"`
 	Diagnoses   []*diagnoses.Diagnosis `json:"patient_diagnoses"`
+	// NextCursor is sent as cursor to get the next page, it is omitted on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is the number of diagnoses matching the filters, across all pages
+	Total int `json:"total"`
+}
+
+type GetDiagnosisResponse struct {
+	Diagnosis *diagnoses.Diagnosis   `json:"diagnosis"`
+	Patient   PatientSummaryResponse `json:"patient"`
+}
+
+// PatientSummaryResponse identifies a patient, without their contact information.
+type PatientSummaryResponse struct {
+	ID        uuid.UUID `json:"id"`
+	LegalID   string    `json:"legal_id"`
+	Name      string    `json:"name"`
+	BirthDate string    `json:"birth_date,omitempty" example:"1980-01-31"`
+}
+
+// AmbiguousPatientResponse is the problem returned when several patients have the name searched.
+type AmbiguousPatientResponse struct {
+	render.Problem
+	Candidates []PatientSummaryResponse `json:"candidates"`
 }
 
 // GetDiagnoses godoc
 //
 //	@Summary		Get patient diagnoses
-//	@Description	Get patient diagnoses
+//	@Description	Get diagnoses filtered by patient, creation date and/or ICD-10 code. At least one filter is required.
+//	@Description	The patient is identified by only one of patientId, legalId or patientName. When several patients
+//	@Description	have the name the response is a 409 listing them, to search again by the ID of the right one.
+//	@Description	Dates accept RFC 3339 timestamps or plain YYYY-MM-DD dates, which are read in the tz timezone.
 //	@Tags			diagnosis
 //	@Accept			json
 //	@Produce		json
-//	@Param			patientName				query					string	true	"diagnoses search by patient name"
+//	@Param			patientId				query					string	false	"diagnoses search by patient ID"
+//	@Param			legalId					query					string	false	"diagnoses search by patient legal ID"
+//	@Param			patientName				query					string	false	"diagnoses search by patient name"
+//	@Param			from					query					string	false	"diagnoses created at or after this date"
+//	@Param			to						query					string	false	"diagnoses created at or before this date"
+//	@Param			tz						query					string	false	"IANA timezone for plain dates, UTC by default"
+//	@Param			code					query					string	false	"diagnoses coded with this ICD-10 code"
+//	@Param			codePrefix				query					string	false	"diagnoses coded with an ICD-10 code starting with this prefix"
+//	@Param			limit					query					int		false	"page size, 50 by default and 500 at most"
+//	@Param			cursor					query					string	false	"next_cursor of the previous page"
+//	@Param			sort					query					string	false	"created_at:asc (default) or created_at:desc"
 //	@Success		200	{object}			GetDiagnosesResponse
-//	@Failure		400	{object}			HTTPError
-//	@Failure		404	{object}			HTTPError
-//	@Failure		500	{object}			HTTPError
+//	@Failure		400	{object}			render.Problem
+//	@Failure		401	{object}			render.Problem
+//	@Failure		403	{object}			render.Problem
+//	@Failure		404	{object}			render.Problem
+//	@Failure		409	{object}			AmbiguousPatientResponse
+//	@Failure		500	{object}			render.Problem
+//	@Security		BearerAuth
 //	@Router			/patient/diagnoses 		[get]
 func (h *Handler) GetDiagnoses(writer http.ResponseWriter, request *http.Request) {
-	patientName := request.URL.Query().Get(PatientNameQueryParam)
-	patientName = strings.TrimSpace(patientName)
-	if patientName == "" {
-		writeError(writer, http.StatusBadRequest, errInvalidPatientName)
+	params := request.URL.Query()
+	patientName := strings.TrimSpace(params.Get(PatientNameQueryParam))
+	if params.Has(PatientNameQueryParam) && patientName == "" {
+		render.Error(writer, request, render.InvalidParam(PatientNameQueryParam, errInvalidPatientName))
+		return
+	}
+
+	legalID := strings.TrimSpace(params.Get(LegalIDQueryParam))
+	if params.Has(LegalIDQueryParam) && legalID == "" {
+		render.Error(writer, request, render.InvalidParam(LegalIDQueryParam, errInvalidLegalID))
 		return
 	}
 
-	pDiagnoses, err := h.diagnosesServices.Queries.GetDiagnoses.Handle(queries.GetDiagnosesQuery{PatientName: patientName})
+	var patientID *uuid.UUID
+	if params.Has(PatientIDQueryParam) {
+		id, err := uuid.Parse(params.Get(PatientIDQueryParam))
+		if err != nil {
+			render.Error(writer, request, render.InvalidParam(PatientIDQueryParam, errInvalidID))
+			return
+		}
+		patientID = &id
+	}
+
+	patientFilters := countSet(patientID != nil, legalID != "", patientName != "")
+	if patientFilters > 1 {
+		render.Error(writer, request, render.NewRequestError(http.StatusBadRequest, "", errMultiplePatients.Error()))
+		return
+	}
+
+	from, to, dateErr := parseDateRange(params.Get(FromQueryParam), params.Get(ToQueryParam), params.Get(TimezoneQueryParam))
+	if dateErr != nil {
+		render.Error(writer, request, dateErr)
+		return
+	}
+
+	code, codePrefix, codeErr := parseCodes(params.Get(CodeQueryParam), params.Get(CodePrefixQueryParam))
+	if codeErr != nil {
+		render.Error(writer, request, codeErr)
+		return
+	}
+
+	if patientFilters == 0 && from == nil && to == nil && code == "" && codePrefix == "" {
+		render.Error(writer, request, render.NewRequestError(http.StatusBadRequest, "", errMissingFilter.Error()))
+		return
+	}
+
+	query, pageErr := parsePage(params.Get(LimitQueryParam), params.Get(CursorQueryParam), params.Get(SortQueryParam))
+	if pageErr != nil {
+		render.Error(writer, request, pageErr)
+		return
+	}
+	query.PatientID = patientID
+	query.LegalID = legalID
+	query.PatientName = patientName
+	query.From = from
+	query.To = to
+	query.Code = code
+	query.CodePrefix = codePrefix
+	query.Actor = authentication.PrincipalFromContext(request.Context())
+	query.RequestID = middleware.GetReqID(request.Context())
+
+	page, err := h.diagnosesServices.Queries.GetDiagnoses.Handle(request.Context(), query)
 	if err != nil {
-		if errors.Is(err, commands.ErrPatientNotFound) {
-			slog.Info("patient not found", "patientName", patientName)
-			writeError(writer, http.StatusNotFound, errPatientNotFound)
+		var ambiguous *queries.AmbiguousPatientError
+		if errors.As(err, &ambiguous) {
+			render.WriteProblem(writer, http.StatusConflict, toAmbiguousPatientResponse(request, ambiguous))
 			return
 		}
 
-		slog.Error("error getting diagnoses", "err", err)
-		writeError(writer, http.StatusInternalServerError, errProcessingRequest)
+		render.Error(writer, request, err)
 		return
 	}
 
-	encodeErr := json.NewEncoder(writer).Encode(GetDiagnosesResponse{
+	response := GetDiagnosesResponse{
 		PatientName: patientName,
-		Diagnoses:   pDiagnoses,
+		Diagnoses:   page.Diagnoses,
+		Total:       page.Total,
+	}
+	if page.Next != nil {
+		response.NextCursor = page.Next.String()
+	}
+
+	render.JSON(writer, http.StatusOK, response)
+}
+
+// GetDiagnosisByID godoc
+//
+//	@Summary		Get diagnosis
+//	@Description	Get the current version of the diagnosis along with its patient. Diagnoses entered in error are
+//	@Description	not found, their versions are still returned by the diagnosis history.
+//	@Tags			diagnosis
+//	@Produce		json
+//	@Param			diagnosisID	path		string	true	"diagnosis ID"
+//	@Success		200			{object}	GetDiagnosisResponse
+//	@Failure		400			{object}	render.Problem
+//	@Failure		401			{object}	render.Problem
+//	@Failure		403			{object}	render.Problem
+//	@Failure		404			{object}	render.Problem
+//	@Failure		500			{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/diagnoses/{diagnosisID} [get]
+func (h *Handler) GetDiagnosisByID(writer http.ResponseWriter, request *http.Request) {
+	diagnosisID, parseErr := uuid.Parse(chi.URLParam(request, DiagnosisIDURLParam))
+	if parseErr != nil {
+		render.Error(writer, request, render.InvalidParam(DiagnosisIDURLParam, errInvalidID))
+		return
+	}
+
+	found, err := h.diagnosesServices.Queries.GetDiagnosisByID.Handle(request.Context(), queries.GetDiagnosisByIDQuery{
+		DiagnosisID: diagnosisID,
+		Actor:       authentication.PrincipalFromContext(request.Context()),
+		RequestID:   middleware.GetReqID(request.Context()),
 	})
-	if encodeErr != nil {
-		slog.Error("error encoding get diagnoses response", "encodeErr", encodeErr)
-		writeError(writer, http.StatusInternalServerError, errProcessingRequest)
+	if err != nil {
+		render.Error(writer, request, err)
 		return
 	}
 
-	writer.WriteHeader(http.StatusOK)
-	return
+	render.JSON(writer, http.StatusOK, GetDiagnosisResponse{
+		Diagnosis: found.Diagnosis,
+		Patient:   toPatientSummary(found.Patient),
+	})
 }
 
-func writeError(writer http.ResponseWriter, code int, err error) {
-	writer.WriteHeader(code)
-	httpErr := HTTPError{
-		Code:    code,
-		Message: err.Error(),
+func toAmbiguousPatientResponse(request *http.Request, err *queries.AmbiguousPatientError) AmbiguousPatientResponse {
+	response := AmbiguousPatientResponse{
+		Problem:    render.NewProblem(request, err),
+		Candidates: make([]PatientSummaryResponse, 0, len(err.Candidates)),
 	}
-	errEncode := json.NewEncoder(writer).Encode(httpErr)
-	if errEncode != nil {
-		slog.Error("error encoding http error", "err", errEncode)
-		return
+	response.Detail = errAmbiguousPatient.Error()
+	for _, patient := range err.Candidates {
+		response.Candidates = append(response.Candidates, toPatientSummary(patient))
+	}
+
+	return response
+}
+
+func toPatientSummary(patient *patients.Patient) PatientSummaryResponse {
+	summary := PatientSummaryResponse{ID: patient.ID, LegalID: patient.LegalID, Name: patient.Name}
+	if patient.BirthDate != nil {
+		summary.BirthDate = patient.BirthDate.Format(plainDateLayout)
+	}
+	return summary
+}
+
+func countSet(conditions ...bool) int {
+	count := 0
+	for _, condition := range conditions {
+		if condition {
+			count++
+		}
+	}
+	return count
+}
+
+func parseCodes(codeParam, codePrefixParam string) (string, string, error) {
+	var code, codePrefix string
+	var err error
+	if strings.TrimSpace(codeParam) != "" {
+		if code, err = diagnoses.NormalizeICD10(codeParam); err != nil {
+			return "", "", render.InvalidParam(CodeQueryParam, errInvalidCode)
+		}
 	}
+
+	if strings.TrimSpace(codePrefixParam) != "" {
+		if codePrefix, err = diagnoses.NormalizeICD10Prefix(codePrefixParam); err != nil {
+			return "", "", render.InvalidParam(CodePrefixQueryParam, errInvalidCode)
+		}
+	}
+
+	return code, codePrefix, nil
 }
 
-// HTTP HTTPError
-type HTTPError struct {
-	Code    int    `json:"code" example:"400"`
-	Message string `json:"message" example:"status bad request"`
+func parsePage(limitParam, cursorParam, sortParam string) (queries.GetDiagnosesQuery, error) {
+	query := queries.GetDiagnosesQuery{}
+	if limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > queries.MaxPageSize {
+			return queries.GetDiagnosesQuery{}, render.InvalidParam(LimitQueryParam, errInvalidLimit)
+		}
+		query.Limit = limit
+	}
+
+	if cursorParam != "" {
+		cursor, err := diagnoses.ParseCursor(cursorParam)
+		if err != nil {
+			return queries.GetDiagnosesQuery{}, render.InvalidParam(CursorQueryParam, errInvalidCursor)
+		}
+		query.Cursor = &cursor
+	}
+
+	switch sortParam {
+	case "", "created_at:asc":
+		query.Order = diagnoses.SortAscending
+	case "created_at:desc":
+		query.Order = diagnoses.SortDescending
+	default:
+		return queries.GetDiagnosesQuery{}, render.InvalidParam(SortQueryParam, errInvalidSort)
+	}
+
+	return query, nil
+}
+
+// parseDateRange reads plain dates in the timezone as whole days, so to=2024-03-01 includes March 1st.
+func parseDateRange(fromParam, toParam, tzParam string) (*time.Time, *time.Time, error) {
+	location := time.UTC
+	if tzParam != "" {
+		loc, err := time.LoadLocation(tzParam)
+		if err != nil {
+			return nil, nil, render.InvalidParam(TimezoneQueryParam, errInvalidTimezone)
+		}
+		location = loc
+	}
+
+	from, err := parseDate(FromQueryParam, fromParam, location, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	to, err := parseDate(ToQueryParam, toParam, location, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if from != nil && to != nil && from.After(*to) {
+		return nil, nil, render.InvalidParam(FromQueryParam, errInvalidDateRange)
+	}
+
+	return from, to, nil
+}
+
+func parseDate(param, value string, location *time.Location, endOfDay bool) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return &date, nil
+	}
+
+	date, err := time.ParseInLocation(plainDateLayout, value, location)
+	if err != nil {
+		return nil, render.InvalidParam(param, errInvalidDate)
+	}
+
+	if endOfDay {
+		date = time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, location).Add(-time.Nanosecond)
+	}
+
+	return &date, nil
 }
@@ -4,26 +4,48 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
 	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestHandler_AddDiagnosis(t *testing.T) {
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+	nurse := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	created := &diagnoses.Diagnosis{
+		ID:             uuid.MustParse("22222222-2222-2222-2222-222222222222"),
+		Description:    "test diagnosis",
+		PatientID:      uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		CreatedAt:      time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC),
+		PractitionerID: clinician.ID,
+		Version:        1,
+		Status:         diagnoses.StatusFinal,
+	}
+
 	tests := []struct {
 		name       string
 		handler    commands.AddPatientDiagnosisHandler
+		actor      auth.Principal
 		body       interface{}
 		PatientID  string
 		wantStatus int
-		wantErr    *HTTPError
+		wantErr    *render.Problem
+		want       *diagnoses.Diagnosis
 	}{
 		{
 			name:    "return bad request on invalid patient id",
@@ -34,35 +56,50 @@ func TestHandler_AddDiagnosis(t *testing.T) {
 			},
 			PatientID:  "",
 			wantStatus: 400,
-			wantErr: &HTTPError{
-				Code:    400,
-				Message: errInvalidID.Error(),
+			wantErr: &render.Problem{
+				Type:     render.TypeInvalidRequest,
+				Title:    "The request is not valid",
+				Status:   400,
+				Detail:   errInvalidID.Error(),
+				Instance: "/patients//diagnoses",
+				Errors:   []render.FieldError{{Field: PatientIDURLParam, Message: errInvalidID.Error()}},
 			},
 		},
 		{
-			name:    "return bad request on invalid diagnosis",
-			handler: nil,
+			name: "return unprocessable entity on invalid diagnosis",
+			handler: func() commands.AddPatientDiagnosisHandler {
+				handler := &commands.MockAddPatientDiagnosis{}
+				handler.On("Handle", mock.Anything, commands.AddPatientDiagnosis{
+					PatientID: uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+					Diagnosis: "",
+				}).Return((*diagnoses.Diagnosis)(nil), validation.Errors{{Field: "diagnosis", Message: "is required"}})
+				return handler
+			}(),
 			body: AddDiagnosisRequest{
 				Diagnosis:    "   \n    ",
 				Prescription: nil,
 			},
 			PatientID:  "11111111-1111-1111-1111-111111111111",
-			wantStatus: 400,
-			wantErr: &HTTPError{
-				Code:    400,
-				Message: errInvalidDiagnosis.Error(),
+			wantStatus: 422,
+			wantErr: &render.Problem{
+				Type:     render.TypeValidationFailed,
+				Title:    "The request has values that can't be accepted",
+				Status:   422,
+				Detail:   "the request has invalid fields",
+				Instance: "/patients/11111111-1111-1111-1111-111111111111/diagnoses",
+				Errors:   []render.FieldError{{Field: "diagnosis", Message: "is required"}},
 			},
 		},
 		{
 			name: "return not found when the patient ID doesn't exists",
 			handler: func() commands.AddPatientDiagnosisHandler {
-				mock := &commands.MockAddPatientDiagnosis{}
-				mock.On("Handle", commands.AddPatientDiagnosis{
+				handler := &commands.MockAddPatientDiagnosis{}
+				handler.On("Handle", mock.Anything, commands.AddPatientDiagnosis{
 					PatientID:    uuid.MustParse("11111111-1111-1111-1111-111111111111"),
 					Diagnosis:    "test diagnosis",
 					Prescription: nil,
-				}).Return(commands.ErrPatientNotFound)
-				return mock
+				}).Return((*diagnoses.Diagnosis)(nil), patients.ErrPatientNotFound)
+				return handler
 			}(),
 			body: AddDiagnosisRequest{
 				Diagnosis:    "test diagnosis",
@@ -70,21 +107,24 @@ func TestHandler_AddDiagnosis(t *testing.T) {
 			},
 			PatientID:  "11111111-1111-1111-1111-111111111111",
 			wantStatus: 404,
-			wantErr: &HTTPError{
-				Code:    404,
-				Message: errPatientNotFound.Error(),
+			wantErr: &render.Problem{
+				Type:     render.TypeNotFound,
+				Title:    "The resource was not found",
+				Status:   404,
+				Detail:   patients.ErrPatientNotFound.Error(),
+				Instance: "/patients/11111111-1111-1111-1111-111111111111/diagnoses",
 			},
 		},
 		{
 			name: "return server error when there is a error adding the diagnosis",
 			handler: func() commands.AddPatientDiagnosisHandler {
-				mock := &commands.MockAddPatientDiagnosis{}
-				mock.On("Handle", commands.AddPatientDiagnosis{
+				handler := &commands.MockAddPatientDiagnosis{}
+				handler.On("Handle", mock.Anything, commands.AddPatientDiagnosis{
 					PatientID:    uuid.MustParse("11111111-1111-1111-1111-111111111111"),
 					Diagnosis:    "test diagnosis",
 					Prescription: nil,
-				}).Return(commands.ErrAddingDiagnosis)
-				return mock
+				}).Return((*diagnoses.Diagnosis)(nil), commands.ErrAddingDiagnosis)
+				return handler
 			}(),
 			body: AddDiagnosisRequest{
 				Diagnosis:    "test diagnosis",
@@ -92,29 +132,112 @@ func TestHandler_AddDiagnosis(t *testing.T) {
 			},
 			PatientID:  "11111111-1111-1111-1111-111111111111",
 			wantStatus: 500,
-			wantErr: &HTTPError{
-				Code:    500,
-				Message: errProcessingRequest.Error(),
+			wantErr: &render.Problem{
+				Type:     render.TypeInternalError,
+				Title:    "The request could not be processed",
+				Status:   500,
+				Detail:   "error processing the request",
+				Instance: "/patients/11111111-1111-1111-1111-111111111111/diagnoses",
+			},
+		},
+		{
+			name: "return forbidden when the actor is not a clinician",
+			handler: func() commands.AddPatientDiagnosisHandler {
+				handler := &commands.MockAddPatientDiagnosis{}
+				handler.On("Handle", mock.Anything, commands.AddPatientDiagnosis{
+					PatientID:    uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+					Diagnosis:    "test diagnosis",
+					Prescription: nil,
+					Actor:        nurse,
+				}).Return((*diagnoses.Diagnosis)(nil), auth.ErrForbidden)
+				return handler
+			}(),
+			actor: nurse,
+			body: AddDiagnosisRequest{
+				Diagnosis:    "test diagnosis",
+				Prescription: nil,
+			},
+			PatientID:  "11111111-1111-1111-1111-111111111111",
+			wantStatus: 403,
+			wantErr: &render.Problem{
+				Type:     render.TypeForbidden,
+				Title:    "The user is not allowed to perform this action",
+				Status:   403,
+				Detail:   auth.ErrForbidden.Error(),
+				Instance: "/patients/11111111-1111-1111-1111-111111111111/diagnoses",
 			},
 		},
+		{
+			name: "return unprocessable entity when the code isn't in the ICD-10 table",
+			handler: func() commands.AddPatientDiagnosisHandler {
+				handler := &commands.MockAddPatientDiagnosis{}
+				handler.On("Handle", mock.Anything, commands.AddPatientDiagnosis{
+					PatientID: uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+					Diagnosis: "test diagnosis",
+					Coding:    &diagnoses.Coding{Code: "J45.3"},
+					Actor:     clinician,
+				}).Return((*diagnoses.Diagnosis)(nil), fmt.Errorf("%w: unknown ICD-10 code \"J45.3\"", commands.ErrInvalidCoding))
+				return handler
+			}(),
+			actor: clinician,
+			body: AddDiagnosisRequest{
+				Diagnosis: "test diagnosis",
+				Coding:    &CodingRequest{Code: "J45.3"},
+			},
+			PatientID:  "11111111-1111-1111-1111-111111111111",
+			wantStatus: 422,
+			wantErr: &render.Problem{
+				Type:     render.TypeValidationFailed,
+				Title:    "The request has values that can't be accepted",
+				Status:   422,
+				Detail:   "invalid diagnosis coding: unknown ICD-10 code \"J45.3\"",
+				Instance: "/patients/11111111-1111-1111-1111-111111111111/diagnoses",
+				Errors: []render.FieldError{
+					{Field: "coding", Message: "invalid diagnosis coding: unknown ICD-10 code \"J45.3\""},
+				},
+			},
+		},
+		{
+			name: "create a coded diagnosis",
+			handler: func() commands.AddPatientDiagnosisHandler {
+				handler := &commands.MockAddPatientDiagnosis{}
+				handler.On("Handle", mock.Anything, commands.AddPatientDiagnosis{
+					PatientID: uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+					Diagnosis: "test diagnosis",
+					Coding:    &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0"},
+					Actor:     clinician,
+				}).Return(created, nil)
+				return handler
+			}(),
+			actor: clinician,
+			body: AddDiagnosisRequest{
+				Diagnosis: "test diagnosis",
+				Coding:    &CodingRequest{System: diagnoses.SystemICD10, Code: "J45.0"},
+			},
+			PatientID:  "11111111-1111-1111-1111-111111111111",
+			wantStatus: 201,
+			want:       created,
+		},
 		{
 			name: "create the diagnosis without error",
 			handler: func() commands.AddPatientDiagnosisHandler {
-				mock := &commands.MockAddPatientDiagnosis{}
-				mock.On("Handle", commands.AddPatientDiagnosis{
+				handler := &commands.MockAddPatientDiagnosis{}
+				handler.On("Handle", mock.Anything, commands.AddPatientDiagnosis{
 					PatientID:    uuid.MustParse("11111111-1111-1111-1111-111111111111"),
 					Diagnosis:    "test diagnosis",
 					Prescription: nil,
-				}).Return(nil)
-				return mock
+					Actor:        clinician,
+				}).Return(created, nil)
+				return handler
 			}(),
+			actor: clinician,
 			body: AddDiagnosisRequest{
 				Diagnosis:    "test diagnosis",
 				Prescription: nil,
 			},
 			PatientID:  "11111111-1111-1111-1111-111111111111",
 			wantStatus: 201,
-			wantErr:    nil,
+			want:       created,
 		},
 	}
 
@@ -126,35 +249,51 @@ func TestHandler_AddDiagnosis(t *testing.T) {
 			r, _ := http.NewRequest("POST", "/patients/"+tt.PatientID+"/diagnoses", buf)
 			rCtx := chi.NewRouteContext()
 			rCtx.URLParams.Add(PatientIDURLParam, tt.PatientID)
-			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rCtx))
+			ctx := authentication.WithPrincipal(context.WithValue(r.Context(), chi.RouteCtxKey, rCtx), tt.actor)
+			r = r.WithContext(ctx)
 			response := httptest.NewRecorder()
 			h.AddDiagnosis(response, r)
 			assert.Equal(t, tt.wantStatus, response.Code)
 			if tt.wantErr != nil {
-				respErr := HTTPError{}
+				respErr := render.Problem{}
 				err := json.NewDecoder(response.Body).Decode(&respErr)
 				assert.Nil(t, err)
 				assert.Equal(t, *tt.wantErr, respErr)
+				assert.Equal(t, render.ProblemContentType, response.Header().Get("Content-Type"))
+			}
+			if tt.want != nil {
+				got := diagnoses.Diagnosis{}
+				assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+				assert.Equal(t, *tt.want, got)
+				assert.Equal(t, "/api/v1/diagnoses/"+tt.want.ID.String(), response.Header().Get("Location"))
 			}
 		})
 	}
 }
 
 func TestHandler_GetDiagnoses(t *testing.T) {
+	buenosAires, _ := time.LoadLocation("America/Argentina/Buenos_Aires")
+	marchFirst := time.Date(2024, 3, 1, 0, 0, 0, 0, buenosAires)
+	marchEnd := time.Date(2024, 4, 1, 0, 0, 0, 0, buenosAires).Add(-time.Nanosecond)
+	timestamp := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", -3*60*60))
+	cursor := diagnoses.Cursor{CreatedAt: timestamp.UTC(), ID: uuid.MustParse("11111111-1111-1111-1111-111111111112")}
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+
 	tests := []struct {
 		name       string
 		queryParam string
 		handler    queries.GetDiagnosesHandler
 		wantStatus int
+		wantBody   *GetDiagnosesResponse
 	}{
 		{
 			name:       "return bad request when patient name is invalid",
 			queryParam: "patientName=",
 			handler: func() queries.GetDiagnosesHandler {
-				mock := &queries.MockGetDiagnoses{}
-				mock.On("Handle", queries.GetDiagnosesQuery{PatientName: ""}).
-					Return(([]*diagnoses.Diagnosis)(nil), commands.ErrGettingPatient)
-				return mock
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{}, patients.ErrGettingPatient)
+				return handler
 			}(),
 			wantStatus: 400,
 		},
@@ -162,21 +301,32 @@ func TestHandler_GetDiagnoses(t *testing.T) {
 			name:       "return not found when the patient doesn't exists",
 			queryParam: "patientName=John Doe",
 			handler: func() queries.GetDiagnosesHandler {
-				mock := &queries.MockGetDiagnoses{}
-				mock.On("Handle", queries.GetDiagnosesQuery{PatientName: "John Doe"}).
-					Return(([]*diagnoses.Diagnosis)(nil), commands.ErrPatientNotFound)
-				return mock
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "John Doe", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{}, patients.ErrPatientNotFound)
+				return handler
 			}(),
 			wantStatus: 404,
 		},
+		{
+			name:       "return unauthorized when there is no authenticated actor",
+			queryParam: "patientName=John Doe",
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "John Doe", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{}, auth.ErrUnauthenticated)
+				return handler
+			}(),
+			wantStatus: 401,
+		},
 		{
 			name:       "return server error when there is an error getting the diagnoses",
 			queryParam: "patientName=John Doe",
 			handler: func() queries.GetDiagnosesHandler {
-				mock := &queries.MockGetDiagnoses{}
-				mock.On("Handle", queries.GetDiagnosesQuery{PatientName: "John Doe"}).
-					Return(([]*diagnoses.Diagnosis)(nil), commands.ErrGettingPatient)
-				return mock
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "John Doe", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{}, patients.ErrGettingPatient)
+				return handler
 			}(),
 			wantStatus: 500,
 		},
@@ -184,10 +334,169 @@ func TestHandler_GetDiagnoses(t *testing.T) {
 			name:       "return the diagnoses without error",
 			queryParam: "patientName=John Doe",
 			handler: func() queries.GetDiagnosesHandler {
-				mock := &queries.MockGetDiagnoses{}
-				mock.On("Handle", queries.GetDiagnosesQuery{PatientName: "John Doe"}).
-					Return([]*diagnoses.Diagnosis{}, nil)
-				return mock
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "John Doe", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+		},
+		{
+			name:       "return the diagnoses of the patient ID",
+			queryParam: "patientId=" + patientID.String(),
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientID: &patientID, Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+		},
+		{
+			name:       "return the diagnoses of the legal ID",
+			queryParam: "legalId=ABC1234",
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{LegalID: "ABC1234", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+		},
+		{
+			name:       "return bad request when the patient ID is invalid",
+			queryParam: "patientId=john",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the legal ID is empty",
+			queryParam: "legalId=%20",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the patient is identified twice",
+			queryParam: "legalId=ABC1234&patientName=John Doe",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when no filter is supplied",
+			queryParam: "",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the date is invalid",
+			queryParam: "from=01/03/2024",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the timezone is invalid",
+			queryParam: "from=2024-03-01&tz=Mars/Olympus",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when from is after to",
+			queryParam: "from=2024-03-02&to=2024-03-01",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "search every patient by plain dates in the requested timezone",
+			queryParam: "from=2024-03-01&to=2024-03-31&tz=America/Argentina/Buenos_Aires",
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{From: &marchFirst, To: &marchEnd, Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+		},
+		{
+			name:       "return bad request when the code is invalid",
+			queryParam: "code=asthma",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the code prefix is invalid",
+			queryParam: "codePrefix=4",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "search every patient by normalized ICD-10 code",
+			queryParam: "code=j450",
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{Code: "J45.0", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+		},
+		{
+			name:       "search patient diagnoses by ICD-10 code prefix",
+			queryParam: "patientName=John Doe&codePrefix=j45",
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "John Doe", CodePrefix: "J45", Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+		},
+		{
+			name:       "return bad request when the limit is out of range",
+			queryParam: "patientName=John Doe&limit=501",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the cursor is invalid",
+			queryParam: "patientName=John Doe&cursor=not-a-cursor",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the sort is invalid",
+			queryParam: "patientName=John Doe&sort=description:asc",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "request a page with the cursor, limit and sort supplied",
+			queryParam: "patientName=John Doe&limit=10&sort=created_at:desc&cursor=" + cursor.String(),
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{
+					PatientName: "John Doe",
+					Limit:       10,
+					Cursor:      &cursor,
+					Order:       diagnoses.SortDescending,
+				}).Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}, Next: &cursor, Total: 25}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody: &GetDiagnosesResponse{
+				PatientName: "John Doe",
+				Diagnoses:   []*diagnoses.Diagnosis{},
+				NextCursor:  cursor.String(),
+				Total:       25,
+			},
+		},
+		{
+			name:       "search patient diagnoses from a RFC 3339 timestamp",
+			queryParam: "patientName=John Doe&from=2024-03-01T10:00:00-03:00",
+			handler: func() queries.GetDiagnosesHandler {
+				handler := &queries.MockGetDiagnoses{}
+				handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "John Doe", From: &timestamp, Order: diagnoses.SortAscending}).
+					Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+				return handler
 			}(),
 			wantStatus: 200,
 		},
@@ -203,6 +512,143 @@ func TestHandler_GetDiagnoses(t *testing.T) {
 			resp := httptest.NewRecorder()
 			h.GetDiagnoses(resp, req)
 			assert.Equal(t, tt.wantStatus, resp.Code)
+			if tt.wantBody != nil {
+				got := GetDiagnosesResponse{}
+				assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+				assert.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestHandler_GetDiagnoses_OmitsPatientName(t *testing.T) {
+	handler := &queries.MockGetDiagnoses{}
+	handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{LegalID: "ABC1234", Order: diagnoses.SortAscending}).
+		Return(diagnoses.Page{Diagnoses: []*diagnoses.Diagnosis{}}, nil)
+	h := &Handler{diagnosesServices: app.DiagnosisServices{Queries: app.Queries{GetDiagnoses: handler}}}
+
+	req, _ := http.NewRequest("GET", "/patients/diagnoses?legalId=ABC1234", nil)
+	resp := httptest.NewRecorder()
+	h.GetDiagnoses(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	got := map[string]any{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.NotContains(t, got, "patient_name")
+}
+
+func TestHandler_GetDiagnoses_AmbiguousName(t *testing.T) {
+	johnID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	otherJohnID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	birthDate := time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)
+	handler := &queries.MockGetDiagnoses{}
+	handler.On("Handle", mock.Anything, queries.GetDiagnosesQuery{PatientName: "John Doe", Order: diagnoses.SortAscending}).
+		Return(diagnoses.Page{}, &queries.AmbiguousPatientError{Candidates: []*patients.Patient{
+			{ID: johnID, LegalID: "ABC1234", Name: "John Doe", BirthDate: &birthDate},
+			{ID: otherJohnID, LegalID: "XYZ9876", Name: "John Doe"},
+		}})
+	h := &Handler{diagnosesServices: app.DiagnosisServices{Queries: app.Queries{GetDiagnoses: handler}}}
+
+	req, _ := http.NewRequest("GET", "/patients/diagnoses?patientName=John%20Doe", nil)
+	resp := httptest.NewRecorder()
+	h.GetDiagnoses(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	got := AmbiguousPatientResponse{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, render.ProblemContentType, resp.Header().Get("Content-Type"))
+	assert.Equal(t, AmbiguousPatientResponse{
+		Problem: render.Problem{
+			Type:     render.TypeAmbiguousPatient,
+			Title:    "Several patients match the search",
+			Status:   http.StatusConflict,
+			Detail:   errAmbiguousPatient.Error(),
+			Instance: "/patients/diagnoses",
+		},
+		Candidates: []PatientSummaryResponse{
+			{ID: johnID, LegalID: "ABC1234", Name: "John Doe", BirthDate: "1980-01-31"},
+			{ID: otherJohnID, LegalID: "XYZ9876", Name: "John Doe"},
+		},
+	}, got)
+}
+
+func TestHandler_GetDiagnosisByID(t *testing.T) {
+	diagnosisID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	nurse := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	birthDate := time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)
+	found := queries.DiagnosisWithPatient{
+		Diagnosis: &diagnoses.Diagnosis{ID: diagnosisID, PatientID: patientID, Description: "asthma", Version: 1,
+			Status: diagnoses.StatusFinal},
+		Patient: &patients.Patient{ID: patientID, LegalID: "ABC1234", Name: "John Doe", BirthDate: &birthDate,
+			Email: "john.doe@example.com"},
+	}
+
+	tests := []struct {
+		name        string
+		diagnosisID string
+		found       queries.DiagnosisWithPatient
+		handlerErr  error
+		wantStatus  int
+		wantBody    *GetDiagnosisResponse
+	}{
+		{
+			name:        "return bad request when the diagnosis ID is invalid",
+			diagnosisID: "invalid",
+			wantStatus:  400,
+		},
+		{
+			name:        "return not found when the diagnosis doesn't exist",
+			diagnosisID: diagnosisID.String(),
+			handlerErr:  commands.ErrDiagnosisNotFound,
+			wantStatus:  404,
+		},
+		{
+			name:        "return not found when the patient of the diagnosis doesn't exist",
+			diagnosisID: diagnosisID.String(),
+			handlerErr:  patients.ErrPatientNotFound,
+			wantStatus:  404,
+		},
+		{
+			name:        "return forbidden when the actor can't read diagnoses",
+			diagnosisID: diagnosisID.String(),
+			handlerErr:  auth.ErrForbidden,
+			wantStatus:  403,
+		},
+		{
+			name:        "return server error when the diagnosis can't be read",
+			diagnosisID: diagnosisID.String(),
+			handlerErr:  commands.ErrGettingDiagnosis,
+			wantStatus:  500,
+		},
+		{
+			name:        "return the diagnosis with its patient summary",
+			diagnosisID: diagnosisID.String(),
+			found:       found,
+			wantStatus:  200,
+			wantBody: &GetDiagnosisResponse{
+				Diagnosis: found.Diagnosis,
+				Patient:   PatientSummaryResponse{ID: patientID, LegalID: "ABC1234", Name: "John Doe", BirthDate: "1980-01-31"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &queries.MockGetDiagnosisByID{}
+			handler.On("Handle", mock.Anything, queries.GetDiagnosisByIDQuery{DiagnosisID: diagnosisID, Actor: nurse}).
+				Return(tt.found, tt.handlerErr)
+			h := NewHandler(app.DiagnosisServices{Queries: app.Queries{GetDiagnosisByID: handler}})
+			r, _ := http.NewRequest("GET", "/diagnoses/"+tt.diagnosisID, nil)
+			response := httptest.NewRecorder()
+			h.GetDiagnosisByID(response, withDiagnosisID(r, tt.diagnosisID, nurse))
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantBody == nil {
+				return
+			}
+
+			var got GetDiagnosisResponse
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&got))
+			assert.Equal(t, *tt.wantBody, got)
 		})
 	}
 }
@@ -0,0 +1,194 @@
+package diagnoses
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PrescriptionRequest is a structured prescription. For backward compatibility a JSON string is accepted too,
+// which is stored as the notes of a prescription without medications.
+type PrescriptionRequest struct {
+	Medications []Medication `json:"medications"`
+	Notes       string       `json:"notes" example:"take with food"`
+	// legacy is set when the prescription was sent as a string
+	legacy bool
+}
+
+func (p *PrescriptionRequest) UnmarshalJSON(data []byte) error {
+	var notes string
+	if err := json.Unmarshal(data, &notes); err == nil {
+		*p = PrescriptionRequest{Notes: notes, legacy: true}
+		return nil
+	}
+
+	// the decoder of the request doesn't reject the unknown fields of the values decoded here
+	type prescription PrescriptionRequest
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*prescription)(p))
+}
+
+// Medication is a prescription line, see diagnoses.Medication.
+type Medication struct {
+	DrugName string `json:"drug_name" example:"Amoxicillin"`
+	// DrugCode optionally identifies the drug in a drug catalog, such as ATC or RxNorm
+	DrugCode string  `json:"drug_code,omitempty" example:"J01CA04"`
+	Dose     float64 `json:"dose" example:"500"`
+	DoseUnit string  `json:"dose_unit" example:"mg"`
+	// Route is one of oral, sublingual, topical, transdermal, inhalation, nasal, ophthalmic, otic, rectal,
+	// vaginal, intravenous, intramuscular or subcutaneous
+	Route     string    `json:"route" example:"oral"`
+	Frequency Frequency `json:"frequency"`
+	Duration  Duration  `json:"duration"`
+	Quantity  int       `json:"quantity" example:"21"`
+	Refills   int       `json:"refills" example:"0"`
+}
+
+// Frequency is taking a dose times every period unit, such as 1 time every 8 hours.
+type Frequency struct {
+	Times  int `json:"times" example:"1"`
+	Period int `json:"period" example:"8"`
+	// Unit is one of hour, day, week or month
+	Unit string `json:"unit" example:"hour"`
+}
+
+type Duration struct {
+	Value int `json:"value" example:"7"`
+	// Unit is one of hour, day, week or month
+	Unit string `json:"unit" example:"day"`
+}
+
+type PrescriptionResponse struct {
+	DiagnosisID    uuid.UUID    `json:"diagnosis_id"`
+	Diagnosis      string       `json:"diagnosis"`
+	PrescribedAt   time.Time    `json:"prescribed_at"`
+	PractitionerID string       `json:"practitioner_id"`
+	Medications    []Medication `json:"medications"`
+	Notes          string       `json:"notes"`
+}
+
+type GetPatientPrescriptionsResponse struct {
+	Prescriptions []PrescriptionResponse `json:"prescriptions"`
+	// NextCursor is sent as cursor to get the next page, it is omitted on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is the number of prescriptions of the patient, across all pages
+	Total int `json:"total"`
+}
+
+// GetPatientPrescriptions godoc
+//
+//	@Summary		Get patient prescriptions
+//	@Description	Get the prescriptions of every diagnosis of the patient, oldest first.
+//	@Description	Prescriptions recorded as free text are returned as notes without medications.
+//	@Tags			prescriptions
+//	@Produce		json
+//	@Param			patientID	path		string	true	"patient ID"
+//	@Param			limit		query		int		false	"page size, 50 by default and 500 at most"
+//	@Param			cursor		query		string	false	"next_cursor of the previous page"
+//	@Success		200			{object}	GetPatientPrescriptionsResponse
+//	@Failure		400			{object}	render.Problem
+//	@Failure		401			{object}	render.Problem
+//	@Failure		403			{object}	render.Problem
+//	@Failure		404			{object}	render.Problem
+//	@Failure		500			{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/patients/{patientID}/prescriptions [get]
+func (h *Handler) GetPatientPrescriptions(writer http.ResponseWriter, request *http.Request) {
+	patientID, parseErr := uuid.Parse(chi.URLParam(request, PatientIDURLParam))
+	if parseErr != nil {
+		render.Error(writer, request, render.InvalidParam(PatientIDURLParam, errInvalidID))
+		return
+	}
+
+	params := request.URL.Query()
+	pageQuery, pageErr := parsePage(params.Get(LimitQueryParam), params.Get(CursorQueryParam), "")
+	if pageErr != nil {
+		render.Error(writer, request, pageErr)
+		return
+	}
+
+	page, err := h.diagnosesServices.Queries.GetPatientPrescriptions.Handle(request.Context(), queries.GetPatientPrescriptionsQuery{
+		PatientID: patientID,
+		Limit:     pageQuery.Limit,
+		Cursor:    pageQuery.Cursor,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	response := GetPatientPrescriptionsResponse{
+		Prescriptions: make([]PrescriptionResponse, 0, len(page.Diagnoses)),
+		Total:         page.Total,
+	}
+	if page.Next != nil {
+		response.NextCursor = page.Next.String()
+	}
+	for _, diagnosis := range page.Diagnoses {
+		response.Prescriptions = append(response.Prescriptions, PrescriptionResponse{
+			DiagnosisID:    diagnosis.ID,
+			Diagnosis:      diagnosis.Description,
+			PrescribedAt:   diagnosis.CreatedAt,
+			PractitionerID: diagnosis.PractitionerID,
+			Medications:    toMedications(diagnosis.Prescription.Medications),
+			Notes:          diagnosis.Prescription.Notes,
+		})
+	}
+
+	render.JSON(writer, http.StatusOK, response)
+}
+
+// toPrescription returns nil for blank legacy prescriptions, which used to be accepted as no prescription.
+func toPrescription(request *PrescriptionRequest) *diagnoses.Prescription {
+	if request == nil || (request.legacy && strings.TrimSpace(request.Notes) == "") {
+		return nil
+	}
+
+	prescription := &diagnoses.Prescription{Notes: strings.TrimSpace(request.Notes)}
+	for _, m := range request.Medications {
+		prescription.Medications = append(prescription.Medications, diagnoses.Medication{
+			DrugName:  strings.TrimSpace(m.DrugName),
+			DrugCode:  strings.TrimSpace(m.DrugCode),
+			Dose:      m.Dose,
+			DoseUnit:  strings.TrimSpace(m.DoseUnit),
+			Route:     diagnoses.Route(strings.ToLower(strings.TrimSpace(m.Route))),
+			Frequency: diagnoses.Frequency{Times: m.Frequency.Times, Period: m.Frequency.Period, Unit: diagnoses.TimeUnit(m.Frequency.Unit)},
+			Duration:  diagnoses.Duration{Value: m.Duration.Value, Unit: diagnoses.TimeUnit(m.Duration.Unit)},
+			Quantity:  m.Quantity,
+			Refills:   m.Refills,
+		})
+	}
+
+	return prescription
+}
+
+func toMedications(medications []diagnoses.Medication) []Medication {
+	response := make([]Medication, 0, len(medications))
+	for _, m := range medications {
+		response = append(response, Medication{
+			DrugName:  m.DrugName,
+			DrugCode:  m.DrugCode,
+			Dose:      m.Dose,
+			DoseUnit:  m.DoseUnit,
+			Route:     string(m.Route),
+			Frequency: Frequency{Times: m.Frequency.Times, Period: m.Frequency.Period, Unit: string(m.Frequency.Unit)},
+			Duration:  Duration{Value: m.Duration.Value, Unit: string(m.Duration.Unit)},
+			Quantity:  m.Quantity,
+			Refills:   m.Refills,
+		})
+	}
+
+	return response
+}
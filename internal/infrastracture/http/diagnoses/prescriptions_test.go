@@ -0,0 +1,302 @@
+package diagnoses
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_AddDiagnosis_Prescription(t *testing.T) {
+	patientID := "11111111-1111-1111-1111-111111111111"
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+	amoxicillin := diagnoses.Medication{
+		DrugName:  "Amoxicillin",
+		DrugCode:  "J01CA04",
+		Dose:      500,
+		DoseUnit:  "mg",
+		Route:     diagnoses.RouteOral,
+		Frequency: diagnoses.Frequency{Times: 1, Period: 8, Unit: diagnoses.UnitHour},
+		Duration:  diagnoses.Duration{Value: 7, Unit: diagnoses.UnitDay},
+		Quantity:  21,
+		Refills:   1,
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		handlerErr       error
+		wantPrescription *diagnoses.Prescription
+		wantStatus       int
+		// wantField is the field of the request error, if any
+		wantField string
+	}{
+		{
+			name:             "accept the legacy string prescription as notes",
+			body:             `{"diagnosis": "migraine", "prescription": "ibuprofen 400mg"}`,
+			wantPrescription: &diagnoses.Prescription{Notes: "ibuprofen 400mg"},
+			wantStatus:       201,
+		},
+		{
+			name:             "accept a blank legacy string as no prescription",
+			body:             `{"diagnosis": "migraine", "prescription": " "}`,
+			wantPrescription: nil,
+			wantStatus:       201,
+		},
+		{
+			name:             "accept a null prescription",
+			body:             `{"diagnosis": "migraine", "prescription": null}`,
+			wantPrescription: nil,
+			wantStatus:       201,
+		},
+		{
+			name: "accept a structured prescription",
+			body: `{"diagnosis": "otitis", "prescription": {"notes": "take with food", "medications": [{
+				"drug_name": "Amoxicillin", "drug_code": "J01CA04", "dose": 500, "dose_unit": "mg", "route": "Oral",
+				"frequency": {"times": 1, "period": 8, "unit": "hour"}, "duration": {"value": 7, "unit": "day"},
+				"quantity": 21, "refills": 1}]}}`,
+			wantPrescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{amoxicillin}, Notes: "take with food"},
+			wantStatus:       201,
+		},
+		{
+			name:             "return unprocessable entity when the prescription is invalid",
+			body:             `{"diagnosis": "otitis", "prescription": {}}`,
+			handlerErr:       validation.Errors{{Field: "prescription", Message: "prescription must have at least one medication or notes"}},
+			wantPrescription: &diagnoses.Prescription{},
+			wantStatus:       422,
+		},
+		{
+			name:       "return bad request when the prescription is malformed",
+			body:       `{"diagnosis": "otitis", "prescription": 42}`,
+			wantStatus: 400,
+		},
+		{
+			name: "return bad request when a medication has unknown fields",
+			body: `{"diagnosis": "otitis", "prescription": {"medications": [{"drug_name": "Amoxicillin",
+				"strength": "500mg"}]}}`,
+			wantStatus: 400,
+			wantField:  "prescription.medications[0].strength",
+		},
+		{
+			name:       "return bad request when the prescription has unknown fields",
+			body:       `{"diagnosis": "otitis", "prescription": {"notes": "take with food", "foo": 1}}`,
+			wantStatus: 400,
+			wantField:  "prescription.foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &commands.MockAddPatientDiagnosis{}
+			handler.On("Handle", mock.Anything, mock.Anything).Return(&diagnoses.Diagnosis{}, tt.handlerErr)
+			h := NewHandler(app.DiagnosisServices{Commands: app.Commands{AddPatientDiagnosisHandler: handler}})
+			r, _ := http.NewRequest("POST", "/patients/"+patientID+"/diagnoses", strings.NewReader(tt.body))
+			rCtx := chi.NewRouteContext()
+			rCtx.URLParams.Add(PatientIDURLParam, patientID)
+			r = r.WithContext(authentication.WithPrincipal(context.WithValue(r.Context(), chi.RouteCtxKey, rCtx), clinician))
+			response := httptest.NewRecorder()
+			h.AddDiagnosis(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantStatus == 400 {
+				handler.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything)
+				if tt.wantField != "" {
+					assert.Contains(t, response.Body.String(), `"field":"`+tt.wantField+`"`)
+				}
+				return
+			}
+			handler.AssertCalled(t, "Handle", mock.Anything, mock.MatchedBy(func(command commands.AddPatientDiagnosis) bool {
+				return assert.ObjectsAreEqual(tt.wantPrescription, command.Prescription)
+			}))
+		})
+	}
+}
+
+func TestHandler_GetPatientPrescriptions(t *testing.T) {
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	nurse := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	prescribedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	diagnosisID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	legacyID := uuid.MustParse("11111111-1111-1111-1111-111111111113")
+	next := diagnoses.Cursor{CreatedAt: prescribedAt, ID: legacyID}
+
+	tests := []struct {
+		name       string
+		patientID  string
+		query      string
+		handler    queries.GetPatientPrescriptionsHandler
+		wantStatus int
+		wantBody   *GetPatientPrescriptionsResponse
+	}{
+		{
+			name:       "return bad request when the patient ID is invalid",
+			patientID:  "invalid",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:      "return not found when the patient doesn't exists",
+			patientID: patientID.String(),
+			handler: func() queries.GetPatientPrescriptionsHandler {
+				handler := &queries.MockGetPatientPrescriptions{}
+				handler.On("Handle", mock.Anything, queries.GetPatientPrescriptionsQuery{PatientID: patientID, Actor: nurse}).
+					Return(diagnoses.Page{}, patients.ErrPatientNotFound)
+				return handler
+			}(),
+			wantStatus: 404,
+		},
+		{
+			name:      "return forbidden when the actor can't read diagnoses",
+			patientID: patientID.String(),
+			handler: func() queries.GetPatientPrescriptionsHandler {
+				handler := &queries.MockGetPatientPrescriptions{}
+				handler.On("Handle", mock.Anything, queries.GetPatientPrescriptionsQuery{PatientID: patientID, Actor: nurse}).
+					Return(diagnoses.Page{}, auth.ErrForbidden)
+				return handler
+			}(),
+			wantStatus: 403,
+		},
+		{
+			name:      "return server error when the prescriptions can't be read",
+			patientID: patientID.String(),
+			handler: func() queries.GetPatientPrescriptionsHandler {
+				handler := &queries.MockGetPatientPrescriptions{}
+				handler.On("Handle", mock.Anything, queries.GetPatientPrescriptionsQuery{PatientID: patientID, Actor: nurse}).
+					Return(diagnoses.Page{}, commands.ErrGettingDiagnoses)
+				return handler
+			}(),
+			wantStatus: 500,
+		},
+		{
+			name:      "return structured and legacy prescriptions",
+			patientID: patientID.String(),
+			handler: func() queries.GetPatientPrescriptionsHandler {
+				handler := &queries.MockGetPatientPrescriptions{}
+				handler.On("Handle", mock.Anything, queries.GetPatientPrescriptionsQuery{PatientID: patientID, Actor: nurse}).
+					Return(diagnoses.Page{Total: 2, Diagnoses: []*diagnoses.Diagnosis{
+						{
+							ID:             legacyID,
+							Description:    "migraine",
+							CreatedAt:      prescribedAt,
+							PractitionerID: "practitioner-1",
+							Prescription:   &diagnoses.Prescription{Notes: "ibuprofen 400mg"},
+						},
+						{
+							ID:             diagnosisID,
+							Description:    "otitis",
+							CreatedAt:      prescribedAt.Add(time.Hour),
+							PractitionerID: "practitioner-1",
+							Prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{{
+								DrugName:  "Amoxicillin",
+								Dose:      500,
+								DoseUnit:  "mg",
+								Route:     diagnoses.RouteOral,
+								Frequency: diagnoses.Frequency{Times: 1, Period: 8, Unit: diagnoses.UnitHour},
+								Duration:  diagnoses.Duration{Value: 7, Unit: diagnoses.UnitDay},
+								Quantity:  21,
+							}}},
+						},
+					}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody: &GetPatientPrescriptionsResponse{Total: 2, Prescriptions: []PrescriptionResponse{
+				{
+					DiagnosisID:    legacyID,
+					Diagnosis:      "migraine",
+					PrescribedAt:   prescribedAt,
+					PractitionerID: "practitioner-1",
+					Medications:    []Medication{},
+					Notes:          "ibuprofen 400mg",
+				},
+				{
+					DiagnosisID:    diagnosisID,
+					Diagnosis:      "otitis",
+					PrescribedAt:   prescribedAt.Add(time.Hour),
+					PractitionerID: "practitioner-1",
+					Medications: []Medication{{
+						DrugName:  "Amoxicillin",
+						Dose:      500,
+						DoseUnit:  "mg",
+						Route:     "oral",
+						Frequency: Frequency{Times: 1, Period: 8, Unit: "hour"},
+						Duration:  Duration{Value: 7, Unit: "day"},
+						Quantity:  21,
+					}},
+				},
+			}},
+		},
+		{
+			name:      "return the requested page with the cursor of the next one",
+			patientID: patientID.String(),
+			query:     "?limit=1&cursor=" + next.String(),
+			handler: func() queries.GetPatientPrescriptionsHandler {
+				handler := &queries.MockGetPatientPrescriptions{}
+				query := queries.GetPatientPrescriptionsQuery{PatientID: patientID, Limit: 1, Cursor: &next, Actor: nurse}
+				handler.On("Handle", mock.Anything, query).Return(diagnoses.Page{
+					Total: 3,
+					Next:  &next,
+					Diagnoses: []*diagnoses.Diagnosis{{
+						ID:             legacyID,
+						Description:    "migraine",
+						CreatedAt:      prescribedAt,
+						PractitionerID: "practitioner-1",
+						Prescription:   &diagnoses.Prescription{Notes: "ibuprofen 400mg"},
+					}},
+				}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody: &GetPatientPrescriptionsResponse{Total: 3, NextCursor: next.String(), Prescriptions: []PrescriptionResponse{{
+				DiagnosisID:    legacyID,
+				Diagnosis:      "migraine",
+				PrescribedAt:   prescribedAt,
+				PractitionerID: "practitioner-1",
+				Medications:    []Medication{},
+				Notes:          "ibuprofen 400mg",
+			}}},
+		},
+		{
+			name:       "return bad request when the limit is invalid",
+			patientID:  patientID.String(),
+			query:      "?limit=501",
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the cursor is invalid",
+			patientID:  patientID.String(),
+			query:      "?cursor=invalid",
+			wantStatus: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.DiagnosisServices{Queries: app.Queries{GetPatientPrescriptions: tt.handler}})
+			r, _ := http.NewRequest("GET", "/patients/"+tt.patientID+"/prescriptions"+tt.query, nil)
+			rCtx := chi.NewRouteContext()
+			rCtx.URLParams.Add(PatientIDURLParam, tt.patientID)
+			r = r.WithContext(authentication.WithPrincipal(context.WithValue(r.Context(), chi.RouteCtxKey, rCtx), nurse))
+			response := httptest.NewRecorder()
+			h.GetPatientPrescriptions(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantBody != nil {
+				got := GetPatientPrescriptionsResponse{}
+				assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+				assert.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
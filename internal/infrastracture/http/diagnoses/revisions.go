@@ -0,0 +1,170 @@
+package diagnoses
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"net/http"
+	"strings"
+)
+
+const DiagnosisIDURLParam = "diagnosisID"
+
+// AmendDiagnosisRequest replaces the diagnosis, its prescription and its coding, see AddDiagnosisRequest.
+type AmendDiagnosisRequest struct {
+	Diagnosis    string               `json:"diagnosis"`
+	Prescription *PrescriptionRequest `json:"prescription"`
+	Coding       *CodingRequest       `json:"coding"`
+	Reason       string               `json:"reason" example:"allergy test results"`
+}
+
+type EnterInErrorRequest struct {
+	Reason string `json:"reason" example:"recorded on the wrong patient"`
+}
+
+type DiagnosisHistoryResponse struct {
+	// Versions are every version of the diagnosis, from the first one
+	Versions []*diagnoses.Diagnosis `json:"versions"`
+}
+
+// AmendDiagnosis godoc
+//
+//	@Summary		Amend diagnosis
+//	@Description	Amend the diagnosis, its prescription and its coding, which are replaced by the ones sent.
+//	@Description	The amendment is written as a new version with the reason, the previous ones are kept in its history.
+//	@Description	Only clinicians can amend diagnoses, and diagnoses entered in error can't be amended.
+//	@Tags			diagnosis
+//	@Accept			json
+//	@Produce		json
+//	@Param			diagnosisID	path		string					true	"diagnosis ID"
+//	@Param			amendment	body		AmendDiagnosisRequest	true	"amended diagnosis"
+//	@Success		204
+//	@Failure		400	{object}	render.Problem
+//	@Failure		401	{object}	render.Problem
+//	@Failure		403	{object}	render.Problem
+//	@Failure		404	{object}	render.Problem
+//	@Failure		409	{object}	render.Problem
+//	@Failure		413	{object}	render.Problem
+//	@Failure		422	{object}	render.Problem
+//	@Failure		500	{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/diagnoses/{diagnosisID}/amend [post]
+func (h *Handler) AmendDiagnosis(writer http.ResponseWriter, request *http.Request) {
+	diagnosisID, parseErr := uuid.Parse(chi.URLParam(request, DiagnosisIDURLParam))
+	if parseErr != nil {
+		render.Error(writer, request, render.InvalidParam(DiagnosisIDURLParam, errInvalidID))
+		return
+	}
+
+	amendRequest := AmendDiagnosisRequest{}
+	if err := render.DecodeJSON(writer, request, &amendRequest); err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	err := h.diagnosesServices.Commands.AmendDiagnosisHandler.Handle(request.Context(), commands.AmendDiagnosis{
+		DiagnosisID:  diagnosisID,
+		Diagnosis:    strings.TrimSpace(amendRequest.Diagnosis),
+		Prescription: toPrescription(amendRequest.Prescription),
+		Coding:       toCoding(amendRequest.Coding),
+		Reason:       strings.TrimSpace(amendRequest.Reason),
+		Actor:        authentication.PrincipalFromContext(request.Context()),
+		RequestID:    middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// EnterInError godoc
+//
+//	@Summary		Enter diagnosis in error
+//	@Description	Retract a diagnosis that should never have been written, such as one added to the wrong patient.
+//	@Description	It is written as a new version with the reason. Searches stop returning the diagnosis, but its
+//	@Description	history is kept. Only clinicians can enter diagnoses in error.
+//	@Tags			diagnosis
+//	@Accept			json
+//	@Produce		json
+//	@Param			diagnosisID	path		string				true	"diagnosis ID"
+//	@Param			retraction	body		EnterInErrorRequest	true	"reason of the retraction"
+//	@Success		204
+//	@Failure		400	{object}	render.Problem
+//	@Failure		401	{object}	render.Problem
+//	@Failure		403	{object}	render.Problem
+//	@Failure		404	{object}	render.Problem
+//	@Failure		409	{object}	render.Problem
+//	@Failure		413	{object}	render.Problem
+//	@Failure		422	{object}	render.Problem
+//	@Failure		500	{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/diagnoses/{diagnosisID}/enter-in-error [post]
+func (h *Handler) EnterInError(writer http.ResponseWriter, request *http.Request) {
+	diagnosisID, parseErr := uuid.Parse(chi.URLParam(request, DiagnosisIDURLParam))
+	if parseErr != nil {
+		render.Error(writer, request, render.InvalidParam(DiagnosisIDURLParam, errInvalidID))
+		return
+	}
+
+	enterInErrorRequest := EnterInErrorRequest{}
+	if err := render.DecodeJSON(writer, request, &enterInErrorRequest); err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	err := h.diagnosesServices.Commands.EnterInErrorHandler.Handle(request.Context(), commands.EnterInError{
+		DiagnosisID: diagnosisID,
+		Reason:      strings.TrimSpace(enterInErrorRequest.Reason),
+		Actor:       authentication.PrincipalFromContext(request.Context()),
+		RequestID:   middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// GetDiagnosisHistory godoc
+//
+//	@Summary		Get diagnosis history
+//	@Description	Get every version of the diagnosis from the first one, with who wrote each version, when and why.
+//	@Description	Diagnoses entered in error are only returned here.
+//	@Tags			diagnosis
+//	@Produce		json
+//	@Param			diagnosisID	path		string	true	"diagnosis ID"
+//	@Success		200			{object}	DiagnosisHistoryResponse
+//	@Failure		400			{object}	render.Problem
+//	@Failure		401			{object}	render.Problem
+//	@Failure		403			{object}	render.Problem
+//	@Failure		404			{object}	render.Problem
+//	@Failure		500			{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/diagnoses/{diagnosisID}/history [get]
+func (h *Handler) GetDiagnosisHistory(writer http.ResponseWriter, request *http.Request) {
+	diagnosisID, parseErr := uuid.Parse(chi.URLParam(request, DiagnosisIDURLParam))
+	if parseErr != nil {
+		render.Error(writer, request, render.InvalidParam(DiagnosisIDURLParam, errInvalidID))
+		return
+	}
+
+	versions, err := h.diagnosesServices.Queries.GetDiagnosisHistory.Handle(request.Context(), queries.GetDiagnosisHistoryQuery{
+		DiagnosisID: diagnosisID,
+		Actor:       authentication.PrincipalFromContext(request.Context()),
+		RequestID:   middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	render.JSON(writer, http.StatusOK, DiagnosisHistoryResponse{Versions: versions})
+}
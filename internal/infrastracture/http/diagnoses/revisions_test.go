@@ -0,0 +1,248 @@
+package diagnoses
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_AmendDiagnosis(t *testing.T) {
+	diagnosisID := "11111111-1111-1111-1111-111111111112"
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+
+	tests := []struct {
+		name        string
+		diagnosisID string
+		body        string
+		handlerErr  error
+		wantCommand *commands.AmendDiagnosis
+		wantStatus  int
+	}{
+		{
+			name:        "return bad request when the diagnosis ID is invalid",
+			diagnosisID: "invalid",
+			body:        `{"diagnosis": "allergic asthma", "reason": "allergy test results"}`,
+			wantStatus:  400,
+		},
+		{
+			name:        "return bad request when the body has unknown fields",
+			diagnosisID: diagnosisID,
+			body:        `{"diagnosis": "allergic asthma", "reason": "allergy test results", "status": "final"}`,
+			wantStatus:  400,
+		},
+		{
+			name:        "return unprocessable entity when the command is invalid",
+			diagnosisID: diagnosisID,
+			body:        `{"diagnosis": "", "reason": "allergy test results"}`,
+			handlerErr:  validation.Errors{{Field: "diagnosis", Message: "is required"}},
+			wantStatus:  422,
+		},
+		{
+			name:        "return not found when the diagnosis doesn't exist",
+			diagnosisID: diagnosisID,
+			body:        `{"diagnosis": "allergic asthma", "reason": "allergy test results"}`,
+			handlerErr:  commands.ErrDiagnosisNotFound,
+			wantStatus:  404,
+		},
+		{
+			name:        "return conflict when the diagnosis was entered in error",
+			diagnosisID: diagnosisID,
+			body:        `{"diagnosis": "allergic asthma", "reason": "allergy test results"}`,
+			handlerErr:  diagnoses.ErrEnteredInError,
+			wantStatus:  409,
+		},
+		{
+			name:        "return unprocessable entity when the coding is invalid",
+			diagnosisID: diagnosisID,
+			body:        `{"diagnosis": "allergic asthma", "coding": {"code": "J45.3"}, "reason": "allergy test results"}`,
+			handlerErr:  commands.ErrInvalidCoding,
+			wantStatus:  422,
+		},
+		{
+			name:        "return forbidden when the actor can't write diagnoses",
+			diagnosisID: diagnosisID,
+			body:        `{"diagnosis": "allergic asthma", "reason": "allergy test results"}`,
+			handlerErr:  auth.ErrForbidden,
+			wantStatus:  403,
+		},
+		{
+			name:        "amend the diagnosis",
+			diagnosisID: diagnosisID,
+			body: `{"diagnosis": " allergic asthma ", "prescription": "avoid pollen", "coding": {"code": "J45.0"},
+				"reason": " allergy test results "}`,
+			wantCommand: &commands.AmendDiagnosis{
+				DiagnosisID:  uuid.MustParse(diagnosisID),
+				Diagnosis:    "allergic asthma",
+				Prescription: &diagnoses.Prescription{Notes: "avoid pollen"},
+				Coding:       &diagnoses.Coding{Code: "J45.0"},
+				Reason:       "allergy test results",
+				Actor:        clinician,
+			},
+			wantStatus: 204,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &commands.MockAmendDiagnosis{}
+			handler.On("Handle", mock.Anything, mock.Anything).Return(tt.handlerErr)
+			h := NewHandler(app.DiagnosisServices{Commands: app.Commands{AmendDiagnosisHandler: handler}})
+			r, _ := http.NewRequest("POST", "/diagnoses/"+tt.diagnosisID+"/amend", strings.NewReader(tt.body))
+			response := httptest.NewRecorder()
+			h.AmendDiagnosis(response, withDiagnosisID(r, tt.diagnosisID, clinician))
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantStatus == 400 {
+				handler.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything)
+			}
+			if tt.wantCommand != nil {
+				handler.AssertCalled(t, "Handle", mock.Anything, *tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestHandler_EnterInError(t *testing.T) {
+	diagnosisID := "11111111-1111-1111-1111-111111111112"
+	clinician := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+
+	tests := []struct {
+		name       string
+		body       string
+		handlerErr error
+		wantStatus int
+	}{
+		{
+			name:       "return unprocessable entity when the reason is invalid",
+			body:       `{"reason": "wrong patient"}`,
+			handlerErr: validation.Errors{{Field: "reason", Message: "can't be longer than 500 characters"}},
+			wantStatus: 422,
+		},
+		{
+			name:       "return bad request when the body is malformed",
+			body:       `{"reason": 42}`,
+			wantStatus: 400,
+		},
+		{
+			name:       "return not found when the diagnosis doesn't exist",
+			body:       `{"reason": "wrong patient"}`,
+			handlerErr: commands.ErrDiagnosisNotFound,
+			wantStatus: 404,
+		},
+		{
+			name:       "return conflict when the diagnosis was already entered in error",
+			body:       `{"reason": "wrong patient"}`,
+			handlerErr: diagnoses.ErrEnteredInError,
+			wantStatus: 409,
+		},
+		{
+			name:       "return server error when the new version can't be stored",
+			body:       `{"reason": "wrong patient"}`,
+			handlerErr: commands.ErrUpdatingDiagnosis,
+			wantStatus: 500,
+		},
+		{
+			name:       "enter the diagnosis in error",
+			body:       `{"reason": "wrong patient"}`,
+			wantStatus: 204,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &commands.MockEnterInError{}
+			handler.On("Handle", mock.Anything, mock.Anything).Return(tt.handlerErr)
+			h := NewHandler(app.DiagnosisServices{Commands: app.Commands{EnterInErrorHandler: handler}})
+			r, _ := http.NewRequest("POST", "/diagnoses/"+diagnosisID+"/enter-in-error", strings.NewReader(tt.body))
+			response := httptest.NewRecorder()
+			h.EnterInError(response, withDiagnosisID(r, diagnosisID, clinician))
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantStatus == 400 {
+				handler.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything)
+				return
+			}
+			handler.AssertCalled(t, "Handle", mock.Anything, commands.EnterInError{
+				DiagnosisID: uuid.MustParse(diagnosisID),
+				Reason:      "wrong patient",
+				Actor:       clinician,
+			})
+		})
+	}
+}
+
+func TestHandler_GetDiagnosisHistory(t *testing.T) {
+	diagnosisID := uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	nurse := auth.Principal{ID: "nurse-1", Roles: []auth.Role{auth.RoleNurse}}
+	query := queries.GetDiagnosisHistoryQuery{DiagnosisID: diagnosisID, Actor: nurse}
+	versions := []*diagnoses.Diagnosis{
+		{ID: diagnosisID, Description: "asthma", Version: 1, Status: diagnoses.StatusFinal},
+		{ID: diagnosisID, Description: "asthma", Version: 2, Status: diagnoses.StatusEnteredInError, Reason: "wrong patient"},
+	}
+
+	tests := []struct {
+		name        string
+		diagnosisID string
+		versions    []*diagnoses.Diagnosis
+		handlerErr  error
+		wantStatus  int
+	}{
+		{
+			name:        "return bad request when the diagnosis ID is invalid",
+			diagnosisID: "invalid",
+			wantStatus:  400,
+		},
+		{
+			name:        "return not found when the diagnosis doesn't exist",
+			diagnosisID: diagnosisID.String(),
+			handlerErr:  commands.ErrDiagnosisNotFound,
+			wantStatus:  404,
+		},
+		{
+			name:        "return unauthorized when the actor is anonymous",
+			diagnosisID: diagnosisID.String(),
+			handlerErr:  auth.ErrUnauthenticated,
+			wantStatus:  401,
+		},
+		{
+			name:        "return every version",
+			diagnosisID: diagnosisID.String(),
+			versions:    versions,
+			wantStatus:  200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &queries.MockGetDiagnosisHistory{}
+			handler.On("Handle", mock.Anything, query).Return(tt.versions, tt.handlerErr)
+			h := NewHandler(app.DiagnosisServices{Queries: app.Queries{GetDiagnosisHistory: handler}})
+			r, _ := http.NewRequest("GET", "/diagnoses/"+tt.diagnosisID+"/history", nil)
+			response := httptest.NewRecorder()
+			h.GetDiagnosisHistory(response, withDiagnosisID(r, tt.diagnosisID, nurse))
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantStatus != 200 {
+				return
+			}
+
+			var got DiagnosisHistoryResponse
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&got))
+			assert.Equal(t, DiagnosisHistoryResponse{Versions: tt.versions}, got)
+		})
+	}
+}
+
+func withDiagnosisID(r *http.Request, diagnosisID string, principal auth.Principal) *http.Request {
+	rCtx := chi.NewRouteContext()
+	rCtx.URLParams.Add(DiagnosisIDURLParam, diagnosisID)
+	return r.WithContext(authentication.WithPrincipal(context.WithValue(r.Context(), chi.RouteCtxKey, rCtx), principal))
+}
@@ -0,0 +1,323 @@
+package fhir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/fhir"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidID         = errors.New("invalid resource ID")
+	errInvalidPatient    = errors.New("invalid patient, expected Patient/{id} or the patient ID")
+	errMissingPatient    = errors.New("the patient search parameter is required")
+	errMissingFilter     = errors.New("a patient or recorded-date search parameter is required")
+	errInvalidCount      = fmt.Errorf("_count must be a number between 1 and %d", queries.MaxPageSize)
+	errInvalidCursor     = errors.New("invalid _cursor, use the next link of a previous bundle")
+	errPatientNotFound   = errors.New("there is no patient matching the ID or reference supplied")
+	errProcessingRequest = errors.New("error processing the request")
+)
+
+const (
+	// BasePath is where the FHIR endpoints are served, the base of the resource URLs.
+	BasePath = "/api/v1/fhir"
+
+	IDURLParam              = "id"
+	PatientSearchParam      = "patient"
+	RecordedDateSearchParam = "recorded-date"
+	CountSearchParam        = "_count"
+	CursorSearchParam       = "_cursor"
+)
+
+type Handler struct {
+	patientServices   app.PatientServices
+	diagnosisServices app.DiagnosisServices
+}
+
+func NewHandler(patientServices app.PatientServices, diagnosisServices app.DiagnosisServices) *Handler {
+	return &Handler{
+		patientServices:   patientServices,
+		diagnosisServices: diagnosisServices,
+	}
+}
+
+// ReadPatient godoc
+//
+//	@Summary		Read FHIR Patient
+//	@Description	Get the patient as a FHIR R4 Patient resource.
+//	@Tags			fhir
+//	@Produce		application/fhir+json
+//	@Param			id	path		string	true	"patient ID"
+//	@Success		200	{object}	fhir.Patient
+//	@Failure		400	{object}	fhir.OperationOutcome
+//	@Failure		401	{object}	fhir.OperationOutcome
+//	@Failure		403	{object}	fhir.OperationOutcome
+//	@Failure		404	{object}	fhir.OperationOutcome
+//	@Failure		500	{object}	fhir.OperationOutcome
+//	@Security		BearerAuth
+//	@Router			/fhir/Patient/{id} [get]
+func (h *Handler) ReadPatient(writer http.ResponseWriter, request *http.Request) {
+	patientID, err := uuid.Parse(chi.URLParam(request, IDURLParam))
+	if err != nil {
+		writeOutcome(writer, http.StatusBadRequest, "invalid", errInvalidID)
+		return
+	}
+
+	patient, err := h.patientServices.Queries.GetPatient.Handle(request.Context(), patientqueries.GetPatientQuery{
+		PatientID: patientID,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+
+	writeResource(writer, http.StatusOK, fhir.NewPatient(*patient))
+}
+
+// SearchConditions godoc
+//
+//	@Summary		Search FHIR Conditions
+//	@Description	Search diagnoses as FHIR R4 Condition resources, returned in a searchset Bundle sorted by recorded date.
+//	@Description	recorded-date can be repeated and takes the eq, ge, gt, le and lt prefixes, such as ge2024-03-01.
+//	@Tags			fhir
+//	@Produce		application/fhir+json
+//	@Param			patient			query		string	false	"Patient/{id} or the patient ID"
+//	@Param			recorded-date	query		string	false	"date the diagnosis was recorded, with an optional prefix"
+//	@Param			_count			query		int		false	"page size, 50 by default and 500 at most"
+//	@Param			_cursor			query		string	false	"set by the next link of the previous page"
+//	@Success		200				{object}	fhir.Bundle
+//	@Failure		400				{object}	fhir.OperationOutcome
+//	@Failure		401				{object}	fhir.OperationOutcome
+//	@Failure		403				{object}	fhir.OperationOutcome
+//	@Failure		404				{object}	fhir.OperationOutcome
+//	@Failure		500				{object}	fhir.OperationOutcome
+//	@Security		BearerAuth
+//	@Router			/fhir/Condition [get]
+func (h *Handler) SearchConditions(writer http.ResponseWriter, request *http.Request) {
+	params := request.URL.Query()
+	query := queries.GetDiagnosesQuery{
+		Order:     diagnoses.SortAscending,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	}
+
+	if params.Has(PatientSearchParam) {
+		patientID, err := fhir.ParseReference("Patient", params.Get(PatientSearchParam))
+		if err != nil {
+			writeOutcome(writer, http.StatusBadRequest, "invalid", errInvalidPatient)
+			return
+		}
+		query.PatientID = &patientID
+	}
+
+	recorded, err := fhir.ParseDateParams(params[RecordedDateSearchParam])
+	if err != nil {
+		writeOutcome(writer, http.StatusBadRequest, "invalid", err)
+		return
+	}
+	query.From, query.To = recorded.From, recorded.To
+
+	if query.PatientID == nil && query.From == nil && query.To == nil {
+		writeOutcome(writer, http.StatusBadRequest, "required", errMissingFilter)
+		return
+	}
+
+	query.Limit, query.Cursor, err = parsePage(params)
+	if err != nil {
+		writeOutcome(writer, http.StatusBadRequest, "invalid", err)
+		return
+	}
+
+	page, err := h.diagnosisServices.Queries.GetDiagnoses.Handle(request.Context(), query)
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+
+	conditions := make([]fhir.Condition, 0, len(page.Diagnoses))
+	for _, diagnosis := range page.Diagnoses {
+		conditions = append(conditions, fhir.NewCondition(*diagnosis))
+	}
+
+	var next string
+	if page.Next != nil {
+		params.Set(CursorSearchParam, page.Next.String())
+		next = baseURL(request) + "/Condition?" + params.Encode()
+	}
+
+	writeResource(writer, http.StatusOK, fhir.NewSearchBundle(baseURL(request), conditions, &page.Total, selfURL(request), next))
+}
+
+// SearchMedicationRequests godoc
+//
+//	@Summary		Search FHIR MedicationRequests
+//	@Description	Get the prescriptions of the patient as FHIR R4 MedicationRequest resources, one per medication,
+//	@Description	returned in a searchset Bundle. Prescriptions recorded as free text are a single MedicationRequest.
+//	@Description	The results are paged by prescription, so a page can have more entries than _count, and the total is
+//	@Description	only given when every MedicationRequest of the patient is in the page.
+//	@Tags			fhir
+//	@Produce		application/fhir+json
+//	@Param			patient	query		string	true	"Patient/{id} or the patient ID"
+//	@Param			_count	query		int		false	"prescriptions per page, 50 by default and 500 at most"
+//	@Param			_cursor	query		string	false	"cursor of the next link of the previous page"
+//	@Success		200		{object}	fhir.Bundle
+//	@Failure		400		{object}	fhir.OperationOutcome
+//	@Failure		401		{object}	fhir.OperationOutcome
+//	@Failure		403		{object}	fhir.OperationOutcome
+//	@Failure		404		{object}	fhir.OperationOutcome
+//	@Failure		500		{object}	fhir.OperationOutcome
+//	@Security		BearerAuth
+//	@Router			/fhir/MedicationRequest [get]
+func (h *Handler) SearchMedicationRequests(writer http.ResponseWriter, request *http.Request) {
+	params := request.URL.Query()
+	if !params.Has(PatientSearchParam) {
+		writeOutcome(writer, http.StatusBadRequest, "required", errMissingPatient)
+		return
+	}
+
+	patientID, err := fhir.ParseReference("Patient", params.Get(PatientSearchParam))
+	if err != nil {
+		writeOutcome(writer, http.StatusBadRequest, "invalid", errInvalidPatient)
+		return
+	}
+
+	query := queries.GetPatientPrescriptionsQuery{
+		PatientID: patientID,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	}
+	query.Limit, query.Cursor, err = parsePage(params)
+	if err != nil {
+		writeOutcome(writer, http.StatusBadRequest, "invalid", err)
+		return
+	}
+
+	page, err := h.diagnosisServices.Queries.GetPatientPrescriptions.Handle(request.Context(), query)
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+
+	medicationRequests := make([]fhir.MedicationRequest, 0, len(page.Diagnoses))
+	for _, diagnosis := range page.Diagnoses {
+		medicationRequests = append(medicationRequests, fhir.NewMedicationRequests(*diagnosis)...)
+	}
+
+	// the page counts prescriptions, which can have several medications each
+	var total *int
+	if query.Cursor == nil && page.Next == nil {
+		count := len(medicationRequests)
+		total = &count
+	}
+
+	var next string
+	if page.Next != nil {
+		params.Set(CursorSearchParam, page.Next.String())
+		next = baseURL(request) + "/MedicationRequest?" + params.Encode()
+	}
+
+	bundle := fhir.NewSearchBundle(baseURL(request), medicationRequests, total, selfURL(request), next)
+	writeResource(writer, http.StatusOK, bundle)
+}
+
+func parsePage(params url.Values) (int, *diagnoses.Cursor, error) {
+	var limit int
+	if count := params.Get(CountSearchParam); count != "" {
+		var err error
+		limit, err = strconv.Atoi(count)
+		if err != nil || limit < 1 || limit > queries.MaxPageSize {
+			return 0, nil, errInvalidCount
+		}
+	}
+
+	if cursor := params.Get(CursorSearchParam); cursor != "" {
+		parsed, err := diagnoses.ParseCursor(cursor)
+		if err != nil {
+			return 0, nil, errInvalidCursor
+		}
+		return limit, &parsed, nil
+	}
+
+	return limit, nil, nil
+}
+
+func writeError(writer http.ResponseWriter, err error) {
+	result := errorResult(err)
+	writeResource(writer, result.status, result.outcome)
+}
+
+type result struct {
+	status  int
+	outcome fhir.OperationOutcome
+}
+
+func (r result) failed() bool {
+	return r.status >= http.StatusBadRequest
+}
+
+func errorResult(err error) result {
+	status := render.StatusOf(err)
+	switch status {
+	case http.StatusUnauthorized:
+		return result{status, fhir.NewOperationOutcome("login", err.Error())}
+	case http.StatusForbidden:
+		return result{status, fhir.NewOperationOutcome("forbidden", err.Error())}
+	case http.StatusNotFound:
+		return result{status, fhir.NewOperationOutcome("not-found", errPatientNotFound.Error())}
+	case http.StatusUnprocessableEntity:
+		if errors.Is(err, validation.ErrInvalidCommand) {
+			return result{status, fhir.NewOperationOutcome("invalid", err.Error())}
+		}
+		return result{status, fhir.NewOperationOutcome("code-invalid", err.Error())}
+	case http.StatusInternalServerError:
+		slog.Error("error handling FHIR request", "error", err)
+		return result{status, fhir.NewOperationOutcome("exception", errProcessingRequest.Error())}
+	default:
+		return result{status, fhir.NewOperationOutcome("processing", err.Error())}
+	}
+}
+
+func writeOutcome(writer http.ResponseWriter, status int, code string, err error) {
+	writeResource(writer, status, fhir.NewOperationOutcome(code, err.Error()))
+}
+
+func writeResource(writer http.ResponseWriter, status int, resource any) {
+	writer.Header().Set("Content-Type", fhir.ContentType)
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(resource); err != nil {
+		slog.Error("error encoding FHIR resource", "error", err)
+	}
+}
+
+func baseURL(request *http.Request) string {
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + request.Host + BasePath
+}
+
+func selfURL(request *http.Request) string {
+	self := baseURL(request) + strings.TrimPrefix(request.URL.Path, BasePath)
+	if request.URL.RawQuery != "" {
+		self += "?" + request.URL.RawQuery
+	}
+	return self
+}
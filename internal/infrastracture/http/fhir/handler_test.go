@@ -0,0 +1,294 @@
+package fhir
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/fhir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var (
+	patientID   = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	diagnosisID = uuid.MustParse("11111111-1111-1111-1111-111111111112")
+	recordedAt  = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+)
+
+func newRouter(h *Handler) http.Handler {
+	router := chi.NewRouter()
+	router.Route(BasePath, func(r chi.Router) {
+		r.Get("/Patient/{"+IDURLParam+"}", h.ReadPatient)
+		r.Get("/Condition", h.SearchConditions)
+		r.Get("/MedicationRequest", h.SearchMedicationRequests)
+	})
+	return router
+}
+
+func serve(h *Handler, target string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	newRouter(h).ServeHTTP(response, httptest.NewRequest(http.MethodGet, target, nil))
+	return response
+}
+
+func TestHandler_ReadPatient(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		patient    *patients.Patient
+		handlerErr error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "return the patient resource",
+			id:         patientID.String(),
+			patient:    &patients.Patient{ID: patientID, Name: "Jane Doe", LegalID: "12345678"},
+			wantStatus: 200,
+		},
+		{
+			name:       "return bad request when the ID is invalid",
+			id:         "abc",
+			wantStatus: 400,
+			wantCode:   "invalid",
+		},
+		{
+			name:       "return not found when there is no patient",
+			id:         patientID.String(),
+			handlerErr: patients.ErrPatientNotFound,
+			wantStatus: 404,
+			wantCode:   "not-found",
+		},
+		{
+			name:       "return forbidden when the actor can't read patients",
+			id:         patientID.String(),
+			handlerErr: auth.ErrForbidden,
+			wantStatus: 403,
+			wantCode:   "forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &patientqueries.MockGetPatient{}
+			handler.On("Handle", mock.Anything, mock.Anything).Return(tt.patient, tt.handlerErr)
+			h := NewHandler(app.PatientServices{Queries: app.PatientQueries{GetPatient: handler}}, app.DiagnosisServices{})
+
+			response := serve(h, BasePath+"/Patient/"+tt.id)
+
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Equal(t, fhir.ContentType, response.Header().Get("Content-Type"))
+			if tt.wantCode != "" {
+				assertOutcome(t, response, tt.wantCode)
+				return
+			}
+
+			var patient fhir.Patient
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&patient))
+			assert.Equal(t, fhir.NewPatient(*tt.patient), patient)
+		})
+	}
+}
+
+func TestHandler_SearchConditions(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	next := diagnoses.Cursor{CreatedAt: recordedAt, ID: diagnosisID}
+	page := diagnoses.Page{
+		Diagnoses: []*diagnoses.Diagnosis{{ID: diagnosisID, PatientID: patientID, Description: "migraine", CreatedAt: recordedAt}},
+		Next:      &next,
+		Total:     3,
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantQuery  queries.GetDiagnosesQuery
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:  "search by patient reference and recorded date",
+			query: "?patient=Patient/" + patientID.String() + "&recorded-date=2024-03&_count=1",
+			wantQuery: queries.GetDiagnosesQuery{
+				PatientID: &patientID, From: &from, To: &to, Limit: 1, Order: diagnoses.SortAscending,
+			},
+			wantStatus: 200,
+		},
+		{
+			name:       "search by patient ID",
+			query:      "?patient=" + patientID.String(),
+			wantQuery:  queries.GetDiagnosesQuery{PatientID: &patientID, Order: diagnoses.SortAscending},
+			wantStatus: 200,
+		},
+		{
+			name:       "return bad request without patient nor recorded date",
+			query:      "?_count=10",
+			wantStatus: 400,
+			wantCode:   "required",
+		},
+		{
+			name:       "return bad request when the patient is invalid",
+			query:      "?patient=Practitioner/" + patientID.String(),
+			wantStatus: 400,
+			wantCode:   "invalid",
+		},
+		{
+			name:       "return bad request when the recorded date is invalid",
+			query:      "?recorded-date=ap2024",
+			wantStatus: 400,
+			wantCode:   "invalid",
+		},
+		{
+			name:       "return bad request when the count is too big",
+			query:      "?patient=" + patientID.String() + "&_count=501",
+			wantStatus: 400,
+			wantCode:   "invalid",
+		},
+		{
+			name:       "return bad request when the cursor is invalid",
+			query:      "?patient=" + patientID.String() + "&_cursor=abc",
+			wantStatus: 400,
+			wantCode:   "invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &queries.MockGetDiagnoses{}
+			handler.On("Handle", mock.Anything, mock.Anything).Return(page, nil)
+			h := NewHandler(app.PatientServices{}, app.DiagnosisServices{Queries: app.Queries{GetDiagnoses: handler}})
+
+			response := serve(h, BasePath+"/Condition"+tt.query)
+
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantCode != "" {
+				handler.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything)
+				assertOutcome(t, response, tt.wantCode)
+				return
+			}
+
+			handler.AssertCalled(t, "Handle", mock.Anything, mock.MatchedBy(func(query queries.GetDiagnosesQuery) bool {
+				query.Actor, query.RequestID = auth.Principal{}, ""
+				return assert.ObjectsAreEqual(tt.wantQuery, query)
+			}))
+
+			var bundle fhir.Bundle
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&bundle))
+			assert.Equal(t, 3, *bundle.Total)
+			if !assert.Len(t, bundle.Entry, 1) {
+				return
+			}
+			assert.Equal(t, "http://example.com"+BasePath+"/Condition/"+diagnosisID.String(), bundle.Entry[0].FullURL)
+			if !assert.Len(t, bundle.Link, 2) {
+				return
+			}
+			assert.Equal(t, "http://example.com"+BasePath+"/Condition"+tt.query, bundle.Link[0].URL)
+			assert.Contains(t, bundle.Link[1].URL, CursorSearchParam+"="+next.String())
+		})
+	}
+}
+
+func TestHandler_SearchMedicationRequests(t *testing.T) {
+	prescribed := []*diagnoses.Diagnosis{
+		{ID: diagnosisID, PatientID: patientID, CreatedAt: recordedAt, Prescription: &diagnoses.Prescription{Notes: "ibuprofen 400mg"}},
+	}
+	next := diagnoses.Cursor{CreatedAt: recordedAt, ID: diagnosisID}
+
+	tests := []struct {
+		name       string
+		query      string
+		page       diagnoses.Page
+		handlerErr error
+		wantStatus int
+		wantCode   string
+		wantTotal  *int
+		wantNext   string
+	}{
+		{
+			name:       "return the medication requests of the patient",
+			query:      "?patient=Patient/" + patientID.String(),
+			page:       diagnoses.Page{Diagnoses: prescribed, Total: 1},
+			wantStatus: 200,
+			wantTotal:  func() *int { total := 1; return &total }(),
+		},
+		{
+			name:       "return a page without total and the link of the next one",
+			query:      "?patient=" + patientID.String() + "&_count=1",
+			page:       diagnoses.Page{Diagnoses: prescribed, Total: 2, Next: &next},
+			wantStatus: 200,
+			wantNext: "http://example.com" + BasePath + "/MedicationRequest?_count=1&_cursor=" + next.String() +
+				"&patient=" + patientID.String(),
+		},
+		{
+			name:       "return bad request when the count is invalid",
+			query:      "?patient=" + patientID.String() + "&_count=0",
+			wantStatus: 400,
+			wantCode:   "invalid",
+		},
+		{
+			name:       "return bad request without patient",
+			wantStatus: 400,
+			wantCode:   "required",
+		},
+		{
+			name:       "return not found when there is no patient",
+			query:      "?patient=" + patientID.String(),
+			handlerErr: patients.ErrPatientNotFound,
+			wantStatus: 404,
+			wantCode:   "not-found",
+		},
+		{
+			name:       "return unauthorized when the actor is not authenticated",
+			query:      "?patient=" + patientID.String(),
+			handlerErr: auth.ErrUnauthenticated,
+			wantStatus: 401,
+			wantCode:   "login",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &queries.MockGetPatientPrescriptions{}
+			handler.On("Handle", mock.Anything, mock.Anything).Return(tt.page, tt.handlerErr)
+			h := NewHandler(app.PatientServices{}, app.DiagnosisServices{Queries: app.Queries{GetPatientPrescriptions: handler}})
+
+			response := serve(h, BasePath+"/MedicationRequest"+tt.query)
+
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantCode != "" {
+				assertOutcome(t, response, tt.wantCode)
+				return
+			}
+
+			var bundle fhir.Bundle
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&bundle))
+			assert.Equal(t, tt.wantTotal, bundle.Total)
+			if tt.wantNext != "" && assert.Len(t, bundle.Link, 2) {
+				assert.Equal(t, tt.wantNext, bundle.Link[1].URL)
+			}
+			if !assert.Len(t, bundle.Entry, 1) {
+				return
+			}
+			assert.Equal(t, "http://example.com"+BasePath+"/MedicationRequest/"+diagnosisID.String()+"-1", bundle.Entry[0].FullURL)
+		})
+	}
+}
+
+func assertOutcome(t *testing.T, response *httptest.ResponseRecorder, wantCode string) {
+	t.Helper()
+	var outcome fhir.OperationOutcome
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&outcome))
+	assert.Equal(t, "OperationOutcome", outcome.ResourceType)
+	if !assert.Len(t, outcome.Issue, 1) {
+		return
+	}
+	assert.Equal(t, wantCode, outcome.Issue[0].Code)
+}
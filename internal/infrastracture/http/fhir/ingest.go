@@ -0,0 +1,245 @@
+package fhir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/fhir"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const maxResourceSize = 4 << 20
+
+var (
+	errResourceTooLarge   = fmt.Errorf("resources can have at most %d bytes", maxResourceSize)
+	errUnsupportedRequest = errors.New("only POST requests to Condition are supported")
+)
+
+// CreateCondition godoc
+//
+//	@Summary		Create FHIR Condition
+//	@Description	Add a diagnosis sent as a FHIR R4 Condition. The subject must reference the patient as Patient/{id},
+//	@Description	or by an identifier of the urn:diagnosis-service:legal-id system. The diagnosis is the code text,
+//	@Description	and the ICD-10 coding, if any, codes it. Only clinicians can add diagnoses.
+//	@Tags			fhir
+//	@Accept			application/fhir+json
+//	@Produce		application/fhir+json
+//	@Param			condition	body		fhir.Condition	true	"condition"
+//	@Success		201			{object}	fhir.OperationOutcome
+//	@Failure		400			{object}	fhir.OperationOutcome
+//	@Failure		401			{object}	fhir.OperationOutcome
+//	@Failure		403			{object}	fhir.OperationOutcome
+//	@Failure		404			{object}	fhir.OperationOutcome
+//	@Failure		422			{object}	fhir.OperationOutcome
+//	@Failure		500			{object}	fhir.OperationOutcome
+//	@Security		BearerAuth
+//	@Router			/fhir/Condition [post]
+func (h *Handler) CreateCondition(writer http.ResponseWriter, request *http.Request) {
+	body, ok := readResource(writer, request)
+	if !ok {
+		return
+	}
+
+	command, res := h.newCommand(request, body)
+	if res.failed() {
+		writeResource(writer, res.status, res.outcome)
+		return
+	}
+
+	res = h.addDiagnosis(request.Context(), command)
+	writeResource(writer, res.status, res.outcome)
+}
+
+// ProcessBundle godoc
+//
+//	@Summary		Process FHIR batch or transaction Bundle
+//	@Description	Add the diagnoses sent as Conditions in a batch or transaction Bundle, whose entries must be POST
+//	@Description	requests to Condition. Batch entries are processed independently and the response has the outcome
+//	@Description	of each of them. Transaction entries are all validated, and their patients resolved, before they are
+//	@Description	added together: if any fails, none is added and the response is an OperationOutcome locating every
+//	@Description	failure.
+//	@Tags			fhir
+//	@Accept			application/fhir+json
+//	@Produce		application/fhir+json
+//	@Param			bundle	body		fhir.IncomingBundle	true	"batch or transaction bundle"
+//	@Success		200		{object}	fhir.Bundle
+//	@Failure		400		{object}	fhir.OperationOutcome
+//	@Failure		401		{object}	fhir.OperationOutcome
+//	@Failure		403		{object}	fhir.OperationOutcome
+//	@Failure		404		{object}	fhir.OperationOutcome
+//	@Failure		413		{object}	fhir.OperationOutcome
+//	@Failure		422		{object}	fhir.OperationOutcome
+//	@Failure		500		{object}	fhir.OperationOutcome
+//	@Security		BearerAuth
+//	@Router			/fhir [post]
+func (h *Handler) ProcessBundle(writer http.ResponseWriter, request *http.Request) {
+	body, ok := readResource(writer, request)
+	if !ok {
+		return
+	}
+
+	bundle, err := fhir.ParseBundle(body)
+	if err != nil {
+		writeOutcome(writer, http.StatusBadRequest, issueCode(err), err)
+		return
+	}
+
+	transaction := bundle.Type == fhir.BundleTypeTransaction
+	commandList := make([]commands.AddPatientDiagnosis, len(bundle.Entry))
+	results := make([]result, len(bundle.Entry))
+	for i, entry := range bundle.Entry {
+		if entry.Request == nil || entry.Request.Method != http.MethodPost || strings.Trim(entry.Request.URL, "/") != "Condition" {
+			results[i] = result{http.StatusBadRequest, fhir.NewOperationOutcome("not-supported", errUnsupportedRequest.Error())}
+			continue
+		}
+		commandList[i], results[i] = h.newCommand(request, entry.Resource)
+	}
+
+	if transaction {
+		if res, failed := transactionFailure(results); failed {
+			writeResource(writer, res.status, res.outcome)
+			return
+		}
+
+		// the entries are added in a single unit of work, so none is added when any fails
+		if res, failed := h.addDiagnoses(request.Context(), commandList, results); failed {
+			writeResource(writer, res.status, res.outcome)
+			return
+		}
+	} else {
+		for i := range bundle.Entry {
+			if !results[i].failed() {
+				results[i] = h.addDiagnosis(request.Context(), commandList[i])
+			}
+		}
+	}
+
+	response := fhir.Bundle{ResourceType: "Bundle", Type: bundle.Type + "-response"}
+	for _, res := range results {
+		outcome := res.outcome
+		response.Entry = append(response.Entry, fhir.BundleEntry{
+			Response: &fhir.EntryResponse{Status: statusLine(res.status), Outcome: &outcome},
+		})
+	}
+
+	writeResource(writer, http.StatusOK, response)
+}
+
+func (h *Handler) newCommand(request *http.Request, resource []byte) (commands.AddPatientDiagnosis, result) {
+	input, err := fhir.ParseCondition(resource)
+	if err != nil {
+		diagnostics := strings.ReplaceAll(err.Error(), "\n", "; ")
+		return commands.AddPatientDiagnosis{}, result{http.StatusBadRequest, fhir.NewOperationOutcome(issueCode(err), diagnostics)}
+	}
+
+	actor := authentication.PrincipalFromContext(request.Context())
+	patient, err := h.patientServices.Queries.GetPatient.Handle(request.Context(), patientqueries.GetPatientQuery{
+		PatientID: input.PatientID,
+		LegalID:   input.LegalID,
+		Actor:     actor,
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		return commands.AddPatientDiagnosis{}, errorResult(err)
+	}
+
+	return commands.AddPatientDiagnosis{
+		PatientID: patient.ID,
+		Diagnosis: input.Description,
+		Coding:    input.Coding,
+		Actor:     actor,
+		RequestID: middleware.GetReqID(request.Context()),
+	}, result{status: http.StatusOK}
+}
+
+func (h *Handler) addDiagnosis(ctx context.Context, command commands.AddPatientDiagnosis) result {
+	if _, err := h.diagnosisServices.Commands.AddPatientDiagnosisHandler.Handle(ctx, command); err != nil {
+		return errorResult(err)
+	}
+
+	return added(command)
+}
+
+// addDiagnoses sets the result of each entry, returning the failure of the transaction when any entry fails.
+func (h *Handler) addDiagnoses(ctx context.Context, commandList []commands.AddPatientDiagnosis, results []result) (result, bool) {
+	_, err := h.diagnosisServices.Commands.AddPatientDiagnosesHandler.Handle(ctx, commands.AddPatientDiagnoses{Diagnoses: commandList})
+	var diagnosesErrs commands.DiagnosesErrors
+	if errors.As(err, &diagnosesErrs) {
+		for _, diagnosisErr := range diagnosesErrs {
+			results[diagnosisErr.Index] = errorResult(diagnosisErr.Err)
+		}
+		return transactionFailure(results)
+	}
+	if err != nil {
+		return errorResult(err), true
+	}
+
+	for i, command := range commandList {
+		results[i] = added(command)
+	}
+	return result{}, false
+}
+
+func added(command commands.AddPatientDiagnosis) result {
+	diagnostics := "diagnosis added to Patient/" + command.PatientID.String()
+	return result{http.StatusCreated, fhir.NewInformationOutcome(diagnostics)}
+}
+
+func transactionFailure(results []result) (result, bool) {
+	failure := result{outcome: fhir.OperationOutcome{ResourceType: "OperationOutcome"}}
+	for i, res := range results {
+		if !res.failed() {
+			continue
+		}
+
+		if failure.status == 0 {
+			failure.status = res.status
+		}
+
+		for _, issue := range res.outcome.Issue {
+			issue.Expression = []string{fmt.Sprintf("Bundle.entry[%d]", i)}
+			failure.outcome.Issue = append(failure.outcome.Issue, issue)
+		}
+	}
+
+	return failure, failure.status != 0
+}
+
+func readResource(writer http.ResponseWriter, request *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxResourceSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeOutcome(writer, http.StatusRequestEntityTooLarge, "too-costly", errResourceTooLarge)
+			return nil, false
+		}
+
+		writeOutcome(writer, http.StatusBadRequest, "structure", err)
+		return nil, false
+	}
+
+	return body, true
+}
+
+func issueCode(err error) string {
+	switch {
+	case errors.Is(err, fhir.ErrInvalidResource):
+		return "structure"
+	case errors.Is(err, fhir.ErrInvalidBundleType):
+		return "not-supported"
+	case errors.Is(err, fhir.ErrTooManyEntries):
+		return "too-costly"
+	default:
+		return "invalid"
+	}
+}
+
+func statusLine(status int) string {
+	return fmt.Sprintf("%d %s", status, http.StatusText(status))
+}
@@ -0,0 +1,247 @@
+package fhir
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	patientqueries "github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/fhir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	migraine = `{"resourceType": "Condition", "subject": {"reference": "Patient/11111111-1111-1111-1111-111111111111"},
+		"code": {"text": "migraine"}}`
+	asthma = `{"resourceType": "Condition", "subject": {"identifier": {"system": "urn:diagnosis-service:legal-id", "value": "123"}},
+		"code": {"coding": [{"system": "http://hl7.org/fhir/sid/icd-10", "code": "J45.0", "display": "Allergic asthma"}]}}`
+	uncoded = `{"resourceType": "Condition", "subject": {"reference": "Patient/11111111-1111-1111-1111-111111111111"}}`
+)
+
+func newIngestHandler(getPatientErr, addErr error) (*Handler, *commands.MockAddPatientDiagnosis) {
+	getPatient := &patientqueries.MockGetPatient{}
+	getPatient.On("Handle", mock.Anything, mock.Anything).Return(&patients.Patient{ID: patientID}, getPatientErr)
+	addDiagnosis := &commands.MockAddPatientDiagnosis{}
+	addDiagnosis.On("Handle", mock.Anything, mock.Anything).Return(&diagnoses.Diagnosis{}, addErr)
+	h := NewHandler(
+		app.PatientServices{Queries: app.PatientQueries{GetPatient: getPatient}},
+		app.DiagnosisServices{Commands: app.Commands{AddPatientDiagnosisHandler: addDiagnosis}},
+	)
+	return h, addDiagnosis
+}
+
+func post(h *Handler, target, body string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Route(BasePath, func(r chi.Router) {
+		r.Post("/", h.ProcessBundle)
+		r.Post("/Condition", h.CreateCondition)
+	})
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	return response
+}
+
+func bundle(bundleType string, resources ...string) string {
+	entries := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		entries = append(entries, `{"resource": `+resource+`, "request": {"method": "POST", "url": "Condition"}}`)
+	}
+	return `{"resourceType": "Bundle", "type": "` + bundleType + `", "entry": [` + strings.Join(entries, ",") + `]}`
+}
+
+func TestHandler_CreateCondition(t *testing.T) {
+	asthmaCommand := &commands.AddPatientDiagnosis{
+		PatientID: patientID,
+		Diagnosis: "Allergic asthma",
+		Coding:    &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0"},
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		getPatientErr error
+		addErr        error
+		wantCommand   *commands.AddPatientDiagnosis
+		wantStatus    int
+		wantCode      string
+	}{
+		{
+			name:        "add the diagnosis of the condition",
+			body:        migraine,
+			wantCommand: &commands.AddPatientDiagnosis{PatientID: patientID, Diagnosis: "migraine"},
+			wantStatus:  201,
+			wantCode:    "informational",
+		},
+		{
+			name:        "add the coded diagnosis of the patient with the legal ID",
+			body:        asthma,
+			wantCommand: asthmaCommand,
+			wantStatus:  201,
+			wantCode:    "informational",
+		},
+		{
+			name:       "return bad request when the condition is invalid",
+			body:       uncoded,
+			wantStatus: 400,
+			wantCode:   "invalid",
+		},
+		{
+			name:       "return bad request when the condition is malformed",
+			body:       `{"resourceType": "Condition", "code": []}`,
+			wantStatus: 400,
+			wantCode:   "structure",
+		},
+		{
+			name:          "return not found when the subject is not a patient",
+			body:          migraine,
+			getPatientErr: patients.ErrPatientNotFound,
+			wantStatus:    404,
+			wantCode:      "not-found",
+		},
+		{
+			name:        "return unprocessable entity when the code is unknown",
+			body:        asthma,
+			addErr:      commands.ErrInvalidCoding,
+			wantCommand: asthmaCommand,
+			wantStatus:  422,
+			wantCode:    "code-invalid",
+		},
+		{
+			name:        "return unprocessable entity when the diagnosis is invalid",
+			body:        migraine,
+			addErr:      validation.Errors{{Field: "diagnosis", Message: "can't be longer than 2000 characters"}},
+			wantCommand: &commands.AddPatientDiagnosis{PatientID: patientID, Diagnosis: "migraine"},
+			wantStatus:  422,
+			wantCode:    "invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, addDiagnosis := newIngestHandler(tt.getPatientErr, tt.addErr)
+
+			response := post(h, BasePath+"/Condition", tt.body)
+
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assertOutcome(t, response, tt.wantCode)
+			if tt.wantCommand == nil {
+				addDiagnosis.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything)
+				return
+			}
+			addDiagnosis.AssertCalled(t, "Handle", mock.Anything, mock.MatchedBy(func(command commands.AddPatientDiagnosis) bool {
+				return command.PatientID == tt.wantCommand.PatientID && command.Diagnosis == tt.wantCommand.Diagnosis &&
+					assert.ObjectsAreEqual(tt.wantCommand.Coding, command.Coding)
+			}))
+		})
+	}
+}
+
+func TestHandler_ProcessBundle(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		addErr error
+		// transactionErr is the error adding the diagnoses of a transaction
+		transactionErr   error
+		wantStatus       int
+		wantType         string
+		wantStatuses     []string
+		wantIssues       []string
+		wantAdded        int
+		wantTransactions int
+	}{
+		{
+			name:         "process every entry of a batch",
+			body:         bundle("batch", migraine, uncoded, asthma),
+			wantStatus:   200,
+			wantType:     "batch-response",
+			wantStatuses: []string{"201 Created", "400 Bad Request", "201 Created"},
+			wantAdded:    2,
+		},
+		{
+			name:             "process a transaction",
+			body:             bundle("transaction", migraine, asthma),
+			wantStatus:       200,
+			wantType:         "transaction-response",
+			wantStatuses:     []string{"201 Created", "201 Created"},
+			wantTransactions: 1,
+		},
+		{
+			name:       "add none of the transaction entries when any is invalid",
+			body:       bundle("transaction", migraine, uncoded, `{"resourceType": "Patient"}`),
+			wantStatus: 400,
+			wantIssues: []string{"Bundle.entry[1]", "Bundle.entry[2]"},
+		},
+		{
+			name:             "add none of the transaction entries when any can't be added",
+			body:             bundle("transaction", migraine, asthma),
+			transactionErr:   commands.DiagnosesErrors{{Index: 1, Err: commands.ErrInvalidCoding}},
+			wantStatus:       422,
+			wantIssues:       []string{"Bundle.entry[1]"},
+			wantTransactions: 1,
+		},
+		{
+			name:             "return server error when the transaction can't be stored",
+			body:             bundle("transaction", migraine, asthma),
+			transactionErr:   commands.DiagnosesErrors{{Index: 0, Err: commands.ErrAddingDiagnosis}},
+			wantStatus:       500,
+			wantIssues:       []string{"Bundle.entry[0]"},
+			wantTransactions: 1,
+		},
+		{
+			name:         "reject entries that are not POST requests to Condition",
+			body:         `{"resourceType": "Bundle", "type": "batch", "entry": [{"resource": {}, "request": {"method": "PUT", "url": "Condition/1"}}]}`,
+			wantStatus:   200,
+			wantType:     "batch-response",
+			wantStatuses: []string{"400 Bad Request"},
+		},
+		{
+			name:       "return bad request when the bundle is not a batch nor a transaction",
+			body:       bundle("collection", migraine),
+			wantStatus: 400,
+			wantIssues: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, addDiagnosis := newIngestHandler(nil, tt.addErr)
+			addDiagnoses := &commands.MockAddPatientDiagnoses{}
+			addDiagnoses.On("Handle", mock.Anything, mock.Anything).Return([]*diagnoses.Diagnosis{}, tt.transactionErr)
+			h.diagnosisServices.Commands.AddPatientDiagnosesHandler = addDiagnoses
+
+			response := post(h, BasePath, tt.body)
+
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Equal(t, fhir.ContentType, response.Header().Get("Content-Type"))
+			addDiagnosis.AssertNumberOfCalls(t, "Handle", tt.wantAdded)
+			addDiagnoses.AssertNumberOfCalls(t, "Handle", tt.wantTransactions)
+			if tt.wantIssues != nil {
+				var outcome fhir.OperationOutcome
+				assert.NoError(t, json.NewDecoder(response.Body).Decode(&outcome))
+				var issues []string
+				for _, issue := range outcome.Issue {
+					issues = append(issues, strings.Join(issue.Expression, ","))
+				}
+				assert.Equal(t, tt.wantIssues, issues)
+				return
+			}
+
+			var got fhir.Bundle
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&got))
+			assert.Equal(t, tt.wantType, got.Type)
+			var statuses []string
+			for _, entry := range got.Entry {
+				statuses = append(statuses, entry.Response.Status)
+				assert.NotNil(t, entry.Response.Outcome)
+			}
+			assert.Equal(t, tt.wantStatuses, statuses)
+		})
+	}
+}
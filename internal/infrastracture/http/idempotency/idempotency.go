@@ -0,0 +1,171 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	KeyHeader      = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+	maxKeyLength   = 255
+	// pendingTimeout bounds how long a key stays reserved by a request that never completes, such as when the
+	// service stops while processing it
+	pendingTimeout = time.Minute
+)
+
+var (
+	ErrInvalidKey = render.InvalidParam(KeyHeader, errors.New("the Idempotency-Key header must have between 1 and 255 characters"))
+	ErrKeyInUse   = render.NewRequestError(http.StatusConflict, render.TypeIdempotencyKeyInUse,
+		"a request with this idempotency key is still being processed")
+	ErrKeyReused = render.NewRequestError(http.StatusUnprocessableEntity, render.TypeIdempotencyKeyReused,
+		"the idempotency key was already used with a different request")
+)
+
+// replayedHeaders are the response headers stored with the response, the rest are set again by the middlewares.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+type handler struct {
+	store idempotency.Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// Middleware makes the requests sent with an Idempotency-Key header safe to retry. The first response to a key
+// is stored for ttl and replayed to the retries, while reusing the key with a different request is rejected
+// with 422. Keys are scoped to the authenticated principal, and server errors are not stored so the request
+// can be retried. Requests without the header are processed as usual.
+func Middleware(store idempotency.Store, ttl time.Duration) func(http.Handler) http.Handler {
+	h := handler{store: store, ttl: ttl, now: time.Now}
+	return h.middleware
+}
+
+func (h handler) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		values, sent := request.Header[KeyHeader]
+		if !sent {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		if len(values) != 1 || len(values[0]) == 0 || len(values[0]) > maxKeyLength {
+			render.Error(writer, request, ErrInvalidKey)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, render.MaxBodySize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			render.Error(writer, request, render.ErrBodyTooLarge)
+			return
+		}
+		if err != nil {
+			render.Error(writer, request, render.InvalidParam("body", errors.New("the body could not be read")))
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		principal := authentication.PrincipalFromContext(request.Context())
+		record := idempotency.Record{
+			Key:         storeKey(principal.ID, values[0]),
+			Fingerprint: fingerprint(request, body),
+			ExpiresAt:   h.now().Add(pendingTimeout),
+		}
+
+		existing, err := h.store.Reserve(request.Context(), record, h.now())
+		if err != nil {
+			render.Error(writer, request, fmt.Errorf("error reserving idempotency key: %w", err))
+			return
+		}
+
+		switch {
+		case existing == nil:
+			h.process(writer, request, next, record.Key)
+		case existing.Fingerprint != record.Fingerprint:
+			render.Error(writer, request, ErrKeyReused)
+		case existing.Response == nil:
+			render.Error(writer, request, ErrKeyInUse)
+		default:
+			replay(writer, *existing.Response)
+		}
+	})
+}
+
+func (h handler) process(writer http.ResponseWriter, request *http.Request, next http.Handler, key string) {
+	var body bytes.Buffer
+	wrapped := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+	wrapped.Tee(&body)
+
+	// the response is stored even when the client is gone, so the key isn't left pending
+	ctx := context.WithoutCancel(request.Context())
+	completed := false
+	defer func() {
+		if !completed {
+			h.release(ctx, key)
+		}
+	}()
+
+	next.ServeHTTP(wrapped, request)
+
+	status := wrapped.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusInternalServerError {
+		return
+	}
+
+	response := idempotency.Response{StatusCode: status, Header: map[string]string{}, Body: body.Bytes()}
+	for _, name := range replayedHeaders {
+		if value := wrapped.Header().Get(name); value != "" {
+			response.Header[name] = value
+		}
+	}
+
+	if err := h.store.Complete(ctx, key, response, h.now().Add(h.ttl)); err != nil {
+		slog.Error("error storing idempotent response", "err", err)
+		return
+	}
+	completed = true
+}
+
+func (h handler) release(ctx context.Context, key string) {
+	if err := h.store.Release(ctx, key); err != nil {
+		slog.Error("error releasing idempotency key", "err", err)
+	}
+}
+
+func replay(writer http.ResponseWriter, response idempotency.Response) {
+	for name, value := range response.Header {
+		writer.Header().Set(name, value)
+	}
+	writer.Header().Set(ReplayedHeader, "true")
+	writer.WriteHeader(response.StatusCode)
+	if _, err := writer.Write(response.Body); err != nil {
+		slog.Error("error replaying idempotent response", "err", err)
+	}
+}
+
+// storeKey prefixes the principal ID with its length, as IDs and keys can hold any character.
+func storeKey(principalID, key string) string {
+	return fmt.Sprintf("%d:%s:%s", len(principalID), principalID, key)
+}
+
+func fingerprint(request *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(request.Method + " " + request.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
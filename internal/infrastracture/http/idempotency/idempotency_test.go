@@ -0,0 +1,162 @@
+package idempotency
+
+import (
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeHandler struct {
+	calls  int
+	status int
+}
+
+func (f *fakeHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	f.calls++
+	body, _ := io.ReadAll(request.Body)
+	writer.Header().Set("Location", "/api/v1/diagnoses/1")
+	writer.WriteHeader(f.status)
+	writer.Write(body)
+}
+
+func newRequest(principalID, key, body string) *http.Request {
+	request := httptest.NewRequest("POST", "/api/v1/patient/1/diagnoses", strings.NewReader(body))
+	if key != "" {
+		request.Header.Set(KeyHeader, key)
+	}
+	return request.WithContext(authentication.WithPrincipal(request.Context(), auth.Principal{ID: principalID}))
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		requests     []*http.Request
+		wantStatuses []int
+		wantCalls    int
+		wantReplayed bool
+	}{
+		{
+			name:         "process requests without key",
+			status:       201,
+			requests:     []*http.Request{newRequest("practitioner-1", "", "asthma"), newRequest("practitioner-1", "", "asthma")},
+			wantStatuses: []int{201, 201},
+			wantCalls:    2,
+		},
+		{
+			name:         "reject key longer than 255 characters",
+			status:       201,
+			requests:     []*http.Request{newRequest("practitioner-1", strings.Repeat("k", 256), "asthma")},
+			wantStatuses: []int{400},
+			wantCalls:    0,
+		},
+		{
+			name:         "replay the response to a retry",
+			status:       201,
+			requests:     []*http.Request{newRequest("practitioner-1", "key-1", "asthma"), newRequest("practitioner-1", "key-1", "asthma")},
+			wantStatuses: []int{201, 201},
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+		{
+			name:         "replay client errors",
+			status:       422,
+			requests:     []*http.Request{newRequest("practitioner-1", "key-1", "asthma"), newRequest("practitioner-1", "key-1", "asthma")},
+			wantStatuses: []int{422, 422},
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+		{
+			name:         "reject key reused with a different request",
+			status:       201,
+			requests:     []*http.Request{newRequest("practitioner-1", "key-1", "asthma"), newRequest("practitioner-1", "key-1", "migraine")},
+			wantStatuses: []int{201, 422},
+			wantCalls:    1,
+		},
+		{
+			name:         "scope keys to the principal",
+			status:       201,
+			requests:     []*http.Request{newRequest("practitioner-1", "key-1", "asthma"), newRequest("practitioner-2", "key-1", "asthma")},
+			wantStatuses: []int{201, 201},
+			wantCalls:    2,
+		},
+		{
+			name:         "keep apart the keys of principals whose ID has colons",
+			status:       201,
+			requests:     []*http.Request{newRequest("practitioner:1", "key-1", "asthma"), newRequest("practitioner", "1:key-1", "asthma")},
+			wantStatuses: []int{201, 201},
+			wantCalls:    2,
+		},
+		{
+			name:         "reject bodies larger than the maximum size",
+			status:       201,
+			requests:     []*http.Request{newRequest("practitioner-1", "key-1", strings.Repeat("a", render.MaxBodySize+1))},
+			wantStatuses: []int{413},
+			wantCalls:    0,
+		},
+		{
+			name:         "process again the requests failing with server errors",
+			status:       500,
+			requests:     []*http.Request{newRequest("practitioner-1", "key-1", "asthma"), newRequest("practitioner-1", "key-1", "asthma")},
+			wantStatuses: []int{500, 500},
+			wantCalls:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeHandler{status: tt.status}
+			handler := Middleware(memory.NewRepository(), time.Hour)(next)
+
+			var last *httptest.ResponseRecorder
+			for i, request := range tt.requests {
+				last = httptest.NewRecorder()
+				handler.ServeHTTP(last, request)
+				assert.Equal(t, tt.wantStatuses[i], last.Code, "request %d", i)
+			}
+
+			assert.Equal(t, tt.wantCalls, next.calls)
+			if tt.wantReplayed {
+				assert.Equal(t, "true", last.Header().Get(ReplayedHeader))
+				assert.Equal(t, "/api/v1/diagnoses/1", last.Header().Get("Location"))
+				assert.Equal(t, "asthma", last.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddleware_PendingAndExpiredKeys(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	store := memory.NewRepository()
+	h := handler{store: store, ttl: time.Hour, now: func() time.Time { return now }}
+
+	var retry *httptest.ResponseRecorder
+	next := &fakeHandler{status: 201}
+	blocking := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// the retry arrives while the first request is being processed
+		if next.calls == 0 {
+			retry = httptest.NewRecorder()
+			h.middleware(next).ServeHTTP(retry, newRequest("practitioner-1", "key-1", "asthma"))
+		}
+		next.ServeHTTP(writer, request)
+	})
+	h.middleware(blocking).ServeHTTP(httptest.NewRecorder(), newRequest("practitioner-1", "key-1", "asthma"))
+
+	assert.Equal(t, http.StatusConflict, retry.Code)
+	assert.Equal(t, 1, next.calls)
+
+	now = now.Add(time.Hour)
+	response := httptest.NewRecorder()
+	h.middleware(next).ServeHTTP(response, newRequest("practitioner-1", "key-1", "asthma"))
+
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Empty(t, response.Header().Get(ReplayedHeader))
+	assert.Equal(t, 2, next.calls)
+}
@@ -0,0 +1,371 @@
+package patients
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/render"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidID          = errors.New("invalid ID")
+	errInvalidBirthDate   = errors.New("invalid birth date, expected YYYY-MM-DD")
+	errMissingName        = errors.New("the name query param is required")
+	errInvalidLimit       = errors.New("invalid limit, expected a number between 1 and 50")
+	errSeveralETags       = errors.New("only the ETag of the patient read can be sent")
+	errPreconditionFailed = render.NewRequestError(http.StatusPreconditionFailed, "",
+		"the patient was modified since the version in If-Match, read it again and retry")
+)
+
+const (
+	PatientIDURLParam = "patientID"
+	NameQueryParam    = "name"
+	LimitQueryParam   = "limit"
+	// DateLayout is the format of birth dates
+	DateLayout = "2006-01-02"
+)
+
+type Handler struct {
+	patientServices app.PatientServices
+}
+
+func NewHandler(patientServices app.PatientServices) *Handler {
+	return &Handler{
+		patientServices: patientServices,
+	}
+}
+
+type CreatePatientRequest struct {
+	LegalID   string `json:"legal_id"`
+	Name      string `json:"name"`
+	BirthDate string `json:"birth_date,omitempty" example:"1980-01-31"`
+	Address   string `json:"address"`
+	Phone     string `json:"phone"`
+	Email     string `json:"email"`
+}
+
+type UpdateContactRequest struct {
+	Address string `json:"address"`
+	Phone   string `json:"phone"`
+	Email   string `json:"email"`
+}
+
+type PatientResponse struct {
+	ID        uuid.UUID `json:"id"`
+	LegalID   string    `json:"legal_id"`
+	Name      string    `json:"name"`
+	BirthDate string    `json:"birth_date,omitempty" example:"1980-01-31"`
+	Address   string    `json:"address"`
+	Phone     string    `json:"phone"`
+	Email     string    `json:"email"`
+}
+
+type ListPatientsResponse struct {
+	Patients []PatientResponse `json:"patients"`
+}
+
+type CandidateResponse struct {
+	Patient PatientResponse `json:"patient"`
+	// Score goes from 0.6 to 1 for an exact match
+	Score float64 `json:"score" example:"0.87"`
+}
+
+type SearchPatientsResponse struct {
+	Candidates []CandidateResponse `json:"candidates"`
+}
+
+// CreatePatient godoc
+//
+//	@Summary		Create patient
+//	@Description	Create a patient. The legal ID must not belong to another patient.
+//	@Tags			patient
+//	@Accept			json
+//	@Produce		json
+//	@Param			patient	body		CreatePatientRequest	true	"patient to create"
+//	@Success		201		{object}	PatientResponse
+//	@Failure		400		{object}	render.Problem
+//	@Failure		401		{object}	render.Problem
+//	@Failure		403		{object}	render.Problem
+//	@Failure		409		{object}	render.Problem
+//	@Failure		413		{object}	render.Problem
+//	@Failure		422		{object}	render.Problem
+//	@Failure		500		{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/patients [post]
+func (h *Handler) CreatePatient(writer http.ResponseWriter, request *http.Request) {
+	createRequest := CreatePatientRequest{}
+	if err := render.DecodeJSON(writer, request, &createRequest); err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	var birthDate *time.Time
+	if createRequest.BirthDate != "" {
+		date, err := time.Parse(DateLayout, createRequest.BirthDate)
+		if err != nil {
+			render.Error(writer, request, render.InvalidParam("birth_date", errInvalidBirthDate))
+			return
+		}
+		birthDate = &date
+	}
+
+	patient, err := h.patientServices.Commands.CreatePatient.Handle(request.Context(), commands.CreatePatient{
+		LegalID:   createRequest.LegalID,
+		Name:      createRequest.Name,
+		BirthDate: birthDate,
+		Address:   createRequest.Address,
+		Phone:     createRequest.Phone,
+		Email:     createRequest.Email,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("Location", "/api/v1/patients/"+patient.ID.String())
+	writer.Header().Set("ETag", etag(patient))
+	render.JSON(writer, http.StatusCreated, toPatientResponse(patient))
+}
+
+// UpdateContact godoc
+//
+//	@Summary		Update patient contact
+//	@Description	Replace the address, phone and email of a patient. Send the ETag of the patient read as If-Match to
+//	@Description	update only that version, otherwise the contact replaces the one of the latest version.
+//	@Tags			patient
+//	@Accept			json
+//	@Produce		json
+//	@Param			patientID	path		string					true	"patient ID"
+//	@Param			If-Match	header		string					false	"ETag of the patient read, a single one"
+//	@Param			contact		body		UpdateContactRequest	true	"new contact data"
+//	@Success		200			{object}	PatientResponse
+//	@Header			200			{string}	ETag	"version of the updated patient"
+//	@Failure		400			{object}	render.Problem
+//	@Failure		401			{object}	render.Problem
+//	@Failure		403			{object}	render.Problem
+//	@Failure		404			{object}	render.Problem
+//	@Failure		409			{object}	render.Problem
+//	@Failure		412			{object}	render.Problem
+//	@Failure		413			{object}	render.Problem
+//	@Failure		422			{object}	render.Problem
+//	@Failure		500			{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/patients/{patientID}/contact [put]
+func (h *Handler) UpdateContact(writer http.ResponseWriter, request *http.Request) {
+	patientID, parseErr := uuid.Parse(chi.URLParam(request, PatientIDURLParam))
+	if parseErr != nil {
+		render.Error(writer, request, render.InvalidParam(PatientIDURLParam, errInvalidID))
+		return
+	}
+
+	version, err := parseIfMatch(request.Header.Get("If-Match"))
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	updateRequest := UpdateContactRequest{}
+	if err := render.DecodeJSON(writer, request, &updateRequest); err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	patient, err := h.patientServices.Commands.UpdatePatientContact.Handle(request.Context(), commands.UpdatePatientContact{
+		PatientID: patientID,
+		Address:   updateRequest.Address,
+		Phone:     updateRequest.Phone,
+		Email:     updateRequest.Email,
+		Version:   version,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		if version != nil && errors.Is(err, patients.ErrConcurrentModification) {
+			err = errPreconditionFailed
+		}
+		render.Error(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("ETag", etag(patient))
+	render.JSON(writer, http.StatusOK, toPatientResponse(patient))
+}
+
+// GetPatient godoc
+//
+//	@Summary		Get patient
+//	@Description	Get the identity and contact data of a patient
+//	@Tags			patient
+//	@Produce		json
+//	@Param			patientID	path		string	true	"patient ID"
+//	@Success		200			{object}	PatientResponse
+//	@Header			200			{string}	ETag	"version of the patient, to send as If-Match when updating it"
+//	@Failure		400			{object}	render.Problem
+//	@Failure		401			{object}	render.Problem
+//	@Failure		403			{object}	render.Problem
+//	@Failure		404			{object}	render.Problem
+//	@Failure		500			{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/patients/{patientID} [get]
+func (h *Handler) GetPatient(writer http.ResponseWriter, request *http.Request) {
+	patientID, parseErr := uuid.Parse(chi.URLParam(request, PatientIDURLParam))
+	if parseErr != nil {
+		render.Error(writer, request, render.InvalidParam(PatientIDURLParam, errInvalidID))
+		return
+	}
+
+	patient, err := h.patientServices.Queries.GetPatient.Handle(request.Context(), queries.GetPatientQuery{
+		PatientID: patientID,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("ETag", etag(patient))
+	render.JSON(writer, http.StatusOK, toPatientResponse(patient))
+}
+
+// ListPatients godoc
+//
+//	@Summary		List patients
+//	@Description	List every patient sorted by name
+//	@Tags			patient
+//	@Produce		json
+//	@Success		200	{object}	ListPatientsResponse
+//	@Failure		401	{object}	render.Problem
+//	@Failure		403	{object}	render.Problem
+//	@Failure		500	{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/patients [get]
+func (h *Handler) ListPatients(writer http.ResponseWriter, request *http.Request) {
+	found, err := h.patientServices.Queries.ListPatients.Handle(request.Context(), queries.ListPatientsQuery{
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	response := ListPatientsResponse{Patients: make([]PatientResponse, 0, len(found))}
+	for _, patient := range found {
+		response.Patients = append(response.Patients, toPatientResponse(patient))
+	}
+
+	render.JSON(writer, http.StatusOK, response)
+}
+
+// SearchPatients godoc
+//
+//	@Summary		Search patients by name
+//	@Description	Search the patients whose name matches ignoring case, accents, word order and small typos, such as
+//	@Description	jose perez for José Pérez. Candidates come from the best match, with the legal ID and birth date
+//	@Description	to tell apart patients with similar names.
+//	@Tags			patient
+//	@Produce		json
+//	@Param			name	query		string	true	"name, or part of it, of the patient"
+//	@Param			limit	query		int		false	"maximum number of candidates, 10 by default and 50 at most"
+//	@Success		200		{object}	SearchPatientsResponse
+//	@Failure		400		{object}	render.Problem
+//	@Failure		401		{object}	render.Problem
+//	@Failure		403		{object}	render.Problem
+//	@Failure		500		{object}	render.Problem
+//	@Security		BearerAuth
+//	@Router			/patients/search [get]
+func (h *Handler) SearchPatients(writer http.ResponseWriter, request *http.Request) {
+	params := request.URL.Query()
+	name := strings.TrimSpace(params.Get(NameQueryParam))
+	if patients.NormalizeName(name) == "" {
+		render.Error(writer, request, render.InvalidParam(NameQueryParam, errMissingName))
+		return
+	}
+
+	var limit int
+	if limitParam := params.Get(LimitQueryParam); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > queries.MaxSearchLimit {
+			render.Error(writer, request, render.InvalidParam(LimitQueryParam, errInvalidLimit))
+			return
+		}
+	}
+
+	candidates, err := h.patientServices.Queries.SearchPatients.Handle(request.Context(), queries.SearchPatientsQuery{
+		Name:      name,
+		Limit:     limit,
+		Actor:     authentication.PrincipalFromContext(request.Context()),
+		RequestID: middleware.GetReqID(request.Context()),
+	})
+	if err != nil {
+		render.Error(writer, request, err)
+		return
+	}
+
+	response := SearchPatientsResponse{Candidates: make([]CandidateResponse, 0, len(candidates))}
+	for _, candidate := range candidates {
+		response.Candidates = append(response.Candidates, CandidateResponse{
+			Patient: toPatientResponse(candidate.Patient),
+			Score:   math.Round(candidate.Score*100) / 100,
+		})
+	}
+
+	render.JSON(writer, http.StatusOK, response)
+}
+
+func toPatientResponse(patient *patients.Patient) PatientResponse {
+	response := PatientResponse{
+		ID:      patient.ID,
+		LegalID: patient.LegalID,
+		Name:    patient.Name,
+		Address: patient.Address,
+		Phone:   patient.Phone,
+		Email:   patient.Email,
+	}
+
+	if patient.BirthDate != nil {
+		response.BirthDate = patient.BirthDate.Format(DateLayout)
+	}
+
+	return response
+}
+
+func etag(patient *patients.Patient) string {
+	return `"` + strconv.Itoa(patient.Version) + `"`
+}
+
+// parseIfMatch returns nil when any version matches. Tags that can't match a patient version, as weak ones, fail.
+func parseIfMatch(header string) (*int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	if strings.Contains(header, ",") {
+		return nil, render.InvalidParam("If-Match", errSeveralETags)
+	}
+
+	unquoted, found := strings.CutPrefix(header, `"`)
+	unquoted, closed := strings.CutSuffix(unquoted, `"`)
+	version, err := strconv.Atoi(unquoted)
+	if !found || !closed || err != nil {
+		return nil, errPreconditionFailed
+	}
+
+	return &version, nil
+}
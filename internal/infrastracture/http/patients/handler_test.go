@@ -0,0 +1,502 @@
+package patients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/patients/queries"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var patientID = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+
+func TestHandler_CreatePatient(t *testing.T) {
+	validRequest := CreatePatientRequest{
+		LegalID: "ABC1234",
+		Name:    "John Doe",
+		Address: "Wall Street 123",
+		Phone:   "123456789",
+		Email:   "john.doe@example.com",
+	}
+	command := commands.CreatePatient{
+		LegalID: "ABC1234",
+		Name:    "John Doe",
+		Address: "Wall Street 123",
+		Phone:   "123456789",
+		Email:   "john.doe@example.com",
+	}
+
+	auditor := auth.Principal{ID: "auditor-1", Roles: []auth.Role{auth.RoleAuditor}}
+	auditorCommand := command
+	auditorCommand.Actor = auditor
+
+	tests := []struct {
+		name         string
+		handler      commands.CreatePatientHandler
+		actor        auth.Principal
+		body         interface{}
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:       "return bad request when the body is not valid JSON",
+			handler:    nil,
+			body:       "{",
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the birth date is not a date",
+			handler:    nil,
+			body:       CreatePatientRequest{LegalID: "ABC1234", Name: "John Doe", BirthDate: "31/01/1980"},
+			wantStatus: 400,
+		},
+		{
+			name: "return unprocessable entity when the patient is invalid",
+			handler: func() commands.CreatePatientHandler {
+				handler := &commands.MockCreatePatient{}
+				handler.On("Handle", mock.Anything, command).
+					Return((*patients.Patient)(nil), fmt.Errorf("%w: %w", commands.ErrInvalidPatient, patients.ErrInvalidEmail))
+				return handler
+			}(),
+			body:       validRequest,
+			wantStatus: 422,
+		},
+		{
+			name: "return conflict when the legal ID is already registered",
+			handler: func() commands.CreatePatientHandler {
+				handler := &commands.MockCreatePatient{}
+				handler.On("Handle", mock.Anything, command).Return((*patients.Patient)(nil), patients.ErrDuplicatedLegalID)
+				return handler
+			}(),
+			body:       validRequest,
+			wantStatus: 409,
+		},
+		{
+			name: "return forbidden when the actor can't write patients",
+			handler: func() commands.CreatePatientHandler {
+				handler := &commands.MockCreatePatient{}
+				handler.On("Handle", mock.Anything, auditorCommand).Return((*patients.Patient)(nil), auth.ErrForbidden)
+				return handler
+			}(),
+			actor:      auditor,
+			body:       validRequest,
+			wantStatus: 403,
+		},
+		{
+			name: "return server error when the patient can't be created",
+			handler: func() commands.CreatePatientHandler {
+				handler := &commands.MockCreatePatient{}
+				handler.On("Handle", mock.Anything, command).Return((*patients.Patient)(nil), commands.ErrCreatingPatient)
+				return handler
+			}(),
+			body:       validRequest,
+			wantStatus: 500,
+		},
+		{
+			name: "create the patient without error",
+			handler: func() commands.CreatePatientHandler {
+				handler := &commands.MockCreatePatient{}
+				handler.On("Handle", mock.Anything, command).Return(&patients.Patient{ID: patientID, Name: "John Doe"}, nil)
+				return handler
+			}(),
+			body:         validRequest,
+			wantStatus:   201,
+			wantLocation: "/api/v1/patients/" + patientID.String(),
+		},
+		{
+			name: "create the patient with birth date without error",
+			handler: func() commands.CreatePatientHandler {
+				birthDate := time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)
+				c := command
+				c.BirthDate = &birthDate
+				handler := &commands.MockCreatePatient{}
+				handler.On("Handle", mock.Anything, c).Return(&patients.Patient{ID: patientID, BirthDate: &birthDate}, nil)
+				return handler
+			}(),
+			body: func() CreatePatientRequest {
+				r := validRequest
+				r.BirthDate = "1980-01-31"
+				return r
+			}(),
+			wantStatus:   201,
+			wantLocation: "/api/v1/patients/" + patientID.String(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.PatientServices{Commands: app.PatientCommands{CreatePatient: tt.handler}})
+			r, _ := http.NewRequest("POST", "/patients", encodeBody(tt.body))
+			r = r.WithContext(authentication.WithPrincipal(r.Context(), tt.actor))
+			response := httptest.NewRecorder()
+			h.CreatePatient(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Equal(t, tt.wantLocation, response.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHandler_UpdateContact(t *testing.T) {
+	body := UpdateContactRequest{Address: "Main Street 1", Phone: "987654321", Email: "new.mail@example.com"}
+	command := commands.UpdatePatientContact{
+		PatientID: patientID,
+		Address:   "Main Street 1",
+		Phone:     "987654321",
+		Email:     "new.mail@example.com",
+	}
+	readVersion := 2
+	versioned := command
+	versioned.Version = &readVersion
+
+	tests := []struct {
+		name       string
+		patientID  string
+		ifMatch    string
+		handler    commands.UpdatePatientContactHandler
+		wantStatus int
+		wantETag   string
+	}{
+		{
+			name:       "return bad request on invalid patient id",
+			patientID:  "invalid",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:      "return not found when the patient doesn't exists",
+			patientID: patientID.String(),
+			handler: func() commands.UpdatePatientContactHandler {
+				handler := &commands.MockUpdatePatientContact{}
+				handler.On("Handle", mock.Anything, command).Return((*patients.Patient)(nil), patients.ErrPatientNotFound)
+				return handler
+			}(),
+			wantStatus: 404,
+		},
+		{
+			name:      "return unprocessable entity when the contact is invalid",
+			patientID: patientID.String(),
+			handler: func() commands.UpdatePatientContactHandler {
+				handler := &commands.MockUpdatePatientContact{}
+				handler.On("Handle", mock.Anything, command).Return((*patients.Patient)(nil), commands.ErrInvalidPatient)
+				return handler
+			}(),
+			wantStatus: 422,
+		},
+		{
+			name:       "return bad request when If-Match lists several versions",
+			patientID:  patientID.String(),
+			ifMatch:    `"2", "3"`,
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return precondition failed when If-Match is not a patient version",
+			patientID:  patientID.String(),
+			ifMatch:    `W/"2"`,
+			handler:    nil,
+			wantStatus: 412,
+		},
+		{
+			name:      "return precondition failed when the patient changed since the version read",
+			patientID: patientID.String(),
+			ifMatch:   `"2"`,
+			handler: func() commands.UpdatePatientContactHandler {
+				handler := &commands.MockUpdatePatientContact{}
+				handler.On("Handle", mock.Anything, versioned).Return((*patients.Patient)(nil), patients.ErrConcurrentModification)
+				return handler
+			}(),
+			wantStatus: 412,
+		},
+		{
+			name:      "return conflict when the patient keeps being modified",
+			patientID: patientID.String(),
+			handler: func() commands.UpdatePatientContactHandler {
+				handler := &commands.MockUpdatePatientContact{}
+				handler.On("Handle", mock.Anything, command).Return((*patients.Patient)(nil), patients.ErrConcurrentModification)
+				return handler
+			}(),
+			wantStatus: 409,
+		},
+		{
+			name:      "update the contact without error",
+			patientID: patientID.String(),
+			handler: func() commands.UpdatePatientContactHandler {
+				handler := &commands.MockUpdatePatientContact{}
+				handler.On("Handle", mock.Anything, command).Return(&patients.Patient{ID: patientID, Version: 3}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantETag:   `"3"`,
+		},
+		{
+			name:      "update the contact of the version read",
+			patientID: patientID.String(),
+			ifMatch:   `"2"`,
+			handler: func() commands.UpdatePatientContactHandler {
+				handler := &commands.MockUpdatePatientContact{}
+				handler.On("Handle", mock.Anything, versioned).Return(&patients.Patient{ID: patientID, Version: 3}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantETag:   `"3"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.PatientServices{Commands: app.PatientCommands{UpdatePatientContact: tt.handler}})
+			r, _ := http.NewRequest("PUT", "/patients/"+tt.patientID+"/contact", encodeBody(body))
+			r.Header.Set("If-Match", tt.ifMatch)
+			r = withPatientIDParam(r, tt.patientID)
+			response := httptest.NewRecorder()
+			h.UpdateContact(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Equal(t, tt.wantETag, response.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestHandler_GetPatient(t *testing.T) {
+	tests := []struct {
+		name       string
+		patientID  string
+		handler    queries.GetPatientHandler
+		wantStatus int
+		wantETag   string
+	}{
+		{
+			name:       "return bad request on invalid patient id",
+			patientID:  "",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:      "return not found when the patient doesn't exists",
+			patientID: patientID.String(),
+			handler: func() queries.GetPatientHandler {
+				handler := &queries.MockGetPatient{}
+				handler.On("Handle", mock.Anything, queries.GetPatientQuery{PatientID: patientID}).
+					Return((*patients.Patient)(nil), patients.ErrPatientNotFound)
+				return handler
+			}(),
+			wantStatus: 404,
+		},
+		{
+			name:      "return the patient without error",
+			patientID: patientID.String(),
+			handler: func() queries.GetPatientHandler {
+				handler := &queries.MockGetPatient{}
+				handler.On("Handle", mock.Anything, queries.GetPatientQuery{PatientID: patientID}).
+					Return(&patients.Patient{ID: patientID, Name: "John Doe", Version: 1}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantETag:   `"1"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.PatientServices{Queries: app.PatientQueries{GetPatient: tt.handler}})
+			r, _ := http.NewRequest("GET", "/patients/"+tt.patientID, nil)
+			r = withPatientIDParam(r, tt.patientID)
+			response := httptest.NewRecorder()
+			h.GetPatient(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Equal(t, tt.wantETag, response.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestHandler_GetPatient_InterruptedRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		// interrupt returns the context of the request, already cancelled or past its deadline
+		interrupt  func(ctx context.Context) (context.Context, context.CancelFunc)
+		wantStatus int
+		wantType   string
+	}{
+		{
+			name: "return service unavailable when the request is cancelled",
+			interrupt: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+				return ctx, cancel
+			},
+			wantStatus: 503,
+			wantType:   "/problems/unavailable",
+		},
+		{
+			name: "return gateway timeout when the request times out",
+			interrupt: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return context.WithDeadline(ctx, time.Now().Add(-time.Second))
+			},
+			wantStatus: 504,
+			wantType:   "/problems/timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &queries.MockGetPatient{}
+			handler.On("Handle", mock.Anything, queries.GetPatientQuery{PatientID: patientID}).
+				Return((*patients.Patient)(nil), patients.ErrGettingPatient)
+			h := NewHandler(app.PatientServices{Queries: app.PatientQueries{GetPatient: handler}})
+			r, _ := http.NewRequest("GET", "/patients/"+patientID.String(), nil)
+			ctx, cancel := tt.interrupt(r.Context())
+			defer cancel()
+			r = withPatientIDParam(r.WithContext(ctx), patientID.String())
+			response := httptest.NewRecorder()
+			h.GetPatient(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Contains(t, response.Body.String(), `"type":"`+tt.wantType+`"`)
+		})
+	}
+}
+
+func TestHandler_ListPatients(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    queries.ListPatientsHandler
+		wantStatus int
+		wantBody   *ListPatientsResponse
+	}{
+		{
+			name: "return server error when the patients can't be listed",
+			handler: func() queries.ListPatientsHandler {
+				handler := &queries.MockListPatients{}
+				handler.On("Handle", mock.Anything, queries.ListPatientsQuery{}).Return(([]*patients.Patient)(nil), patients.ErrGettingPatient)
+				return handler
+			}(),
+			wantStatus: 500,
+		},
+		{
+			name: "return unauthorized when there is no authenticated actor",
+			handler: func() queries.ListPatientsHandler {
+				handler := &queries.MockListPatients{}
+				handler.On("Handle", mock.Anything, queries.ListPatientsQuery{}).Return(([]*patients.Patient)(nil), auth.ErrUnauthenticated)
+				return handler
+			}(),
+			wantStatus: 401,
+		},
+		{
+			name: "return the patients without error",
+			handler: func() queries.ListPatientsHandler {
+				handler := &queries.MockListPatients{}
+				handler.On("Handle", mock.Anything, queries.ListPatientsQuery{}).
+					Return([]*patients.Patient{{ID: patientID, Name: "John Doe"}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody:   &ListPatientsResponse{Patients: []PatientResponse{{ID: patientID, Name: "John Doe"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.PatientServices{Queries: app.PatientQueries{ListPatients: tt.handler}})
+			r, _ := http.NewRequest("GET", "/patients", nil)
+			response := httptest.NewRecorder()
+			h.ListPatients(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantBody != nil {
+				got := ListPatientsResponse{}
+				assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+				assert.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestHandler_SearchPatients(t *testing.T) {
+	birthDate := time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)
+	query := queries.SearchPatientsQuery{Name: "jose perez"}
+
+	tests := []struct {
+		name       string
+		url        string
+		handler    queries.SearchPatientsHandler
+		wantStatus int
+		wantBody   *SearchPatientsResponse
+	}{
+		{
+			name:       "return bad request when there is no name",
+			url:        "/patients/search?name=%20-",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name:       "return bad request when the limit is out of range",
+			url:        "/patients/search?name=jose+perez&limit=51",
+			handler:    nil,
+			wantStatus: 400,
+		},
+		{
+			name: "return forbidden when the actor can't read patients",
+			url:  "/patients/search?name=jose+perez",
+			handler: func() queries.SearchPatientsHandler {
+				handler := &queries.MockSearchPatients{}
+				handler.On("Handle", mock.Anything, query).Return(([]patients.Candidate)(nil), auth.ErrForbidden)
+				return handler
+			}(),
+			wantStatus: 403,
+		},
+		{
+			name: "return the candidates without error",
+			url:  "/patients/search?name=jose+perez&limit=5",
+			handler: func() queries.SearchPatientsHandler {
+				handler := &queries.MockSearchPatients{}
+				q := query
+				q.Limit = 5
+				handler.On("Handle", mock.Anything, q).Return([]patients.Candidate{{
+					Patient: &patients.Patient{ID: patientID, LegalID: "ABC1234", Name: "José Pérez", BirthDate: &birthDate},
+					Score:   0.9166666,
+				}}, nil)
+				return handler
+			}(),
+			wantStatus: 200,
+			wantBody: &SearchPatientsResponse{Candidates: []CandidateResponse{{
+				Patient: PatientResponse{ID: patientID, LegalID: "ABC1234", Name: "José Pérez", BirthDate: "1980-01-31"},
+				Score:   0.92,
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(app.PatientServices{Queries: app.PatientQueries{SearchPatients: tt.handler}})
+			r, _ := http.NewRequest("GET", tt.url, nil)
+			response := httptest.NewRecorder()
+			h.SearchPatients(response, r)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			if tt.wantBody != nil {
+				got := SearchPatientsResponse{}
+				assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+				assert.Equal(t, *tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func encodeBody(body interface{}) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	if raw, ok := body.(string); ok {
+		buf.WriteString(raw)
+		return buf
+	}
+	_ = json.NewEncoder(buf).Encode(body)
+	return buf
+}
+
+func withPatientIDParam(r *http.Request, patientID string) *http.Request {
+	rCtx := chi.NewRouteContext()
+	rCtx.URLParams.Add(PatientIDURLParam, patientID)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rCtx))
+}
@@ -0,0 +1,189 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxBodySize is the maximum size of the request bodies of the API, in bytes.
+const MaxBodySize = 1 << 20
+
+// ErrBodyTooLarge answers the requests whose body is larger than MaxBodySize.
+var ErrBodyTooLarge = NewRequestError(http.StatusRequestEntityTooLarge, "", "the body is larger than 1 MiB")
+
+const unknownFieldPrefix = "json: unknown field "
+
+// DecodeJSON decodes the body of the request into target, rejecting fields target doesn't have and anything after
+// the JSON value. It returns a 400 *RequestError telling what is wrong with the body without disclosing the decoder
+// messages, or ErrBodyTooLarge when the body is larger than MaxBodySize.
+func DecodeJSON(writer http.ResponseWriter, request *http.Request, target any) error {
+	// the body is kept to find the path of unknown fields, which the decoder errors only name
+	var body bytes.Buffer
+	decoder := json.NewDecoder(io.TeeReader(http.MaxBytesReader(writer, request.Body, MaxBodySize), &body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(target)
+	if err == nil {
+		if _, err = decoder.Token(); errors.Is(err, io.EOF) {
+			return nil
+		}
+		if isTooLarge(err) {
+			return ErrBodyTooLarge
+		}
+		return invalidBody("the body has data after the JSON value")
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case isTooLarge(err):
+		return ErrBodyTooLarge
+	case errors.Is(err, io.EOF):
+		return invalidBody("the body is empty, expected a JSON object")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidBody("the body is not valid JSON")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return InvalidParam(fieldPath(typeErr.Field), fmt.Errorf("expected a %s", jsonType(typeErr.Type.Kind().String())))
+	case errors.As(err, &typeErr):
+		return invalidBody("expected a JSON object")
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+		if path := unknownFieldPath(body.Bytes(), reflect.TypeOf(target), field, ""); path != "" {
+			field = path
+		}
+		return InvalidParam(field, errors.New("unknown field"))
+	default:
+		return invalidBody("the body has values that can't be read")
+	}
+}
+
+func isTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+func invalidBody(message string) *RequestError {
+	return InvalidParam("body", errors.New(message))
+}
+
+func fieldPath(field string) string {
+	segments := strings.Split(field, ".")
+	path := segments[0]
+	for _, segment := range segments[1:] {
+		if _, err := strconv.Atoi(segment); err == nil {
+			path += "[" + segment + "]"
+		} else {
+			path += "." + segment
+		}
+	}
+	return path
+}
+
+// unknownFieldPath returns the path of the key named field that t doesn't have, such as
+// prescription.medications[0].strength, or "" when there is none.
+func unknownFieldPath(data []byte, t reflect.Type, field, path string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return ""
+		}
+		for i, item := range items {
+			if found := unknownFieldPath(item, t.Elem(), field, path+"["+strconv.Itoa(i)+"]"); found != "" {
+				return found
+			}
+		}
+	case reflect.Map, reflect.Struct:
+		var object map[string]json.RawMessage
+		if json.Unmarshal(data, &object) != nil {
+			return ""
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			valueType, ok := mapOrFieldType(t, key)
+			if !ok {
+				if key == field {
+					return keyPath
+				}
+				continue
+			}
+			if found := unknownFieldPath(object[key], valueType, field, keyPath); found != "" {
+				return found
+			}
+		}
+	}
+	return ""
+}
+
+func mapOrFieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	if t.Kind() == reflect.Map {
+		return t.Elem(), true
+	}
+	return fieldType(t, key)
+}
+
+func fieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := range t.NumField() {
+		structField := t.Field(i)
+		name, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if structField.Anonymous && name == "" {
+			embedded := structField.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if found, ok := fieldType(embedded, key); ok {
+					return found, true
+				}
+				continue
+			}
+		}
+		if !structField.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = structField.Name
+		}
+		if strings.EqualFold(name, key) {
+			return structField.Type, true
+		}
+	}
+	return nil, false
+}
+
+func jsonType(kind string) string {
+	switch kind {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "slice", "array":
+		return "array"
+	case "struct", "map", "ptr":
+		return "object"
+	default:
+		return "number"
+	}
+}
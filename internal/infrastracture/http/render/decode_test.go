@@ -0,0 +1,73 @@
+package render
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type medication struct {
+		Dose float64 `json:"dose"`
+	}
+	type body struct {
+		Name        string       `json:"name"`
+		Medications []medication `json:"medications"`
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    body
+		wantErr *RequestError
+	}{
+		{name: "decode the body", body: `{"name":"asthma"}`, want: body{Name: "asthma"}},
+		{name: "decode the body ending with a line break", body: "{\"name\":\"asthma\"}\n", want: body{Name: "asthma"}},
+		{name: "reject an empty body", body: "", wantErr: invalidBody("the body is empty, expected a JSON object")},
+		{name: "reject malformed JSON", body: `{"name":`, wantErr: invalidBody("the body is not valid JSON")},
+		{name: "reject a body that is not an object", body: `["asthma"]`, wantErr: invalidBody("expected a JSON object")},
+		{
+			name:    "reject a second JSON value",
+			body:    `{"name":"asthma"}{"name":"flu"}`,
+			wantErr: invalidBody("the body has data after the JSON value"),
+		},
+		{
+			name:    "reject data after the JSON value",
+			body:    `{"name":"asthma"} }`,
+			wantErr: invalidBody("the body has data after the JSON value"),
+		},
+		{
+			name:    "reject bodies larger than the maximum size",
+			body:    `{"name":"` + strings.Repeat("a", MaxBodySize) + `"}`,
+			wantErr: ErrBodyTooLarge,
+		},
+		{
+			name:    "report the field with a wrong type",
+			body:    `{"name":"asthma","medications":[{"dose":"500mg"}]}`,
+			wantErr: &RequestError{Status: 400, Message: "expected a number", Errors: []FieldError{{Field: "medications[0].dose", Message: "expected a number"}}},
+		},
+		{
+			name:    "reject unknown fields",
+			body:    `{"name":"asthma","status":"final"}`,
+			wantErr: &RequestError{Status: 400, Message: "unknown field", Errors: []FieldError{{Field: "status", Message: "unknown field"}}},
+		},
+		{
+			name:    "report the path of nested unknown fields",
+			body:    `{"name":"asthma","medications":[{"dose":500},{"dose":250,"strength":"250mg"}]}`,
+			wantErr: &RequestError{Status: 400, Message: "unknown field", Errors: []FieldError{{Field: "medications[1].strength", Message: "unknown field"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := body{}
+			err := DecodeJSON(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(tt.body)), &got)
+			if tt.wantErr == nil {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
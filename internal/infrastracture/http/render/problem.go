@@ -0,0 +1,152 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of the error responses, see RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// Problem details of an error response, see RFC 7807.
+type Problem struct {
+	// Type identifies the kind of problem, relative to the API base URL
+	Type string `json:"type" example:"/problems/invalid-request"`
+	// Title summarizes the kind of problem, it is the same for every problem of a type
+	Title  string `json:"title" example:"The request is not valid"`
+	Status int    `json:"status" example:"400"`
+	// Detail explains this occurrence of the problem
+	Detail string `json:"detail,omitempty" example:"the request has invalid fields"`
+	// Instance is the path of the request
+	Instance string `json:"instance,omitempty" example:"/api/v1/patients"`
+	// RequestID correlates the problem with the service logs and the audit trail
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists the parameters and body fields that aren't valid, if any
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a validation failure of a parameter or body field of the request.
+type FieldError struct {
+	// Field is the name of the parameter, or the path of the body field such as prescription.medications[0].dose
+	Field   string `json:"field" example:"birth_date"`
+	Message string `json:"message" example:"invalid birth date, expected YYYY-MM-DD"`
+}
+
+// RequestError is an error found in the request before handing it to the application, such as an invalid
+// parameter, which is answered with its status.
+type RequestError struct {
+	Status int
+	// Type is the problem type, the one of the status when empty
+	Type    string
+	Message string
+	Errors  []FieldError
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// NewRequestError returns an error answered with the status and the problem type, the one of the status when empty.
+func NewRequestError(status int, problemType, message string) *RequestError {
+	return &RequestError{Status: status, Type: problemType, Message: message}
+}
+
+// InvalidParam returns a 400 error for the parameter or body field, err tells what is wrong with it.
+func InvalidParam(field string, err error) *RequestError {
+	return &RequestError{
+		Status:  http.StatusBadRequest,
+		Message: err.Error(),
+		Errors:  []FieldError{{Field: field, Message: err.Error()}},
+	}
+}
+
+// Error answers the request with the problem of err. Unknown errors are logged and answered with a 500 that
+// doesn't disclose them.
+func Error(writer http.ResponseWriter, request *http.Request, err error) {
+	problem := NewProblem(request, err)
+	WriteProblem(writer, problem.Status, problem)
+}
+
+// NewProblem returns the problem of err for the request, see problemOf. Unknown errors of requests that were
+// cancelled or timed out are answered as their cancellation, as the application hides the storage failures it caused.
+// Cancellations aren't logged as errors, the request didn't fail on its own.
+func NewProblem(request *http.Request, err error) Problem {
+	problem := problemOf(err)
+	if ctxErr := request.Context().Err(); ctxErr != nil && problem.Status == http.StatusInternalServerError {
+		err = ctxErr
+		problem = problemOf(err)
+	}
+
+	cancelled := errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+	if problem.Status >= http.StatusInternalServerError && !cancelled {
+		slog.Error("error handling request", "err", err, "method", request.Method, "path", request.URL.Path,
+			"requestID", middleware.GetReqID(request.Context()))
+	}
+
+	problem.Instance = request.URL.Path
+	problem.RequestID = middleware.GetReqID(request.Context())
+	return problem
+}
+
+// WriteProblem writes the status code followed by the problem, which can embed Problem to add extension members.
+func WriteProblem(writer http.ResponseWriter, status int, problem any) {
+	writer.Header().Set("Content-Type", ProblemContentType)
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(problem); err != nil {
+		slog.Error("error encoding problem", "err", err)
+	}
+}
+
+// StatusOf returns the status code answering err.
+func StatusOf(err error) int {
+	return problemOf(err).Status
+}
+
+func problemOf(err error) Problem {
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		problem := newProblem(requestErr.Status, requestErr.Type, requestErr.Message)
+		problem.Errors = requestErr.Errors
+		return problem
+	}
+
+	var validationErrs validation.Errors
+	if errors.As(err, &validationErrs) {
+		problem := newProblem(http.StatusUnprocessableEntity, TypeValidationFailed, "the request has invalid fields")
+		for _, fieldErr := range validationErrs {
+			problem.Errors = append(problem.Errors, FieldError{Field: fieldErr.Field, Message: fieldErr.Message})
+		}
+		return problem
+	}
+
+	for _, mapping := range appErrors {
+		if errors.Is(err, mapping.err) {
+			problem := newProblem(mapping.status, mapping.problemType, detail(err))
+			problem.Errors = fieldErrors(err)
+			return problem
+		}
+	}
+
+	return newProblem(http.StatusInternalServerError, "", "error processing the request")
+}
+
+func newProblem(status int, problemType, detail string) Problem {
+	if problemType == "" {
+		problemType = statusTypes[status]
+	}
+	if problemType == "" {
+		problemType = TypeInternalError
+	}
+
+	return Problem{Type: problemType, Title: titles[problemType], Status: status, Detail: detail}
+}
+
+func detail(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", "; ")
+}
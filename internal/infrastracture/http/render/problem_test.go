@@ -0,0 +1,128 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	patientcommands "github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/validation"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		// cancelled cancels the context of the request
+		cancelled bool
+		want      Problem
+	}{
+		{
+			name: "answer request errors with their status and fields",
+			err:  InvalidParam("limit", errors.New("invalid limit")),
+			want: Problem{Type: TypeInvalidRequest, Title: titles[TypeInvalidRequest], Status: 400, Detail: "invalid limit",
+				Errors: []FieldError{{Field: "limit", Message: "invalid limit"}}},
+		},
+		{
+			name: "answer request errors with their own type",
+			err:  NewRequestError(http.StatusConflict, TypeIdempotencyKeyInUse, "key in use"),
+			want: Problem{Type: TypeIdempotencyKeyInUse, Title: titles[TypeIdempotencyKeyInUse], Status: 409, Detail: "key in use"},
+		},
+		{
+			name: "map wrapped application errors",
+			err:  fmt.Errorf("%w: missing bearer token", auth.ErrUnauthenticated),
+			want: Problem{Type: TypeUnauthenticated, Title: titles[TypeUnauthenticated], Status: 401,
+				Detail: "the request is not authenticated: missing bearer token"},
+		},
+		{
+			name: "map application errors with a type of their own",
+			err:  patients.ErrConcurrentModification,
+			want: Problem{Type: TypeConcurrentModification, Title: titles[TypeConcurrentModification], Status: 409,
+				Detail: patients.ErrConcurrentModification.Error()},
+		},
+		{
+			name: "list the fields of joined validation errors",
+			err: fmt.Errorf("%w: %w", patientcommands.ErrInvalidPatient,
+				errors.Join(patients.ErrInvalidName, errors.Join(patients.ErrInvalidPhone, patients.ErrInvalidEmail))),
+			want: Problem{Type: TypeValidationFailed, Title: titles[TypeValidationFailed], Status: 422,
+				Detail: "invalid patient: name cannot be empty; " + patients.ErrInvalidPhone.Error() + "; invalid email address",
+				Errors: []FieldError{
+					{Field: "name", Message: patients.ErrInvalidName.Error()},
+					{Field: "phone", Message: patients.ErrInvalidPhone.Error()},
+					{Field: "email", Message: patients.ErrInvalidEmail.Error()},
+				}},
+		},
+		{
+			name: "list the fields of invalid commands",
+			err: validation.Errors{
+				{Field: "diagnosis", Message: "is required"},
+				{Field: "prescription.medications[1].dose", Message: diagnoses.ErrInvalidDose.Error(), Err: diagnoses.ErrInvalidDose},
+			},
+			want: Problem{Type: TypeValidationFailed, Title: titles[TypeValidationFailed], Status: 422,
+				Detail: "the request has invalid fields",
+				Errors: []FieldError{
+					{Field: "diagnosis", Message: "is required"},
+					{Field: "prescription.medications[1].dose", Message: diagnoses.ErrInvalidDose.Error()},
+				}},
+		},
+		{
+			name: "hide unknown errors",
+			err:  errors.New("database is locked"),
+			want: Problem{Type: TypeInternalError, Title: titles[TypeInternalError], Status: 500,
+				Detail: "error processing the request"},
+		},
+		{
+			name: "answer timed out requests with a gateway timeout",
+			err:  fmt.Errorf("getting patients: %w", context.DeadlineExceeded),
+			want: Problem{Type: TypeTimeout, Title: titles[TypeTimeout], Status: 504,
+				Detail: "getting patients: context deadline exceeded"},
+		},
+		{
+			name: "answer cancelled requests with service unavailable",
+			err:  context.Canceled,
+			want: Problem{Type: TypeUnavailable, Title: titles[TypeUnavailable], Status: 503, Detail: "context canceled"},
+		},
+		{
+			name:      "answer the unknown errors of cancelled requests as their cancellation",
+			err:       patients.ErrGettingPatient,
+			cancelled: true,
+			want:      Problem{Type: TypeUnavailable, Title: titles[TypeUnavailable], Status: 503, Detail: "context canceled"},
+		},
+		{
+			name:      "keep the known errors of cancelled requests",
+			err:       patients.ErrPatientNotFound,
+			cancelled: true,
+			want: Problem{Type: TypeNotFound, Title: titles[TypeNotFound], Status: 404,
+				Detail: patients.ErrPatientNotFound.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/api/v1/patients", nil)
+			ctx, cancel := context.WithCancel(context.WithValue(request.Context(), middleware.RequestIDKey, "request-1"))
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+			request = request.WithContext(ctx)
+			response := httptest.NewRecorder()
+			Error(response, request, tt.err)
+
+			got := Problem{}
+			assert.Nil(t, json.NewDecoder(response.Body).Decode(&got))
+			tt.want.Instance = "/api/v1/patients"
+			tt.want.RequestID = "request-1"
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.Status, response.Code)
+			assert.Equal(t, ProblemContentType, response.Header().Get("Content-Type"))
+		})
+	}
+}
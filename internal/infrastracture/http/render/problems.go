@@ -0,0 +1,129 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	diagnosiscommands "github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/queries"
+	patientcommands "github.com/juanmabaracat/diagnosis-service/internal/app/patients/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"net/http"
+)
+
+// Problem types, documented in the README.
+const (
+	TypeInvalidRequest         = "/problems/invalid-request"
+	TypeUnauthenticated        = "/problems/unauthenticated"
+	TypeForbidden              = "/problems/forbidden"
+	TypeNotFound               = "/problems/not-found"
+	TypeConflict               = "/problems/conflict"
+	TypePreconditionFailed     = "/problems/precondition-failed"
+	TypeValidationFailed       = "/problems/validation-failed"
+	TypeInternalError          = "/problems/internal-error"
+	TypeUnavailable            = "/problems/unavailable"
+	TypeTimeout                = "/problems/timeout"
+	TypeDuplicatedLegalID      = "/problems/duplicated-legal-id"
+	TypeConcurrentModification = "/problems/concurrent-modification"
+	TypeEnteredInError         = "/problems/entered-in-error"
+	TypeAmbiguousPatient       = "/problems/ambiguous-patient"
+	TypeIdempotencyKeyInUse    = "/problems/idempotency-key-in-use"
+	TypeIdempotencyKeyReused   = "/problems/idempotency-key-reused"
+	TypeBodyTooLarge           = "/problems/body-too-large"
+)
+
+var titles = map[string]string{
+	TypeInvalidRequest:         "The request is not valid",
+	TypeUnauthenticated:        "The request is not authenticated",
+	TypeForbidden:              "The user is not allowed to perform this action",
+	TypeNotFound:               "The resource was not found",
+	TypeConflict:               "The request conflicts with the state of the resource",
+	TypePreconditionFailed:     "The resource changed since it was read",
+	TypeValidationFailed:       "The request has values that can't be accepted",
+	TypeInternalError:          "The request could not be processed",
+	TypeUnavailable:            "The request was cancelled before it was processed",
+	TypeTimeout:                "The request took too long to be processed",
+	TypeDuplicatedLegalID:      "There is already a patient with the legal ID",
+	TypeConcurrentModification: "The resource was modified by someone else",
+	TypeEnteredInError:         "The diagnosis was entered in error",
+	TypeAmbiguousPatient:       "Several patients match the search",
+	TypeIdempotencyKeyInUse:    "The idempotency key is in use",
+	TypeIdempotencyKeyReused:   "The idempotency key was used with another request",
+	TypeBodyTooLarge:           "The request body is too large",
+}
+
+var statusTypes = map[int]string{
+	http.StatusBadRequest:            TypeInvalidRequest,
+	http.StatusUnauthorized:          TypeUnauthenticated,
+	http.StatusForbidden:             TypeForbidden,
+	http.StatusNotFound:              TypeNotFound,
+	http.StatusConflict:              TypeConflict,
+	http.StatusPreconditionFailed:    TypePreconditionFailed,
+	http.StatusRequestEntityTooLarge: TypeBodyTooLarge,
+	http.StatusUnprocessableEntity:   TypeValidationFailed,
+	http.StatusInternalServerError:   TypeInternalError,
+	http.StatusServiceUnavailable:    TypeUnavailable,
+	http.StatusGatewayTimeout:        TypeTimeout,
+}
+
+// appErrors maps the errors of the application to their status and problem type, the first match wins.
+// Errors that aren't here are answered with a 500.
+var appErrors = []struct {
+	err         error
+	status      int
+	problemType string
+}{
+	{auth.ErrUnauthenticated, http.StatusUnauthorized, ""},
+	{auth.ErrForbidden, http.StatusForbidden, ""},
+	{patients.ErrPatientNotFound, http.StatusNotFound, ""},
+	{diagnosiscommands.ErrDiagnosisNotFound, http.StatusNotFound, ""},
+	{patients.ErrDuplicatedLegalID, http.StatusConflict, TypeDuplicatedLegalID},
+	{patients.ErrConcurrentModification, http.StatusConflict, TypeConcurrentModification},
+	{diagnoses.ErrEnteredInError, http.StatusConflict, TypeEnteredInError},
+	{queries.ErrAmbiguousPatient, http.StatusConflict, TypeAmbiguousPatient},
+	{patientcommands.ErrInvalidPatient, http.StatusUnprocessableEntity, ""},
+	{diagnosiscommands.ErrInvalidCoding, http.StatusUnprocessableEntity, ""},
+	// the request was cancelled, by the client closing the connection or the server shutting down, or timed out
+	{context.Canceled, http.StatusServiceUnavailable, ""},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, ""},
+}
+
+// fields are the request fields of the validation errors of the domain, the first match wins.
+var fields = []struct {
+	err   error
+	field string
+}{
+	{patients.ErrInvalidName, "name"},
+	{patients.ErrInvalidLegalID, "legal_id"},
+	{patients.ErrInvalidBirthDate, "birth_date"},
+	{patients.ErrInvalidPhone, "phone"},
+	{patients.ErrInvalidEmail, "email"},
+	{diagnosiscommands.ErrInvalidCoding, "coding"},
+}
+
+func fieldErrors(err error) []FieldError {
+	var found []FieldError
+	for _, cause := range causes(err) {
+		for _, mapping := range fields {
+			if errors.Is(cause, mapping.err) {
+				found = append(found, FieldError{Field: mapping.field, Message: detail(cause)})
+				break
+			}
+		}
+	}
+	return found
+}
+
+func causes(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+
+	var found []error
+	for _, wrapped := range joined.Unwrap() {
+		found = append(found, causes(wrapped)...)
+	}
+	return found
+}
@@ -0,0 +1,16 @@
+package render
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// JSON writes the status code followed by the body encoded as JSON.
+func JSON(writer http.ResponseWriter, code int, body any) {
+	writer.WriteHeader(code)
+	errEncode := json.NewEncoder(writer).Encode(body)
+	if errEncode != nil {
+		slog.Error("error encoding http response", "err", errEncode)
+	}
+}
@@ -1,32 +1,78 @@
 package http
 
 import (
+	"context"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
 	_ "github.com/juanmabaracat/diagnosis-service/docs"
 	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	idempotencystore "github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/authentication"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/codes"
 	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/fhir"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/idempotency"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/http/patients"
 	"github.com/swaggo/http-swagger/v2"
-	"log"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
+const requestTimeout = 30 * time.Second
+
+// Timeouts of the server. Write should be longer than the 30 seconds requests are handled for, so the response
+// of a request that times out can still be written.
+type Timeouts struct {
+	// Read is how long reading a request, including its body, can take
+	Read time.Duration
+	// Write is how long handling a request and writing its response can take
+	Write time.Duration
+	// Idle is how long a keep-alive connection waits for the next request
+	Idle time.Duration
+	// Shutdown is how long the requests being handled are waited for when the server stops
+	Shutdown time.Duration
+}
+
+// DefaultTimeouts are the timeouts of the server unless configured otherwise.
+var DefaultTimeouts = Timeouts{
+	Read:     30 * time.Second,
+	Write:    requestTimeout + 5*time.Second,
+	Idle:     2 * time.Minute,
+	Shutdown: 20 * time.Second,
+}
+
 type Server struct {
-	appServices app.Services
-	router      chi.Router
+	appServices      app.Services
+	authenticator    authentication.Authenticator
+	idempotencyStore idempotencystore.Store
+	idempotencyTTL   time.Duration
+	timeouts         Timeouts
+	router           chi.Router
 }
 
-func NewServer(services app.Services) *Server {
+// NewServer returns the API server. The responses to the requests sent with an Idempotency-Key are kept in
+// idempotencyStore for idempotencyTTL.
+func NewServer(services app.Services, authenticator authentication.Authenticator,
+	idempotencyStore idempotencystore.Store, idempotencyTTL time.Duration, timeouts Timeouts) *Server {
 	router := chi.NewRouter()
+	router.Use(requestID)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Logger)
-	router.Use(middleware.Timeout(30 * time.Second))
+	router.Use(middleware.Timeout(requestTimeout))
 	router.Use(commonMiddleware)
 	server := &Server{
-		appServices: services,
-		router:      router,
+		appServices:      services,
+		authenticator:    authenticator,
+		idempotencyStore: idempotencyStore,
+		idempotencyTTL:   idempotencyTTL,
+		timeouts:         timeouts,
+		router:           router,
 	}
 
 	server.addHTTPRoutes()
@@ -35,24 +81,117 @@ func NewServer(services app.Services) *Server {
 
 func (s *Server) addHTTPRoutes() {
 	handler := diagnoses.NewHandler(s.appServices.DiagnosisServices)
+	patientHandler := patients.NewHandler(s.appServices.PatientServices)
+	auditHandler := audit.NewHandler(s.appServices.AuditServices)
+	codeHandler := codes.NewHandler(s.appServices.CodeServices)
+	fhirHandler := fhir.NewHandler(s.appServices.PatientServices, s.appServices.DiagnosisServices)
 	s.router.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8080/swagger/doc.json")))
 	s.router.Route("/api/v1", func(r chi.Router) {
+		r.Use(authentication.Middleware(s.authenticator))
 		r.Get("/patient/diagnoses", handler.GetDiagnoses)
-		r.Post("/patient/{"+diagnoses.PatientIDURLParam+"}/diagnoses", handler.AddDiagnosis)
+		r.With(idempotency.Middleware(s.idempotencyStore, s.idempotencyTTL)).
+			Post("/patient/{"+diagnoses.PatientIDURLParam+"}/diagnoses", handler.AddDiagnosis)
+		r.Route("/diagnoses/{"+diagnoses.DiagnosisIDURLParam+"}", func(r chi.Router) {
+			r.Get("/", handler.GetDiagnosisByID)
+			r.Post("/amend", handler.AmendDiagnosis)
+			r.Post("/enter-in-error", handler.EnterInError)
+			r.Get("/history", handler.GetDiagnosisHistory)
+		})
+		r.Route("/patients", func(r chi.Router) {
+			r.Get("/", patientHandler.ListPatients)
+			r.Post("/", patientHandler.CreatePatient)
+			r.Get("/search", patientHandler.SearchPatients)
+			r.Get("/{"+patients.PatientIDURLParam+"}", patientHandler.GetPatient)
+			r.Put("/{"+patients.PatientIDURLParam+"}/contact", patientHandler.UpdateContact)
+			r.Get("/{"+diagnoses.PatientIDURLParam+"}/prescriptions", handler.GetPatientPrescriptions)
+		})
+		r.Route("/audit", func(r chi.Router) {
+			r.Get("/records", auditHandler.GetAuditTrail)
+			r.Get("/verify", auditHandler.VerifyAuditTrail)
+		})
+		r.Get("/codes/icd10", codeHandler.SearchICD10)
+		r.Route("/fhir", func(r chi.Router) {
+			r.Post("/", fhirHandler.ProcessBundle)
+			r.Get("/Patient/{"+fhir.IDURLParam+"}", fhirHandler.ReadPatient)
+			r.Get("/Condition", fhirHandler.SearchConditions)
+			r.Post("/Condition", fhirHandler.CreateCondition)
+			r.Get("/MedicationRequest", fhirHandler.SearchMedicationRequests)
+		})
+	})
+}
+
+// requestID replaces the X-Request-Id of the client, which can be reused or forged, with one of the server.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		id := uuid.NewString()
+		if clientID := request.Header.Get(middleware.RequestIDHeader); clientID != "" {
+			slog.Info("client request ID", "requestID", id, "clientRequestID", clientID)
+		}
+		ctx := context.WithValue(request.Context(), middleware.RequestIDKey, id)
+		next.ServeHTTP(writer, request.WithContext(ctx))
 	})
 }
 
 func commonMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		writer.Header().Add("Content-Type", "application/json")
+		writer.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(request.Context()))
 		next.ServeHTTP(writer, request)
 	})
 }
 
-func (s *Server) Run(port string) {
-	slog.Info("Listening on http://localhost" + port)
-	err := http.ListenAndServe(port, s.router)
+// Run listens on the TCP address, such as :8080, and serves the API until ctx is done, see Serve.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves the API on the listener until ctx is done. The server then stops accepting connections and waits
+// for the requests being handled, up to the shutdown timeout. The requests still running by then are cancelled, so
+// their writes are rolled back, and Serve returns the error of the deadline once they end. The storage can be closed
+// when Serve returns, as no request is running by then.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	// requests don't inherit ctx, as they are drained when it is done instead of cancelled
+	requests, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	var handling sync.WaitGroup
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			handling.Add(1)
+			defer handling.Done()
+			s.router.ServeHTTP(writer, request)
+		}),
+		ReadTimeout:  s.timeouts.Read,
+		WriteTimeout: s.timeouts.Write,
+		IdleTimeout:  s.timeouts.Idle,
+		BaseContext:  func(net.Listener) context.Context { return requests },
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	slog.Info("Listening on http://" + listener.Addr().String())
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining the requests being handled", "timeout", s.timeouts.Shutdown)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.timeouts.Shutdown)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		cancelRequests()
+		_ = server.Close()
+		handling.Wait()
+		return fmt.Errorf("draining requests: %w", err)
+	}
+
+	slog.Info("server stopped")
+	return nil
 }
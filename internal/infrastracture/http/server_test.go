@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServer_Serve_DrainsRequestsWhenStopped(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	server := newTestServer(Timeouts{Shutdown: 5 * time.Second})
+	server.router.Get("/slow", func(writer http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-release
+		_, _ = writer.Write([]byte(`{"status":"done"}`))
+	})
+	addr, stop, served := serveTestServer(t, server)
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		response, err := http.Get("http://" + addr + "/slow")
+		assert.NoError(t, err)
+		responses <- response
+	}()
+	<-started
+	stop()
+
+	// the server stops accepting connections while the request is still running
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("Serve() returned %v before the running request ended", err)
+	default:
+	}
+
+	close(release)
+	response := <-responses
+	if assert.NotNil(t, response) {
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.JSONEq(t, `{"status":"done"}`, string(body))
+	}
+	assert.NoError(t, <-served)
+}
+
+func TestServer_Serve_CancelsRequestsAfterShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	server := newTestServer(Timeouts{Shutdown: 50 * time.Millisecond})
+	server.router.Get("/stuck", func(writer http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-request.Context().Done()
+		cancelled <- request.Context().Err()
+	})
+	addr, stop, served := serveTestServer(t, server)
+
+	go func() {
+		response, err := http.Get("http://" + addr + "/stuck")
+		if err == nil {
+			response.Body.Close()
+		}
+	}()
+	<-started
+	stop()
+
+	// Serve returns once the cancelled request ended, so the storage can be closed
+	assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+	select {
+	case err := <-cancelled:
+		assert.ErrorIs(t, err, context.Canceled)
+	default:
+		t.Errorf("Serve() returned before the request was cancelled")
+	}
+}
+
+func TestServer_Serve_ReturnsListenerErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error=%v, but no error expected", err)
+	}
+	listener.Close()
+
+	err = newTestServer(DefaultTimeouts).Serve(context.Background(), listener)
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestServer_IdentifiesRequests(t *testing.T) {
+	server := newTestServer(DefaultTimeouts)
+	var got string
+	server.router.Get("/ping", func(writer http.ResponseWriter, request *http.Request) {
+		got = middleware.GetReqID(request.Context())
+	})
+
+	request := httptest.NewRequest("GET", "/ping", nil)
+	request.Header.Set(middleware.RequestIDHeader, "client-1")
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, request)
+
+	_, err := uuid.Parse(got)
+	assert.NoError(t, err, "the request ID should be generated by the server")
+	assert.Equal(t, got, response.Header().Get(middleware.RequestIDHeader))
+}
+
+func newTestServer(timeouts Timeouts) *Server {
+	return NewServer(app.Services{}, nil, nil, time.Hour, timeouts)
+}
+
+// serveTestServer serves the server on a local port, returning its address, the function stopping it and the
+// channel receiving the result of Serve.
+func serveTestServer(t *testing.T, server *Server) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error=%v, but no error expected", err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	t.Cleanup(stop)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, listener)
+	}()
+	return listener.Addr().String(), stop, served
+}
@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+)
+
+func (r *Repository) Append(ctx context.Context, record audit.Record) (audit.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return audit.Record{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var last *audit.Record
+	if len(r.auditRecords) > 0 {
+		last = &r.auditRecords[len(r.auditRecords)-1]
+	}
+
+	record = audit.Chain(last, record)
+	r.auditRecords = append(r.auditRecords, record)
+	return record, nil
+}
+
+func (r *Repository) Find(ctx context.Context, filter audit.Filter) ([]audit.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	found := make([]audit.Record, 0)
+	for _, record := range r.auditRecords {
+		if filter.Matches(record) {
+			found = append(found, record)
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"slices"
+)
+
+func clonePatient(patient patients.Patient) patients.Patient {
+	if patient.BirthDate != nil {
+		birthDate := *patient.BirthDate
+		patient.BirthDate = &birthDate
+	}
+
+	return patient
+}
+
+func cloneDiagnosis(diagnosis diagnoses.Diagnosis) diagnoses.Diagnosis {
+	if diagnosis.Prescription != nil {
+		prescription := *diagnosis.Prescription
+		prescription.Medications = slices.Clone(prescription.Medications)
+		diagnosis.Prescription = &prescription
+	}
+
+	if diagnosis.Coding != nil {
+		coding := *diagnosis.Coding
+		diagnosis.Coding = &coding
+	}
+
+	return diagnosis
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"maps"
+	"slices"
+	"time"
+)
+
+// idempotencyPurgeInterval is how often the expired idempotency records are removed, they are ignored meanwhile.
+const idempotencyPurgeInterval = time.Minute
+
+func (r *Repository) Reserve(ctx context.Context, record idempotency.Record, now time.Time) (*idempotency.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if now.Sub(r.idempotencyPurgedAt) >= idempotencyPurgeInterval {
+		maps.DeleteFunc(r.idempotencyRecords, func(_ string, record idempotency.Record) bool {
+			return record.Expired(now)
+		})
+		r.idempotencyPurgedAt = now
+	}
+
+	if existing, ok := r.idempotencyRecords[record.Key]; ok && !existing.Expired(now) {
+		existing = cloneIdempotencyRecord(existing)
+		return &existing, nil
+	}
+
+	r.idempotencyRecords[record.Key] = cloneIdempotencyRecord(record)
+	return nil, nil
+}
+
+func (r *Repository) Complete(ctx context.Context, key string, response idempotency.Response, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record, ok := r.idempotencyRecords[key]
+	if !ok {
+		return nil
+	}
+
+	record.Response = &response
+	record.ExpiresAt = expiresAt
+	r.idempotencyRecords[key] = cloneIdempotencyRecord(record)
+	return nil
+}
+
+func (r *Repository) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.idempotencyRecords, key)
+	return nil
+}
+
+func cloneIdempotencyRecord(record idempotency.Record) idempotency.Record {
+	if record.Response != nil {
+		response := *record.Response
+		response.Header = maps.Clone(response.Header)
+		response.Body = slices.Clone(response.Body)
+		record.Response = &response
+	}
+
+	return record
+}
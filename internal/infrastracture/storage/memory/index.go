@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"slices"
+	"sort"
+	"time"
+)
+
+// diagnosisKey is the entry of a diagnosis in the date indexes, which are sorted like diagnoses.Less.
+type diagnosisKey struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func keyOf(diagnosis diagnoses.Diagnosis) diagnosisKey {
+	return diagnosisKey{CreatedAt: diagnosis.CreatedAt, ID: diagnosis.ID}
+}
+
+func (k diagnosisKey) less(other diagnosisKey) bool {
+	return diagnoses.Less(diagnoses.Diagnosis{CreatedAt: k.CreatedAt, ID: k.ID},
+		diagnoses.Diagnosis{CreatedAt: other.CreatedAt, ID: other.ID})
+}
+
+func insertKey(keys []diagnosisKey, key diagnosisKey) []diagnosisKey {
+	i := sort.Search(len(keys), func(i int) bool { return key.less(keys[i]) })
+	return slices.Insert(keys, i, key)
+}
+
+func removeKey(keys []diagnosisKey, key diagnosisKey) []diagnosisKey {
+	i := sort.Search(len(keys), func(i int) bool { return !keys[i].less(key) })
+	if i < len(keys) && keys[i].ID == key.ID {
+		return slices.Delete(keys, i, i+1)
+	}
+	return keys
+}
+
+func inRange(keys []diagnosisKey, filter diagnoses.Filter) []diagnosisKey {
+	if filter.To != nil {
+		end := sort.Search(len(keys), func(i int) bool { return keys[i].CreatedAt.After(*filter.To) })
+		keys = keys[:end]
+	}
+
+	if filter.From != nil {
+		start := sort.Search(len(keys), func(i int) bool { return !keys[i].CreatedAt.Before(*filter.From) })
+		keys = keys[start:]
+	}
+
+	return keys
+}
+
+func insertID(IDs []uuid.UUID, ID uuid.UUID) []uuid.UUID {
+	i := sort.Search(len(IDs), func(i int) bool { return IDs[i].String() >= ID.String() })
+	return slices.Insert(IDs, i, ID)
+}
+
+func removeID(IDs []uuid.UUID, ID uuid.UUID) []uuid.UUID {
+	if i := slices.Index(IDs, ID); i >= 0 {
+		return slices.Delete(IDs, i, i+1)
+	}
+	return IDs
+}
@@ -1,73 +1,361 @@
 package memory
 
 import (
+	"context"
 	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"sort"
 	"sync"
+	"time"
 )
 
-func NewRepository() Repository {
-	repo := Repository{}
+func NewRepository() *Repository {
+	repo := &Repository{
+		patients:           make(map[uuid.UUID]patients.Patient),
+		patientsByLegalID:  make(map[string]uuid.UUID),
+		patientsByName:     make(map[string][]uuid.UUID),
+		diagnoses:          make(map[uuid.UUID]diagnoses.Diagnosis),
+		diagnosesByPatient: make(map[uuid.UUID][]diagnosisKey),
+		diagnosisHistory:   make(map[uuid.UUID][]diagnoses.Diagnosis),
+		idempotencyRecords: make(map[string]idempotency.Record),
+	}
 
-	repo.patients = createFakePatients()
-	repo.diagnoses = make(map[string]diagnoses.Diagnosis)
-	repo.mutex = &sync.RWMutex{}
+	for _, patient := range createFakePatients() {
+		repo.putPatient(patient)
+	}
 
 	return repo
 }
 
+// Repository keeps patients and diagnoses in maps guarded by mutex, along with the indexes that spare scanning
+// them. Exported methods take the lock and delegate to the unexported ones, which are shared with transaction.
+// Values are deep copied when stored and when returned, so callers never share memory with the repository.
+// Methods fail with the error of their context when it is done before they run, as the queries of a database would.
 type Repository struct {
-	patients  map[string]patients.Patient
-	diagnoses map[string]diagnoses.Diagnosis
-	mutex     *sync.RWMutex
+	mutex sync.RWMutex
+
+	patients map[uuid.UUID]patients.Patient
+	// patientsByLegalID indexes the patients by their unique legal ID
+	patientsByLegalID map[string]uuid.UUID
+	// patientsByName indexes the patients by name, the IDs of each name are sorted
+	patientsByName map[string][]uuid.UUID
+
+	diagnoses map[uuid.UUID]diagnoses.Diagnosis
+	// diagnosesByDate holds every diagnosis sorted by creation date and ID, date ranges are found by binary search
+	diagnosesByDate []diagnosisKey
+	// diagnosesByPatient holds the diagnoses of each patient sorted like diagnosesByDate
+	diagnosesByPatient map[uuid.UUID][]diagnosisKey
+	// diagnosisHistory holds every version of each diagnosis, from the first one, diagnoses the current ones
+	diagnosisHistory map[uuid.UUID][]diagnoses.Diagnosis
+
+	// auditRecords is the audit trail sorted by sequence, records are only ever appended
+	auditRecords []audit.Record
+
+	// idempotencyRecords are keyed by idempotency key, the expired ones are purged at most once per
+	// idempotencyPurgeInterval
+	idempotencyRecords  map[string]idempotency.Record
+	idempotencyPurgedAt time.Time
 }
 
-func (r *Repository) GetByName(name string) (*patients.Patient, error) {
-	for _, p := range r.patients {
-		if p.Name == name {
-			return &p, nil
-		}
+func (r *Repository) Create(ctx context.Context, patient patients.Patient) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	return nil, nil
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.create(patient)
 }
 
-func (r *Repository) GetByID(ID uuid.UUID) (*patients.Patient, error) {
+func (r *Repository) FindByName(ctx context.Context, name string) ([]*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mutex.RLock()
-	patient, ok := r.patients[ID.String()]
-	r.mutex.RUnlock()
-	if !ok {
-		return nil, nil
+	defer r.mutex.RUnlock()
+	return r.findByName(name), nil
+}
+
+func (r *Repository) GetByID(ctx context.Context, ID uuid.UUID) (*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	return &patient, nil
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.getByID(ID), nil
 }
 
-func (r *Repository) Update(patient patients.Patient) error {
+func (r *Repository) GetByLegalID(ctx context.Context, legalID string) (*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.getByLegalID(legalID), nil
+}
+
+func (r *Repository) List(ctx context.Context) ([]*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.list(), nil
+}
+
+func (r *Repository) Update(ctx context.Context, patient patients.Patient) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mutex.Lock()
-	r.patients[patient.ID.String()] = patient
-	r.mutex.Unlock()
-	return nil
+	defer r.mutex.Unlock()
+	return r.update(patient)
+}
+
+func (r *Repository) AddDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.addDiagnosis(diagnosis)
 }
 
-func (r *Repository) AddDiagnosis(diagnosis diagnoses.Diagnosis) error {
+func (r *Repository) UpdateDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mutex.Lock()
-	r.diagnoses[diagnosis.ID.String()] = diagnosis
-	r.mutex.Unlock()
+	defer r.mutex.Unlock()
+	r.putVersion(diagnosis)
 	return nil
 }
 
-func createFakePatients() map[string]patients.Patient {
-	patientID := "11111111-1111-1111-1111-111111111111"
+func (r *Repository) GetDiagnosisByID(ctx context.Context, ID uuid.UUID) (*diagnoses.Diagnosis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.getDiagnosis(ID), nil
+}
+
+func (r *Repository) GetDiagnosisHistory(ctx context.Context, ID uuid.UUID) ([]*diagnoses.Diagnosis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.getDiagnosisHistory(ID), nil
+}
+
+func (r *Repository) GetDiagnoses(ctx context.Context, filter diagnoses.Filter, page diagnoses.PageRequest) (diagnoses.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return diagnoses.Page{}, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.getDiagnoses(filter, page), nil
+}
+
+func (r *Repository) create(patient patients.Patient) error {
+	if _, ok := r.patients[patient.ID]; ok {
+		return patients.ErrDuplicatedID
+	}
+
+	if _, ok := r.patientsByLegalID[patient.LegalID]; ok {
+		return patients.ErrDuplicatedLegalID
+	}
+
+	patient.Version = 1
+	r.putPatient(patient)
+	return nil
+}
+
+func (r *Repository) findByName(name string) []*patients.Patient {
+	IDs := r.patientsByName[name]
+	found := make([]*patients.Patient, 0, len(IDs))
+	for _, ID := range IDs {
+		found = append(found, r.getByID(ID))
+	}
+
+	return found
+}
+
+func (r *Repository) getByID(ID uuid.UUID) *patients.Patient {
+	patient, ok := r.patients[ID]
+	if !ok {
+		return nil
+	}
+
+	patient = clonePatient(patient)
+	return &patient
+}
+
+func (r *Repository) getByLegalID(legalID string) *patients.Patient {
+	ID, ok := r.patientsByLegalID[legalID]
+	if !ok {
+		return nil
+	}
+
+	return r.getByID(ID)
+}
+
+func (r *Repository) list() []*patients.Patient {
+	found := make([]*patients.Patient, 0, len(r.patients))
+	for ID := range r.patients {
+		found = append(found, r.getByID(ID))
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Name == found[j].Name {
+			return found[i].ID.String() < found[j].ID.String()
+		}
+		return found[i].Name < found[j].Name
+	})
+
+	return found
+}
+
+// update stores the next version of the patient, which must be made on the stored one and whose legal ID can't
+// belong to another patient.
+func (r *Repository) update(patient patients.Patient) error {
+	if stored, ok := r.patients[patient.ID]; !ok || stored.Version != patient.Version {
+		return patients.ErrConcurrentModification
+	}
+
+	if ID, ok := r.patientsByLegalID[patient.LegalID]; ok && ID != patient.ID {
+		return patients.ErrDuplicatedLegalID
+	}
+
+	patient.Version++
+	r.putPatient(patient)
+	return nil
+}
+
+func (r *Repository) putPatient(patient patients.Patient) {
+	r.deletePatient(patient.ID)
+
+	r.patients[patient.ID] = clonePatient(patient)
+	r.patientsByLegalID[patient.LegalID] = patient.ID
+	r.patientsByName[patient.Name] = insertID(r.patientsByName[patient.Name], patient.ID)
+}
+
+func (r *Repository) deletePatient(ID uuid.UUID) {
+	previous, ok := r.patients[ID]
+	if !ok {
+		return
+	}
+
+	delete(r.patients, ID)
+	delete(r.patientsByLegalID, previous.LegalID)
+	if IDs := removeID(r.patientsByName[previous.Name], ID); len(IDs) > 0 {
+		r.patientsByName[previous.Name] = IDs
+	} else {
+		delete(r.patientsByName, previous.Name)
+	}
+}
+
+func (r *Repository) addDiagnosis(diagnosis diagnoses.Diagnosis) error {
+	if _, ok := r.diagnoses[diagnosis.ID]; ok {
+		return diagnoses.ErrDuplicatedID
+	}
+
+	r.putVersion(diagnosis)
+	return nil
+}
+
+func (r *Repository) putVersion(diagnosis diagnoses.Diagnosis) {
+	r.putDiagnosis(diagnosis)
+	r.diagnosisHistory[diagnosis.ID] = append(r.diagnosisHistory[diagnosis.ID], cloneDiagnosis(diagnosis))
+}
+
+func (r *Repository) putDiagnosis(diagnosis diagnoses.Diagnosis) {
+	r.deleteDiagnosis(diagnosis.ID)
+
+	key := keyOf(diagnosis)
+	r.diagnoses[diagnosis.ID] = cloneDiagnosis(diagnosis)
+	r.diagnosesByDate = insertKey(r.diagnosesByDate, key)
+	r.diagnosesByPatient[diagnosis.PatientID] = insertKey(r.diagnosesByPatient[diagnosis.PatientID], key)
+}
+
+func (r *Repository) deleteDiagnosis(ID uuid.UUID) {
+	previous, ok := r.diagnoses[ID]
+	if !ok {
+		return
+	}
+
+	key := keyOf(previous)
+	delete(r.diagnoses, ID)
+	r.diagnosesByDate = removeKey(r.diagnosesByDate, key)
+	if keys := removeKey(r.diagnosesByPatient[previous.PatientID], key); len(keys) > 0 {
+		r.diagnosesByPatient[previous.PatientID] = keys
+	} else {
+		delete(r.diagnosesByPatient, previous.PatientID)
+	}
+}
+
+func (r *Repository) getDiagnosis(ID uuid.UUID) *diagnoses.Diagnosis {
+	d, ok := r.diagnoses[ID]
+	if !ok {
+		return nil
+	}
+
+	diagnosis := cloneDiagnosis(d)
+	return &diagnosis
+}
+
+func (r *Repository) getDiagnosisHistory(ID uuid.UUID) []*diagnoses.Diagnosis {
+	versions := r.diagnosisHistory[ID]
+	found := make([]*diagnoses.Diagnosis, 0, len(versions))
+	for _, d := range versions {
+		diagnosis := cloneDiagnosis(d)
+		found = append(found, &diagnosis)
+	}
+
+	return found
+}
+
+func (r *Repository) getDiagnoses(filter diagnoses.Filter, page diagnoses.PageRequest) diagnoses.Page {
+	keys := r.diagnosesByDate
+	if filter.PatientID != nil {
+		keys = r.diagnosesByPatient[*filter.PatientID]
+	}
+
+	keys = inRange(keys, filter)
+	found := make([]*diagnoses.Diagnosis, 0, len(keys))
+	for _, key := range keys {
+		d := r.diagnoses[key.ID]
+		if filter.Matches(d) {
+			diagnosis := cloneDiagnosis(d)
+			found = append(found, &diagnosis)
+		}
+	}
+
+	return diagnoses.Paginate(found, page)
+}
 
-	return map[string]patients.Patient{patientID: {
-		ID:          uuid.MustParse(patientID),
-		LegalID:     "ABC1234",
-		Name:        "John Doe",
-		Address:     "Wall Street 123",
-		Phone:       "123456789",
-		Email:       "john.doe@example.com",
-		Diagnostics: []*diagnoses.Diagnosis{},
+func createFakePatients() []patients.Patient {
+	return []patients.Patient{{
+		ID:      uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		LegalID: "ABC1234",
+		Name:    "John Doe",
+		Address: "Wall Street 123",
+		Phone:   "123456789",
+		Email:   "john.doe@example.com",
+		Version: 1,
 	}}
 }
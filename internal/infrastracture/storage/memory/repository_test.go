@@ -1,13 +1,21 @@
 package memory
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/storage/storagetest"
+	"sync"
 	"testing"
 	"time"
 )
 
 func TestRepository_AddDiagnosis(t *testing.T) {
+	ctx := context.Background()
 	repo := NewRepository()
 
 	newDiagnosis := diagnoses.Diagnosis{
@@ -17,21 +25,22 @@ func TestRepository_AddDiagnosis(t *testing.T) {
 		CreatedAt:    time.Now(),
 		Prescription: nil,
 	}
-	err := repo.AddDiagnosis(newDiagnosis)
+	err := repo.AddDiagnosis(ctx, newDiagnosis)
 	if err != nil {
 		t.Errorf("AddDiagnosis() Error = %v, but no error expected", err)
 	}
 
-	got := repo.diagnoses["11111111-1111-1111-1111-111111111112"]
+	got := repo.diagnoses[newDiagnosis.ID]
 	if got != newDiagnosis {
 		t.Errorf("got=%v, expected=%v", got, newDiagnosis)
 	}
 }
 
 func TestRepository_GetByID(t *testing.T) {
+	ctx := context.Background()
 	repo := NewRepository()
 
-	got, err := repo.GetByID(uuid.MustParse("11111111-1111-1111-1111-111111111111"))
+	got, err := repo.GetByID(ctx, uuid.MustParse("11111111-1111-1111-1111-111111111111"))
 	if got == nil {
 		t.Errorf("got <nil>, but a value was expected")
 	}
@@ -46,19 +55,316 @@ func TestRepository_GetByID(t *testing.T) {
 	}
 }
 
-func TestRepository_GetByName(t *testing.T) {
+func TestRepository_FindByName(t *testing.T) {
+	ctx := context.Background()
 	repo := NewRepository()
 	expected := "John Doe"
-	got, err := repo.GetByName(expected)
+	got, err := repo.FindByName(ctx, expected)
 
 	if err != nil {
 		t.Errorf("got error=%v, but no error expected", err)
 	}
-	if got == nil {
-		t.Errorf("got nil when value was expected")
+	if len(got) != 1 {
+		t.Fatalf("got %d patients, expected 1", len(got))
+	}
+
+	if got[0].Name != expected {
+		t.Errorf("got=%s, expected=%s", got[0].Name, expected)
+	}
+}
+
+func TestRepository_GetDiagnoses(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository()
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	otherPatientID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	earlyMarch := time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC)
+	march := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC)
+
+	for _, d := range []diagnoses.Diagnosis{
+		{ID: uuid.New(), PatientID: patientID, CreatedAt: april, Description: "april"},
+		{ID: uuid.New(), PatientID: otherPatientID, CreatedAt: earlyMarch, Description: "other march"},
+		{ID: uuid.New(), PatientID: patientID, CreatedAt: march, Description: "march"},
+	} {
+		_ = repo.AddDiagnosis(ctx, d)
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter diagnoses.Filter
+		want   []string
+	}{
+		{name: "without filters", filter: diagnoses.Filter{}, want: []string{"other march", "march", "april"}},
+		{name: "by date range across patients", filter: diagnoses.Filter{From: &from, To: &to}, want: []string{"other march", "march"}},
+		{name: "by patient and date", filter: diagnoses.Filter{PatientID: &patientID, From: &from}, want: []string{"march", "april"}},
+		{name: "without matches", filter: diagnoses.Filter{PatientID: &otherPatientID, From: &april}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetDiagnoses(ctx, tt.filter, diagnoses.PageRequest{})
+			if err != nil {
+				t.Fatalf("got error=%v, but no error expected", err)
+			}
+
+			descriptions := make([]string, 0, len(got.Diagnoses))
+			for _, d := range got.Diagnoses {
+				descriptions = append(descriptions, d.Description)
+			}
+			if len(descriptions) != len(tt.want) {
+				t.Fatalf("got=%v, expected=%v", descriptions, tt.want)
+			}
+			for i := range tt.want {
+				if descriptions[i] != tt.want[i] {
+					t.Errorf("got=%v, expected=%v", descriptions, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRepository_Create(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository()
+	newPatient := patients.Patient{
+		ID:      uuid.MustParse("22222222-2222-2222-2222-222222222222"),
+		LegalID: "XYZ9876",
+		Name:    "Jane Roe",
+		Phone:   "+5491112345678",
+		Email:   "jane.roe@example.com",
+	}
+
+	if err := repo.Create(ctx, newPatient); err != nil {
+		t.Errorf("Create() Error = %v, but no error expected", err)
+	}
+
+	got, _ := repo.GetByLegalID(ctx, "XYZ9876")
+	if got == nil || got.ID != newPatient.ID {
+		t.Errorf("got=%v, expected=%v", got, newPatient)
+	}
+
+	duplicated := newPatient
+	duplicated.ID = uuid.New()
+	if err := repo.Create(ctx, duplicated); !errors.Is(err, patients.ErrDuplicatedLegalID) {
+		t.Errorf("Create() Error = %v, expected %v", err, patients.ErrDuplicatedLegalID)
+	}
+}
+
+func TestRepository_List(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository()
+	_ = repo.Create(ctx, patients.Patient{ID: uuid.New(), LegalID: "XYZ9876", Name: "Adam Smith"})
+
+	got, err := repo.List(ctx)
+	if err != nil {
+		t.Errorf("got error=%v, but no error expected", err)
+	}
+
+	if len(got) != 2 || got[0].Name != "Adam Smith" || got[1].Name != "John Doe" {
+		t.Errorf("got=%v, expected Adam Smith and John Doe sorted by name", got)
+	}
+}
+
+func TestRepository_Behavior(t *testing.T) {
+	storagetest.RunRepositoryTests(t, func(t *testing.T) storagetest.Repository {
+		return NewRepository()
+	})
+}
+
+func TestRepository_DeepCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository()
+	birthDate := time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)
+	patient := patients.Patient{ID: uuid.New(), LegalID: "XYZ9876", Name: "Jane Roe", BirthDate: &birthDate}
+	diagnosis := diagnoses.Diagnosis{ID: uuid.New(), PatientID: patient.ID, CreatedAt: birthDate,
+		Prescription: &diagnoses.Prescription{Medications: []diagnoses.Medication{{DrugName: "Ibuprofen"}}},
+		Coding:       &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0"}}
+	_ = repo.Create(ctx, patient)
+	_ = repo.AddDiagnosis(ctx, diagnosis)
+
+	// changing the values written or read must not change the stored ones
+	*patient.BirthDate = time.Time{}
+	diagnosis.Prescription.Medications[0].DrugName = "changed"
+	read, _ := repo.GetByID(ctx, patient.ID)
+	*read.BirthDate = time.Time{}
+	page, _ := repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patient.ID}, diagnoses.PageRequest{})
+	page.Diagnoses[0].Coding.Code = "changed"
+	page.Diagnoses[0].Prescription.Medications[0].DrugName = "changed"
+
+	got, _ := repo.GetByID(ctx, patient.ID)
+	if !got.BirthDate.Equal(time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got=%+v, expected the patient as created", got)
+	}
+
+	page, _ = repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patient.ID}, diagnoses.PageRequest{})
+	if page.Diagnoses[0].Coding.Code != "J45.0" || page.Diagnoses[0].Prescription.Medications[0].DrugName != "Ibuprofen" {
+		t.Errorf("got=%+v, expected the diagnosis as added", page.Diagnoses[0])
+	}
+}
+
+func TestRepository_RollbackIndexes(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository()
+	patientID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	errRollback := errors.New("rollback")
+
+	err := repo.Do(ctx, func(repos unitofwork.Repositories) error {
+		patient, _ := repos.Patients.GetByID(ctx, patientID)
+		patient.Name = "Renamed"
+		patient.LegalID = "RENAMED"
+		_ = repos.Patients.Update(ctx, *patient)
+		_ = repos.Patients.Create(ctx, patients.Patient{ID: uuid.New(), LegalID: "NEW", Name: "New Patient"})
+		_ = repos.Diagnoses.AddDiagnosis(ctx, diagnoses.Diagnosis{ID: uuid.New(), PatientID: patientID, CreatedAt: time.Now()})
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Do() error=%v, expected=%v", err, errRollback)
+	}
+
+	byName, _ := repo.FindByName(ctx, "John Doe")
+	byLegalID, _ := repo.GetByLegalID(ctx, "ABC1234")
+	if len(byName) != 1 || byLegalID == nil {
+		t.Errorf("got=(%v, %v), expected the indexes to find the patient as before", byName, byLegalID)
+	}
+
+	for _, legalID := range []string{"RENAMED", "NEW"} {
+		if got, _ := repo.GetByLegalID(ctx, legalID); got != nil {
+			t.Errorf("GetByLegalID(%s) got=%v, expected the write to be rolled back", legalID, got)
+		}
+	}
+
+	if len(repo.diagnosesByDate) != 0 || len(repo.diagnosesByPatient) != 0 {
+		t.Errorf("got=(%v, %v), expected no diagnoses indexed", repo.diagnosesByDate, repo.diagnosesByPatient)
+	}
+}
+
+// TestRepository_Concurrency mixes writes, reads, rolled back units of work and changes to the values read. Run it
+// with -race.
+func TestRepository_Concurrency(t *testing.T) {
+	ctx := context.Background()
+	const workers, operations = 8, 100
+	repo := NewRepository()
+	johnID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < operations; i++ {
+				legalID := fmt.Sprintf("W%d-%d", w, i)
+				patient := patients.Patient{ID: uuid.New(), LegalID: legalID, Name: fmt.Sprintf("Patient %d", i%10), Version: 1}
+				if err := repo.Create(ctx, patient); err != nil {
+					t.Errorf("Create() error=%v, but no error expected", err)
+					return
+				}
+
+				patient.Email = "updated@example.com"
+				_ = repo.Update(ctx, patient)
+				_ = repo.AddDiagnosis(ctx, diagnoses.Diagnosis{ID: uuid.New(), PatientID: patient.ID,
+					CreatedAt: start.Add(time.Duration(i) * time.Hour), Coding: &diagnoses.Coding{Code: "J45.0"}})
+				_ = repo.Do(ctx, func(repos unitofwork.Repositories) error {
+					_ = repos.Diagnoses.AddDiagnosis(ctx, diagnoses.Diagnosis{ID: uuid.New(), PatientID: johnID, CreatedAt: start})
+					return errors.New("rollback")
+				})
+
+				found, _ := repo.FindByName(ctx, patient.Name)
+				for _, p := range found {
+					p.Name = "changed"
+				}
+				if got, _ := repo.GetByLegalID(ctx, legalID); got == nil || got.Email != "updated@example.com" {
+					t.Errorf("GetByLegalID(%s) got=%v, expected the updated patient", legalID, got)
+				}
+				page, _ := repo.GetDiagnoses(ctx, diagnoses.Filter{From: &start}, diagnoses.PageRequest{Limit: 10})
+				for _, d := range page.Diagnoses {
+					d.Coding.Code = "changed"
+				}
+				_, _ = repo.List(ctx)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	all, _ := repo.List(ctx)
+	if len(all) != workers*operations+1 {
+		t.Errorf("got %d patients, expected %d", len(all), workers*operations+1)
+	}
+
+	page, _ := repo.GetDiagnoses(ctx, diagnoses.Filter{Code: "J45.0"}, diagnoses.PageRequest{})
+	if page.Total != workers*operations {
+		t.Errorf("got %d diagnoses, expected %d unchanged by the readers", page.Total, workers*operations)
 	}
 
-	if got.Name != expected {
-		t.Errorf("got=%s, expected=%s", got.Name, expected)
+	for i := 0; i < 10; i++ {
+		found, _ := repo.FindByName(ctx, fmt.Sprintf("Patient %d", i))
+		if len(found) != workers*operations/10 {
+			t.Errorf("FindByName() got %d patients, expected %d", len(found), workers*operations/10)
+		}
+	}
+}
+
+// The lookups take about the same time whatever the number of patients, run with -bench=Repository -benchmem.
+func BenchmarkRepository_GetByLegalID(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			repo := newBenchmarkRepository(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = repo.GetByLegalID(ctx, fmt.Sprintf("L%d", i%size))
+			}
+		})
+	}
+}
+
+func BenchmarkRepository_FindByName(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			repo := newBenchmarkRepository(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = repo.FindByName(ctx, fmt.Sprintf("Patient %d", i%size))
+			}
+		})
+	}
+}
+
+func BenchmarkRepository_GetDiagnoses(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		repo := newBenchmarkRepository(size)
+		patientID := repo.patientsByLegalID["L0"]
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(24 * time.Hour)
+		b.Run(fmt.Sprintf("patient/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patientID}, diagnoses.PageRequest{Limit: 50})
+			}
+		})
+		b.Run(fmt.Sprintf("day/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = repo.GetDiagnoses(ctx, diagnoses.Filter{From: &from, To: &to}, diagnoses.PageRequest{Limit: 50})
+			}
+		})
+	}
+}
+
+// newBenchmarkRepository returns a repository with size patients and two diagnoses each, one every minute.
+func newBenchmarkRepository(size int) *Repository {
+	ctx := context.Background()
+	repo := NewRepository()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < size; i++ {
+		patient := patients.Patient{ID: uuid.New(), LegalID: fmt.Sprintf("L%d", i), Name: fmt.Sprintf("Patient %d", i)}
+		_ = repo.Create(ctx, patient)
+		for j := 0; j < 2; j++ {
+			createdAt = createdAt.Add(time.Minute)
+			_ = repo.AddDiagnosis(ctx, diagnoses.Diagnosis{ID: uuid.New(), PatientID: patient.ID, CreatedAt: createdAt})
+		}
 	}
+	return repo
 }
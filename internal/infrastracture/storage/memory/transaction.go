@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+)
+
+// Do implements unitofwork.UnitOfWork. The repository stays locked until fn returns, so fn must only use the
+// repositories it receives. Writes are applied right away and undone in reverse order on rollback, which is also
+// done when ctx is done by the time fn returns.
+func (r *Repository) Do(ctx context.Context, fn func(repos unitofwork.Repositories) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tx := &transaction{repo: r}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(unitofwork.Repositories{Patients: tx, Diagnoses: tx}); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	return nil
+}
+
+type transaction struct {
+	repo *Repository
+	undo []func()
+}
+
+func (t *transaction) Create(ctx context.Context, patient patients.Patient) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.savePatient(patient.ID)
+	return t.repo.create(patient)
+}
+
+func (t *transaction) FindByName(ctx context.Context, name string) ([]*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.repo.findByName(name), nil
+}
+
+func (t *transaction) GetByID(ctx context.Context, ID uuid.UUID) (*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.repo.getByID(ID), nil
+}
+
+func (t *transaction) GetByLegalID(ctx context.Context, legalID string) (*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.repo.getByLegalID(legalID), nil
+}
+
+func (t *transaction) List(ctx context.Context) ([]*patients.Patient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.repo.list(), nil
+}
+
+func (t *transaction) Update(ctx context.Context, patient patients.Patient) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.savePatient(patient.ID)
+	return t.repo.update(patient)
+}
+
+func (t *transaction) AddDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.saveDiagnosis(diagnosis.ID)
+	return t.repo.addDiagnosis(diagnosis)
+}
+
+func (t *transaction) UpdateDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.saveDiagnosis(diagnosis.ID)
+	t.repo.putVersion(diagnosis)
+	return nil
+}
+
+func (t *transaction) GetDiagnosisByID(ctx context.Context, ID uuid.UUID) (*diagnoses.Diagnosis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.repo.getDiagnosis(ID), nil
+}
+
+func (t *transaction) GetDiagnosisHistory(ctx context.Context, ID uuid.UUID) ([]*diagnoses.Diagnosis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return t.repo.getDiagnosisHistory(ID), nil
+}
+
+func (t *transaction) GetDiagnoses(ctx context.Context, filter diagnoses.Filter, page diagnoses.PageRequest) (diagnoses.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return diagnoses.Page{}, err
+	}
+
+	return t.repo.getDiagnoses(filter, page), nil
+}
+
+func (t *transaction) savePatient(ID uuid.UUID) {
+	previous, existed := t.repo.patients[ID]
+	t.undo = append(t.undo, func() {
+		if existed {
+			t.repo.putPatient(previous)
+			return
+		}
+		t.repo.deletePatient(ID)
+	})
+}
+
+func (t *transaction) saveDiagnosis(ID uuid.UUID) {
+	previous, existed := t.repo.diagnoses[ID]
+	versions := len(t.repo.diagnosisHistory[ID])
+	t.undo = append(t.undo, func() {
+		if existed {
+			t.repo.putDiagnosis(previous)
+			t.repo.diagnosisHistory[ID] = t.repo.diagnosisHistory[ID][:versions]
+			return
+		}
+		t.repo.deleteDiagnosis(ID)
+		delete(t.repo.diagnosisHistory, ID)
+	})
+}
+
+func (t *transaction) rollback() {
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+}
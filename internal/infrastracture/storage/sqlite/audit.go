@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"time"
+)
+
+const auditColumns = `sequence, timestamp, actor_id, action, patient_id, outcome, request_id, prev_hash, hash`
+
+// Append chains the record in a transaction of its own, so no other record can be appended between reading
+// the last one and inserting the new one.
+func (r *Repository) Append(ctx context.Context, record audit.Record) (audit.Record, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return audit.Record{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_records ORDER BY sequence DESC LIMIT 1`)
+	if err != nil {
+		return audit.Record{}, err
+	}
+	last, err := scanAuditRecords(rows)
+	if err != nil {
+		return audit.Record{}, err
+	}
+
+	var prev *audit.Record
+	if len(last) > 0 {
+		prev = &last[0]
+	}
+
+	record = audit.Chain(prev, record)
+	var patientID *string
+	if record.PatientID != nil {
+		id := record.PatientID.String()
+		patientID = &id
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO audit_records (`+auditColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Sequence, record.Timestamp.UnixNano(), record.ActorID, record.Action, patientID, record.Outcome,
+		record.RequestID, record.PrevHash, record.Hash)
+	if err != nil {
+		return audit.Record{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return audit.Record{}, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r *Repository) Find(ctx context.Context, filter audit.Filter) ([]audit.Record, error) {
+	var conditions []string
+	var args []any
+	if filter.PatientID != nil {
+		conditions = append(conditions, "patient_id = ?")
+		args = append(args, filter.PatientID.String())
+	}
+
+	if filter.ActorID != "" {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+
+	rows, err := r.q.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_records`+where(conditions)+` ORDER BY sequence`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAuditRecords(rows)
+}
+
+func scanAuditRecords(rows *sql.Rows) ([]audit.Record, error) {
+	defer rows.Close()
+
+	found := make([]audit.Record, 0)
+	for rows.Next() {
+		var record audit.Record
+		var timestamp int64
+		var patientID sql.NullString
+		err := rows.Scan(&record.Sequence, &timestamp, &record.ActorID, &record.Action, &patientID, &record.Outcome,
+			&record.RequestID, &record.PrevHash, &record.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		record.Timestamp = time.Unix(0, timestamp).UTC()
+		if patientID.Valid {
+			id, err := uuid.Parse(patientID.String)
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("invalid patient ID in audit record %d", record.Sequence), err)
+			}
+			record.PatientID = &id
+		}
+		found = append(found, record)
+	}
+
+	return found, rows.Err()
+}
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"time"
+)
+
+// Reserve purges the expired records and inserts the record in a transaction of its own, so only one of the
+// requests sent at the same time with a key reserves it.
+func (r *Repository) Reserve(ctx context.Context, record idempotency.Record,
+	now time.Time) (*idempotency.Record, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, now.UnixNano()); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO idempotency_keys (key, fingerprint, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO NOTHING`, record.Key, record.Fingerprint, record.ExpiresAt.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	var existing *idempotency.Record
+	if inserted == 0 {
+		existing, err = scanIdempotencyRecord(tx.QueryRowContext(ctx, `SELECT key, fingerprint, status_code, header,
+			body, expires_at FROM idempotency_keys WHERE key = ?`, record.Key))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return existing, nil
+}
+
+func (r *Repository) Complete(ctx context.Context, key string, response idempotency.Response,
+	expiresAt time.Time) error {
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.q.ExecContext(ctx, `UPDATE idempotency_keys SET status_code = ?, header = ?, body = ?, expires_at = ?
+		WHERE key = ?`,
+		response.StatusCode, string(header), response.Body, expiresAt.UnixNano(), key)
+	return err
+}
+
+func (r *Repository) Release(ctx context.Context, key string) error {
+	_, err := r.q.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ?`, key)
+	return err
+}
+
+func scanIdempotencyRecord(row scanner) (*idempotency.Record, error) {
+	var record idempotency.Record
+	var statusCode sql.NullInt64
+	var header sql.NullString
+	var body []byte
+	var expiresAt int64
+	err := row.Scan(&record.Key, &record.Fingerprint, &statusCode, &header, &body, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	if statusCode.Valid {
+		record.Response = &idempotency.Response{StatusCode: int(statusCode.Int64), Body: body}
+		if err := json.Unmarshal([]byte(header.String), &record.Response.Header); err != nil {
+			return nil, fmt.Errorf("invalid header of idempotency key %s: %w", record.Key, err)
+		}
+	}
+
+	return &record, nil
+}
@@ -0,0 +1,22 @@
+CREATE TABLE patients (
+    id       TEXT PRIMARY KEY,
+    legal_id TEXT NOT NULL UNIQUE,
+    name     TEXT NOT NULL,
+    address  TEXT NOT NULL DEFAULT '',
+    phone    TEXT NOT NULL DEFAULT '',
+    email    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_patients_name ON patients (name);
+
+CREATE TABLE diagnoses (
+    id           TEXT PRIMARY KEY,
+    patient_id   TEXT NOT NULL REFERENCES patients (id),
+    description  TEXT NOT NULL,
+    prescription TEXT,
+    -- unix nanoseconds, so date ranges can be compared without parsing
+    created_at   INTEGER NOT NULL
+);
+
+CREATE INDEX idx_diagnoses_patient_created_at ON diagnoses (patient_id, created_at);
+CREATE INDEX idx_diagnoses_created_at ON diagnoses (created_at);
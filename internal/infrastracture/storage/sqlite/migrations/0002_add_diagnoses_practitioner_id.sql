@@ -0,0 +1 @@
+ALTER TABLE diagnoses ADD COLUMN practitioner_id TEXT NOT NULL DEFAULT '';
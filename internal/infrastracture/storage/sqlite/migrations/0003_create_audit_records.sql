@@ -0,0 +1,26 @@
+CREATE TABLE audit_records (
+    sequence   INTEGER PRIMARY KEY,
+    -- unix nanoseconds, the same value the record hash is computed from
+    timestamp  INTEGER NOT NULL,
+    actor_id   TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    patient_id TEXT,
+    outcome    TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    prev_hash  TEXT NOT NULL,
+    hash       TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_records_patient_id ON audit_records (patient_id);
+CREATE INDEX idx_audit_records_actor_id ON audit_records (actor_id);
+
+-- the trail is append-only, the hash chain detects changes made bypassing these triggers
+CREATE TRIGGER audit_records_no_update BEFORE UPDATE ON audit_records
+BEGIN
+    SELECT RAISE(ABORT, 'audit records are append-only');
+END;
+
+CREATE TRIGGER audit_records_no_delete BEFORE DELETE ON audit_records
+BEGIN
+    SELECT RAISE(ABORT, 'audit records are append-only');
+END;
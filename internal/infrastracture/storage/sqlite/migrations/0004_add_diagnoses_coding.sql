@@ -0,0 +1,6 @@
+-- the coding of a diagnosis is optional, the three columns are either all set or all NULL
+ALTER TABLE diagnoses ADD COLUMN code_system TEXT;
+ALTER TABLE diagnoses ADD COLUMN code TEXT;
+ALTER TABLE diagnoses ADD COLUMN code_display TEXT;
+
+CREATE INDEX idx_diagnoses_code ON diagnoses (code);
@@ -0,0 +1,20 @@
+-- diagnoses.prescription keeps the notes of the prescription, it is NULL when there is no prescription and
+-- legacy free-text prescriptions are read as notes without medications
+CREATE TABLE prescription_medications (
+    diagnosis_id     TEXT NOT NULL REFERENCES diagnoses (id),
+    -- position of the medication in the prescription, starting at 1
+    line             INTEGER NOT NULL,
+    drug_name        TEXT NOT NULL,
+    drug_code        TEXT NOT NULL DEFAULT '',
+    dose             REAL NOT NULL,
+    dose_unit        TEXT NOT NULL,
+    route            TEXT NOT NULL,
+    frequency_times  INTEGER NOT NULL,
+    frequency_period INTEGER NOT NULL,
+    frequency_unit   TEXT NOT NULL,
+    duration_value   INTEGER NOT NULL,
+    duration_unit    TEXT NOT NULL,
+    quantity         INTEGER NOT NULL,
+    refills          INTEGER NOT NULL,
+    PRIMARY KEY (diagnosis_id, line)
+);
@@ -0,0 +1,2 @@
+-- YYYY-MM-DD, NULL when the birth date is unknown
+ALTER TABLE patients ADD COLUMN birth_date TEXT;
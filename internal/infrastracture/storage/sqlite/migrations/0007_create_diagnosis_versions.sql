@@ -0,0 +1,63 @@
+-- diagnoses keeps the current version of each diagnosis, and diagnosis_versions every version of it, the current
+-- one included, so the history is read from a single table
+ALTER TABLE diagnoses ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+-- final, amended or entered-in-error
+ALTER TABLE diagnoses ADD COLUMN status TEXT NOT NULL DEFAULT 'final';
+-- unix nanoseconds, when and by whom the version was written
+ALTER TABLE diagnoses ADD COLUMN recorded_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE diagnoses ADD COLUMN recorded_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE diagnoses ADD COLUMN reason TEXT NOT NULL DEFAULT '';
+
+UPDATE diagnoses SET recorded_at = created_at, recorded_by = practitioner_id;
+
+CREATE TABLE diagnosis_versions (
+    id              TEXT NOT NULL REFERENCES diagnoses (id),
+    version         INTEGER NOT NULL,
+    patient_id      TEXT NOT NULL,
+    description     TEXT NOT NULL,
+    prescription    TEXT,
+    created_at      INTEGER NOT NULL,
+    practitioner_id TEXT NOT NULL,
+    code_system     TEXT,
+    code            TEXT,
+    code_display    TEXT,
+    status          TEXT NOT NULL,
+    recorded_at     INTEGER NOT NULL,
+    recorded_by     TEXT NOT NULL,
+    reason          TEXT NOT NULL,
+    PRIMARY KEY (id, version)
+);
+
+CREATE TABLE diagnosis_version_medications (
+    diagnosis_id     TEXT NOT NULL,
+    version          INTEGER NOT NULL,
+    line             INTEGER NOT NULL,
+    drug_name        TEXT NOT NULL,
+    drug_code        TEXT NOT NULL DEFAULT '',
+    dose             REAL NOT NULL,
+    dose_unit        TEXT NOT NULL,
+    route            TEXT NOT NULL,
+    frequency_times  INTEGER NOT NULL,
+    frequency_period INTEGER NOT NULL,
+    frequency_unit   TEXT NOT NULL,
+    duration_value   INTEGER NOT NULL,
+    duration_unit    TEXT NOT NULL,
+    quantity         INTEGER NOT NULL,
+    refills          INTEGER NOT NULL,
+    PRIMARY KEY (diagnosis_id, version, line),
+    FOREIGN KEY (diagnosis_id, version) REFERENCES diagnosis_versions (id, version)
+);
+
+-- the diagnoses written before versioning become their first version
+INSERT INTO diagnosis_versions (id, version, patient_id, description, prescription, created_at, practitioner_id,
+                                code_system, code, code_display, status, recorded_at, recorded_by, reason)
+SELECT id, version, patient_id, description, prescription, created_at, practitioner_id, code_system, code,
+       code_display, status, recorded_at, recorded_by, reason
+FROM diagnoses;
+
+INSERT INTO diagnosis_version_medications (diagnosis_id, version, line, drug_name, drug_code, dose, dose_unit, route,
+                                           frequency_times, frequency_period, frequency_unit, duration_value,
+                                           duration_unit, quantity, refills)
+SELECT diagnosis_id, 1, line, drug_name, drug_code, dose, dose_unit, route, frequency_times, frequency_period,
+       frequency_unit, duration_value, duration_unit, quantity, refills
+FROM prescription_medications;
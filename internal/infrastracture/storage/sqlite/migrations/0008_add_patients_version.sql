@@ -0,0 +1,2 @@
+-- counts the updates of each patient, updates are only applied on the version they were made on
+ALTER TABLE patients ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
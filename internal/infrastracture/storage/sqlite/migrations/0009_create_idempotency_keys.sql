@@ -0,0 +1,14 @@
+-- responses to the requests sent with an idempotency key, replayed to their retries until expires_at
+CREATE TABLE idempotency_keys (
+    key         TEXT PRIMARY KEY,
+    fingerprint TEXT    NOT NULL,
+    -- the response columns are NULL while the first request is being processed
+    status_code INTEGER,
+    -- JSON object of the replayed headers
+    header      TEXT,
+    body        BLOB,
+    -- Unix nanoseconds
+    expires_at  INTEGER NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
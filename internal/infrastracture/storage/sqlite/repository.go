@@ -0,0 +1,520 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Repository is a durable implementation of patients.Repository and diagnoses.Repository on an embedded
+// SQLite database. Queries run with the context of the call, which interrupts them when it is done.
+type Repository struct {
+	db *sql.DB
+	// q runs the queries, it is db itself or the transaction the repository is bound to
+	q querier
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Open opens, or creates, the SQLite database at path and migrates it to the latest schema version.
+// Use ":memory:" for a throwaway database.
+func Open(path string) (*Repository, error) {
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+
+	// SQLite allows a single writer, serializing the connections avoids SQLITE_BUSY errors
+	// and keeps ":memory:" databases shared.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Repository{db: db, q: db}, nil
+}
+
+func dsn(path string) string {
+	return "file:" + path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+}
+
+// Close writes the WAL back into the database file, so it is complete on its own, and closes the database.
+func (r *Repository) Close() error {
+	_, checkpointErr := r.db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+	if checkpointErr != nil {
+		checkpointErr = fmt.Errorf("flushing sqlite database: %w", checkpointErr)
+	}
+	return errors.Join(checkpointErr, r.db.Close())
+}
+
+const (
+	patientColumns = `id, legal_id, name, address, phone, email, birth_date, version`
+	// diagnosisColumns are the columns of both diagnoses and diagnosis_versions
+	diagnosisColumns = `id, patient_id, description, prescription, created_at, practitioner_id, code_system, code, ` +
+		`code_display, version, status, recorded_at, recorded_by, reason`
+	diagnosisPlaceholders = `?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?`
+	// medicationColumns follow the columns identifying the prescription in prescription_medications and
+	// diagnosis_version_medications
+	medicationColumns = `line, drug_name, drug_code, dose, dose_unit, route, frequency_times, frequency_period, ` +
+		`frequency_unit, duration_value, duration_unit, quantity, refills`
+)
+
+func (r *Repository) Create(ctx context.Context, patient patients.Patient) error {
+	_, err := r.q.ExecContext(ctx, `INSERT INTO patients (`+patientColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, 1)`,
+		patient.ID.String(), patient.LegalID, patient.Name, patient.Address, patient.Phone, patient.Email,
+		formatDate(patient.BirthDate))
+	if isUniqueViolation(err, "patients.legal_id") {
+		return patients.ErrDuplicatedLegalID
+	}
+
+	if isUniqueViolation(err, "patients.id") {
+		return patients.ErrDuplicatedID
+	}
+
+	return err
+}
+
+func (r *Repository) FindByName(ctx context.Context, name string) ([]*patients.Patient, error) {
+	return r.queryPatients(ctx, `SELECT `+patientColumns+` FROM patients WHERE name = ? ORDER BY id`, name)
+}
+
+func (r *Repository) GetByID(ctx context.Context, ID uuid.UUID) (*patients.Patient, error) {
+	return r.getPatient(ctx, `SELECT `+patientColumns+` FROM patients WHERE id = ?`, ID.String())
+}
+
+func (r *Repository) GetByLegalID(ctx context.Context, legalID string) (*patients.Patient, error) {
+	return r.getPatient(ctx, `SELECT `+patientColumns+` FROM patients WHERE legal_id = ?`, legalID)
+}
+
+func (r *Repository) List(ctx context.Context) ([]*patients.Patient, error) {
+	return r.queryPatients(ctx, `SELECT `+patientColumns+` FROM patients ORDER BY name, id`)
+}
+
+func (r *Repository) queryPatients(ctx context.Context, query string, args ...any) ([]*patients.Patient, error) {
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make([]*patients.Patient, 0)
+	for rows.Next() {
+		patient, err := scanPatient(rows)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, patient)
+	}
+
+	return found, rows.Err()
+}
+
+// Update stores the identity and contact data of the patient as its next version, the row is only updated while
+// its version is the one of the patient.
+func (r *Repository) Update(ctx context.Context, patient patients.Patient) error {
+	result, err := r.q.ExecContext(ctx, `UPDATE patients SET legal_id = ?, name = ?, address = ?, phone = ?, email = ?,
+		birth_date = ?, version = version + 1 WHERE id = ? AND version = ?`, patient.LegalID, patient.Name,
+		patient.Address, patient.Phone, patient.Email, formatDate(patient.BirthDate), patient.ID.String(), patient.Version)
+	if isUniqueViolation(err, "patients.legal_id") {
+		return patients.ErrDuplicatedLegalID
+	}
+
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return patients.ErrConcurrentModification
+	}
+
+	return nil
+}
+
+// AddDiagnosis stores the diagnosis and the medications of its prescription, as its current and first version.
+// Outside a unit of work they are stored in a transaction of their own.
+func (r *Repository) AddDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	if r.q != r.db {
+		return r.addDiagnosis(ctx, diagnosis)
+	}
+
+	return r.Do(ctx, func(repos unitofwork.Repositories) error {
+		return repos.Diagnoses.AddDiagnosis(ctx, diagnosis)
+	})
+}
+
+func (r *Repository) addDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	_, err := r.q.ExecContext(ctx, `INSERT INTO diagnoses (`+diagnosisColumns+`) VALUES (`+diagnosisPlaceholders+`)`,
+		diagnosisValues(diagnosis)...)
+	if isUniqueViolation(err, "diagnoses.id") {
+		return diagnoses.ErrDuplicatedID
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = r.insertMedications(ctx, "prescription_medications", "diagnosis_id", diagnosis, diagnosis.ID.String())
+	if err != nil {
+		return err
+	}
+
+	return r.addVersion(ctx, diagnosis)
+}
+
+// UpdateDiagnosis replaces the current version of the diagnosis and adds it to the versions. Outside a unit of
+// work it is stored in a transaction of its own.
+func (r *Repository) UpdateDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	if r.q != r.db {
+		return r.updateDiagnosis(ctx, diagnosis)
+	}
+
+	return r.Do(ctx, func(repos unitofwork.Repositories) error {
+		return repos.Diagnoses.UpdateDiagnosis(ctx, diagnosis)
+	})
+}
+
+func (r *Repository) updateDiagnosis(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	_, err := r.q.ExecContext(ctx, `DELETE FROM prescription_medications WHERE diagnosis_id = ?`, diagnosis.ID.String())
+	if err != nil {
+		return err
+	}
+
+	// the row is updated rather than replaced, replacing it would delete it and break the versions references
+	values := diagnosisValues(diagnosis)
+	_, err = r.q.ExecContext(ctx, `UPDATE diagnoses SET (`+diagnosisColumns+`) = (`+diagnosisPlaceholders+`) WHERE id = ?`,
+		append(values, diagnosis.ID.String())...)
+	if err != nil {
+		return err
+	}
+
+	err = r.insertMedications(ctx, "prescription_medications", "diagnosis_id", diagnosis, diagnosis.ID.String())
+	if err != nil {
+		return err
+	}
+
+	return r.addVersion(ctx, diagnosis)
+}
+
+func (r *Repository) addVersion(ctx context.Context, diagnosis diagnoses.Diagnosis) error {
+	_, err := r.q.ExecContext(ctx,
+		`INSERT INTO diagnosis_versions (`+diagnosisColumns+`) VALUES (`+diagnosisPlaceholders+`)`,
+		diagnosisValues(diagnosis)...)
+	if err != nil {
+		return err
+	}
+
+	return r.insertMedications(ctx, "diagnosis_version_medications", "diagnosis_id, version", diagnosis,
+		diagnosis.ID.String(), diagnosis.Version)
+}
+
+func diagnosisValues(diagnosis diagnoses.Diagnosis) []any {
+	var codeSystem, code, codeDisplay *string
+	if diagnosis.Coding != nil {
+		codeSystem, code, codeDisplay = &diagnosis.Coding.System, &diagnosis.Coding.Code, &diagnosis.Coding.Display
+	}
+
+	var notes *string
+	if diagnosis.Prescription != nil {
+		notes = &diagnosis.Prescription.Notes
+	}
+
+	return []any{diagnosis.ID.String(), diagnosis.PatientID.String(), diagnosis.Description, notes,
+		diagnosis.CreatedAt.UnixNano(), diagnosis.PractitionerID, codeSystem, code, codeDisplay, diagnosis.Version,
+		diagnosis.Status, diagnosis.RecordedAt.UnixNano(), diagnosis.RecordedBy, diagnosis.Reason}
+}
+
+func (r *Repository) insertMedications(ctx context.Context, table, keyColumns string, diagnosis diagnoses.Diagnosis,
+	key ...any) error {
+	if diagnosis.Prescription == nil {
+		return nil
+	}
+
+	placeholders := strings.Repeat("?, ", len(key)) + `?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?`
+	for i, m := range diagnosis.Prescription.Medications {
+		values := append(slices.Clone(key), i+1, m.DrugName, m.DrugCode, m.Dose, m.DoseUnit, m.Route,
+			m.Frequency.Times, m.Frequency.Period, m.Frequency.Unit, m.Duration.Value, m.Duration.Unit, m.Quantity,
+			m.Refills)
+		_, err := r.q.ExecContext(ctx,
+			`INSERT INTO `+table+` (`+keyColumns+`, `+medicationColumns+`) VALUES (`+placeholders+`)`, values...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) GetDiagnosisByID(ctx context.Context, ID uuid.UUID) (*diagnoses.Diagnosis, error) {
+	found, err := r.queryDiagnoses(ctx, `SELECT `+diagnosisColumns+` FROM diagnoses WHERE id = ?`, ID.String())
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+
+	return found[0], nil
+}
+
+func (r *Repository) GetDiagnosisHistory(ctx context.Context, ID uuid.UUID) ([]*diagnoses.Diagnosis, error) {
+	versions, err := r.scanDiagnoses(ctx,
+		`SELECT `+diagnosisColumns+` FROM diagnosis_versions WHERE id = ? ORDER BY version`, ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	prescriptions := make(map[string]*diagnoses.Prescription)
+	for _, version := range versions {
+		if version.Prescription != nil {
+			prescriptions[strconv.Itoa(version.Version)] = version.Prescription
+		}
+	}
+
+	return versions, r.loadMedications(ctx, prescriptions, `SELECT version, `+medicationColumns+`
+		FROM diagnosis_version_medications WHERE diagnosis_id = ? ORDER BY version, line`, ID.String())
+}
+
+func (r *Repository) GetDiagnoses(ctx context.Context, filter diagnoses.Filter,
+	page diagnoses.PageRequest) (diagnoses.Page, error) {
+	// diagnoses entered in error are only read through their history
+	conditions := []string{"status <> ?"}
+	args := []any{diagnoses.StatusEnteredInError}
+	if filter.PatientID != nil {
+		conditions = append(conditions, "patient_id = ?")
+		args = append(args, filter.PatientID.String())
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UnixNano())
+	}
+
+	if filter.To != nil {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.To.UnixNano())
+	}
+
+	if filter.Code != "" {
+		conditions = append(conditions, "code = ?")
+		args = append(args, filter.Code)
+	}
+
+	if filter.CodePrefix != "" {
+		conditions = append(conditions, "substr(code, 1, ?) = ?")
+		args = append(args, len(filter.CodePrefix), filter.CodePrefix)
+	}
+
+	if filter.HasPrescription {
+		conditions = append(conditions, "prescription IS NOT NULL")
+	}
+
+	var total int
+	err := r.q.QueryRowContext(ctx, `SELECT COUNT(*) FROM diagnoses`+where(conditions), args...).Scan(&total)
+	if err != nil {
+		return diagnoses.Page{}, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if page.Order == diagnoses.SortDescending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.After != nil {
+		conditions = append(conditions, "(created_at, id) "+comparison+" (?, ?)")
+		args = append(args, page.After.CreatedAt.UnixNano(), page.After.ID.String())
+	}
+
+	query := `SELECT ` + diagnosisColumns + ` FROM diagnoses` + where(conditions) +
+		` ORDER BY created_at ` + direction + `, id ` + direction
+	if page.Limit > 0 {
+		// one more row tells whether there is a next page
+		query += ` LIMIT ?`
+		args = append(args, page.Limit+1)
+	}
+
+	found, err := r.queryDiagnoses(ctx, query, args...)
+	if err != nil {
+		return diagnoses.Page{}, err
+	}
+
+	result := diagnoses.Page{Diagnoses: found, Total: total}
+	if page.Limit > 0 && len(found) > page.Limit {
+		result.Diagnoses = found[:page.Limit]
+		last := result.Diagnoses[page.Limit-1]
+		result.Next = &diagnoses.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	return result, nil
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND ")
+}
+
+func (r *Repository) getPatient(ctx context.Context, query string, args ...any) (*patients.Patient, error) {
+	patient, err := scanPatient(r.q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return patient, nil
+}
+
+func (r *Repository) queryDiagnoses(ctx context.Context, query string, args ...any) ([]*diagnoses.Diagnosis, error) {
+	found, err := r.scanDiagnoses(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	prescriptions := make(map[string]*diagnoses.Prescription)
+	var ids []any
+	for _, diagnosis := range found {
+		if diagnosis.Prescription != nil {
+			prescriptions[diagnosis.ID.String()] = diagnosis.Prescription
+			ids = append(ids, diagnosis.ID.String())
+		}
+	}
+
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	return found, r.loadMedications(ctx, prescriptions, `SELECT diagnosis_id, `+medicationColumns+`
+		FROM prescription_medications WHERE diagnosis_id IN (?`+strings.Repeat(", ?", len(ids)-1)+`) ORDER BY diagnosis_id, line`, ids...)
+}
+
+func (r *Repository) scanDiagnoses(ctx context.Context, query string, args ...any) ([]*diagnoses.Diagnosis, error) {
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	// rows are closed on return, before the medications are queried, since the pool holds a single connection
+	defer rows.Close()
+
+	found := make([]*diagnoses.Diagnosis, 0)
+	for rows.Next() {
+		var diagnosis diagnoses.Diagnosis
+		var createdAt, recordedAt int64
+		var notes, codeSystem, code, codeDisplay sql.NullString
+		err := rows.Scan(&diagnosis.ID, &diagnosis.PatientID, &diagnosis.Description, &notes,
+			&createdAt, &diagnosis.PractitionerID, &codeSystem, &code, &codeDisplay, &diagnosis.Version,
+			&diagnosis.Status, &recordedAt, &diagnosis.RecordedBy, &diagnosis.Reason)
+		if err != nil {
+			return nil, err
+		}
+		diagnosis.CreatedAt = time.Unix(0, createdAt).UTC()
+		diagnosis.RecordedAt = time.Unix(0, recordedAt).UTC()
+		if notes.Valid {
+			diagnosis.Prescription = &diagnoses.Prescription{Notes: notes.String}
+		}
+		if code.Valid {
+			diagnosis.Coding = &diagnoses.Coding{System: codeSystem.String, Code: code.String, Display: codeDisplay.String}
+		}
+		found = append(found, &diagnosis)
+	}
+
+	return found, rows.Err()
+}
+
+// loadMedications appends to the prescriptions the medications selected by query, which selects the key of the
+// prescription in prescriptions and then medicationColumns, in line order.
+func (r *Repository) loadMedications(ctx context.Context, prescriptions map[string]*diagnoses.Prescription,
+	query string, args ...any) error {
+	if len(prescriptions) == 0 {
+		return nil
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var line int
+		var m diagnoses.Medication
+		err := rows.Scan(&key, &line, &m.DrugName, &m.DrugCode, &m.Dose, &m.DoseUnit, &m.Route,
+			&m.Frequency.Times, &m.Frequency.Period, &m.Frequency.Unit, &m.Duration.Value, &m.Duration.Unit,
+			&m.Quantity, &m.Refills)
+		if err != nil {
+			return err
+		}
+		prescription := prescriptions[key]
+		prescription.Medications = append(prescription.Medications, m)
+	}
+
+	return rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPatient(row scanner) (*patients.Patient, error) {
+	var patient patients.Patient
+	var birthDate sql.NullString
+	err := row.Scan(&patient.ID, &patient.LegalID, &patient.Name, &patient.Address, &patient.Phone, &patient.Email,
+		&birthDate, &patient.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if birthDate.Valid {
+		date, err := time.Parse(dateLayout, birthDate.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid birth date of patient %s: %w", patient.ID, err)
+		}
+		patient.BirthDate = &date
+	}
+
+	return &patient, nil
+}
+
+const dateLayout = "2006-01-02"
+
+func formatDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+
+	formatted := date.Format(dateLayout)
+	return &formatted
+}
+
+func isUniqueViolation(err error, column string) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	code := sqliteErr.Code()
+	return (code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) &&
+		strings.Contains(sqliteErr.Error(), column)
+}
@@ -0,0 +1,202 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/infrastracture/storage/storagetest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRepository_Behavior(t *testing.T) {
+	storagetest.RunRepositoryTests(t, func(t *testing.T) storagetest.Repository {
+		return openTestRepository(t, filepath.Join(t.TempDir(), "diagnoses.db"))
+	})
+}
+
+func TestOpen_KeepsDataAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "diagnoses.db")
+	patient := storagetest.NewPatient("ABC1234", "John Doe")
+
+	repo := openTestRepository(t, path)
+	if err := repo.Create(ctx, patient); err != nil {
+		t.Fatalf("Create() error=%v, but no error expected", err)
+	}
+	_ = repo.Close()
+
+	reopened := openTestRepository(t, path)
+	got, err := reopened.GetByID(ctx, patient.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetByID() after reopening got=(%v, %v), expected the patient", got, err)
+	}
+}
+
+func TestRepository_Close_FlushesWAL(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "diagnoses.db")
+	repo := openTestRepository(t, path)
+	if err := repo.Create(ctx, storagetest.NewPatient("ABC1234", "John Doe")); err != nil {
+		t.Fatalf("Create() error=%v, but no error expected", err)
+	}
+
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close() error=%v, but no error expected", err)
+	}
+
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() > 0 {
+		t.Errorf("got a WAL of %d bytes after Close(), expected it to be written back to the database", info.Size())
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	repo := openTestRepository(t, ":memory:")
+
+	// running the migrations again must be a no-op
+	if err := migrate(repo.db); err != nil {
+		t.Fatalf("migrate() error=%v, but no error expected", err)
+	}
+
+	migrations, _ := loadMigrations()
+	var version, applied int
+	_ = repo.db.QueryRow(`SELECT MAX(version), COUNT(*) FROM schema_migrations`).Scan(&version, &applied)
+	if version != migrations[len(migrations)-1].version || applied != len(migrations) {
+		t.Errorf("got version=%d with %d migrations applied, expected version=%d with %d migrations",
+			version, applied, migrations[len(migrations)-1].version, len(migrations))
+	}
+}
+
+func TestRepository_AuditTrailIsAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestRepository(t, ":memory:")
+	patientID := uuid.New()
+	for _, actorID := range []string{"practitioner-1", "practitioner-2"} {
+		_, err := repo.Append(ctx, audit.Record{ActorID: actorID, PatientID: &patientID, Outcome: audit.OutcomeSuccess})
+		if err != nil {
+			t.Fatalf("Append() error=%v, but no error expected", err)
+		}
+	}
+
+	if _, err := repo.db.Exec(`UPDATE audit_records SET actor_id = 'someone-else'`); err == nil {
+		t.Errorf("updating an audit record succeeded, expected it to be rejected")
+	}
+	if _, err := repo.db.Exec(`DELETE FROM audit_records`); err == nil {
+		t.Errorf("deleting an audit record succeeded, expected it to be rejected")
+	}
+
+	// changes made bypassing the triggers are caught by the hash chain
+	_, err := repo.db.Exec(`DROP TRIGGER audit_records_no_update;
+		UPDATE audit_records SET actor_id = 'someone-else' WHERE sequence = 1`)
+	if err != nil {
+		t.Fatalf("tampering the audit trail error=%v, but no error expected", err)
+	}
+
+	records, err := repo.Find(ctx, audit.Filter{})
+	if err != nil {
+		t.Fatalf("Find() error=%v, but no error expected", err)
+	}
+	var brokenChain *audit.BrokenChainError
+	if err := audit.Verify(records); !errors.As(err, &brokenChain) || brokenChain.Sequence != 1 {
+		t.Errorf("Verify() error=%v, expected the chain to be broken at record 1", err)
+	}
+}
+
+func TestRepository_ReadsLegacyPrescriptionsAsNotes(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestRepository(t, ":memory:")
+	patient := storagetest.NewPatient("ABC1234", "John Doe")
+	if err := repo.Create(ctx, patient); err != nil {
+		t.Fatalf("Create() error=%v, but no error expected", err)
+	}
+
+	// free-text prescriptions stored before they were structured have no medications
+	_, err := repo.db.Exec(`INSERT INTO diagnoses (id, patient_id, description, prescription, created_at)
+		VALUES (?, ?, 'migraine', 'ibuprofen 400mg', 0)`, uuid.NewString(), patient.ID.String())
+	if err != nil {
+		t.Fatalf("inserting legacy diagnosis error=%v, but no error expected", err)
+	}
+
+	page, err := repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patient.ID}, diagnoses.PageRequest{})
+	if err != nil || len(page.Diagnoses) != 1 {
+		t.Fatalf("GetDiagnoses() got=(%v, %v), expected the legacy diagnosis", page, err)
+	}
+	want := &diagnoses.Prescription{Notes: "ibuprofen 400mg"}
+	if got := page.Diagnoses[0].Prescription; !reflect.DeepEqual(got, want) {
+		t.Errorf("got prescription=%+v, expected=%+v", got, want)
+	}
+}
+
+func TestMigrate_VersionsExistingDiagnoses(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", dsn(filepath.Join(t.TempDir(), "diagnoses.db")))
+	if err != nil {
+		t.Fatalf("sql.Open() error=%v, but no error expected", err)
+	}
+	defer db.Close()
+
+	// the schema as it was before diagnoses were versioned
+	_, err = db.Exec(`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at INTEGER NOT NULL DEFAULT 0)`)
+	if err != nil {
+		t.Fatalf("creating schema_migrations error=%v, but no error expected", err)
+	}
+	migrations, _ := loadMigrations()
+	for _, m := range migrations {
+		if m.version < 7 {
+			if err := apply(db, m); err != nil {
+				t.Fatalf("apply(%s) error=%v, but no error expected", m.name, err)
+			}
+		}
+	}
+
+	patientID, diagnosisID := uuid.New(), uuid.New()
+	inserts := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO patients (id, legal_id, name) VALUES (?, 'ABC1234', 'John Doe')`, []any{patientID.String()}},
+		{`INSERT INTO diagnoses (id, patient_id, description, prescription, created_at, practitioner_id)
+			VALUES (?, ?, 'migraine', 'take with food', 1000, 'practitioner-1')`, []any{diagnosisID.String(), patientID.String()}},
+		{`INSERT INTO prescription_medications (diagnosis_id, line, drug_name, dose, dose_unit, route, frequency_times,
+			frequency_period, frequency_unit, duration_value, duration_unit, quantity, refills)
+			VALUES (?, 1, 'Ibuprofen', 400, 'mg', 'oral', 1, 8, 'hour', 5, 'day', 15, 0)`, []any{diagnosisID.String()}},
+	}
+	for _, insert := range inserts {
+		if _, err := db.Exec(insert.query, insert.args...); err != nil {
+			t.Fatalf("inserting legacy diagnosis error=%v, but no error expected", err)
+		}
+	}
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate() error=%v, but no error expected", err)
+	}
+
+	repo := &Repository{db: db, q: db}
+	history, err := repo.GetDiagnosisHistory(ctx, diagnosisID)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("GetDiagnosisHistory() got=(%v, %v), expected the legacy diagnosis as first version", history, err)
+	}
+	current, _ := repo.GetDiagnosisByID(ctx, diagnosisID)
+	if !reflect.DeepEqual(history[0], current) {
+		t.Errorf("got first version=%+v, expected the current version=%+v", history[0], current)
+	}
+	if current.Version != 1 || current.Status != diagnoses.StatusFinal || current.RecordedBy != "practitioner-1" ||
+		!current.RecordedAt.Equal(current.CreatedAt) || len(current.Prescription.Medications) != 1 {
+		t.Errorf("got diagnosis=%+v, expected a final first version recorded when it was created", current)
+	}
+}
+
+func openTestRepository(t *testing.T, path string) *Repository {
+	t.Helper()
+	repo, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error=%v, but no error expected", err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+	return repo
+}
@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+)
+
+// Do implements unitofwork.UnitOfWork on a database transaction. Since the pool holds a single connection,
+// fn must only use the repositories it receives.
+func (r *Repository) Do(ctx context.Context, fn func(repos unitofwork.Repositories) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	txRepo := &Repository{db: r.db, q: tx}
+	if err := fn(unitofwork.Repositories{Patients: txRepo, Diagnoses: txRepo}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testAppendAuditRecords(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patientID := uuid.New()
+	first := mustAppend(t, repo, newAuditRecord("practitioner-1", &patientID, audit.OutcomeSuccess))
+	second := mustAppend(t, repo, newAuditRecord("nurse-1", nil, audit.OutcomeDenied))
+
+	if first.Sequence != 1 || first.PrevHash != "" || second.Sequence != 2 || second.PrevHash != first.Hash {
+		t.Fatalf("got records %+v and %+v, expected them to be chained", first, second)
+	}
+
+	records, err := repo.Find(ctx, audit.Filter{})
+	if err != nil {
+		t.Fatalf("Find() error=%v, but no error expected", err)
+	}
+	if !reflect.DeepEqual(records, []audit.Record{first, second}) {
+		t.Fatalf("Find() got=%+v, expected the appended records", records)
+	}
+
+	if err := audit.Verify(records); err != nil {
+		t.Errorf("Verify() error=%v, but no error expected", err)
+	}
+
+	tampered := append([]audit.Record{}, records...)
+	tampered[0].ActorID = "someone-else"
+	if err := audit.Verify(tampered); !errors.Is(err, audit.ErrBrokenChain) {
+		t.Errorf("Verify() of a changed record error=%v, expected=%v", err, audit.ErrBrokenChain)
+	}
+
+	if err := audit.Verify(records[1:]); !errors.Is(err, audit.ErrBrokenChain) {
+		t.Errorf("Verify() without the first record error=%v, expected=%v", err, audit.ErrBrokenChain)
+	}
+}
+
+func testFindAuditRecords(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	johnID, janeID := uuid.New(), uuid.New()
+	mustAppend(t, repo, newAuditRecord("practitioner-1", &johnID, audit.OutcomeSuccess))
+	mustAppend(t, repo, newAuditRecord("practitioner-2", &janeID, audit.OutcomeSuccess))
+	mustAppend(t, repo, newAuditRecord("practitioner-1", &janeID, audit.OutcomeFailure))
+	mustAppend(t, repo, newAuditRecord("practitioner-1", nil, audit.OutcomeSuccess))
+
+	tests := []struct {
+		name          string
+		filter        audit.Filter
+		wantSequences []uint64
+	}{
+		{name: "by patient", filter: audit.Filter{PatientID: &janeID}, wantSequences: []uint64{2, 3}},
+		{name: "by actor", filter: audit.Filter{ActorID: "practitioner-1"}, wantSequences: []uint64{1, 3, 4}},
+		{name: "by patient and actor", filter: audit.Filter{PatientID: &janeID, ActorID: "practitioner-1"}, wantSequences: []uint64{3}},
+		{name: "unknown actor", filter: audit.Filter{ActorID: "nobody"}, wantSequences: []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := repo.Find(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Find() error=%v, but no error expected", err)
+			}
+
+			sequences := make([]uint64, 0, len(records))
+			for _, record := range records {
+				sequences = append(sequences, record.Sequence)
+			}
+			if len(sequences) != len(tt.wantSequences) {
+				t.Fatalf("Find() got sequences %v, expected %v", sequences, tt.wantSequences)
+			}
+			for i := range sequences {
+				if sequences[i] != tt.wantSequences[i] {
+					t.Fatalf("Find() got sequences %v, expected %v", sequences, tt.wantSequences)
+				}
+			}
+		})
+	}
+}
+
+func newAuditRecord(actorID string, patientID *uuid.UUID, outcome audit.Outcome) audit.Record {
+	return audit.Record{
+		Timestamp: time.Now().UTC(),
+		ActorID:   actorID,
+		Action:    audit.ActionReadDiagnoses,
+		PatientID: patientID,
+		Outcome:   outcome,
+		RequestID: uuid.NewString(),
+	}
+}
+
+func mustAppend(t *testing.T, repo Repository, record audit.Record) audit.Record {
+	t.Helper()
+	ctx := context.Background()
+	appended, err := repo.Append(ctx, record)
+	if err != nil {
+		t.Fatalf("Append() error=%v, but no error expected", err)
+	}
+	return appended
+}
@@ -0,0 +1,102 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"testing"
+	"time"
+)
+
+func testCancelledContext(t *testing.T, repo Repository) {
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	operations := []struct {
+		name string
+		run  func() error
+	}{
+		{"Create", func() error { return repo.Create(ctx, patient) }},
+		{"GetByID", func() error { _, err := repo.GetByID(ctx, patient.ID); return err }},
+		{"List", func() error { _, err := repo.List(ctx); return err }},
+		{"AddDiagnosis", func() error { return repo.AddDiagnosis(ctx, newDiagnosis(patient.ID, "asthma", time.Now())) }},
+		{"GetDiagnoses", func() error {
+			_, err := repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patient.ID}, diagnoses.PageRequest{})
+			return err
+		}},
+		{"Append", func() error {
+			_, err := repo.Append(ctx, newAuditRecord("practitioner-1", &patient.ID, audit.OutcomeSuccess))
+			return err
+		}},
+		{"Find", func() error { _, err := repo.Find(ctx, audit.Filter{}); return err }},
+		{"Reserve", func() error {
+			_, err := repo.Reserve(ctx, idempotency.Record{Key: "practitioner-1:key-1", ExpiresAt: time.Now().Add(time.Minute)},
+				time.Now())
+			return err
+		}},
+		{"Do", func() error {
+			return repo.Do(ctx, func(repos unitofwork.Repositories) error { return repos.Patients.Create(ctx, patient) })
+		}},
+	}
+	for _, operation := range operations {
+		if err := operation.run(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s() error=%v, expected=%v", operation.name, err, context.Canceled)
+		}
+	}
+
+	assertNotStored(t, repo, patient.ID)
+}
+
+func testCancelledUnitOfWork(t *testing.T, repo Repository) {
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the request is cancelled once the writes are done, before the unit of work commits them
+	err := repo.Do(ctx, func(repos unitofwork.Repositories) error {
+		if err := repos.Patients.Create(ctx, patient); err != nil {
+			return err
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do() error=%v, expected=%v", err, context.Canceled)
+	}
+
+	assertNotStored(t, repo, patient.ID)
+}
+
+func testExpiredDeadline(t *testing.T, repo Repository) {
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	if got, err := repo.GetByID(ctx, patient.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetByID() got=(%v, %v), expected=%v", got, err, context.DeadlineExceeded)
+	}
+
+	updated := patient
+	updated.Email = "expired@example.com"
+	err := repo.Do(ctx, func(repos unitofwork.Repositories) error { return repos.Patients.Update(ctx, updated) })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error=%v, expected=%v", err, context.DeadlineExceeded)
+	}
+
+	got, err := repo.GetByID(context.Background(), patient.ID)
+	assertPatient(t, "GetByID", patient, got, err)
+}
+
+func assertNotStored(t *testing.T, repo Repository, patientID uuid.UUID) {
+	t.Helper()
+	got, err := repo.GetByID(context.Background(), patientID)
+	if got != nil || err != nil {
+		t.Errorf("GetByID() got=(%v, %v), expected the patient not to be stored", got, err)
+	}
+}
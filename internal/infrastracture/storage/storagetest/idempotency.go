@@ -0,0 +1,60 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testIdempotencyKeys(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	pending := idempotency.Record{Key: "practitioner-1:key-1", Fingerprint: "fingerprint-1", ExpiresAt: now.Add(time.Minute)}
+	if existing, err := repo.Reserve(ctx, pending, now); existing != nil || err != nil {
+		t.Fatalf("Reserve() got=(%v, %v), expected=(<nil>, <nil>)", existing, err)
+	}
+
+	// a request sent while the first one is processed finds it pending
+	existing, err := repo.Reserve(ctx, idempotency.Record{Key: pending.Key, Fingerprint: "fingerprint-2",
+		ExpiresAt: now.Add(2 * time.Minute)}, now)
+	if err != nil || existing == nil || !reflect.DeepEqual(*existing, pending) {
+		t.Fatalf("Reserve() got=(%v, %v), expected the pending record", existing, err)
+	}
+
+	response := idempotency.Response{
+		StatusCode: 201,
+		Header:     map[string]string{"Content-Type": "application/json", "Location": "/api/v1/diagnoses/1"},
+		Body:       []byte(`{"id":"1"}`),
+	}
+	if err := repo.Complete(ctx, pending.Key, response, now.Add(time.Hour)); err != nil {
+		t.Fatalf("Complete() error=%v, but no error expected", err)
+	}
+
+	completed := pending
+	completed.Response = &response
+	completed.ExpiresAt = now.Add(time.Hour)
+	existing, err = repo.Reserve(ctx, pending, now.Add(30*time.Minute))
+	if err != nil || existing == nil || !reflect.DeepEqual(*existing, completed) {
+		t.Fatalf("Reserve() got=(%+v, %v), expected=%+v", existing, err, completed)
+	}
+
+	// other clients have keys of their own
+	other := idempotency.Record{Key: "practitioner-2:key-1", Fingerprint: "fingerprint-1", ExpiresAt: now.Add(time.Minute)}
+	if existing, err := repo.Reserve(ctx, other, now); existing != nil || err != nil {
+		t.Errorf("Reserve() of another key got=(%v, %v), expected=(<nil>, <nil>)", existing, err)
+	}
+
+	// the key can be reserved again once expired
+	if existing, err := repo.Reserve(ctx, pending, now.Add(time.Hour)); existing != nil || err != nil {
+		t.Errorf("Reserve() of an expired key got=(%v, %v), expected=(<nil>, <nil>)", existing, err)
+	}
+
+	if err := repo.Release(ctx, pending.Key); err != nil {
+		t.Fatalf("Release() error=%v, but no error expected", err)
+	}
+	if existing, err := repo.Reserve(ctx, pending, now.Add(time.Hour)); existing != nil || err != nil {
+		t.Errorf("Reserve() of a released key got=(%v, %v), expected=(<nil>, <nil>)", existing, err)
+	}
+}
@@ -0,0 +1,642 @@
+// Package storagetest holds the behavioral tests every storage backend must pass, so they are interchangeable.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/idempotency"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type Repository interface {
+	patients.Repository
+	diagnoses.Repository
+	unitofwork.UnitOfWork
+	audit.Repository
+	idempotency.Store
+}
+
+// RunRepositoryTests runs the behavioral tests against the repositories returned by newRepository,
+// which must return an empty repository, or one holding only seed data, on each call.
+func RunRepositoryTests(t *testing.T, newRepository func(t *testing.T) Repository) {
+	t.Run("create and get patient", func(t *testing.T) {
+		testCreateAndGetPatient(t, newRepository(t))
+	})
+	t.Run("reject duplicated legal ID", func(t *testing.T) {
+		testDuplicatedLegalID(t, newRepository(t))
+	})
+	t.Run("reject duplicated patient ID", func(t *testing.T) {
+		testDuplicatedPatientID(t, newRepository(t))
+	})
+	t.Run("find patients sharing a name", func(t *testing.T) {
+		testFindByName(t, newRepository(t))
+	})
+	t.Run("list patients sorted by name", func(t *testing.T) {
+		testListPatients(t, newRepository(t))
+	})
+	t.Run("update patient contact", func(t *testing.T) {
+		testUpdatePatient(t, newRepository(t))
+	})
+	t.Run("reject stale patient updates", func(t *testing.T) {
+		testStalePatientUpdate(t, newRepository(t))
+	})
+	t.Run("update patient identity", func(t *testing.T) {
+		testUpdatePatientIdentity(t, newRepository(t))
+	})
+	t.Run("add diagnosis to patient", func(t *testing.T) {
+		testAddDiagnosis(t, newRepository(t))
+	})
+	t.Run("reject duplicated diagnosis ID", func(t *testing.T) {
+		testDuplicatedDiagnosisID(t, newRepository(t))
+	})
+	t.Run("keep every diagnosis version", func(t *testing.T) {
+		testDiagnosisVersions(t, newRepository(t))
+	})
+	t.Run("filter diagnoses", func(t *testing.T) {
+		testGetDiagnoses(t, newRepository(t))
+	})
+	t.Run("paginate diagnoses", func(t *testing.T) {
+		testPaginateDiagnoses(t, newRepository(t))
+	})
+	t.Run("commit unit of work", func(t *testing.T) {
+		testUnitOfWorkCommit(t, newRepository(t))
+	})
+	t.Run("roll back unit of work when the second write fails", func(t *testing.T) {
+		testUnitOfWorkRollback(t, newRepository(t))
+	})
+	t.Run("roll back unit of work on panic", func(t *testing.T) {
+		testUnitOfWorkPanic(t, newRepository(t))
+	})
+	t.Run("roll back diagnosis version", func(t *testing.T) {
+		testUpdateDiagnosisRollback(t, newRepository(t))
+	})
+	t.Run("roll back add patient diagnosis command", func(t *testing.T) {
+		testAddPatientDiagnosisRollback(t, newRepository(t))
+	})
+	t.Run("roll back add patient diagnoses command when the second diagnosis fails", func(t *testing.T) {
+		testAddPatientDiagnosesRollback(t, newRepository(t))
+	})
+	t.Run("append audit records to the chain", func(t *testing.T) {
+		testAppendAuditRecords(t, newRepository(t))
+	})
+	t.Run("find audit records", func(t *testing.T) {
+		testFindAuditRecords(t, newRepository(t))
+	})
+	t.Run("reserve and replay idempotency keys", func(t *testing.T) {
+		testIdempotencyKeys(t, newRepository(t))
+	})
+	t.Run("fail when the context is cancelled", func(t *testing.T) {
+		testCancelledContext(t, newRepository(t))
+	})
+	t.Run("roll back unit of work when the context is cancelled", func(t *testing.T) {
+		testCancelledUnitOfWork(t, newRepository(t))
+	})
+	t.Run("fail when the deadline is exceeded", func(t *testing.T) {
+		testExpiredDeadline(t, newRepository(t))
+	})
+}
+
+func NewPatient(legalID, name string) patients.Patient {
+	return patients.Patient{
+		ID:      uuid.New(),
+		LegalID: legalID,
+		Name:    name,
+		Address: "Wall Street 123",
+		Phone:   "123456789",
+		Email:   "patient@example.com",
+		Version: 1,
+	}
+}
+
+func testCreateAndGetPatient(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	birthDate := time.Date(1980, 1, 31, 0, 0, 0, 0, time.UTC)
+	patient.BirthDate = &birthDate
+	mustCreate(t, repo, patient)
+
+	byID, err := repo.GetByID(ctx, patient.ID)
+	assertPatient(t, "GetByID", patient, byID, err)
+
+	byName, err := repo.FindByName(ctx, patient.Name)
+	if len(byName) != 1 {
+		t.Fatalf("FindByName() got %d patients, expected 1", len(byName))
+	}
+	assertPatient(t, "FindByName", patient, byName[0], err)
+
+	byLegalID, err := repo.GetByLegalID(ctx, patient.LegalID)
+	assertPatient(t, "GetByLegalID", patient, byLegalID, err)
+
+	missing, err := repo.GetByID(ctx, uuid.New())
+	if missing != nil || err != nil {
+		t.Errorf("GetByID() of unknown patient got=(%v, %v), expected=(<nil>, <nil>)", missing, err)
+	}
+
+	missing, err = repo.GetByLegalID(ctx, "unknown")
+	if missing != nil || err != nil {
+		t.Errorf("GetByLegalID() of unknown patient got=(%v, %v), expected=(<nil>, <nil>)", missing, err)
+	}
+}
+
+func testDuplicatedLegalID(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	mustCreate(t, repo, NewPatient("STT0001", "First Patient"))
+
+	err := repo.Create(ctx, NewPatient("STT0001", "Second Patient"))
+	if !errors.Is(err, patients.ErrDuplicatedLegalID) {
+		t.Errorf("Create() error=%v, expected=%v", err, patients.ErrDuplicatedLegalID)
+	}
+}
+
+func testDuplicatedPatientID(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "First Patient")
+	mustCreate(t, repo, patient)
+
+	duplicated := NewPatient("STT0002", "Second Patient")
+	duplicated.ID = patient.ID
+	if err := repo.Create(ctx, duplicated); !errors.Is(err, patients.ErrDuplicatedID) {
+		t.Errorf("Create() error=%v, expected=%v", err, patients.ErrDuplicatedID)
+	}
+
+	got, err := repo.GetByID(ctx, patient.ID)
+	assertPatient(t, "GetByID", patient, got, err)
+}
+
+func testFindByName(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	first := NewPatient("STT0001", "Shared Name")
+	second := NewPatient("STT0002", "Shared Name")
+	mustCreate(t, repo, first)
+	mustCreate(t, repo, second)
+	mustCreate(t, repo, NewPatient("STT0003", "Shared Name Jr"))
+
+	got, err := repo.FindByName(ctx, "Shared Name")
+	if err != nil {
+		t.Fatalf("FindByName() error=%v, but no error expected", err)
+	}
+
+	want := []uuid.UUID{first.ID, second.ID}
+	sort.Slice(want, func(i, j int) bool { return want[i].String() < want[j].String() })
+	var ids []uuid.UUID
+	for _, p := range got {
+		ids = append(ids, p.ID)
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("FindByName() got=%v, expected=%v sorted by ID", ids, want)
+	}
+
+	none, err := repo.FindByName(ctx, "Nobody")
+	if err != nil || len(none) != 0 {
+		t.Errorf("FindByName() of unknown name got=(%v, %v), expected no patients", none, err)
+	}
+}
+
+func testListPatients(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	zoe := NewPatient("STT0001", "Zoe Storage")
+	adam := NewPatient("STT0002", "Adam Storage")
+	mustCreate(t, repo, zoe)
+	mustCreate(t, repo, adam)
+
+	got, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error=%v, but no error expected", err)
+	}
+
+	names := make([]string, 0, len(got))
+	for _, p := range got {
+		names = append(names, p.Name)
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("List() got=%v, expected patients sorted by name", names)
+	}
+	if !contains(names, zoe.Name) || !contains(names, adam.Name) {
+		t.Errorf("List() got=%v, expected it to include %s and %s", names, zoe.Name, adam.Name)
+	}
+}
+
+func testUpdatePatient(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	patient.Address = "Main Street 1"
+	patient.Phone = "987654321"
+	patient.Email = "new.mail@example.com"
+	if err := repo.Update(ctx, patient); err != nil {
+		t.Fatalf("Update() error=%v, but no error expected", err)
+	}
+
+	patient.Version = 2
+	got, err := repo.GetByID(ctx, patient.ID)
+	assertPatient(t, "GetByID", patient, got, err)
+}
+
+func testStalePatientUpdate(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	first := patient
+	first.Email = "first@example.com"
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("Update() error=%v, but no error expected", err)
+	}
+
+	// the second update was made on version 1 too, it would drop the first one
+	second := patient
+	second.Phone = "987654321"
+	if err := repo.Update(ctx, second); !errors.Is(err, patients.ErrConcurrentModification) {
+		t.Errorf("Update() error=%v, expected=%v", err, patients.ErrConcurrentModification)
+	}
+
+	first.Version = 2
+	got, err := repo.GetByID(ctx, patient.ID)
+	assertPatient(t, "GetByID", first, got, err)
+
+	missing := NewPatient("STT0002", "Missing Storage Patient")
+	if err := repo.Update(ctx, missing); !errors.Is(err, patients.ErrConcurrentModification) {
+		t.Errorf("Update() of unknown patient error=%v, expected=%v", err, patients.ErrConcurrentModification)
+	}
+}
+
+func testUpdatePatientIdentity(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	other := NewPatient("STT0002", "Other Storage Patient")
+	mustCreate(t, repo, patient)
+	mustCreate(t, repo, other)
+
+	renamed := patient
+	renamed.LegalID = "STT0003"
+	renamed.Name = "Renamed Storage Patient"
+	if err := repo.Update(ctx, renamed); err != nil {
+		t.Fatalf("Update() error=%v, but no error expected", err)
+	}
+	renamed.Version = 2
+
+	byLegalID, err := repo.GetByLegalID(ctx, "STT0003")
+	assertPatient(t, "GetByLegalID", renamed, byLegalID, err)
+
+	if old, err := repo.GetByLegalID(ctx, patient.LegalID); old != nil || err != nil {
+		t.Errorf("GetByLegalID() of the previous legal ID got=(%v, %v), expected=(<nil>, <nil>)", old, err)
+	}
+
+	if old, err := repo.FindByName(ctx, patient.Name); len(old) != 0 || err != nil {
+		t.Errorf("FindByName() of the previous name got=(%v, %v), expected no patients", old, err)
+	}
+
+	duplicated := renamed
+	duplicated.LegalID = other.LegalID
+	if err := repo.Update(ctx, duplicated); !errors.Is(err, patients.ErrDuplicatedLegalID) {
+		t.Errorf("Update() error=%v, expected=%v", err, patients.ErrDuplicatedLegalID)
+	}
+}
+
+func testAddDiagnosis(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	prescription := diagnoses.Prescription{
+		Medications: []diagnoses.Medication{
+			{
+				DrugName:  "Ibuprofen",
+				DrugCode:  "M01AE01",
+				Dose:      400,
+				DoseUnit:  "mg",
+				Route:     diagnoses.RouteOral,
+				Frequency: diagnoses.Frequency{Times: 1, Period: 8, Unit: diagnoses.UnitHour},
+				Duration:  diagnoses.Duration{Value: 5, Unit: diagnoses.UnitDay},
+				Quantity:  15,
+				Refills:   0,
+			},
+			{
+				DrugName:  "Sumatriptan",
+				Dose:      50,
+				DoseUnit:  "mg",
+				Route:     diagnoses.RouteOral,
+				Frequency: diagnoses.Frequency{Times: 1, Period: 1, Unit: diagnoses.UnitDay},
+				Duration:  diagnoses.Duration{Value: 1, Unit: diagnoses.UnitMonth},
+				Quantity:  6,
+				Refills:   2,
+			},
+		},
+		Notes: "take with food",
+	}
+	diagnosis := diagnoses.Diagnosis{
+		ID:             uuid.New(),
+		Description:    "migraine",
+		PatientID:      patient.ID,
+		CreatedAt:      time.Date(2024, 3, 15, 10, 30, 0, 123, time.UTC),
+		Prescription:   &prescription,
+		PractitionerID: "practitioner-1",
+		Coding:         &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "G43.9", Display: "Migraine, unspecified"},
+		Version:        1,
+		Status:         diagnoses.StatusFinal,
+		RecordedAt:     time.Date(2024, 3, 15, 10, 30, 0, 123, time.UTC),
+		RecordedBy:     "practitioner-1",
+	}
+	if err := repo.AddDiagnosis(ctx, diagnosis); err != nil {
+		t.Fatalf("AddDiagnosis() error=%v, but no error expected", err)
+	}
+
+	got, err := repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patient.ID}, diagnoses.PageRequest{})
+	if err != nil {
+		t.Fatalf("GetDiagnoses() error=%v, but no error expected", err)
+	}
+	if len(got.Diagnoses) != 1 {
+		t.Fatalf("got %d diagnoses, expected 1", len(got.Diagnoses))
+	}
+	assertDiagnosis(t, diagnosis, *got.Diagnoses[0])
+}
+
+func testDuplicatedDiagnosisID(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	diagnosis := newDiagnosis(patient.ID, "asthma", time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC))
+	if err := repo.AddDiagnosis(ctx, diagnosis); err != nil {
+		t.Fatalf("AddDiagnosis() error=%v, but no error expected", err)
+	}
+
+	duplicated := newDiagnosis(patient.ID, "migraine", diagnosis.CreatedAt.Add(time.Hour))
+	duplicated.ID = diagnosis.ID
+	if err := repo.AddDiagnosis(ctx, duplicated); !errors.Is(err, diagnoses.ErrDuplicatedID) {
+		t.Errorf("AddDiagnosis() error=%v, expected=%v", err, diagnoses.ErrDuplicatedID)
+	}
+
+	got, err := repo.GetDiagnosisByID(ctx, diagnosis.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetDiagnosisByID() got=(%v, %v), expected the diagnosis", got, err)
+	}
+	assertDiagnosis(t, diagnosis, *got)
+
+	history, err := repo.GetDiagnosisHistory(ctx, diagnosis.ID)
+	if err != nil || len(history) != 1 {
+		t.Errorf("GetDiagnosisHistory() got=(%v, %v), expected 1 version", history, err)
+	}
+}
+
+func testDiagnosisVersions(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	first := newDiagnosis(patient.ID, "asthma", time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC))
+	first.Prescription = &diagnoses.Prescription{Medications: []diagnoses.Medication{{
+		DrugName:  "Salbutamol",
+		Dose:      100,
+		DoseUnit:  "mcg",
+		Route:     diagnoses.RouteInhalation,
+		Frequency: diagnoses.Frequency{Times: 2, Period: 1, Unit: diagnoses.UnitDay},
+		Duration:  diagnoses.Duration{Value: 1, Unit: diagnoses.UnitMonth},
+		Quantity:  1,
+	}}}
+	if err := repo.AddDiagnosis(ctx, first); err != nil {
+		t.Fatalf("AddDiagnosis() error=%v, but no error expected", err)
+	}
+
+	amended, err := first.Amend("allergic asthma", &diagnoses.Prescription{Notes: "avoid pollen"},
+		&diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0", Display: "Predominantly allergic asthma"},
+		diagnoses.Revision{AuthorID: "practitioner-2", At: first.CreatedAt.Add(time.Hour), Reason: "allergy test results"})
+	if err != nil {
+		t.Fatalf("Amend() error=%v, but no error expected", err)
+	}
+	if err := repo.UpdateDiagnosis(ctx, amended); err != nil {
+		t.Fatalf("UpdateDiagnosis() error=%v, but no error expected", err)
+	}
+
+	got, err := repo.GetDiagnosisByID(ctx, first.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetDiagnosisByID() got=(%v, %v), expected the diagnosis", got, err)
+	}
+	assertDiagnosis(t, amended, *got)
+	assertDiagnosesCount(t, repo, patient.ID, 1)
+
+	retracted, err := amended.EnterInError(diagnoses.Revision{AuthorID: "practitioner-1", At: first.CreatedAt.Add(2 * time.Hour),
+		Reason: "wrong patient"})
+	if err != nil {
+		t.Fatalf("EnterInError() error=%v, but no error expected", err)
+	}
+	if err := repo.UpdateDiagnosis(ctx, retracted); err != nil {
+		t.Fatalf("UpdateDiagnosis() error=%v, but no error expected", err)
+	}
+
+	// the retracted diagnosis is only read by ID and through its history
+	got, err = repo.GetDiagnosisByID(ctx, first.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetDiagnosisByID() got=(%v, %v), expected the diagnosis", got, err)
+	}
+	assertDiagnosis(t, retracted, *got)
+	assertDiagnosesCount(t, repo, patient.ID, 0)
+
+	history, err := repo.GetDiagnosisHistory(ctx, first.ID)
+	if err != nil || len(history) != 3 {
+		t.Fatalf("GetDiagnosisHistory() got=(%v, %v), expected 3 versions", history, err)
+	}
+	for i, want := range []diagnoses.Diagnosis{first, amended, retracted} {
+		assertDiagnosis(t, want, *history[i])
+	}
+
+	missing, err := repo.GetDiagnosisByID(ctx, uuid.New())
+	if missing != nil || err != nil {
+		t.Errorf("GetDiagnosisByID() of unknown diagnosis got=(%v, %v), expected=(<nil>, <nil>)", missing, err)
+	}
+
+	none, err := repo.GetDiagnosisHistory(ctx, uuid.New())
+	if none == nil || len(none) != 0 || err != nil {
+		t.Errorf("GetDiagnosisHistory() of unknown diagnosis got=(%v, %v), expected no versions", none, err)
+	}
+}
+
+func testGetDiagnoses(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	other := NewPatient("STT0002", "Other Storage Patient")
+	mustCreate(t, repo, patient)
+	mustCreate(t, repo, other)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+	april := newDiagnosis(patient.ID, "april", time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC))
+	otherMarch := newDiagnosis(other.ID, "other march", time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC))
+	march := newDiagnosis(patient.ID, "march", time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC))
+	onFrom := newDiagnosis(patient.ID, "on from", from)
+	onFrom.Prescription = &diagnoses.Prescription{Notes: "paracetamol 500mg every 8 hours"}
+	march.Coding = &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45.0", Display: "Predominantly allergic asthma"}
+	otherMarch.Coding = &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J45", Display: "Asthma"}
+	april.Coding = &diagnoses.Coding{System: diagnoses.SystemICD10, Code: "J40", Display: "Bronchitis, not specified as acute or chronic"}
+	for _, d := range []diagnoses.Diagnosis{april, otherMarch, march, onFrom} {
+		if err := repo.AddDiagnosis(ctx, d); err != nil {
+			t.Fatalf("AddDiagnosis() error=%v, but no error expected", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter diagnoses.Filter
+		want   []diagnoses.Diagnosis
+	}{
+		{name: "without filters", filter: diagnoses.Filter{}, want: []diagnoses.Diagnosis{onFrom, otherMarch, march, april}},
+		{name: "by date range across patients", filter: diagnoses.Filter{From: &from, To: &to}, want: []diagnoses.Diagnosis{onFrom, otherMarch, march}},
+		{name: "by patient", filter: diagnoses.Filter{PatientID: &other.ID}, want: []diagnoses.Diagnosis{otherMarch}},
+		{name: "by patient and date", filter: diagnoses.Filter{PatientID: &patient.ID, From: &march.CreatedAt}, want: []diagnoses.Diagnosis{march, april}},
+		{name: "without matches", filter: diagnoses.Filter{PatientID: &other.ID, From: &april.CreatedAt}, want: []diagnoses.Diagnosis{}},
+		{name: "by code", filter: diagnoses.Filter{Code: "J45"}, want: []diagnoses.Diagnosis{otherMarch}},
+		{name: "by code prefix", filter: diagnoses.Filter{CodePrefix: "J45"}, want: []diagnoses.Diagnosis{otherMarch, march}},
+		{name: "with prescription", filter: diagnoses.Filter{HasPrescription: true}, want: []diagnoses.Diagnosis{onFrom}},
+		{name: "by chapter prefix and patient", filter: diagnoses.Filter{PatientID: &patient.ID, CodePrefix: "J4"}, want: []diagnoses.Diagnosis{march, april}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetDiagnoses(ctx, tt.filter, diagnoses.PageRequest{})
+			if err != nil {
+				t.Fatalf("GetDiagnoses() error=%v, but no error expected", err)
+			}
+			if got.Diagnoses == nil || len(got.Diagnoses) != len(tt.want) || got.Total != len(tt.want) {
+				t.Fatalf("GetDiagnoses() got %d diagnoses of %d, expected %d", len(got.Diagnoses), got.Total, len(tt.want))
+			}
+			if got.Next != nil {
+				t.Errorf("GetDiagnoses() got next cursor=%v, expected <nil>", got.Next)
+			}
+			for i := range tt.want {
+				assertDiagnosis(t, tt.want[i], *got.Diagnoses[i])
+			}
+		})
+	}
+}
+
+func testPaginateDiagnoses(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	// two diagnoses share the creation date to check that ties are broken by ID
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	want := []diagnoses.Diagnosis{
+		newDiagnosis(patient.ID, "first", createdAt),
+		newDiagnosis(patient.ID, "second", createdAt.Add(time.Hour)),
+		newDiagnosis(patient.ID, "third", createdAt.Add(time.Hour)),
+		newDiagnosis(patient.ID, "fourth", createdAt.Add(2*time.Hour)),
+		newDiagnosis(patient.ID, "fifth", createdAt.Add(3*time.Hour)),
+	}
+	sort.Slice(want, func(i, j int) bool { return diagnoses.Less(want[i], want[j]) })
+	for _, d := range want {
+		if err := repo.AddDiagnosis(ctx, d); err != nil {
+			t.Fatalf("AddDiagnosis() error=%v, but no error expected", err)
+		}
+	}
+
+	reversed := make([]diagnoses.Diagnosis, len(want))
+	for i := range want {
+		reversed[len(want)-1-i] = want[i]
+	}
+
+	for _, order := range []diagnoses.SortOrder{diagnoses.SortAscending, diagnoses.SortDescending} {
+		t.Run(string(order), func(t *testing.T) {
+			expected := want
+			if order == diagnoses.SortDescending {
+				expected = reversed
+			}
+
+			var got []diagnoses.Diagnosis
+			page := diagnoses.PageRequest{Limit: 2, Order: order}
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatalf("pagination didn't finish after %d pages", pages)
+				}
+
+				result, err := repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patient.ID}, page)
+				if err != nil {
+					t.Fatalf("GetDiagnoses() error=%v, but no error expected", err)
+				}
+				if result.Total != len(want) {
+					t.Errorf("GetDiagnoses() total=%d, expected %d", result.Total, len(want))
+				}
+				for _, d := range result.Diagnoses {
+					got = append(got, *d)
+				}
+				if result.Next == nil {
+					break
+				}
+				page.After = result.Next
+			}
+
+			if len(got) != len(expected) {
+				t.Fatalf("got %d diagnoses across pages, expected %d", len(got), len(expected))
+			}
+			for i := range expected {
+				assertDiagnosis(t, expected[i], got[i])
+			}
+		})
+	}
+}
+
+func newDiagnosis(patientID uuid.UUID, description string, createdAt time.Time) diagnoses.Diagnosis {
+	return diagnoses.Diagnosis{
+		ID:          uuid.New(),
+		Description: description,
+		PatientID:   patientID,
+		CreatedAt:   createdAt,
+		Version:     1,
+		Status:      diagnoses.StatusFinal,
+		RecordedAt:  createdAt,
+	}
+}
+
+func mustCreate(t *testing.T, repo Repository, patient patients.Patient) {
+	t.Helper()
+	ctx := context.Background()
+	if err := repo.Create(ctx, patient); err != nil {
+		t.Fatalf("Create() error=%v, but no error expected", err)
+	}
+}
+
+func assertPatient(t *testing.T, method string, want patients.Patient, got *patients.Patient, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s() error=%v, but no error expected", method, err)
+	}
+	if got == nil {
+		t.Fatalf("%s() got <nil>, but a patient was expected", method)
+	}
+	sameBirthDate := (want.BirthDate == nil && got.BirthDate == nil) ||
+		(want.BirthDate != nil && got.BirthDate != nil && want.BirthDate.Equal(*got.BirthDate))
+	if got.ID != want.ID || got.LegalID != want.LegalID || got.Name != want.Name || !sameBirthDate ||
+		got.Address != want.Address || got.Phone != want.Phone || got.Email != want.Email || got.Version != want.Version {
+		t.Errorf("%s() got=%+v, expected=%+v", method, *got, want)
+	}
+}
+
+func assertDiagnosis(t *testing.T, want diagnoses.Diagnosis, got diagnoses.Diagnosis) {
+	t.Helper()
+	samePrescription := reflect.DeepEqual(want.Prescription, got.Prescription)
+	sameCoding := (want.Coding == nil && got.Coding == nil) ||
+		(want.Coding != nil && got.Coding != nil && *want.Coding == *got.Coding)
+	if got.ID != want.ID || got.PatientID != want.PatientID || got.Description != want.Description ||
+		!got.CreatedAt.Equal(want.CreatedAt) || !samePrescription || got.PractitionerID != want.PractitionerID ||
+		!sameCoding || got.Version != want.Version || got.Status != want.Status ||
+		!got.RecordedAt.Equal(want.RecordedAt) || got.RecordedBy != want.RecordedBy || got.Reason != want.Reason {
+		t.Errorf("got diagnosis=%+v, expected=%+v", got, want)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
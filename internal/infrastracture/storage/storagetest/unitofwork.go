@@ -0,0 +1,210 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auditing"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/auth"
+	"github.com/juanmabaracat/diagnosis-service/internal/app/diagnoses/commands"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/audit"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/codes"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/diagnoses"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/patients"
+	"github.com/juanmabaracat/diagnosis-service/internal/domain/unitofwork"
+	"testing"
+	"time"
+)
+
+var errInjected = errors.New("injected failure")
+
+type failingDiagnoses struct {
+	diagnoses.Repository
+}
+
+func (f failingDiagnoses) AddDiagnosis(context.Context, diagnoses.Diagnosis) error {
+	return errInjected
+}
+
+type failingUnitOfWork struct {
+	unitofwork.UnitOfWork
+}
+
+func (f failingUnitOfWork) Do(ctx context.Context, fn func(repos unitofwork.Repositories) error) error {
+	return f.UnitOfWork.Do(ctx, func(repos unitofwork.Repositories) error {
+		repos.Diagnoses = failingDiagnoses{repos.Diagnoses}
+		return fn(repos)
+	})
+}
+
+func testUnitOfWorkCommit(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	diagnosis := newDiagnosis(patient.ID, "committed", time.Now())
+
+	err := repo.Do(ctx, func(repos unitofwork.Repositories) error {
+		if err := repos.Patients.Create(ctx, patient); err != nil {
+			return err
+		}
+		return repos.Diagnoses.AddDiagnosis(ctx, diagnosis)
+	})
+	if err != nil {
+		t.Fatalf("Do() error=%v, but no error expected", err)
+	}
+
+	got, _ := repo.GetByID(ctx, patient.ID)
+	if got == nil {
+		t.Errorf("got <nil>, expected the patient created in the unit of work")
+	}
+	assertDiagnosesCount(t, repo, patient.ID, 1)
+}
+
+func testUnitOfWorkRollback(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	err := failingUnitOfWork{repo}.Do(ctx, func(repos unitofwork.Repositories) error {
+		updated := patient
+		updated.Email = "rolled.back@example.com"
+		if err := repos.Patients.Update(ctx, updated); err != nil {
+			return err
+		}
+		return repos.Diagnoses.AddDiagnosis(ctx, newDiagnosis(patient.ID, "rolled back", time.Now()))
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("Do() error=%v, expected=%v", err, errInjected)
+	}
+
+	got, err := repo.GetByID(ctx, patient.ID)
+	assertPatient(t, "GetByID", patient, got, err)
+	assertDiagnosesCount(t, repo, patient.ID, 0)
+}
+
+func testUnitOfWorkPanic(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Do() should propagate the panic")
+			}
+		}()
+		_ = repo.Do(ctx, func(repos unitofwork.Repositories) error {
+			_ = repos.Patients.Create(ctx, patient)
+			panic("unexpected failure")
+		})
+	}()
+
+	got, err := repo.GetByID(ctx, patient.ID)
+	if got != nil || err != nil {
+		t.Errorf("GetByID() got=(%v, %v), expected the creation to be rolled back", got, err)
+	}
+}
+
+func testUpdateDiagnosisRollback(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+	diagnosis := newDiagnosis(patient.ID, "asthma", time.Now())
+	if err := repo.AddDiagnosis(ctx, diagnosis); err != nil {
+		t.Fatalf("AddDiagnosis() error=%v, but no error expected", err)
+	}
+
+	err := repo.Do(ctx, func(repos unitofwork.Repositories) error {
+		retracted, err := diagnosis.EnterInError(diagnoses.Revision{AuthorID: "practitioner-1", At: time.Now(), Reason: "wrong patient"})
+		if err != nil {
+			return err
+		}
+		if err := repos.Diagnoses.UpdateDiagnosis(ctx, retracted); err != nil {
+			return err
+		}
+		return errInjected
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("Do() error=%v, expected=%v", err, errInjected)
+	}
+
+	got, err := repo.GetDiagnosisByID(ctx, diagnosis.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetDiagnosisByID() got=(%v, %v), expected the diagnosis", got, err)
+	}
+	assertDiagnosis(t, diagnosis, *got)
+	assertDiagnosesCount(t, repo, patient.ID, 1)
+
+	history, err := repo.GetDiagnosisHistory(ctx, diagnosis.ID)
+	if err != nil || len(history) != 1 {
+		t.Errorf("GetDiagnosisHistory() got=(%v, %v), expected the first version only", history, err)
+	}
+}
+
+func testAddPatientDiagnosisRollback(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+
+	handler := commands.NewAddPatientDiagnosisHandler(failingUnitOfWork{repo}, auditing.NewRecorder(repo), &codes.MockCatalog{})
+	_, err := handler.Handle(ctx, commands.AddPatientDiagnosis{
+		PatientID: patient.ID,
+		Diagnosis: "rolled back",
+		Actor:     auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}},
+		RequestID: "request-1",
+	})
+	if !errors.Is(err, commands.ErrAddingDiagnosis) {
+		t.Fatalf("Handle() error=%v, expected=%v", err, commands.ErrAddingDiagnosis)
+	}
+
+	assertDiagnosesCount(t, repo, patient.ID, 0)
+
+	// the failed attempt is still audited
+	records, err := repo.Find(ctx, audit.Filter{PatientID: &patient.ID})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Find() got=(%v, %v), expected a single audit record", records, err)
+	}
+	if records[0].Outcome != audit.OutcomeFailure || records[0].RequestID != "request-1" {
+		t.Errorf("got audit record %+v, expected a failure of request-1", records[0])
+	}
+}
+
+func testAddPatientDiagnosesRollback(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	patient := NewPatient("STT0001", "Storage Test Patient")
+	mustCreate(t, repo, patient)
+	actor := auth.Principal{ID: "practitioner-1", Roles: []auth.Role{auth.RoleClinician}}
+
+	// the patient of the second diagnosis doesn't exist, which is only found once the first one is added
+	handler := commands.NewAddPatientDiagnosesHandler(repo, auditing.NewRecorder(repo), &codes.MockCatalog{})
+	_, err := handler.Handle(ctx, commands.AddPatientDiagnoses{Diagnoses: []commands.AddPatientDiagnosis{
+		{PatientID: patient.ID, Diagnosis: "rolled back", Actor: actor, RequestID: "request-1"},
+		{PatientID: uuid.New(), Diagnosis: "missing patient", Actor: actor, RequestID: "request-1"},
+	}})
+	var diagnosesErrs commands.DiagnosesErrors
+	if !errors.As(err, &diagnosesErrs) || len(diagnosesErrs) != 1 || diagnosesErrs[0].Index != 1 ||
+		!errors.Is(diagnosesErrs[0], patients.ErrPatientNotFound) {
+		t.Fatalf("Handle() error=%v, expected the second diagnosis to fail with %v", err, patients.ErrPatientNotFound)
+	}
+
+	assertDiagnosesCount(t, repo, patient.ID, 0)
+
+	// the diagnosis rolled back is audited as a failure
+	records, err := repo.Find(ctx, audit.Filter{PatientID: &patient.ID})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Find() got=(%v, %v), expected a single audit record", records, err)
+	}
+	if records[0].Outcome != audit.OutcomeFailure {
+		t.Errorf("got audit record %+v, expected a failure", records[0])
+	}
+}
+
+func assertDiagnosesCount(t *testing.T, repo Repository, patientID uuid.UUID, want int) {
+	t.Helper()
+	ctx := context.Background()
+	got, err := repo.GetDiagnoses(ctx, diagnoses.Filter{PatientID: &patientID}, diagnoses.PageRequest{})
+	if err != nil {
+		t.Fatalf("GetDiagnoses() error=%v, but no error expected", err)
+	}
+	if len(got.Diagnoses) != want {
+		t.Errorf("got %d diagnoses, expected %d", len(got.Diagnoses), want)
+	}
+}